@@ -0,0 +1,136 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/bwen19/blog/grpc/pb"
+	"github.com/bwen19/blog/psql/db"
+	"github.com/bwen19/blog/util"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	dateLayout        = "2006-01-02"
+	maxAnalyticsRange = 366
+)
+
+// ========================// GetAnalytics //======================== //
+
+func (server *Server) GetAnalytics(ctx context.Context, req *pb.GetAnalyticsRequest) (*pb.GetAnalyticsResponse, error) {
+	authUser, gErr := server.grpcGuard(ctx, roleAuthor)
+	if gErr != nil {
+		return nil, gErr.GrpcErr()
+	}
+
+	startDay, endDay, err := validateAnalyticsRange(req.GetStartDate(), req.GetEndDate())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if req.GetSiteWide() && authUser.Role != "admin" {
+		return nil, status.Error(codes.PermissionDenied, "only admins can view site-wide analytics")
+	}
+
+	arg := db.ListDailyStatsParams{
+		SiteWide: req.GetSiteWide(),
+		AuthorID: authUser.ID,
+		StartDay: startDay,
+		EndDay:   endDay,
+	}
+
+	stats, err := server.store.ListDailyStats(ctx, arg)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to list daily stats")
+	}
+	return convertGetAnalytics(stats), nil
+}
+
+// validateAnalyticsRange parses an inclusive date range of at most one year
+func validateAnalyticsRange(startDate string, endDate string) (time.Time, time.Time, error) {
+	startDay, err := time.Parse(dateLayout, startDate)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("startDate: must be in the format of %s", dateLayout)
+	}
+	endDay, err := time.Parse(dateLayout, endDate)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("endDate: must be in the format of %s", dateLayout)
+	}
+	if endDay.Before(startDay) {
+		return time.Time{}, time.Time{}, fmt.Errorf("endDate: must not be before startDate")
+	}
+	if endDay.Sub(startDay) > maxAnalyticsRange*24*time.Hour {
+		return time.Time{}, time.Time{}, fmt.Errorf("date range must not exceed %d days", maxAnalyticsRange)
+	}
+	return startDay, endDay, nil
+}
+
+// ========================// GetTopPosts //======================== //
+
+func (server *Server) GetTopPosts(ctx context.Context, req *pb.GetTopPostsRequest) (*pb.GetTopPostsResponse, error) {
+	authUser, gErr := server.grpcGuard(ctx, roleAuthor)
+	if gErr != nil {
+		return nil, gErr.GrpcErr()
+	}
+
+	startDay, endDay, err := validateAnalyticsRange(req.GetStartDate(), req.GetEndDate())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err := util.ValidateNumber(int64(req.GetNum()), 1, 50); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "num: %s", err.Error())
+	}
+	if req.GetSiteWide() && authUser.Role != "admin" {
+		return nil, status.Error(codes.PermissionDenied, "only admins can view site-wide analytics")
+	}
+
+	arg := db.ListTopPostsParams{
+		SiteWide: req.GetSiteWide(),
+		AuthorID: authUser.ID,
+		Num:      req.GetNum(),
+		StartDay: startDay,
+		EndDay:   endDay,
+	}
+
+	posts, err := server.store.ListTopPosts(ctx, arg)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to list top posts")
+	}
+	return convertGetTopPosts(posts), nil
+}
+
+// ========================// GetTrafficSources //======================== //
+
+func (server *Server) GetTrafficSources(ctx context.Context, req *pb.GetTrafficSourcesRequest) (*pb.GetTrafficSourcesResponse, error) {
+	authUser, gErr := server.grpcGuard(ctx, roleAuthor)
+	if gErr != nil {
+		return nil, gErr.GrpcErr()
+	}
+
+	startDay, endDay, err := validateAnalyticsRange(req.GetStartDate(), req.GetEndDate())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err := util.ValidateNumber(int64(req.GetNum()), 1, 50); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "num: %s", err.Error())
+	}
+	if req.GetSiteWide() && authUser.Role != "admin" {
+		return nil, status.Error(codes.PermissionDenied, "only admins can view site-wide analytics")
+	}
+
+	// Sources are only captured for views recorded since referers were tracked
+	arg := db.ListTrafficSourcesParams{
+		StartDay: startDay,
+		EndDay:   endDay,
+		SiteWide: req.GetSiteWide(),
+		AuthorID: authUser.ID,
+		Num:      req.GetNum(),
+	}
+
+	sources, err := server.store.ListTrafficSources(ctx, arg)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to list traffic sources")
+	}
+	return convertGetTrafficSources(sources), nil
+}
@@ -130,7 +130,7 @@ func (server *Server) Login(ctx context.Context, req *pb.LoginRequest) (*pb.Logi
 	}
 
 	rsp := &pb.LoginResponse{
-		User:         convertUser(user),
+		User:         server.userWithVariants(ctx, user),
 		AccessToken:  accessToken,
 		RefreshToken: refreshToken,
 		UnreadCount:  unreadCount,
@@ -195,7 +195,7 @@ func (server *Server) AutoLogin(ctx context.Context, req *pb.AutoLoginRequest) (
 	}
 
 	rsp := &pb.AutoLoginResponse{
-		User:        convertUser(*user),
+		User:        server.userWithVariants(ctx, *user),
 		AccessToken: accessToken,
 		UnreadCount: unreadCount,
 	}
@@ -0,0 +1,270 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/bwen19/blog/grpc/pb"
+	"github.com/bwen19/blog/psql/db"
+	"github.com/bwen19/blog/util"
+	"github.com/lib/pq"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+// ========================// CreateReadingList //======================== //
+
+func (server *Server) CreateReadingList(ctx context.Context, req *pb.CreateReadingListRequest) (*pb.CreateReadingListResponse, error) {
+	authUser, gErr := server.grpcGuard(ctx, roleUser)
+	if gErr != nil {
+		return nil, gErr.GrpcErr()
+	}
+
+	if err := util.ValidateString(req.GetName(), 1, 50); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "name: %s", err.Error())
+	}
+
+	arg := db.CreateReadingListParams{
+		UserID: authUser.ID,
+		Name:   req.GetName(),
+	}
+
+	list, err := server.store.CreateReadingList(ctx, arg)
+	if err != nil {
+		if pgErr, ok := err.(*pq.Error); ok {
+			switch pgErr.Code.Name() {
+			case "unique_violation":
+				return nil, status.Error(codes.AlreadyExists, "reading list already exists")
+			}
+		}
+		return nil, status.Error(codes.Internal, "failed to create reading list")
+	}
+
+	rsp := &pb.CreateReadingListResponse{List: convertReadingList(list, 0)}
+	return rsp, nil
+}
+
+// ========================// RenameReadingList //======================== //
+
+func (server *Server) RenameReadingList(ctx context.Context, req *pb.RenameReadingListRequest) (*emptypb.Empty, error) {
+	authUser, gErr := server.grpcGuard(ctx, roleUser)
+	if gErr != nil {
+		return nil, gErr.GrpcErr()
+	}
+
+	if err := util.ValidateID(req.GetListId()); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "listId: %s", err.Error())
+	}
+	if err := util.ValidateString(req.GetName(), 1, 50); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "name: %s", err.Error())
+	}
+
+	arg := db.UpdateReadingListParams{
+		ID:     req.GetListId(),
+		UserID: authUser.ID,
+		Name:   req.GetName(),
+	}
+
+	if _, err := server.store.UpdateReadingList(ctx, arg); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, status.Error(codes.NotFound, "reading list not found")
+		}
+		if pgErr, ok := err.(*pq.Error); ok {
+			switch pgErr.Code.Name() {
+			case "unique_violation":
+				return nil, status.Error(codes.AlreadyExists, "reading list already exists")
+			}
+		}
+		return nil, status.Error(codes.Internal, "failed to rename reading list")
+	}
+	return &emptypb.Empty{}, nil
+}
+
+// ========================// DeleteReadingList //======================== //
+
+func (server *Server) DeleteReadingList(ctx context.Context, req *pb.DeleteReadingListRequest) (*emptypb.Empty, error) {
+	authUser, gErr := server.grpcGuard(ctx, roleUser)
+	if gErr != nil {
+		return nil, gErr.GrpcErr()
+	}
+
+	if err := util.ValidateID(req.GetListId()); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "listId: %s", err.Error())
+	}
+
+	// Bookmarks in the list are removed along with it
+	arg := db.DeleteReadingListParams{
+		ID:     req.GetListId(),
+		UserID: authUser.ID,
+	}
+
+	nrows, err := server.store.DeleteReadingList(ctx, arg)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to delete reading list")
+	}
+	if nrows == 0 {
+		return nil, status.Error(codes.NotFound, "reading list not found")
+	}
+	return &emptypb.Empty{}, nil
+}
+
+// ========================// ReorderReadingLists //======================== //
+
+func (server *Server) ReorderReadingLists(ctx context.Context, req *pb.ReorderReadingListsRequest) (*emptypb.Empty, error) {
+	authUser, gErr := server.grpcGuard(ctx, roleUser)
+	if gErr != nil {
+		return nil, gErr.GrpcErr()
+	}
+
+	listIDs, err := util.ValidateRepeatedIDs(req.GetListIds())
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "listId: %s", err.Error())
+	}
+
+	lists, err := server.store.ListReadingLists(ctx, authUser.ID)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to list reading lists")
+	}
+
+	// The new order must contain every list of the user exactly once
+	if len(lists) != len(listIDs) {
+		return nil, status.Error(codes.InvalidArgument, "listIds: must contain all reading lists")
+	}
+	dict := map[int64]bool{}
+	for _, list := range lists {
+		dict[list.ID] = true
+	}
+	for _, listID := range listIDs {
+		if !dict[listID] {
+			return nil, status.Errorf(codes.InvalidArgument, "listIds: reading list %d not found", listID)
+		}
+	}
+
+	arg := db.UpdateReadingListPositionsParams{
+		UserID:  authUser.ID,
+		ListIds: listIDs,
+	}
+	if _, err = server.store.UpdateReadingListPositions(ctx, arg); err != nil {
+		return nil, status.Error(codes.Internal, "failed to reorder reading lists")
+	}
+	return &emptypb.Empty{}, nil
+}
+
+// ========================// ListReadingLists //======================== //
+
+func (server *Server) ListReadingLists(ctx context.Context, req *pb.ListReadingListsRequest) (*pb.ListReadingListsResponse, error) {
+	authUser, gErr := server.grpcGuard(ctx, roleUser)
+	if gErr != nil {
+		return nil, gErr.GrpcErr()
+	}
+
+	lists, err := server.store.ListReadingLists(ctx, authUser.ID)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to list reading lists")
+	}
+	return convertListReadingLists(lists), nil
+}
+
+// ========================// BookmarkPost //======================== //
+
+func (server *Server) BookmarkPost(ctx context.Context, req *pb.BookmarkPostRequest) (*emptypb.Empty, error) {
+	authUser, gErr := server.grpcGuard(ctx, roleUser)
+	if gErr != nil {
+		return nil, gErr.GrpcErr()
+	}
+
+	if err := validateBookmarkPostRequest(req); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	// Bookmarking a post again moves it to the given list, the note is
+	// only replaced when a new one is provided
+	arg := db.UpsertBookmarkParams{
+		UserID:  authUser.ID,
+		PostID:  req.GetPostId(),
+		ListID:  req.GetListId(),
+		HasNote: req.Note != nil,
+		Note:    req.GetNote(),
+	}
+
+	if _, err := server.store.UpsertBookmark(ctx, arg); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, status.Error(codes.NotFound, "post or reading list not found")
+		}
+		return nil, status.Error(codes.Internal, "failed to bookmark post")
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func validateBookmarkPostRequest(req *pb.BookmarkPostRequest) error {
+	if err := util.ValidateID(req.GetPostId()); err != nil {
+		return fmt.Errorf("postId: %s", err.Error())
+	}
+	if err := util.ValidateID(req.GetListId()); err != nil {
+		return fmt.Errorf("listId: %s", err.Error())
+	}
+	if req.Note != nil {
+		if err := util.ValidateString(req.GetNote(), 0, 500); err != nil {
+			return fmt.Errorf("note: %s", err.Error())
+		}
+	}
+	return nil
+}
+
+// ========================// DeleteBookmark //======================== //
+
+func (server *Server) DeleteBookmark(ctx context.Context, req *pb.DeleteBookmarkRequest) (*emptypb.Empty, error) {
+	authUser, gErr := server.grpcGuard(ctx, roleUser)
+	if gErr != nil {
+		return nil, gErr.GrpcErr()
+	}
+
+	if err := util.ValidateID(req.GetPostId()); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "postId: %s", err.Error())
+	}
+
+	arg := db.DeleteBookmarkParams{
+		UserID: authUser.ID,
+		PostID: req.GetPostId(),
+	}
+
+	nrows, err := server.store.DeleteBookmark(ctx, arg)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to delete bookmark")
+	}
+	if nrows == 0 {
+		return nil, status.Error(codes.NotFound, "bookmark not found")
+	}
+	return &emptypb.Empty{}, nil
+}
+
+// ========================// ListBookmarks //======================== //
+
+func (server *Server) ListBookmarks(ctx context.Context, req *pb.ListBookmarksRequest) (*pb.ListBookmarksResponse, error) {
+	authUser, gErr := server.grpcGuard(ctx, roleUser)
+	if gErr != nil {
+		return nil, gErr.GrpcErr()
+	}
+
+	if err := util.ValidateID(req.GetListId()); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "listId: %s", err.Error())
+	}
+	if err := util.ValidatePage(req); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	arg := db.ListBookmarksParams{
+		Limit:  req.GetPageSize(),
+		Offset: (req.GetPageId() - 1) * req.GetPageSize(),
+		ListID: req.GetListId(),
+		UserID: authUser.ID,
+	}
+
+	bookmarks, err := server.store.ListBookmarks(ctx, arg)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to list bookmarks")
+	}
+	return convertListBookmarks(bookmarks), nil
+}
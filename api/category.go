@@ -55,6 +55,7 @@ func (server *Server) DeleteCategories(ctx context.Context, req *pb.DeleteCatego
 	if err != nil || len(categoryIDs) != int(nrows) {
 		return nil, status.Error(codes.Internal, "failed to delete categories")
 	}
+	server.related.invalidate()
 
 	return &emptypb.Empty{}, nil
 }
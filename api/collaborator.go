@@ -0,0 +1,193 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+
+	"github.com/bwen19/blog/grpc/pb"
+	"github.com/bwen19/blog/psql/db"
+	"github.com/bwen19/blog/util"
+	"github.com/lib/pq"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+// ========================// InviteCollaborator //======================== //
+
+func (server *Server) InviteCollaborator(ctx context.Context, req *pb.InviteCollaboratorRequest) (*emptypb.Empty, error) {
+	authUser, gErr := server.grpcGuard(ctx, roleAuthor)
+	if gErr != nil {
+		return nil, gErr.GrpcErr()
+	}
+
+	if err := util.ValidateID(req.GetPostId()); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "postId: %s", err.Error())
+	}
+	if err := util.ValidateID(req.GetUserId()); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "userId: %s", err.Error())
+	}
+	if req.GetUserId() == authUser.ID {
+		return nil, status.Error(codes.InvalidArgument, "cannot invite yourself")
+	}
+
+	arg1 := db.GetPostCollaboratorParams{
+		PostID: req.GetPostId(),
+		UserID: authUser.ID,
+	}
+	owner, err := server.store.GetPostCollaborator(ctx, arg1)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, status.Error(codes.PermissionDenied, "no permission to invite collaborators")
+		}
+		return nil, status.Error(codes.Internal, "failed to get post collaborator")
+	}
+	if owner.Role != "owner" {
+		return nil, status.Error(codes.PermissionDenied, "only the owner can invite collaborators")
+	}
+
+	user, err := server.store.GetUser(ctx, req.GetUserId())
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, status.Error(codes.NotFound, "user not found")
+		}
+		return nil, status.Error(codes.Internal, "failed to get user")
+	}
+	if user.Deleted || roleToRank(user.Role) < roleAuthor {
+		return nil, status.Error(codes.FailedPrecondition, "only active authors can be invited")
+	}
+
+	arg2 := db.CreatePostCollaboratorParams{
+		PostID:   req.GetPostId(),
+		UserID:   req.GetUserId(),
+		Role:     "editor",
+		Accepted: false,
+	}
+	if _, err = server.store.CreatePostCollaborator(ctx, arg2); err != nil {
+		if pgErr, ok := err.(*pq.Error); ok {
+			switch pgErr.Code.Name() {
+			case "unique_violation":
+				return nil, status.Error(codes.AlreadyExists, "user has already been invited")
+			}
+		}
+		return nil, status.Error(codes.Internal, "failed to invite collaborator")
+	}
+
+	arg3 := db.CreateNotificationParams{
+		UserID:  req.GetUserId(),
+		Kind:    "system",
+		Title:   "Collaboration invitation",
+		Content: fmt.Sprintf("%s invited you to co-author post %d", authUser.Username, req.GetPostId()),
+	}
+	if err = server.store.CreateNotification(ctx, arg3); err != nil {
+		log.Println("failed to create new notification for inviting collaborator")
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+// ========================// AcceptCollaboration //======================== //
+
+func (server *Server) AcceptCollaboration(ctx context.Context, req *pb.AcceptCollaborationRequest) (*emptypb.Empty, error) {
+	authUser, gErr := server.grpcGuard(ctx, roleAuthor)
+	if gErr != nil {
+		return nil, gErr.GrpcErr()
+	}
+
+	if err := util.ValidateID(req.GetPostId()); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "postId: %s", err.Error())
+	}
+
+	arg := db.AcceptPostCollaboratorParams{
+		PostID: req.GetPostId(),
+		UserID: authUser.ID,
+	}
+	if _, err := server.store.AcceptPostCollaborator(ctx, arg); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, status.Error(codes.NotFound, "invitation not found")
+		}
+		return nil, status.Error(codes.Internal, "failed to accept collaboration")
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+// ========================// RemoveCollaborator //======================== //
+
+func (server *Server) RemoveCollaborator(ctx context.Context, req *pb.RemoveCollaboratorRequest) (*emptypb.Empty, error) {
+	authUser, gErr := server.grpcGuard(ctx, roleAuthor)
+	if gErr != nil {
+		return nil, gErr.GrpcErr()
+	}
+
+	if err := util.ValidateID(req.GetPostId()); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "postId: %s", err.Error())
+	}
+	if err := util.ValidateID(req.GetUserId()); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "userId: %s", err.Error())
+	}
+
+	// Collaborators can leave by themselves, others need to be removed by the owner
+	if req.GetUserId() != authUser.ID && authUser.Role != "admin" {
+		arg := db.GetPostCollaboratorParams{
+			PostID: req.GetPostId(),
+			UserID: authUser.ID,
+		}
+		owner, err := server.store.GetPostCollaborator(ctx, arg)
+		if err != nil && err != sql.ErrNoRows {
+			return nil, status.Error(codes.Internal, "failed to get post collaborator")
+		}
+		if err == sql.ErrNoRows || owner.Role != "owner" {
+			return nil, status.Error(codes.PermissionDenied, "only the owner can remove collaborators")
+		}
+	}
+
+	arg := db.DeletePostCollaboratorParams{
+		PostID: req.GetPostId(),
+		UserID: req.GetUserId(),
+	}
+	nrows, err := server.store.DeletePostCollaborator(ctx, arg)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to remove collaborator")
+	}
+	if nrows == 0 {
+		return nil, status.Error(codes.NotFound, "collaborator not found")
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+// ========================// ListCollaborators //======================== //
+
+func (server *Server) ListCollaborators(ctx context.Context, req *pb.ListCollaboratorsRequest) (*pb.ListCollaboratorsResponse, error) {
+	authUser, gErr := server.grpcGuard(ctx, roleAuthor)
+	if gErr != nil {
+		return nil, gErr.GrpcErr()
+	}
+
+	if err := util.ValidateID(req.GetPostId()); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "postId: %s", err.Error())
+	}
+
+	collaborators, err := server.store.ListPostCollaborators(ctx, req.GetPostId())
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to list collaborators")
+	}
+
+	if authUser.Role != "admin" {
+		allowed := false
+		for _, collaborator := range collaborators {
+			if collaborator.UserID == authUser.ID {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return nil, status.Error(codes.PermissionDenied, "no permission to list collaborators")
+		}
+	}
+
+	return convertListCollaborators(collaborators), nil
+}
@@ -25,6 +25,9 @@ func (server *Server) CreateComment(ctx context.Context, req *pb.CreateCommentRe
 	if err := validateCreateCommentRequest(req); err != nil {
 		return nil, status.Error(codes.InvalidArgument, err.Error())
 	}
+	if err := server.checkPostAccess(ctx, req.GetPostId(), authUser); err != nil {
+		return nil, err
+	}
 
 	arg := db.CreateCommentParams{
 		PostID:      req.GetPostId(),
@@ -113,6 +116,9 @@ func (server *Server) ListComments(ctx context.Context, req *pb.ListCommentsRequ
 	if err := validateListCommentsRequest(req); err != nil {
 		return nil, status.Error(codes.InvalidArgument, err.Error())
 	}
+	if err := server.checkPostAccess(ctx, req.GetPostId(), authUser); err != nil {
+		return nil, err
+	}
 
 	arg := db.ListCommentsParams{
 		Limit:         req.GetPageSize(),
@@ -155,6 +161,17 @@ func (server *Server) ListReplies(ctx context.Context, req *pb.ListRepliesReques
 		return nil, status.Error(codes.InvalidArgument, err.Error())
 	}
 
+	comment, err := server.store.GetComment(ctx, req.GetCommentId())
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, status.Error(codes.NotFound, "comment not found")
+		}
+		return nil, status.Error(codes.Internal, "failed to get comment")
+	}
+	if err = server.checkPostAccess(ctx, comment.PostID, authUser); err != nil {
+		return nil, err
+	}
+
 	arg := db.ListRepliesParams{
 		Limit:         req.GetPageSize(),
 		Offset:        (req.GetPageId() - 1) * req.GetPageSize(),
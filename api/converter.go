@@ -1,6 +1,8 @@
 package api
 
 import (
+	"time"
+
 	"github.com/bwen19/blog/grpc/pb"
 	"github.com/bwen19/blog/psql/db"
 	"google.golang.org/protobuf/types/known/timestamppb"
@@ -8,12 +10,13 @@ import (
 
 func convertUser(user db.User) *pb.User {
 	return &pb.User{
-		Id:       user.ID,
-		Username: user.Username,
-		Email:    user.Email,
-		Avatar:   user.Avatar,
-		Intro:    user.Intro,
-		Role:     user.Role,
+		Id:          user.ID,
+		Username:    user.Username,
+		Email:       user.Email,
+		Avatar:      user.Avatar,
+		Intro:       user.Intro,
+		Role:        user.Role,
+		PublicStars: user.PublicStars,
 	}
 }
 
@@ -245,19 +248,24 @@ func convertNewPost(post db.CreateNewPostRow) *pb.Post {
 		Content:    post.Content,
 		Featured:   post.Featured,
 		Status:     post.Status,
+		Version:    post.Version,
+		Visibility: post.Visibility,
 	}
 }
 
-func convertUpdatePost(post db.Post, content db.PostContent, categories []db.Category, tags []db.Tag) *pb.UpdatePostResponse {
+func convertUpdatePost(result db.EditPostResult) *pb.UpdatePostResponse {
+	post := result.Post
 	pbPost := &pb.Post{
 		Id:         post.ID,
 		Title:      post.Title,
 		CoverImage: post.CoverImage,
-		Content:    content.Content,
-		Categories: convertCategories(categories),
-		Tags:       convertTags(tags),
+		Content:    result.Content,
+		Categories: convertCategories(result.Categories),
+		Tags:       convertTags(result.Tags),
 		Featured:   post.Featured,
 		Status:     post.Status,
+		Version:    post.Version,
+		Visibility: post.Visibility,
 	}
 	return &pb.UpdatePostResponse{Post: pbPost}
 }
@@ -304,6 +312,10 @@ func convertListPosts(posts []db.ListPostsRow) *pb.ListPostsResponse {
 			ViewCount:  post.ViewCount,
 			UpdateAt:   timestamppb.New(post.UpdateAt),
 			PublishAt:  timestamppb.New(post.PublishAt),
+			Visibility: post.Visibility,
+		}
+		if post.DeleteAt.Valid {
+			pbPost.DeleteAt = timestamppb.New(post.DeleteAt.Time)
 		}
 		rspPosts = append(rspPosts, pbPost)
 	}
@@ -334,14 +346,17 @@ func convertGetPost(post db.GetPostRow) *pb.GetPostResponse {
 	}
 
 	pbPost := &pb.Post{
-		Id:         post.ID,
-		Title:      post.Title,
-		CoverImage: post.CoverImage,
-		Content:    post.Content,
-		Categories: categories,
-		Tags:       tags,
-		Featured:   post.Featured,
-		Status:     post.Status,
+		Id:             post.ID,
+		Title:          post.Title,
+		CoverImage:     post.CoverImage,
+		Content:        post.Content,
+		Categories:     categories,
+		Tags:           tags,
+		Featured:       post.Featured,
+		Status:         post.Status,
+		Version:        post.Version,
+		Visibility:     post.Visibility,
+		RevisionStatus: post.RevisionStatus,
 	}
 
 	return &pb.GetPostResponse{Post: pbPost}
@@ -397,15 +412,20 @@ func convertGetPosts(posts []db.GetPostsRow) *pb.GetPostsResponse {
 		}
 
 		pbPost := &pb.GetPostsResponse_PostItem{
-			Id:           post.ID,
-			Title:        post.Title,
-			Author:       author,
-			CoverImage:   post.CoverImage,
-			Tags:         tags,
-			ViewCount:    post.ViewCount,
-			StarCount:    post.StarCount,
-			CommentCount: post.CommentCount,
-			PublishAt:    timestamppb.New(post.PublishAt),
+			Id:             post.ID,
+			Title:          post.Title,
+			Author:         author,
+			CoverImage:     post.CoverImage,
+			Tags:           tags,
+			ViewCount:      post.ViewCount,
+			StarCount:      post.StarCount,
+			CommentCount:   post.CommentCount,
+			PublishAt:      timestamppb.New(post.PublishAt),
+			Authors:        convertAuthors(post.AuthorIds, post.AuthorNames, post.AuthorAvatars),
+			Bookmarked:     post.BookmarkListID.Valid,
+			BookmarkListId: post.BookmarkListID.Int64,
+			Starred:        post.Starred.Valid,
+			Visibility:     post.Visibility,
 		}
 		rspPosts = append(rspPosts, pbPost)
 	}
@@ -446,20 +466,454 @@ func convertReadPost(post db.ReadPostRow) *pb.ReadPostResponse {
 	}
 
 	pbPost := &pb.ReadPostResponse_Post{
-		Id:         post.ID,
-		Title:      post.Title,
-		Author:     author,
-		Content:    post.Content,
-		Categories: categories,
-		Tags:       tags,
-		ViewCount:  post.ViewCount,
-		StarCount:  post.StarCount,
-		PublishAt:  timestamppb.New(post.PublishAt),
+		Id:             post.ID,
+		Title:          post.Title,
+		Author:         author,
+		Content:        post.Content,
+		Categories:     categories,
+		Tags:           tags,
+		ViewCount:      post.ViewCount,
+		StarCount:      post.StarCount,
+		PublishAt:      timestamppb.New(post.PublishAt),
+		Authors:        convertAuthors(post.AuthorIds, post.AuthorNames, post.AuthorAvatars),
+		Bookmarked:     post.BookmarkListID.Valid,
+		BookmarkListId: post.BookmarkListID.Int64,
+		Starred:        post.Starred.Valid,
+		Visibility:     post.Visibility,
 	}
 
 	return &pb.ReadPostResponse{Post: pbPost}
 }
 
+func convertAuthors(ids []int64, names []string, avatars []string) []*pb.UserItem {
+	authors := make([]*pb.UserItem, 0, len(ids))
+	for i, id := range ids {
+		author := &pb.UserItem{
+			Id:       id,
+			Username: names[i],
+			Avatar:   avatars[i],
+		}
+		authors = append(authors, author)
+	}
+	return authors
+}
+
+func convertListCollaborators(collaborators []db.ListPostCollaboratorsRow) *pb.ListCollaboratorsResponse {
+	rspCollaborators := make([]*pb.ListCollaboratorsResponse_CollaboratorItem, 0, len(collaborators))
+	for _, collaborator := range collaborators {
+		user := &pb.UserItem{
+			Id:       collaborator.UserID,
+			Username: collaborator.Username,
+			Avatar:   collaborator.Avatar,
+		}
+		pbCollaborator := &pb.ListCollaboratorsResponse_CollaboratorItem{
+			User:     user,
+			Role:     collaborator.Role,
+			Accepted: collaborator.Accepted,
+			CreateAt: timestamppb.New(collaborator.CreateAt),
+		}
+		rspCollaborators = append(rspCollaborators, pbCollaborator)
+	}
+	return &pb.ListCollaboratorsResponse{Collaborators: rspCollaborators}
+}
+
+func convertSeries(series db.Series, postCount int64) *pb.Series {
+	return &pb.Series{
+		Id:          series.ID,
+		Title:       series.Title,
+		Description: series.Description,
+		CoverImage:  series.CoverImage,
+		PostCount:   postCount,
+		CreateAt:    timestamppb.New(series.CreateAt),
+	}
+}
+
+func convertListSeries(series []db.ListSeriesRow) *pb.ListSeriesResponse {
+	if len(series) == 0 {
+		return &pb.ListSeriesResponse{}
+	}
+
+	rspSeries := make([]*pb.Series, 0, 5)
+	for _, s := range series {
+		pbSeries := &pb.Series{
+			Id:          s.ID,
+			Title:       s.Title,
+			Description: s.Description,
+			CoverImage:  s.CoverImage,
+			PostCount:   s.PostCount,
+			CreateAt:    timestamppb.New(s.CreateAt),
+		}
+		rspSeries = append(rspSeries, pbSeries)
+	}
+
+	return &pb.ListSeriesResponse{
+		Total:  series[0].Total,
+		Series: rspSeries,
+	}
+}
+
+func convertGetSeries(series db.GetSeriesRow, posts []db.ListSeriesPostsRow) *pb.GetSeriesResponse {
+	var postCount int64
+	rspPosts := make([]*pb.GetSeriesResponse_PostItem, 0, len(posts))
+	for _, post := range posts {
+		if post.Status == "publish" {
+			postCount++
+		}
+		pbPost := &pb.GetSeriesResponse_PostItem{
+			Id:        post.ID,
+			Title:     post.Title,
+			Status:    post.Status,
+			PublishAt: timestamppb.New(post.PublishAt),
+		}
+		rspPosts = append(rspPosts, pbPost)
+	}
+
+	author := &pb.UserItem{
+		Id:       series.AuthorID,
+		Username: series.Username,
+		Avatar:   series.Avatar,
+	}
+
+	pbSeries := &pb.Series{
+		Id:          series.ID,
+		Title:       series.Title,
+		Description: series.Description,
+		CoverImage:  series.CoverImage,
+		PostCount:   postCount,
+		CreateAt:    timestamppb.New(series.CreateAt),
+	}
+
+	return &pb.GetSeriesResponse{
+		Series: pbSeries,
+		Author: author,
+		Posts:  rspPosts,
+	}
+}
+
+func convertSeriesContext(series db.GetPostSeriesRow) *pb.SeriesContext {
+	seriesContext := &pb.SeriesContext{
+		Id:       series.ID,
+		Title:    series.Title,
+		Position: series.Part,
+		Total:    series.Total,
+	}
+	if series.PrevID != 0 {
+		seriesContext.Prev = &pb.SeriesContext_Part{
+			Id:    series.PrevID,
+			Title: series.PrevTitle,
+		}
+	}
+	if series.NextID != 0 {
+		seriesContext.Next = &pb.SeriesContext_Part{
+			Id:    series.NextID,
+			Title: series.NextTitle,
+		}
+	}
+	return seriesContext
+}
+
+func convertReviewNote(note db.ReviewNote, user *db.User) *pb.ReviewNote {
+	pbNote := &pb.ReviewNote{
+		Id: note.ID,
+		User: &pb.UserItem{
+			Id:       user.ID,
+			Username: user.Username,
+			Avatar:   user.Avatar,
+		},
+		Kind:       note.Kind,
+		Content:    note.Content,
+		AnchorText: note.AnchorText,
+		CreateAt:   timestamppb.New(note.CreateAt),
+	}
+	if note.AnchorStart.Valid && note.AnchorEnd.Valid {
+		pbNote.AnchorStart = &note.AnchorStart.Int32
+		pbNote.AnchorEnd = &note.AnchorEnd.Int32
+	}
+	return pbNote
+}
+
+func convertListReviewNotes(notes []db.ListReviewNotesRow) *pb.ListReviewNotesResponse {
+	rspNotes := make([]*pb.ReviewNote, 0, len(notes))
+	for _, note := range notes {
+		pbNote := &pb.ReviewNote{
+			Id: note.ID,
+			User: &pb.UserItem{
+				Id:       note.UserID,
+				Username: note.Username,
+				Avatar:   note.Avatar,
+			},
+			Kind:       note.Kind,
+			Content:    note.Content,
+			AnchorText: note.AnchorText,
+			CreateAt:   timestamppb.New(note.CreateAt),
+		}
+		if note.AnchorStart.Valid && note.AnchorEnd.Valid {
+			anchorStart, anchorEnd := note.AnchorStart.Int32, note.AnchorEnd.Int32
+			pbNote.AnchorStart = &anchorStart
+			pbNote.AnchorEnd = &anchorEnd
+		}
+		rspNotes = append(rspNotes, pbNote)
+	}
+	return &pb.ListReviewNotesResponse{Notes: rspNotes}
+}
+
+func convertListReviewQueue(posts []db.ListReviewQueueRow) *pb.ListReviewQueueResponse {
+	if len(posts) == 0 {
+		return &pb.ListReviewQueueResponse{}
+	}
+
+	rspPosts := make([]*pb.ListReviewQueueResponse_PostItem, 0, 5)
+	for _, post := range posts {
+		pbPost := &pb.ListReviewQueueResponse_PostItem{
+			Id:    post.ID,
+			Title: post.Title,
+			Author: &pb.UserItem{
+				Id:       post.AuthorID,
+				Username: post.Username,
+				Avatar:   post.Avatar,
+			},
+			UpdateAt: timestamppb.New(post.UpdateAt),
+			Revision: post.Revision,
+		}
+		if post.ReviewerID.Valid {
+			pbPost.Reviewer = &pb.UserItem{
+				Id:       post.ReviewerID.Int64,
+				Username: post.RUsername.String,
+				Avatar:   post.RAvatar.String,
+			}
+		}
+		rspPosts = append(rspPosts, pbPost)
+	}
+
+	return &pb.ListReviewQueueResponse{
+		Total: posts[0].Total,
+		Posts: rspPosts,
+	}
+}
+
+func convertGetAnalytics(stats []db.ListDailyStatsRow) *pb.GetAnalyticsResponse {
+	rsp := &pb.GetAnalyticsResponse{
+		Series: make([]*pb.GetAnalyticsResponse_DailyStat, 0, len(stats)),
+	}
+	for _, stat := range stats {
+		pbStat := &pb.GetAnalyticsResponse_DailyStat{
+			Date:      stat.Day.Format(dateLayout),
+			Views:     stat.Views,
+			Stars:     stat.Stars,
+			Comments:  stat.Comments,
+			Followers: stat.Followers,
+		}
+		rsp.Series = append(rsp.Series, pbStat)
+
+		rsp.TotalViews += stat.Views
+		rsp.TotalStars += stat.Stars
+		rsp.TotalComments += stat.Comments
+		rsp.TotalFollowers += stat.Followers
+	}
+	return rsp
+}
+
+func convertGetTopPosts(posts []db.ListTopPostsRow) *pb.GetTopPostsResponse {
+	rspPosts := make([]*pb.GetTopPostsResponse_PostItem, 0, len(posts))
+	for _, post := range posts {
+		author := &pb.UserItem{
+			Id:       post.AuthorID,
+			Username: post.Username,
+			Avatar:   post.Avatar,
+		}
+		pbPost := &pb.GetTopPostsResponse_PostItem{
+			Id:       post.ID,
+			Title:    post.Title,
+			Author:   author,
+			Views:    post.Views,
+			Stars:    post.Stars,
+			Comments: post.Comments,
+		}
+		rspPosts = append(rspPosts, pbPost)
+	}
+	return &pb.GetTopPostsResponse{Posts: rspPosts}
+}
+
+func convertGetTrafficSources(sources []db.ListTrafficSourcesRow) *pb.GetTrafficSourcesResponse {
+	rspSources := make([]*pb.GetTrafficSourcesResponse_SourceItem, 0, len(sources))
+	for _, source := range sources {
+		pbSource := &pb.GetTrafficSourcesResponse_SourceItem{
+			Source: source.Source,
+			Views:  source.Views,
+		}
+		rspSources = append(rspSources, pbSource)
+	}
+	return &pb.GetTrafficSourcesResponse{Sources: rspSources}
+}
+
+func convertGetRelatedPosts(posts []db.GetRelatedPostsRow) *pb.GetRelatedPostsResponse {
+	rspPosts := make([]*pb.GetRelatedPostsResponse_PostItem, 0, len(posts))
+	for _, post := range posts {
+		author := &pb.UserItem{
+			Id:       post.AuthorID,
+			Username: post.Username,
+			Avatar:   post.Avatar,
+		}
+		pbPost := &pb.GetRelatedPostsResponse_PostItem{
+			Id:         post.ID,
+			Title:      post.Title,
+			Author:     author,
+			CoverImage: post.CoverImage,
+			ViewCount:  post.ViewCount,
+			PublishAt:  timestamppb.New(post.PublishAt),
+		}
+		rspPosts = append(rspPosts, pbPost)
+	}
+	return &pb.GetRelatedPostsResponse{Posts: rspPosts}
+}
+
+func convertGetFeed(posts []db.GetFeedRow, lastVisit time.Time) []*pb.GetFeedResponse_PostItem {
+	rspPosts := make([]*pb.GetFeedResponse_PostItem, 0, len(posts))
+	for _, post := range posts {
+		author := &pb.UserItem{
+			Id:       post.AuthorID,
+			Username: post.Username,
+			Avatar:   post.Avatar,
+		}
+
+		tags := make([]*pb.Tag, 0, 5)
+		for i, tagID := range post.TagIds {
+			tag := &pb.Tag{
+				Id:   tagID,
+				Name: post.TagNames[i],
+			}
+			tags = append(tags, tag)
+		}
+
+		pbPost := &pb.GetFeedResponse_PostItem{
+			Id:           post.ID,
+			Title:        post.Title,
+			Author:       author,
+			CoverImage:   post.CoverImage,
+			Tags:         tags,
+			ViewCount:    post.ViewCount,
+			StarCount:    post.StarCount,
+			CommentCount: post.CommentCount,
+			PublishAt:    timestamppb.New(post.PublishAt),
+			Authors:      convertAuthors(post.AuthorIds, post.AuthorNames, post.AuthorAvatars),
+			Unread:       post.PublishAt.After(lastVisit),
+			Visibility:   post.Visibility,
+		}
+		rspPosts = append(rspPosts, pbPost)
+	}
+	return rspPosts
+}
+
+func convertReadingList(list db.ReadingList, bookmarkCount int64) *pb.ReadingList {
+	return &pb.ReadingList{
+		Id:            list.ID,
+		Name:          list.Name,
+		BookmarkCount: bookmarkCount,
+		CreateAt:      timestamppb.New(list.CreateAt),
+	}
+}
+
+func convertListReadingLists(lists []db.ListReadingListsRow) *pb.ListReadingListsResponse {
+	rspLists := make([]*pb.ReadingList, 0, len(lists))
+	for _, list := range lists {
+		pbList := &pb.ReadingList{
+			Id:            list.ID,
+			Name:          list.Name,
+			BookmarkCount: list.BookmarkCount,
+			CreateAt:      timestamppb.New(list.CreateAt),
+		}
+		rspLists = append(rspLists, pbList)
+	}
+	return &pb.ListReadingListsResponse{Lists: rspLists}
+}
+
+func convertListBookmarks(bookmarks []db.ListBookmarksRow) *pb.ListBookmarksResponse {
+	if len(bookmarks) == 0 {
+		return &pb.ListBookmarksResponse{}
+	}
+
+	rspBookmarks := make([]*pb.ListBookmarksResponse_BookmarkItem, 0, len(bookmarks))
+	for _, bookmark := range bookmarks {
+		author := &pb.UserItem{
+			Id:       bookmark.AuthorID,
+			Username: bookmark.Username,
+			Avatar:   bookmark.Avatar,
+		}
+
+		pbBookmark := &pb.ListBookmarksResponse_BookmarkItem{
+			PostId:     bookmark.PostID,
+			Title:      bookmark.Title,
+			Author:     author,
+			CoverImage: bookmark.CoverImage,
+			Status:     bookmark.Status,
+			Note:       bookmark.Note,
+			PublishAt:  timestamppb.New(bookmark.PublishAt),
+			BookmarkAt: timestamppb.New(bookmark.CreateAt),
+		}
+		rspBookmarks = append(rspBookmarks, pbBookmark)
+	}
+
+	return &pb.ListBookmarksResponse{
+		Total:     bookmarks[0].Total,
+		Bookmarks: rspBookmarks,
+	}
+}
+
+func convertListStargazers(users []db.ListStargazersRow) *pb.ListStargazersResponse {
+	if len(users) == 0 {
+		return &pb.ListStargazersResponse{}
+	}
+
+	rspUsers := make([]*pb.ListStargazersResponse_UserItem, 0, len(users))
+	for _, user := range users {
+		pbUser := &pb.ListStargazersResponse_UserItem{
+			Id:       user.UserID,
+			Username: user.Username,
+			Avatar:   user.Avatar,
+			Intro:    user.Intro,
+			Followed: user.Followed.Valid,
+			StarAt:   timestamppb.New(user.StarAt),
+		}
+		rspUsers = append(rspUsers, pbUser)
+	}
+
+	return &pb.ListStargazersResponse{
+		Total: users[0].Total,
+		Users: rspUsers,
+	}
+}
+
+func convertListStarredPosts(posts []db.ListStarredPostsRow) *pb.ListStarredPostsResponse {
+	if len(posts) == 0 {
+		return &pb.ListStarredPostsResponse{}
+	}
+
+	rspPosts := make([]*pb.ListStarredPostsResponse_PostItem, 0, len(posts))
+	for _, post := range posts {
+		author := &pb.UserItem{
+			Id:       post.AuthorID,
+			Username: post.Username,
+			Avatar:   post.Avatar,
+		}
+
+		pbPost := &pb.ListStarredPostsResponse_PostItem{
+			Id:         post.ID,
+			Title:      post.Title,
+			Author:     author,
+			CoverImage: post.CoverImage,
+			ViewCount:  post.ViewCount,
+			PublishAt:  timestamppb.New(post.PublishAt),
+			StarAt:     timestamppb.New(post.StarAt),
+		}
+		rspPosts = append(rspPosts, pbPost)
+	}
+
+	return &pb.ListStarredPostsResponse{
+		Total: posts[0].Total,
+		Posts: rspPosts,
+	}
+}
+
 func convertCreateComment(comment db.CreateCommentRow, user *db.User) *pb.CreateCommentResponse {
 	var replyUser *pb.UserInfo
 	if comment.ReplyUserID.Valid {
@@ -612,3 +1066,59 @@ func convertListReplies(replies []db.ListRepliesRow) *pb.ListRepliesResponse {
 		CommentReplies: commentReplies,
 	}
 }
+
+func convertMedia(media db.Media) *pb.Media {
+	pbMedia := &pb.Media{
+		Id:       media.ID,
+		Path:     media.Path,
+		Size:     media.Size,
+		MimeType: media.MimeType,
+		Width:    media.Width,
+		Height:   media.Height,
+		Sha256:   media.Sha256,
+		AltText:  media.AltText,
+		CreateAt: timestamppb.New(media.CreateAt),
+	}
+	if media.OrphanAt.Valid {
+		pbMedia.OrphanAt = timestamppb.New(media.OrphanAt.Time)
+	}
+	return pbMedia
+}
+
+func convertImageVariant(variant db.MediaVariant) *pb.ImageVariant {
+	return &pb.ImageVariant{
+		Path:     variant.Path,
+		Width:    variant.Width,
+		Height:   variant.Height,
+		MimeType: variant.MimeType,
+		Size:     variant.Size,
+	}
+}
+
+func convertListMedia(media []db.ListMediaRow) *pb.ListMediaResponse {
+	if len(media) == 0 {
+		return &pb.ListMediaResponse{}
+	}
+
+	rspMedia := make([]*pb.Media, 0, len(media))
+	for _, m := range media {
+		rspMedia = append(rspMedia, convertMedia(db.Media{
+			ID:       m.ID,
+			OwnerID:  m.OwnerID,
+			Path:     m.Path,
+			Size:     m.Size,
+			MimeType: m.MimeType,
+			Width:    m.Width,
+			Height:   m.Height,
+			Sha256:   m.Sha256,
+			AltText:  m.AltText,
+			CreateAt: m.CreateAt,
+			OrphanAt: m.OrphanAt,
+		}))
+	}
+
+	return &pb.ListMediaResponse{
+		Total: media[0].Total,
+		Media: rspMedia,
+	}
+}
@@ -0,0 +1,121 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bwen19/blog/grpc/pb"
+	"github.com/bwen19/blog/psql/db"
+	"github.com/bwen19/blog/util"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// feedCursor points after the last post of a page. It also carries the
+// previous visit so that unread markers stay the same on every page.
+type feedCursor struct {
+	publishAt time.Time
+	postID    int64
+	lastVisit time.Time
+}
+
+func (cursor feedCursor) encode() string {
+	raw := fmt.Sprintf("%d:%d:%d", cursor.publishAt.UnixMicro(), cursor.postID, cursor.lastVisit.UnixMicro())
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeFeedCursor(value string) (feedCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return feedCursor{}, fmt.Errorf("cursor: invalid cursor")
+	}
+
+	parts := strings.Split(string(raw), ":")
+	if len(parts) != 3 {
+		return feedCursor{}, fmt.Errorf("cursor: invalid cursor")
+	}
+
+	nums := make([]int64, 0, 3)
+	for _, part := range parts {
+		num, err := strconv.ParseInt(part, 10, 64)
+		if err != nil {
+			return feedCursor{}, fmt.Errorf("cursor: invalid cursor")
+		}
+		nums = append(nums, num)
+	}
+
+	cursor := feedCursor{
+		publishAt: time.UnixMicro(nums[0]),
+		postID:    nums[1],
+		lastVisit: time.UnixMicro(nums[2]),
+	}
+	return cursor, nil
+}
+
+// ========================// GetFeed //======================== //
+
+func (server *Server) GetFeed(ctx context.Context, req *pb.GetFeedRequest) (*pb.GetFeedResponse, error) {
+	authUser, gErr := server.grpcGuard(ctx, roleUser)
+	if gErr != nil {
+		return nil, gErr.GrpcErr()
+	}
+
+	if err := util.ValidateNumber(int64(req.GetPageSize()), 1, 50); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "pageSize: %s", err.Error())
+	}
+
+	arg := db.GetFeedParams{
+		SelfID: authUser.ID,
+		Num:    req.GetPageSize(),
+	}
+
+	var lastVisit time.Time
+	if req.GetCursor() != "" {
+		cursor, err := decodeFeedCursor(req.GetCursor())
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		arg.HasCursor = true
+		arg.CursorTime = cursor.publishAt
+		arg.CursorID = cursor.postID
+		lastVisit = cursor.lastVisit
+	} else {
+		// Opening the feed counts as a visit, posts published since the
+		// previous one are marked as unread
+		visitAt, err := server.store.GetFeedVisit(ctx, authUser.ID)
+		if err != nil && err != sql.ErrNoRows {
+			return nil, status.Error(codes.Internal, "failed to get last visit")
+		}
+		lastVisit = visitAt
+
+		if err = server.store.UpsertFeedVisit(ctx, authUser.ID); err != nil {
+			return nil, status.Error(codes.Internal, "failed to update last visit")
+		}
+	}
+
+	posts, err := server.store.GetFeed(ctx, arg)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to get feed")
+	}
+
+	rsp := &pb.GetFeedResponse{
+		Posts:       convertGetFeed(posts, lastVisit),
+		LastVisitAt: timestamppb.New(lastVisit),
+	}
+	if len(posts) == int(req.GetPageSize()) {
+		last := posts[len(posts)-1]
+		cursor := feedCursor{
+			publishAt: last.PublishAt,
+			postID:    last.ID,
+			lastVisit: lastVisit,
+		}
+		rsp.NextCursor = cursor.encode()
+	}
+	return rsp, nil
+}
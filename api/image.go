@@ -0,0 +1,657 @@
+package api
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"io/fs"
+	"log"
+	"net/http"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/bwen19/blog/grpc/pb"
+	"github.com/bwen19/blog/imaging"
+	"github.com/bwen19/blog/psql/db"
+	_ "golang.org/x/image/webp"
+)
+
+// maxImagePixels limits the decoded size of uploaded images, so that a small
+// file cannot expand into a huge bitmap in memory
+const maxImagePixels = 40_000_000
+
+var (
+	errUnsupportedMedia = errors.New("media type is not supported")
+	errInvalidImage     = errors.New("image is corrupted or cannot be decoded")
+	errTooManyPixels    = errors.New("image dimensions are too large")
+)
+
+// imageTypes is the allow-list of image types with their file extensions
+var imageTypes = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+	"image/webp": ".webp",
+	"image/avif": ".avif",
+}
+
+// sniffImage detects the image type from the first bytes of a file and
+// returns its mime type and extension
+func sniffImage(head []byte) (string, string, error) {
+	mimeType := http.DetectContentType(head)
+	if isAVIF(head) {
+		mimeType = "image/avif"
+	}
+
+	ext, ok := imageTypes[mimeType]
+	if !ok {
+		return "", "", errUnsupportedMedia
+	}
+	return mimeType, ext, nil
+}
+
+// checkImage decodes an image file to make sure that it is valid and returns
+// the image with its dimensions. AVIF files are only checked by their header,
+// since there is no decoder for them, and no image is returned.
+func checkImage(fileName string, mimeType string) (image.Image, int32, int32, error) {
+	f, err := os.Open(fileName)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	defer f.Close()
+
+	var width, height int
+	if mimeType == "image/avif" {
+		width, height, err = avifSize(f)
+	} else {
+		var config image.Config
+		config, _, err = image.DecodeConfig(f)
+		width, height = config.Width, config.Height
+	}
+	if err != nil || width <= 0 || height <= 0 {
+		return nil, 0, 0, errInvalidImage
+	}
+	if int64(width)*int64(height) > maxImagePixels {
+		return nil, 0, 0, errTooManyPixels
+	}
+
+	var img image.Image
+	if mimeType != "image/avif" {
+		if _, err = f.Seek(0, io.SeekStart); err != nil {
+			return nil, 0, 0, err
+		}
+		if img, _, err = image.Decode(f); err != nil {
+			return nil, 0, 0, errInvalidImage
+		}
+	}
+	return img, int32(width), int32(height), nil
+}
+
+// isAVIF reports whether the head of a file is an ftyp box of AVIF brand
+func isAVIF(head []byte) bool {
+	if len(head) < 12 || string(head[4:8]) != "ftyp" {
+		return false
+	}
+	size := int(binary.BigEndian.Uint32(head[:4]))
+	if size < 16 || size > len(head) {
+		size = len(head)
+	}
+
+	// Major brand followed by minor version and compatible brands
+	brands := append([]byte{}, head[8:12]...)
+	if size > 16 {
+		brands = append(brands, head[16:size]...)
+	}
+	for i := 0; i+4 <= len(brands); i += 4 {
+		if brand := string(brands[i : i+4]); brand == "avif" || brand == "avis" {
+			return true
+		}
+	}
+	return false
+}
+
+// avifHeaderSize limits the bytes read for the boxes of an AVIF header. The
+// meta box sits near the start of the file, before the image data.
+const avifHeaderSize = 64 << 10
+
+// avifSize reads the dimensions of an AVIF image from the ispe property of
+// its primary item, following the box structure of ISO/IEC 14496-12:
+// ftyp, then meta with pitm and iprp, where iprp holds the properties in
+// ipco and their associations with items in ipma.
+func avifSize(r io.Reader) (int, int, error) {
+	buf, err := io.ReadAll(io.LimitReader(r, avifHeaderSize))
+	if err != nil {
+		return 0, 0, err
+	}
+	if !isAVIF(buf) {
+		return 0, 0, errInvalidImage
+	}
+
+	top, ok := parseBoxes(buf)
+	if !ok {
+		return 0, 0, errInvalidImage
+	}
+	meta, ok := findBox(top, "meta")
+	if !ok || len(meta) < 4 {
+		return 0, 0, errInvalidImage
+	}
+	// meta is a full box, with version and flags before its children
+	metaBoxes, ok := parseBoxes(meta[4:])
+	if !ok {
+		return 0, 0, errInvalidImage
+	}
+
+	primary, ok := primaryItem(metaBoxes)
+	if !ok {
+		return 0, 0, errInvalidImage
+	}
+	iprp, ok := findBox(metaBoxes, "iprp")
+	if !ok {
+		return 0, 0, errInvalidImage
+	}
+	iprpBoxes, ok := parseBoxes(iprp)
+	if !ok {
+		return 0, 0, errInvalidImage
+	}
+	ipco, ok := findBox(iprpBoxes, "ipco")
+	if !ok {
+		return 0, 0, errInvalidImage
+	}
+	properties, ok := parseBoxes(ipco)
+	if !ok {
+		return 0, 0, errInvalidImage
+	}
+	ipma, ok := findBox(iprpBoxes, "ipma")
+	if !ok {
+		return 0, 0, errInvalidImage
+	}
+	indexes, ok := itemProperties(ipma, primary)
+	if !ok {
+		return 0, 0, errInvalidImage
+	}
+
+	for _, index := range indexes {
+		// Property indexes start at 1, and 0 means no property
+		if index < 1 || index > len(properties) || properties[index-1].typ != "ispe" {
+			continue
+		}
+		// version and flags (4) + width (4) + height (4)
+		ispe := properties[index-1].data
+		if len(ispe) < 12 {
+			return 0, 0, errInvalidImage
+		}
+		width := binary.BigEndian.Uint32(ispe[4:8])
+		height := binary.BigEndian.Uint32(ispe[8:12])
+		if width > 1<<20 || height > 1<<20 {
+			return 0, 0, errTooManyPixels
+		}
+		return int(width), int(height), nil
+	}
+	return 0, 0, errInvalidImage
+}
+
+// isoBox is a box of an ISO base media file with its type and payload
+type isoBox struct {
+	typ  string
+	data []byte
+}
+
+// parseBoxes splits buf into consecutive boxes. It fails if a box does not
+// fit in buf, which also happens when the header is longer than was read.
+func parseBoxes(buf []byte) ([]isoBox, bool) {
+	boxes := []isoBox{}
+	for len(buf) > 0 {
+		if len(buf) < 8 {
+			return nil, false
+		}
+		size := uint64(binary.BigEndian.Uint32(buf[:4]))
+		typ := string(buf[4:8])
+		header := uint64(8)
+		switch size {
+		case 0:
+			// The box extends to the end of its parent
+			size = uint64(len(buf))
+		case 1:
+			if len(buf) < 16 {
+				return nil, false
+			}
+			size, header = binary.BigEndian.Uint64(buf[8:16]), 16
+		}
+		if size < header || size > uint64(len(buf)) {
+			return nil, false
+		}
+		boxes = append(boxes, isoBox{typ: typ, data: buf[header:size]})
+		buf = buf[size:]
+	}
+	return boxes, true
+}
+
+func findBox(boxes []isoBox, typ string) ([]byte, bool) {
+	for _, box := range boxes {
+		if box.typ == typ {
+			return box.data, true
+		}
+	}
+	return nil, false
+}
+
+// primaryItem reads the id of the primary item from the pitm box
+func primaryItem(metaBoxes []isoBox) (uint32, bool) {
+	pitm, ok := findBox(metaBoxes, "pitm")
+	if !ok || len(pitm) < 4 {
+		return 0, false
+	}
+	if pitm[0] == 0 {
+		if len(pitm) < 6 {
+			return 0, false
+		}
+		return uint32(binary.BigEndian.Uint16(pitm[4:6])), true
+	}
+	if len(pitm) < 8 {
+		return 0, false
+	}
+	return binary.BigEndian.Uint32(pitm[4:8]), true
+}
+
+// itemProperties reads the property indexes associated with an item from
+// the ipma box
+func itemProperties(ipma []byte, item uint32) ([]int, bool) {
+	if len(ipma) < 8 {
+		return nil, false
+	}
+	version := ipma[0]
+	largeIndex := ipma[3]&1 == 1
+	count := binary.BigEndian.Uint32(ipma[4:8])
+	buf := ipma[8:]
+
+	for ; count > 0; count-- {
+		var id uint32
+		if version < 1 {
+			if len(buf) < 2 {
+				return nil, false
+			}
+			id, buf = uint32(binary.BigEndian.Uint16(buf[:2])), buf[2:]
+		} else {
+			if len(buf) < 4 {
+				return nil, false
+			}
+			id, buf = binary.BigEndian.Uint32(buf[:4]), buf[4:]
+		}
+		if len(buf) < 1 {
+			return nil, false
+		}
+		n := int(buf[0])
+		buf = buf[1:]
+
+		indexes := make([]int, 0, n)
+		for i := 0; i < n; i++ {
+			// The high bit marks an essential property
+			if largeIndex {
+				if len(buf) < 2 {
+					return nil, false
+				}
+				indexes, buf = append(indexes, int(binary.BigEndian.Uint16(buf[:2])&0x7fff)), buf[2:]
+			} else {
+				if len(buf) < 1 {
+					return nil, false
+				}
+				indexes, buf = append(indexes, int(buf[0]&0x7f)), buf[1:]
+			}
+		}
+		if id == item {
+			return indexes, true
+		}
+	}
+	return nil, false
+}
+
+// variantSpec describes the resized copies made of an upload. Square variants
+// are center crops, otherwise widths keep the aspect ratio and are only made
+// for images wider than them.
+type variantSpec struct {
+	widths []int
+	square bool
+}
+
+var (
+	avatarVariants    = variantSpec{widths: []int{48, 96, 192}, square: true}
+	postImageVariants = variantSpec{widths: []int{320, 640, 960, 1280}}
+)
+
+type imageEncoder struct {
+	mimeType string
+	ext      string
+	encode   func(io.Writer, image.Image) error
+}
+
+var (
+	jpegEncoder = imageEncoder{"image/jpeg", ".jpg", func(w io.Writer, m image.Image) error {
+		return jpeg.Encode(w, m, &jpeg.Options{Quality: 85})
+	}}
+	pngEncoder       = imageEncoder{"image/png", ".png", png.Encode}
+	webpEncoder      = imageEncoder{"image/webp", ".webp", imaging.EncodeWebP}
+	lossyWebPEncoder = imageEncoder{"image/webp", ".webp", func(w io.Writer, m image.Image) error {
+		return imaging.EncodeWebPLossy(w, m, 80)
+	}}
+)
+
+// variantEncoders returns the format of the variants of an image, and the
+// WebP format tried next to it if any. Photos get lossy WebP and graphics
+// lossless WebP, which is far larger than JPEG for photos. Variants of GIF
+// images are still PNG images, and WebP images keep lossy WebP when they
+// decode without transparency, as lossy WebP images do.
+func variantEncoders(img image.Image, mimeType string) (imageEncoder, *imageEncoder) {
+	switch mimeType {
+	case "image/jpeg":
+		return jpegEncoder, &lossyWebPEncoder
+	case "image/png", "image/gif":
+		return pngEncoder, &webpEncoder
+	}
+	if _, ok := img.(*image.YCbCr); ok {
+		return lossyWebPEncoder, nil
+	}
+	return webpEncoder, nil
+}
+
+// writeVariants writes the variants of an image next to its file src under
+// the public path. WebP variants are only kept when they are smaller than the
+// variant of the same size in the source format, and images up to the
+// largest width also get a WebP variant of their own size when it is smaller
+// than the source of srcSize bytes. AVIF images get no variants, since there
+// is no decoder for them, and they are smaller than WebP already. Written
+// files are removed again if any variant fails.
+func (server *Server) writeVariants(img image.Image, mimeType string, src string, srcSize int64, spec variantSpec) ([]db.CreateMediaVariantParams, error) {
+	variants := []db.CreateMediaVariantParams{}
+	if img == nil || len(spec.widths) == 0 {
+		return variants, nil
+	}
+
+	enc, webp := variantEncoders(img, mimeType)
+	b := img.Bounds()
+	base := strings.TrimSuffix(src, path.Ext(src))
+	for _, width := range spec.widths {
+		var resized *image.NRGBA
+		var name string
+		if spec.square {
+			if width > b.Dx() || width > b.Dy() {
+				continue
+			}
+			resized, name = imaging.Square(img, width), fmt.Sprintf("%s-%dx%d", base, width, width)
+		} else {
+			if width >= b.Dx() {
+				continue
+			}
+			resized, name = imaging.Fit(img, width), fmt.Sprintf("%s-%dw", base, width)
+		}
+
+		variant, err := server.writeVariant(name, resized, enc)
+		if err != nil {
+			server.removeVariantFiles(variants)
+			return nil, err
+		}
+		variants = append(variants, variant)
+
+		if webp != nil {
+			variant, ok, err := server.writeSmallerVariant(name, resized, *webp, variant.Size)
+			if err != nil {
+				server.removeVariantFiles(variants)
+				return nil, err
+			}
+			if ok {
+				variants = append(variants, variant)
+			}
+		}
+	}
+
+	// No resized variant stands in for an image up to the largest width
+	if webp != nil && !spec.square && b.Dx() <= spec.widths[len(spec.widths)-1] {
+		name := fmt.Sprintf("%s-%dw", base, b.Dx())
+		variant, ok, err := server.writeSmallerVariant(name, img, *webp, srcSize)
+		if err != nil {
+			server.removeVariantFiles(variants)
+			return nil, err
+		}
+		if ok {
+			variants = append(variants, variant)
+		}
+	}
+	return variants, nil
+}
+
+// writeVariant writes an image as the variant named name plus the extension
+// of its format
+func (server *Server) writeVariant(name string, img image.Image, enc imageEncoder) (db.CreateMediaVariantParams, error) {
+	b := img.Bounds()
+	variant := db.CreateMediaVariantParams{
+		Path:     name + enc.ext,
+		MimeType: enc.mimeType,
+		Width:    int32(b.Dx()),
+		Height:   int32(b.Dy()),
+	}
+
+	size, err := server.writeImageFile(variant.Path, img, enc)
+	if err != nil {
+		return variant, err
+	}
+	variant.Size = size
+	return variant, nil
+}
+
+// writeSmallerVariant writes a variant like writeVariant, but removes it again
+// and returns false unless it is smaller than limit bytes
+func (server *Server) writeSmallerVariant(name string, img image.Image, enc imageEncoder, limit int64) (db.CreateMediaVariantParams, bool, error) {
+	variant, err := server.writeVariant(name, img, enc)
+	if err != nil {
+		return variant, false, err
+	}
+	if variant.Size >= limit {
+		server.removeVariantFiles([]db.CreateMediaVariantParams{variant})
+		return variant, false, nil
+	}
+	return variant, true, nil
+}
+
+// maxPendingVariants limits the uploads waiting in the queue for their
+// variants, so that a burst of uploads or an import cannot pile up work
+// without bound. Uploads that find the queue full are left to the media
+// sweeper instead.
+const maxPendingVariants = 32
+
+// variantJob is an upload whose variants are still to be written
+type variantJob struct {
+	media db.Media
+	spec  variantSpec
+}
+
+// queueVariants hands an upload over to RunVariantWriter. When the queue is
+// full the upload is marked as pending, and requeueVariants hands it over on
+// a later sweep, so that requests never wait for the queue.
+func (server *Server) queueVariants(ctx context.Context, media db.Media, spec variantSpec) {
+	select {
+	case server.variantJobs <- variantJob{media: media, spec: spec}:
+	default:
+		if err := server.store.MarkMediaVariantsPending(ctx, media.ID); err != nil {
+			log.Println("variants of media not queued:", media.Path)
+		}
+	}
+}
+
+// requeueVariants hands pending uploads over to RunVariantWriter while there
+// is room in the queue, and returns how many it queued
+func (server *Server) requeueVariants(ctx context.Context) (int, error) {
+	room := cap(server.variantJobs) - len(server.variantJobs)
+	if room <= 0 {
+		return 0, nil
+	}
+	pending, err := server.store.ListVariantsPendingMedia(ctx, int32(room))
+	if err != nil {
+		return 0, err
+	}
+
+	for i, media := range pending {
+		spec := postImageVariants
+		if strings.HasPrefix(media.Path, server.config.AvatarPath+"/") {
+			spec = avatarVariants
+		}
+		select {
+		case server.variantJobs <- variantJob{media: media, spec: spec}:
+		default:
+			return i, nil
+		}
+	}
+	return len(pending), nil
+}
+
+// RunVariantWriter writes the variants of uploads one at a time, which keeps
+// decoding and encoding off the upload requests and on a single core. Uploads
+// still queued on shutdown keep only their original images.
+func (server *Server) RunVariantWriter(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case job := <-server.variantJobs:
+			server.addVariants(ctx, job)
+		}
+	}
+}
+
+// addVariants decodes an upload again and records its variants. Clients fall
+// back to the original images, so failures are only logged. Pending uploads
+// are claimed first, since a sweep may queue them again while they wait.
+func (server *Server) addVariants(ctx context.Context, job variantJob) {
+	if job.media.VariantsPending {
+		claimed, err := server.store.ClaimMediaVariants(ctx, job.media.ID)
+		if err != nil {
+			log.Println("failed to claim media for variants:", err)
+			return
+		}
+		if claimed == 0 {
+			return
+		}
+	}
+
+	fileName := path.Join(server.config.PublicPath, job.media.Path)
+	img, _, _, err := checkImage(fileName, job.media.MimeType)
+	if err != nil {
+		// The media may have been deleted in the meantime
+		if !errors.Is(err, fs.ErrNotExist) {
+			log.Println("failed to decode media for variants:", err)
+		}
+		return
+	}
+
+	variants, err := server.writeVariants(img, job.media.MimeType, job.media.Path, job.media.Size, job.spec)
+	if err != nil {
+		log.Println("failed to write media variants:", err)
+		return
+	}
+	if len(variants) == 0 {
+		return
+	}
+
+	arg := db.AddMediaVariantsParams{
+		MediaID:  job.media.ID,
+		Variants: variants,
+	}
+	if _, err = server.store.AddMediaVariants(ctx, arg); err != nil {
+		server.removeVariantFiles(variants)
+		log.Println("failed to record media variants:", err)
+	}
+}
+
+func (server *Server) writeImageFile(src string, img image.Image, enc imageEncoder) (int64, error) {
+	fileName := path.Join(server.config.PublicPath, src)
+	fn, err := os.OpenFile(fileName, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return 0, err
+	}
+	defer fn.Close()
+
+	if err = enc.encode(fn, img); err != nil {
+		os.Remove(fileName)
+		return 0, err
+	}
+	info, err := fn.Stat()
+	if err != nil {
+		os.Remove(fileName)
+		return 0, err
+	}
+	return info.Size(), nil
+}
+
+func (server *Server) removeVariantFiles(variants []db.CreateMediaVariantParams) {
+	for _, variant := range variants {
+		os.Remove(path.Join(server.config.PublicPath, variant.Path))
+	}
+}
+
+// contentImage matches the paths of uploaded images in post content. Names of
+// variants have a suffix before the extension and do not match.
+var contentImage = regexp.MustCompile(`/[\w/.-]*img-\d+-\d+\.(?:jpg|png|gif|webp|avif)`)
+
+// maxContentImages limits the images of a post looked up for variants
+const maxContentImages = 100
+
+// contentImages returns the distinct paths of uploaded images in content
+func contentImages(content string) []string {
+	paths := []string{}
+	seen := map[string]bool{}
+	for _, p := range contentImage.FindAllString(content, -1) {
+		if !seen[p] && len(paths) < maxContentImages {
+			seen[p] = true
+			paths = append(paths, p)
+		}
+	}
+	return paths
+}
+
+// imageVariants looks up the variants of images by their paths. Clients can
+// always fall back to the original images, so a failed lookup only logs.
+func (server *Server) imageVariants(ctx context.Context, paths []string) map[string][]*pb.ImageVariant {
+	variants := map[string][]*pb.ImageVariant{}
+	if len(paths) == 0 {
+		return variants
+	}
+
+	rows, err := server.store.ListVariantsByPaths(ctx, paths)
+	if err != nil {
+		log.Println("failed to list image variants:", err)
+		return variants
+	}
+	for _, row := range rows {
+		variants[row.MediaPath] = append(variants[row.MediaPath], convertImageVariant(db.MediaVariant{
+			ID:       row.ID,
+			MediaID:  row.MediaID,
+			Path:     row.Path,
+			Size:     row.Size,
+			MimeType: row.MimeType,
+			Width:    row.Width,
+			Height:   row.Height,
+		}))
+	}
+	return variants
+}
+
+// setAvatarVariants sets the variants of the avatars of users from a lookup
+// of imageVariants
+func setAvatarVariants(users []*pb.UserItem, variants map[string][]*pb.ImageVariant) {
+	for _, user := range users {
+		user.AvatarVariants = variants[user.Avatar]
+	}
+}
+
+// userWithVariants converts a user along with the variants of its avatar
+func (server *Server) userWithVariants(ctx context.Context, user db.User) *pb.User {
+	pbUser := convertUser(user)
+	pbUser.AvatarVariants = server.imageVariants(ctx, []string{user.Avatar})[user.Avatar]
+	return pbUser
+}
@@ -0,0 +1,391 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bwen19/blog/psql/db"
+	"github.com/bwen19/blog/util"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/image/webp"
+)
+
+// isoBoxBytes encodes a box of an ISO base media file
+func isoBoxBytes(typ string, payload ...[]byte) []byte {
+	data := bytes.Join(payload, nil)
+	buf := binary.BigEndian.AppendUint32(nil, uint32(8+len(data)))
+	buf = append(buf, typ...)
+	return append(buf, data...)
+}
+
+func ftypBytes(major string, compatible ...string) []byte {
+	payload := []byte(major + "\x00\x00\x00\x00")
+	for _, brand := range compatible {
+		payload = append(payload, brand...)
+	}
+	return isoBoxBytes("ftyp", payload)
+}
+
+func ispeBytes(width, height uint32) []byte {
+	payload := binary.BigEndian.AppendUint32(make([]byte, 4), width)
+	return isoBoxBytes("ispe", binary.BigEndian.AppendUint32(payload, height))
+}
+
+// avifBytes builds the header of an AVIF file whose primary item 1 has the
+// properties at indexes of ipco, which holds an ispe box per size
+func avifBytes(sizes [][2]uint32, indexes ...byte) []byte {
+	pitm := isoBoxBytes("pitm", []byte{0, 0, 0, 0, 0, 1})
+
+	properties := [][]byte{}
+	for _, size := range sizes {
+		properties = append(properties, ispeBytes(size[0], size[1]))
+	}
+	ipco := isoBoxBytes("ipco", properties...)
+
+	// version 0 and flags 0, one entry for item 1
+	ipma := []byte{0, 0, 0, 0, 0, 0, 0, 1, 0, 1, byte(len(indexes))}
+	ipma = isoBoxBytes("ipma", ipma, indexes)
+
+	meta := isoBoxBytes("meta", make([]byte, 4), pitm, isoBoxBytes("iprp", ipco, ipma))
+	return append(ftypBytes("avif", "mif1", "miaf"), meta...)
+}
+
+func encodeImage(t *testing.T, encode func(*bytes.Buffer, image.Image) error) []byte {
+	img := image.NewNRGBA(image.Rect(0, 0, 40, 30))
+	for x := 0; x < 40; x++ {
+		img.Set(x, x%30, color.NRGBA{R: 200, A: 255})
+	}
+	var buf bytes.Buffer
+	require.NoError(t, encode(&buf, img))
+	return buf.Bytes()
+}
+
+func TestSniffImage(t *testing.T) {
+	pngFile := encodeImage(t, func(w *bytes.Buffer, m image.Image) error { return png.Encode(w, m) })
+	jpegFile := encodeImage(t, func(w *bytes.Buffer, m image.Image) error { return jpeg.Encode(w, m, nil) })
+	gifFile := encodeImage(t, func(w *bytes.Buffer, m image.Image) error { return gif.Encode(w, m, nil) })
+	webpFile := []byte("RIFF\x1a\x00\x00\x00WEBPVP8L\x0d\x00\x00\x00")
+
+	testCases := []struct {
+		name     string
+		head     []byte
+		mimeType string
+		ext      string
+	}{
+		{"PNG", pngFile, "image/png", ".png"},
+		{"JPEG", jpegFile, "image/jpeg", ".jpg"},
+		{"GIF", gifFile, "image/gif", ".gif"},
+		{"WebP", webpFile, "image/webp", ".webp"},
+		{"AVIF", avifBytes([][2]uint32{{40, 30}}, 1), "image/avif", ".avif"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mimeType, ext, err := sniffImage(tc.head)
+			require.NoError(t, err)
+			require.Equal(t, tc.mimeType, mimeType)
+			require.Equal(t, tc.ext, ext)
+		})
+	}
+
+	for _, head := range [][]byte{
+		[]byte("<svg xmlns=\"http://www.w3.org/2000/svg\"></svg>"),
+		[]byte("%PDF-1.7"),
+		ftypBytes("heic", "mif1"),
+		{},
+	} {
+		_, _, err := sniffImage(head)
+		require.ErrorIs(t, err, errUnsupportedMedia)
+	}
+}
+
+func TestIsAVIF(t *testing.T) {
+	require.True(t, isAVIF(ftypBytes("avif")))
+	require.True(t, isAVIF(ftypBytes("avis")))
+	require.True(t, isAVIF(ftypBytes("mif1", "miaf", "avif")))
+
+	require.False(t, isAVIF(ftypBytes("heic", "mif1", "heix")))
+	require.False(t, isAVIF(ftypBytes("isom", "mp41")))
+	require.False(t, isAVIF([]byte("\x00\x00\x00\x10ftyp")))
+	require.False(t, isAVIF([]byte("avif avif avif avif")))
+	require.False(t, isAVIF(nil))
+
+	// A brand is only read from the ftyp box itself
+	head := append(ftypBytes("mif1", "miaf"), isoBoxBytes("free", []byte("avif"))...)
+	require.False(t, isAVIF(head))
+}
+
+func writeTestFile(t *testing.T, data []byte) string {
+	fileName := filepath.Join(t.TempDir(), "upload")
+	require.NoError(t, os.WriteFile(fileName, data, 0644))
+	return fileName
+}
+
+func TestCheckImage(t *testing.T) {
+	pngFile := encodeImage(t, func(w *bytes.Buffer, m image.Image) error { return png.Encode(w, m) })
+	img, width, height, err := checkImage(writeTestFile(t, pngFile), "image/png")
+	require.NoError(t, err)
+	require.NotNil(t, img)
+	require.Equal(t, int32(40), width)
+	require.Equal(t, int32(30), height)
+
+	jpegFile := encodeImage(t, func(w *bytes.Buffer, m image.Image) error { return jpeg.Encode(w, m, nil) })
+	img, width, height, err = checkImage(writeTestFile(t, jpegFile), "image/jpeg")
+	require.NoError(t, err)
+	require.NotNil(t, img)
+	require.Equal(t, int32(40), width)
+	require.Equal(t, int32(30), height)
+
+	// A valid header followed by broken image data
+	_, _, _, err = checkImage(writeTestFile(t, pngFile[:len(pngFile)/2]), "image/png")
+	require.ErrorIs(t, err, errInvalidImage)
+	_, _, _, err = checkImage(writeTestFile(t, []byte("not an image")), "image/png")
+	require.ErrorIs(t, err, errInvalidImage)
+
+	_, _, _, err = checkImage(filepath.Join(t.TempDir(), "missing"), "image/png")
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestCheckImageAVIF(t *testing.T) {
+	img, width, height, err := checkImage(writeTestFile(t, avifBytes([][2]uint32{{640, 480}}, 1)), "image/avif")
+	require.NoError(t, err)
+	require.Nil(t, img)
+	require.Equal(t, int32(640), width)
+	require.Equal(t, int32(480), height)
+
+	// The size is read from the property of the primary item, with the high
+	// bit of the index marking it as essential
+	_, width, height, err = checkImage(writeTestFile(t, avifBytes([][2]uint32{{64, 64}, {800, 600}}, 0x82)), "image/avif")
+	require.NoError(t, err)
+	require.Equal(t, int32(800), width)
+	require.Equal(t, int32(600), height)
+
+	_, _, _, err = checkImage(writeTestFile(t, avifBytes([][2]uint32{{8000, 8000}}, 1)), "image/avif")
+	require.ErrorIs(t, err, errTooManyPixels)
+
+	invalid := map[string][]byte{
+		"no association":   avifBytes([][2]uint32{{640, 480}}),
+		"bad index":        avifBytes([][2]uint32{{640, 480}}, 2),
+		"zero size":        avifBytes([][2]uint32{{0, 480}}, 1),
+		"no meta":          ftypBytes("avif", "mif1"),
+		"ispe in the data": append(ftypBytes("avif", "mif1"), isoBoxBytes("mdat", ispeBytes(640, 480))...),
+		"stray ispe":       append(ftypBytes("avif"), []byte("junk ispe\x00\x00\x00\x00\x00\x00\x02\x80\x00\x00\x01\xe0")...),
+		"truncated":        avifBytes([][2]uint32{{640, 480}}, 1)[:60],
+		"not avif":         append(ftypBytes("heic", "mif1"), avifBytes([][2]uint32{{640, 480}}, 1)[20:]...),
+	}
+	for name, data := range invalid {
+		_, _, _, err = checkImage(writeTestFile(t, data), "image/avif")
+		require.ErrorIs(t, err, errInvalidImage, name)
+	}
+}
+
+func TestMediaErrorStatus(t *testing.T) {
+	testCases := []struct {
+		name string
+		err  error
+		code int
+		msg  string
+	}{
+		{"TooLarge", fmt.Errorf("save: %w", errMediaTooLarge), http.StatusRequestEntityTooLarge, "file is larger than 1024 bytes"},
+		{"MaxBytes", &http.MaxBytesError{Limit: 1024}, http.StatusRequestEntityTooLarge, "file is larger than 1024 bytes"},
+		{"TooManyPixels", errTooManyPixels, http.StatusRequestEntityTooLarge, fmt.Sprintf("image is larger than %d pixels", maxImagePixels)},
+		{"Unsupported", errUnsupportedMedia, http.StatusUnsupportedMediaType, "only jpeg, png, gif, webp and avif images are allowed"},
+		{"Invalid", errInvalidImage, http.StatusUnsupportedMediaType, errInvalidImage.Error()},
+		{"Internal", os.ErrPermission, http.StatusInternalServerError, "failed to save the file"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			code, msg := mediaErrorStatus(tc.err, 1024)
+			require.Equal(t, tc.code, code)
+			require.Equal(t, tc.msg, msg)
+		})
+	}
+}
+
+func graphicTestImage(width, height int) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			c := color.NRGBA{R: uint8(x), G: uint8(y), B: 160, A: 255}
+			if (x/30+y/30)%4 == 0 {
+				c = color.NRGBA{R: 250, G: 250, B: 250, A: 255}
+			}
+			img.SetNRGBA(x, y, c)
+		}
+	}
+	return img
+}
+
+// requireLossyWebP checks that a file is a WebP image of the lossy format,
+// which decodes to YCbCr
+func requireLossyWebP(t *testing.T, fileName string) {
+	f, err := os.Open(fileName)
+	require.NoError(t, err)
+	defer f.Close()
+
+	img, err := webp.Decode(f)
+	require.NoError(t, err)
+	require.IsType(t, &image.YCbCr{}, img)
+}
+
+func TestWriteVariants(t *testing.T) {
+	publicPath := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(publicPath, "image", "post"), 0755))
+	server := &Server{config: util.Config{PublicPath: publicPath}}
+
+	// JPEG images get JPEG variants of the widths below their own, next to
+	// smaller lossy WebP variants
+	img := graphicTestImage(700, 400)
+	variants, err := server.writeVariants(img, "image/jpeg", "/image/post/photo.jpg", 1<<20, postImageVariants)
+	require.NoError(t, err)
+	require.Len(t, variants, 5)
+	for i, width := range []int32{320, 640} {
+		jpegVariant, webpVariant := variants[2*i], variants[2*i+1]
+		require.Equal(t, fmt.Sprintf("/image/post/photo-%dw.jpg", width), jpegVariant.Path)
+		require.Equal(t, "image/jpeg", jpegVariant.MimeType)
+		require.Equal(t, width, jpegVariant.Width)
+		require.FileExists(t, filepath.Join(publicPath, jpegVariant.Path))
+
+		require.Equal(t, fmt.Sprintf("/image/post/photo-%dw.webp", width), webpVariant.Path)
+		require.Equal(t, "image/webp", webpVariant.MimeType)
+		require.Less(t, webpVariant.Size, jpegVariant.Size)
+		requireLossyWebP(t, filepath.Join(publicPath, webpVariant.Path))
+	}
+	require.Equal(t, "/image/post/photo-700w.webp", variants[4].Path)
+	requireLossyWebP(t, filepath.Join(publicPath, variants[4].Path))
+
+	// Lossy WebP images keep lossy variants
+	ycbcr := image.NewYCbCr(image.Rect(0, 0, 700, 400), image.YCbCrSubsampleRatio420)
+	variants, err = server.writeVariants(ycbcr, "image/webp", "/image/post/lossy.webp", 1<<20, postImageVariants)
+	require.NoError(t, err)
+	require.Len(t, variants, 2)
+	for _, variant := range variants {
+		require.Equal(t, "image/webp", variant.MimeType)
+		requireLossyWebP(t, filepath.Join(publicPath, variant.Path))
+	}
+
+	// WebP variants of PNG images are kept when smaller than the PNG variant
+	// of the same width, and the image gets one of its own width as well
+	variants, err = server.writeVariants(img, "image/png", "/image/post/graphic.png", 1<<20, postImageVariants)
+	require.NoError(t, err)
+	sizes := map[string]int64{}
+	for _, variant := range variants {
+		require.FileExists(t, filepath.Join(publicPath, variant.Path))
+		sizes[variant.Path] = variant.Size
+	}
+	require.Contains(t, sizes, "/image/post/graphic-320w.png")
+	require.Contains(t, sizes, "/image/post/graphic-640w.png")
+	require.Contains(t, sizes, "/image/post/graphic-700w.webp")
+	for p, size := range sizes {
+		if strings.HasSuffix(p, "w.webp") && p != "/image/post/graphic-700w.webp" {
+			require.Less(t, size, sizes[strings.TrimSuffix(p, ".webp")+".png"])
+		}
+	}
+
+	// Images narrower than the smallest width still get a WebP variant
+	img = graphicTestImage(200, 120)
+	variants, err = server.writeVariants(img, "image/png", "/image/post/small.png", 1<<20, postImageVariants)
+	require.NoError(t, err)
+	require.Len(t, variants, 1)
+	require.Equal(t, "/image/post/small-200w.webp", variants[0].Path)
+	require.Equal(t, int32(200), variants[0].Width)
+	require.Equal(t, int32(120), variants[0].Height)
+
+	// but not when it is larger than the source
+	variants, err = server.writeVariants(img, "image/png", "/image/post/tiny.png", 10, postImageVariants)
+	require.NoError(t, err)
+	require.Empty(t, variants)
+	require.NoFileExists(t, filepath.Join(publicPath, "image", "post", "tiny-200w.webp"))
+
+	// Images without a decoder get no variants
+	variants, err = server.writeVariants(nil, "image/avif", "/image/post/photo.avif", 1<<20, postImageVariants)
+	require.NoError(t, err)
+	require.Empty(t, variants)
+}
+
+// mediaStore keeps media in memory for the variant queue
+type mediaStore struct {
+	db.Store
+	media map[int64]db.Media
+}
+
+func (s *mediaStore) MarkMediaVariantsPending(ctx context.Context, id int64) error {
+	media := s.media[id]
+	media.VariantsPending = true
+	s.media[id] = media
+	return nil
+}
+
+func (s *mediaStore) ListVariantsPendingMedia(ctx context.Context, limit int32) ([]db.Media, error) {
+	pending := []db.Media{}
+	for id := int64(1); id <= int64(len(s.media)) && len(pending) < int(limit); id++ {
+		if s.media[id].VariantsPending {
+			pending = append(pending, s.media[id])
+		}
+	}
+	return pending, nil
+}
+
+func (s *mediaStore) ClaimMediaVariants(ctx context.Context, id int64) (int64, error) {
+	media := s.media[id]
+	if !media.VariantsPending {
+		return 0, nil
+	}
+	media.VariantsPending = false
+	s.media[id] = media
+	return 1, nil
+}
+
+func TestQueueVariants(t *testing.T) {
+	store := &mediaStore{media: map[int64]db.Media{
+		1: {ID: 1, Path: "/image/post/img-1-1.png", MimeType: "image/png"},
+		2: {ID: 2, Path: "/image/avatar/img-2-1.png", MimeType: "image/png"},
+	}}
+	server := &Server{
+		config:      util.Config{PublicPath: t.TempDir(), AvatarPath: "/image/avatar"},
+		store:       store,
+		variantJobs: make(chan variantJob, 1),
+	}
+	ctx := context.Background()
+
+	// Uploads that find the queue full are marked as pending without waiting
+	server.queueVariants(ctx, store.media[1], postImageVariants)
+	server.queueVariants(ctx, store.media[2], avatarVariants)
+	require.False(t, store.media[1].VariantsPending)
+	require.True(t, store.media[2].VariantsPending)
+
+	queued, err := server.requeueVariants(ctx)
+	require.NoError(t, err)
+	require.Zero(t, queued)
+
+	// and are queued again with their kind of variants once there is room
+	job := <-server.variantJobs
+	require.Equal(t, int64(1), job.media.ID)
+
+	queued, err = server.requeueVariants(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 1, queued)
+	job = <-server.variantJobs
+	require.Equal(t, int64(2), job.media.ID)
+	require.Equal(t, avatarVariants, job.spec)
+
+	// A pending upload is claimed once, however often it is queued
+	server.addVariants(ctx, job)
+	require.False(t, store.media[2].VariantsPending)
+	server.addVariants(ctx, job)
+
+	queued, err = server.requeueVariants(ctx)
+	require.NoError(t, err)
+	require.Zero(t, queued)
+}
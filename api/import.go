@@ -0,0 +1,529 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/bwen19/blog/importer"
+	"github.com/bwen19/blog/psql/db"
+	"github.com/bwen19/blog/util"
+)
+
+const (
+	// Limit of the size of an uploaded export file
+	maxImportSize = 64 << 20
+	// Limit of the size of a single media file copied from the old blog
+	maxMediaSize = 10 << 20
+)
+
+// mediaClient only connects to public addresses, so that an export cannot
+// make the server fetch from itself or its private network. Addresses are
+// checked after name resolution and for every redirect. Proxies are not
+// used, since the proxy would be checked instead of the old blog.
+var mediaClient = &http.Client{
+	Timeout: 30 * time.Second,
+	Transport: &http.Transport{
+		DialContext: (&net.Dialer{
+			Timeout: 10 * time.Second,
+			Control: dialPublicOnly,
+		}).DialContext,
+		TLSHandshakeTimeout: 10 * time.Second,
+		IdleConnTimeout:     90 * time.Second,
+	},
+}
+
+var errNotPublicAddress = errors.New("address is not public")
+
+// Ranges of global unicast addresses that are not routed on the internet
+var nonPublicPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+}
+
+// dialPublicOnly refuses to connect to loopback, private, link-local and
+// other addresses that are not public
+func dialPublicOnly(network string, address string, c syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip, err := netip.ParseAddr(host)
+	if err != nil {
+		return err
+	}
+
+	ip = ip.Unmap()
+	if !ip.IsGlobalUnicast() || ip.IsPrivate() {
+		return errNotPublicAddress
+	}
+	for _, prefix := range nonPublicPrefixes {
+		if prefix.Contains(ip) {
+			return errNotPublicAddress
+		}
+	}
+	return nil
+}
+
+type ImportCount struct {
+	Created  int `json:"created"`
+	Existing int `json:"existing"`
+	Skipped  int `json:"skipped"`
+}
+
+// ImportReport summarizes an import, a dry run reports what would be created
+type ImportReport struct {
+	DryRun     bool        `json:"dryRun"`
+	Source     string      `json:"source"`
+	Users      ImportCount `json:"users"`
+	Categories ImportCount `json:"categories"`
+	Tags       ImportCount `json:"tags"`
+	Posts      ImportCount `json:"posts"`
+	Comments   ImportCount `json:"comments"`
+	Media      ImportCount `json:"media"`
+	Warnings   []string    `json:"warnings"`
+}
+
+func (report *ImportReport) warn(format string, args ...interface{}) {
+	report.Warnings = append(report.Warnings, fmt.Sprintf(format, args...))
+}
+
+// HandleImport imports a WordPress WXR or Ghost JSON export. Posts and
+// comments are recorded by their IDs in the export, so that running the
+// same import again only adds what is missing.
+func (server *Server) HandleImport(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	if _, gErr := server.httpGuard(r, roleAdmin); gErr != nil {
+		gErr.HttpErr(w)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportSize)
+	if err := r.ParseMultipartForm(maxImportSize); err != nil {
+		httpError(w, http.StatusBadRequest, "failed to parse multipart form")
+		return
+	}
+
+	f, _, err := r.FormFile("file")
+	if err != nil {
+		httpError(w, http.StatusBadRequest, "failed to get export file from multipart form")
+		return
+	}
+	defer f.Close()
+
+	var site *importer.Site
+	switch params["format"] {
+	case "wordpress":
+		site, err = importer.ParseWordPress(f)
+	case "ghost":
+		site, err = importer.ParseGhost(f, r.FormValue("baseUrl"))
+	default:
+		httpError(w, http.StatusBadRequest, "invalid import format")
+		return
+	}
+	if err != nil {
+		httpError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if baseURL := r.FormValue("baseUrl"); baseURL != "" {
+		site.BaseURL = strings.TrimRight(baseURL, "/")
+	}
+
+	// Records are kept per source, the base URL tells different blogs apart
+	source := r.FormValue("source")
+	if source == "" {
+		source = params["format"] + ":" + site.BaseURL
+	}
+
+	report := &ImportReport{
+		DryRun:   r.FormValue("dryRun") == "true",
+		Source:   source,
+		Warnings: []string{},
+	}
+	if err = server.importSite(r.Context(), site, report); err != nil {
+		log.Println("failed to import site:", err)
+		httpError(w, http.StatusInternalServerError, "failed to import site")
+		return
+	}
+
+	if !report.DryRun && report.Posts.Created > 0 {
+		server.sitemap.invalidate()
+		server.related.invalidate()
+	}
+	httpResponse(w, report)
+}
+
+func (server *Server) importSite(ctx context.Context, site *importer.Site, report *ImportReport) error {
+	users, err := server.importUsers(ctx, site, report)
+	if err != nil {
+		return err
+	}
+	categories, err := server.importCategories(ctx, site.Categories, report)
+	if err != nil {
+		return err
+	}
+	tags, err := server.importTags(ctx, site.Tags, report)
+	if err != nil {
+		return err
+	}
+
+	for _, post := range site.Posts {
+		authorID, ok := users[post.AuthorKey]
+		if !ok {
+			report.Posts.Skipped++
+			report.warn("post %s (%s): author %s has no email", post.ID, post.Title, post.AuthorKey)
+			continue
+		}
+
+		arg1 := db.GetImportRecordParams{
+			Source:     report.Source,
+			Kind:       "post",
+			ExternalID: post.ID,
+		}
+		postID, err := server.store.GetImportRecord(ctx, arg1)
+		if err == nil {
+			report.Posts.Existing++
+		} else if err != sql.ErrNoRows {
+			return err
+		} else {
+			// Media files are copied to the upload store, a dry run only counts them
+			copied := []db.Media{}
+			copyMedia := func(mediaURL string) string {
+				if report.DryRun {
+					report.Media.Created++
+					return ""
+				}
+				media, ok := server.copyMedia(ctx, mediaURL, site.BaseURL, authorID, report)
+				if !ok {
+					return ""
+				}
+				copied = append(copied, media)
+				return media.Path
+			}
+
+			arg2 := db.ImportNewPostParams{
+				Source:     report.Source,
+				ExternalID: post.ID,
+				AuthorID:   authorID,
+				Title:      post.Title,
+				CoverImage: server.config.DefaultCover,
+				Content:    importer.RewriteMedia(post.Content, copyMedia),
+				Status:     post.Status,
+				UpdateAt:   post.UpdateAt,
+				PublishAt:  post.PublishAt,
+			}
+			if importer.IsMedia(post.CoverImage) {
+				if cover := copyMedia(post.CoverImage); cover != "" {
+					arg2.CoverImage = cover
+				}
+			}
+			for _, name := range post.Categories {
+				arg2.CategoryIDs = append(arg2.CategoryIDs, categories[name])
+			}
+			for _, name := range post.Tags {
+				arg2.TagIDs = append(arg2.TagIDs, tags[name])
+			}
+
+			// A dry run goes on to count the comments of the new post
+			if !report.DryRun {
+				newPost, err := server.store.ImportNewPost(ctx, arg2)
+				if err != nil {
+					server.discardMedia(ctx, copied)
+					return err
+				}
+				postID = newPost.ID
+			}
+			report.Posts.Created++
+		}
+
+		if err = server.importComments(ctx, postID, post, users, report); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// importUsers maps users of the export to accounts by email and creates
+// accounts for the rest. Authors get the author role.
+func (server *Server) importUsers(ctx context.Context, site *importer.Site, report *ImportReport) (map[string]int64, error) {
+	users := map[string]int64{}
+	for _, user := range site.Users {
+		if user.Email == "" {
+			continue
+		}
+
+		existing, err := server.store.GetUserByEmail(ctx, user.Email)
+		if err == nil {
+			users[user.Key] = existing.ID
+			report.Users.Existing++
+			continue
+		} else if err != sql.ErrNoRows {
+			return nil, err
+		}
+
+		report.Users.Created++
+		if report.DryRun {
+			users[user.Key] = 0
+			continue
+		}
+
+		// Usernames taken by someone else get a random suffix
+		username := user.Username
+		if _, err = server.store.GetUserByUsername(ctx, username); err == nil {
+			username = fmt.Sprintf("%s-%s", username, util.RandomNumString(4))
+		} else if err != sql.ErrNoRows {
+			return nil, err
+		}
+
+		// Imported users set their password with a password reset
+		hashedPassword, err := util.HashPassword(util.RandomString(32))
+		if err != nil {
+			return nil, err
+		}
+
+		arg := db.CreateUserParams{
+			Username:       username,
+			Email:          user.Email,
+			HashedPassword: hashedPassword,
+			Avatar:         server.config.DefaultAvatar,
+			Role:           "user",
+		}
+		if user.Author {
+			arg.Role = "author"
+		}
+
+		newUser, err := server.store.CreateUser(ctx, arg)
+		if err != nil {
+			return nil, err
+		}
+		users[user.Key] = newUser.ID
+	}
+	return users, nil
+}
+
+func (server *Server) importCategories(ctx context.Context, names []string, report *ImportReport) (map[string]int64, error) {
+	categories := map[string]int64{}
+	for _, name := range names {
+		category, err := server.store.GetCategoryByName(ctx, name)
+		if err == nil {
+			categories[name] = category.ID
+			report.Categories.Existing++
+			continue
+		} else if err != sql.ErrNoRows {
+			return nil, err
+		}
+
+		report.Categories.Created++
+		if report.DryRun {
+			continue
+		}
+
+		category, err = server.store.CreateCategory(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		categories[name] = category.ID
+	}
+	return categories, nil
+}
+
+func (server *Server) importTags(ctx context.Context, names []string, report *ImportReport) (map[string]int64, error) {
+	tags := map[string]int64{}
+	for _, name := range names {
+		tag, err := server.store.GetTagsByName(ctx, name)
+		if err == nil {
+			tags[name] = tag.ID
+			report.Tags.Existing++
+			continue
+		} else if err != sql.ErrNoRows {
+			return nil, err
+		}
+
+		report.Tags.Created++
+		if report.DryRun {
+			continue
+		}
+
+		tag, err = server.store.CreateTag(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		tags[name] = tag.ID
+	}
+	return tags, nil
+}
+
+// importComments keeps the threads of the export within the two levels of
+// comments here: replies are attached to the top-level comment of their
+// thread and mention the user they replied to.
+func (server *Server) importComments(ctx context.Context, postID int64, post importer.Post, users map[string]int64, report *ImportReport) error {
+	type threadInfo struct {
+		id     int64
+		rootID int64
+		userID int64
+	}
+	threads := map[string]threadInfo{}
+
+	for _, comment := range post.Comments {
+		userID, ok := users[comment.UserKey]
+		if !ok {
+			report.Comments.Skipped++
+			continue
+		}
+
+		var parent threadInfo
+		if comment.ParentID != "" {
+			if parent, ok = threads[comment.ParentID]; !ok {
+				report.Comments.Skipped++
+				report.warn("comment %s of post %s: parent comment was not imported", comment.ID, post.ID)
+				continue
+			}
+		}
+
+		arg1 := db.GetImportRecordParams{
+			Source:     report.Source,
+			Kind:       "comment",
+			ExternalID: comment.ID,
+		}
+		commentID, err := server.store.GetImportRecord(ctx, arg1)
+		if err == nil {
+			// Imported comments that were deleted here are not brought back,
+			// and neither are their replies
+			existing, err := server.store.GetComment(ctx, commentID)
+			if err == sql.ErrNoRows {
+				report.Comments.Skipped++
+				report.warn("comment %s of post %s: imported comment was deleted", comment.ID, post.ID)
+				continue
+			} else if err != nil {
+				return err
+			}
+			threads[comment.ID] = threadInfo{
+				id:     existing.ID,
+				rootID: existing.ParentID.Int64,
+				userID: existing.UserID,
+			}
+			report.Comments.Existing++
+			continue
+		} else if err != sql.ErrNoRows {
+			return err
+		}
+
+		report.Comments.Created++
+		if report.DryRun {
+			threads[comment.ID] = threadInfo{}
+			continue
+		}
+
+		arg2 := db.ImportNewCommentParams{
+			Source:     report.Source,
+			ExternalID: comment.ID,
+			ImportCommentParams: db.ImportCommentParams{
+				PostID:   postID,
+				UserID:   userID,
+				Content:  comment.Content,
+				CreateAt: comment.CreateAt,
+			},
+		}
+		if parent.id != 0 {
+			rootID := parent.id
+			if parent.rootID != 0 {
+				rootID = parent.rootID
+				arg2.ReplyUserID = sql.NullInt64{Int64: parent.userID, Valid: true}
+			}
+			arg2.ParentID = sql.NullInt64{Int64: rootID, Valid: true}
+		}
+
+		newComment, err := server.store.ImportNewComment(ctx, arg2)
+		if err != nil {
+			return err
+		}
+		threads[comment.ID] = threadInfo{
+			id:     newComment.ID,
+			rootID: newComment.ParentID.Int64,
+			userID: newComment.UserID,
+		}
+	}
+	return nil
+}
+
+// copyMedia downloads a media file of the old blog into the media library of
+// the author and returns the recorded media, or false if it cannot be copied
+func (server *Server) copyMedia(ctx context.Context, mediaURL string, baseURL string, ownerID int64, report *ImportReport) (db.Media, bool) {
+	src, err := url.Parse(mediaURL)
+	if err != nil {
+		report.Media.Skipped++
+		report.warn("media %s: invalid url", mediaURL)
+		return db.Media{}, false
+	}
+	if !src.IsAbs() {
+		base, err := url.Parse(baseURL + "/")
+		if err != nil || baseURL == "" {
+			report.Media.Skipped++
+			report.warn("media %s: relative url without base url", mediaURL)
+			return db.Media{}, false
+		}
+		src = base.ResolveReference(src)
+	}
+
+	rsp, err := mediaClient.Get(src.String())
+	if err != nil {
+		report.Media.Skipped++
+		report.warn("media %s: %s", mediaURL, err.Error())
+		return db.Media{}, false
+	}
+	defer rsp.Body.Close()
+
+	if rsp.StatusCode != http.StatusOK {
+		report.Media.Skipped++
+		report.warn("media %s: unexpected status %s", mediaURL, rsp.Status)
+		return db.Media{}, false
+	}
+
+	media, err := server.saveMedia(ctx, rsp.Body, server.config.PostPath, ownerID, "", maxMediaSize, postImageVariants)
+	if err != nil {
+		report.Media.Skipped++
+		_, msg := mediaErrorStatus(err, maxMediaSize)
+		report.warn("media %s: %s", mediaURL, msg)
+		return db.Media{}, false
+	}
+
+	report.Media.Created++
+	return media, true
+}
+
+// discardMedia removes the media copied for a post that could not be created.
+// Variants that are written after the media is deleted fail to be recorded
+// and are removed by the variant writer.
+func (server *Server) discardMedia(ctx context.Context, copied []db.Media) {
+	for _, media := range copied {
+		paths := []string{media.Path}
+		variants, err := server.store.ListMediaVariants(ctx, []int64{media.ID})
+		if err != nil {
+			log.Println("failed to list copied media variants:", err)
+		}
+		for _, variant := range variants {
+			paths = append(paths, variant.Path)
+		}
+
+		arg := db.DeleteMediaByPathParams{
+			Path:    media.Path,
+			OwnerID: media.OwnerID,
+		}
+		if err := server.store.DeleteMediaByPath(ctx, arg); err != nil {
+			log.Println("failed to delete copied media:", err)
+			continue
+		}
+		server.removeMediaFiles(paths...)
+	}
+}
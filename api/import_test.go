@@ -0,0 +1,284 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/bwen19/blog/importer"
+	"github.com/bwen19/blog/psql/db"
+	"github.com/bwen19/blog/util"
+	"github.com/stretchr/testify/require"
+)
+
+// importStore keeps the records of imports in memory
+type importStore struct {
+	db.Store
+	users      []db.User
+	categories []db.Category
+	tags       []db.Tag
+	posts      []db.ImportNewPostParams
+	comments   []db.Comment
+	records    map[db.GetImportRecordParams]int64
+}
+
+func (s *importStore) GetUserByEmail(ctx context.Context, email string) (db.User, error) {
+	for _, user := range s.users {
+		if user.Email == email {
+			return user, nil
+		}
+	}
+	return db.User{}, sql.ErrNoRows
+}
+
+func (s *importStore) GetUserByUsername(ctx context.Context, username string) (db.User, error) {
+	for _, user := range s.users {
+		if user.Username == username {
+			return user, nil
+		}
+	}
+	return db.User{}, sql.ErrNoRows
+}
+
+func (s *importStore) CreateUser(ctx context.Context, arg db.CreateUserParams) (db.User, error) {
+	user := db.User{
+		ID:       int64(len(s.users) + 1),
+		Username: arg.Username,
+		Email:    arg.Email,
+		Role:     arg.Role,
+	}
+	s.users = append(s.users, user)
+	return user, nil
+}
+
+func (s *importStore) GetCategoryByName(ctx context.Context, name string) (db.Category, error) {
+	for _, category := range s.categories {
+		if category.Name == name {
+			return category, nil
+		}
+	}
+	return db.Category{}, sql.ErrNoRows
+}
+
+func (s *importStore) CreateCategory(ctx context.Context, name string) (db.Category, error) {
+	category := db.Category{ID: int64(len(s.categories) + 1), Name: name}
+	s.categories = append(s.categories, category)
+	return category, nil
+}
+
+func (s *importStore) GetTagsByName(ctx context.Context, name string) (db.Tag, error) {
+	for _, tag := range s.tags {
+		if tag.Name == name {
+			return tag, nil
+		}
+	}
+	return db.Tag{}, sql.ErrNoRows
+}
+
+func (s *importStore) CreateTag(ctx context.Context, name string) (db.Tag, error) {
+	tag := db.Tag{ID: int64(len(s.tags) + 1), Name: name}
+	s.tags = append(s.tags, tag)
+	return tag, nil
+}
+
+func (s *importStore) GetImportRecord(ctx context.Context, arg db.GetImportRecordParams) (int64, error) {
+	id, ok := s.records[arg]
+	if !ok {
+		return 0, sql.ErrNoRows
+	}
+	return id, nil
+}
+
+func (s *importStore) ImportNewPost(ctx context.Context, arg db.ImportNewPostParams) (db.Post, error) {
+	s.posts = append(s.posts, arg)
+	id := int64(len(s.posts))
+	s.records[db.GetImportRecordParams{Source: arg.Source, Kind: "post", ExternalID: arg.ExternalID}] = id
+	return db.Post{ID: id, Title: arg.Title}, nil
+}
+
+func (s *importStore) GetComment(ctx context.Context, id int64) (db.Comment, error) {
+	for _, comment := range s.comments {
+		if comment.ID == id {
+			return comment, nil
+		}
+	}
+	return db.Comment{}, sql.ErrNoRows
+}
+
+func (s *importStore) ImportNewComment(ctx context.Context, arg db.ImportNewCommentParams) (db.Comment, error) {
+	comment := db.Comment{
+		ID:          int64(len(s.comments) + 1),
+		PostID:      arg.PostID,
+		UserID:      arg.UserID,
+		ParentID:    arg.ParentID,
+		ReplyUserID: arg.ReplyUserID,
+		Content:     arg.Content,
+		CreateAt:    arg.CreateAt,
+	}
+	s.comments = append(s.comments, comment)
+	s.records[db.GetImportRecordParams{Source: arg.Source, Kind: "comment", ExternalID: arg.ExternalID}] = comment.ID
+	return comment, nil
+}
+
+func importTestSite() *importer.Site {
+	now := time.Now().UTC().Truncate(time.Second)
+	return &importer.Site{
+		BaseURL: "https://old.example.com",
+		Users: []importer.User{
+			{Key: "alice", Username: "alice", Email: "alice@example.com", Author: true},
+			{Key: "email:bob@example.com", Username: "bob", Email: "bob@example.com"},
+			{Key: "carol", Author: true},
+		},
+		Categories: []string{"News"},
+		Tags:       []string{"go", "web"},
+		Posts: []importer.Post{
+			{
+				ID:         "1",
+				AuthorKey:  "alice",
+				Title:      "Hello",
+				Content:    "Text",
+				Status:     "publish",
+				PublishAt:  now,
+				UpdateAt:   now,
+				Categories: []string{"News"},
+				Tags:       []string{"go"},
+				Comments: []importer.Comment{
+					{ID: "5", UserKey: "email:bob@example.com", Content: "Top", CreateAt: now},
+					{ID: "6", ParentID: "5", UserKey: "alice", Content: "Reply", CreateAt: now},
+					{ID: "7", ParentID: "6", UserKey: "email:bob@example.com", Content: "Reply to reply", CreateAt: now},
+					{ID: "8", Content: "Guest", CreateAt: now},
+				},
+			},
+			{ID: "2", AuthorKey: "carol", Title: "No email", Status: "draft", PublishAt: now, UpdateAt: now},
+		},
+	}
+}
+
+func TestImportSiteTwice(t *testing.T) {
+	store := &importStore{records: map[db.GetImportRecordParams]int64{}}
+	server := &Server{
+		config: util.Config{DefaultAvatar: "/image/avatar/default", DefaultCover: "/image/post/default"},
+		store:  store,
+	}
+	ctx := context.Background()
+
+	created := ImportReport{
+		Source:     "wordpress:https://old.example.com",
+		Users:      ImportCount{Created: 2},
+		Categories: ImportCount{Created: 1},
+		Tags:       ImportCount{Created: 2},
+		Posts:      ImportCount{Created: 1, Skipped: 1},
+		Comments:   ImportCount{Created: 3, Skipped: 1},
+	}
+
+	// A dry run reports what an import creates, without creating anything
+	report := &ImportReport{DryRun: true, Source: created.Source, Warnings: []string{}}
+	require.NoError(t, server.importSite(ctx, importTestSite(), report))
+	require.Len(t, report.Warnings, 1)
+	report.Warnings, report.DryRun = nil, false
+	require.Equal(t, created, *report)
+	require.Empty(t, store.users)
+	require.Empty(t, store.posts)
+
+	report = &ImportReport{Source: created.Source, Warnings: []string{}}
+	require.NoError(t, server.importSite(ctx, importTestSite(), report))
+	report.Warnings = nil
+	require.Equal(t, created, *report)
+
+	require.Len(t, store.posts, 1)
+	post := store.posts[0]
+	require.Equal(t, store.users[0].ID, post.AuthorID)
+	require.Equal(t, "author", store.users[0].Role)
+	require.Equal(t, "user", store.users[1].Role)
+	require.Equal(t, "/image/post/default", post.CoverImage)
+	require.Equal(t, []int64{1}, post.CategoryIDs)
+	require.Equal(t, []int64{1}, post.TagIDs)
+
+	// Replies are attached to the top-level comment of their thread and
+	// mention the user they replied to
+	require.Len(t, store.comments, 3)
+	top, reply, nested := store.comments[0], store.comments[1], store.comments[2]
+	require.False(t, top.ParentID.Valid)
+	require.Equal(t, top.ID, reply.ParentID.Int64)
+	require.False(t, reply.ReplyUserID.Valid)
+	require.Equal(t, top.ID, nested.ParentID.Int64)
+	require.Equal(t, reply.UserID, nested.ReplyUserID.Int64)
+
+	// A second run finds everything it created before
+	report = &ImportReport{Source: created.Source, Warnings: []string{}}
+	require.NoError(t, server.importSite(ctx, importTestSite(), report))
+	report.Warnings = nil
+	require.Equal(t, ImportReport{
+		Source:     created.Source,
+		Users:      ImportCount{Existing: 2},
+		Categories: ImportCount{Existing: 1},
+		Tags:       ImportCount{Existing: 2},
+		Posts:      ImportCount{Existing: 1, Skipped: 1},
+		Comments:   ImportCount{Existing: 3, Skipped: 1},
+	}, *report)
+	require.Len(t, store.users, 2)
+	require.Len(t, store.posts, 1)
+	require.Len(t, store.comments, 3)
+}
+
+func TestDialPublicOnly(t *testing.T) {
+	testCases := []struct {
+		address string
+		public  bool
+	}{
+		{"93.184.216.34:443", true},
+		{"[2606:2800:220:1::]:443", true},
+		{"127.0.0.1:80", false},
+		{"[::1]:80", false},
+		{"[::ffff:127.0.0.1]:80", false},
+		{"10.1.2.3:80", false},
+		{"172.16.0.1:80", false},
+		{"192.168.1.1:80", false},
+		{"[fd00::1]:80", false},
+		{"169.254.169.254:80", false},
+		{"[fe80::1]:80", false},
+		{"100.100.100.200:80", false},
+		{"0.0.0.0:80", false},
+		{"[::]:80", false},
+		{"224.0.0.1:80", false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.address, func(t *testing.T) {
+			err := dialPublicOnly("tcp", tc.address, nil)
+			if tc.public {
+				require.NoError(t, err)
+			} else {
+				require.ErrorIs(t, err, errNotPublicAddress)
+			}
+		})
+	}
+}
+
+func TestCopyMediaPrivateAddress(t *testing.T) {
+	requests := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Write([]byte("image"))
+	}))
+	defer ts.Close()
+
+	server := &Server{}
+	report := &ImportReport{Warnings: []string{}}
+
+	// Neither absolute URLs nor ones relative to the base URL of an export
+	// reach the local network
+	_, ok := server.copyMedia(context.Background(), ts.URL+"/a.png", "", 1, report)
+	require.False(t, ok)
+	_, ok = server.copyMedia(context.Background(), "/b.png", ts.URL, 1, report)
+	require.False(t, ok)
+
+	require.Zero(t, requests)
+	require.Equal(t, ImportCount{Skipped: 2}, report.Media)
+	require.Len(t, report.Warnings, 2)
+	for _, warning := range report.Warnings {
+		require.Contains(t, warning, errNotPublicAddress.Error())
+	}
+}
@@ -0,0 +1,461 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"io/fs"
+	"log"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/bwen19/blog/grpc/pb"
+	"github.com/bwen19/blog/psql/db"
+	"github.com/bwen19/blog/util"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+var errMediaTooLarge = errors.New("media file is too large")
+
+// saveMedia writes an image under dir of the public path and records it in
+// the media library of its owner, then queues the image for its variants.
+// Files larger than limit, of types out of the allow-list or failing to
+// decode are rejected.
+func (server *Server) saveMedia(ctx context.Context, r io.Reader, dir string, ownerID int64, altText string, limit int64, spec variantSpec) (db.Media, error) {
+	var media db.Media
+
+	br := bufio.NewReaderSize(io.LimitReader(r, limit+1), 512)
+	head, _ := br.Peek(512)
+
+	mimeType, ext, err := sniffImage(head)
+	if err != nil {
+		return media, err
+	}
+
+	src := path.Join(dir, util.RandomImageName()+ext)
+	fileName := path.Join(server.config.PublicPath, src)
+
+	fn, err := os.OpenFile(fileName, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return media, err
+	}
+	defer fn.Close()
+
+	hash := sha256.New()
+	n, err := io.Copy(io.MultiWriter(fn, hash), br)
+	if err == nil && n > limit {
+		err = errMediaTooLarge
+	}
+	if err != nil {
+		os.Remove(fileName)
+		return media, err
+	}
+
+	arg := db.CreateMediaParams{
+		OwnerID:  ownerID,
+		Path:     src,
+		Size:     n,
+		MimeType: mimeType,
+		Sha256:   hex.EncodeToString(hash.Sum(nil)),
+		AltText:  altText,
+	}
+
+	_, width, height, err := checkImage(fileName, mimeType)
+	if err != nil {
+		os.Remove(fileName)
+		return media, err
+	}
+	arg.Width, arg.Height = width, height
+
+	media, err = server.store.CreateMedia(ctx, arg)
+	if err != nil {
+		os.Remove(fileName)
+		return media, err
+	}
+
+	server.queueVariants(ctx, media, spec)
+	return media, nil
+}
+
+// removeMediaFiles removes files of deleted media and variants from the
+// public path. The default avatar and cover are never removed.
+func (server *Server) removeMediaFiles(paths ...string) {
+	for _, p := range paths {
+		if p == server.config.DefaultAvatar || p == server.config.DefaultCover {
+			continue
+		}
+		if err := os.Remove(path.Join(server.config.PublicPath, p)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			log.Println("failed to remove media file:", err)
+		}
+	}
+}
+
+// mediaErrorStatus maps an error of saveMedia to the http status code and
+// message returned to the uploader
+func mediaErrorStatus(err error, limit int64) (int, string) {
+	var maxErr *http.MaxBytesError
+	switch {
+	case errors.Is(err, errMediaTooLarge), errors.As(err, &maxErr):
+		return http.StatusRequestEntityTooLarge, fmt.Sprintf("file is larger than %d bytes", limit)
+	case errors.Is(err, errTooManyPixels):
+		return http.StatusRequestEntityTooLarge, fmt.Sprintf("image is larger than %d pixels", maxImagePixels)
+	case errors.Is(err, errUnsupportedMedia):
+		return http.StatusUnsupportedMediaType, "only jpeg, png, gif, webp and avif images are allowed"
+	case errors.Is(err, errInvalidImage):
+		return http.StatusUnsupportedMediaType, err.Error()
+	default:
+		return http.StatusInternalServerError, "failed to save the file"
+	}
+}
+
+// ========================// ListMedia //======================== //
+
+func (server *Server) ListMedia(ctx context.Context, req *pb.ListMediaRequest) (*pb.ListMediaResponse, error) {
+	authUser, gErr := server.grpcGuard(ctx, roleUser)
+	if gErr != nil {
+		return nil, gErr.GrpcErr()
+	}
+
+	if err := validateListMediaRequest(req); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	// A type without subtype matches all of its subtypes, such as image
+	mimeType := req.GetMimeType()
+	if !strings.Contains(mimeType, "/") {
+		mimeType += "/%"
+	}
+
+	arg := db.ListMediaParams{
+		Limit:      req.GetPageSize(),
+		Offset:     (req.GetPageId() - 1) * req.GetPageSize(),
+		OwnerID:    authUser.ID,
+		AnyKeyword: req.Keyword == nil,
+		Keyword:    "%" + req.GetKeyword() + "%",
+		AnyType:    req.MimeType == nil,
+		MimeType:   mimeType,
+	}
+
+	media, err := server.store.ListMedia(ctx, arg)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to list media")
+	}
+
+	ids := make([]int64, 0, len(media))
+	for _, m := range media {
+		ids = append(ids, m.ID)
+	}
+	variants, err := server.store.ListMediaVariants(ctx, ids)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to list media variants")
+	}
+
+	rsp := convertListMedia(media)
+	for _, m := range rsp.Media {
+		m.Variants = []*pb.ImageVariant{}
+		for _, variant := range variants {
+			if variant.MediaID == m.Id {
+				m.Variants = append(m.Variants, convertImageVariant(variant))
+			}
+		}
+	}
+	return rsp, nil
+}
+
+func validateListMediaRequest(req *pb.ListMediaRequest) error {
+	if err := util.ValidatePage(req); err != nil {
+		return err
+	}
+	if req.Keyword != nil {
+		if err := util.ValidateString(req.GetKeyword(), 1, 50); err != nil {
+			return fmt.Errorf("keyword: %s", err.Error())
+		}
+	}
+	if req.MimeType != nil {
+		if err := util.ValidateString(req.GetMimeType(), 1, 50); err != nil {
+			return fmt.Errorf("mimeType: %s", err.Error())
+		}
+	}
+	return nil
+}
+
+// ========================// DeleteMedia //======================== //
+
+func (server *Server) DeleteMedia(ctx context.Context, req *pb.DeleteMediaRequest) (*emptypb.Empty, error) {
+	authUser, gErr := server.grpcGuard(ctx, roleUser)
+	if gErr != nil {
+		return nil, gErr.GrpcErr()
+	}
+
+	if err := util.ValidateID(req.GetMediaId()); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "mediaId: %s", err.Error())
+	}
+
+	arg1 := db.GetMediaParams{
+		ID:      req.GetMediaId(),
+		OwnerID: authUser.ID,
+	}
+	if _, err := server.store.GetMedia(ctx, arg1); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, status.Error(codes.NotFound, "media not found")
+		}
+		return nil, status.Error(codes.Internal, "failed to get media")
+	}
+
+	// Variants are deleted along with their media, so they are listed first
+	variants, err := server.store.ListMediaVariants(ctx, []int64{req.GetMediaId()})
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to list media variants")
+	}
+
+	// Covers and avatars have to be replaced before their media is deleted
+	arg2 := db.DeleteMediaParams{
+		ID:      req.GetMediaId(),
+		OwnerID: authUser.ID,
+	}
+	media, err := server.store.DeleteMedia(ctx, arg2)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, status.Error(codes.FailedPrecondition, "media is still used by a post, series or avatar")
+		}
+		return nil, status.Error(codes.Internal, "failed to delete media")
+	}
+
+	server.removeMediaFiles(media.Path)
+	for _, variant := range variants {
+		server.removeMediaFiles(variant.Path)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+// sweepMedia collects the uploads that are no longer referenced anywhere once
+// their grace period is over. The default avatar and cover are always kept.
+func (server *Server) sweepMedia(ctx context.Context, dryRun bool) (db.SweepMediaResult, error) {
+	keepPaths := []string{server.config.DefaultAvatar, server.config.DefaultCover}
+
+	arg := db.SweepMediaParams{
+		KeepPaths:    keepPaths,
+		OrphanBefore: time.Now().Add(-server.config.MediaOrphanGrace),
+		DryRun:       dryRun,
+	}
+	result, err := server.store.SweepMedia(ctx, arg)
+	if err != nil || dryRun {
+		return result, err
+	}
+
+	for _, media := range result.Deleted {
+		server.removeMediaFiles(media.Path)
+	}
+	for _, variant := range result.Variants {
+		server.removeMediaFiles(variant.Path)
+	}
+	return result, nil
+}
+
+// walkUnrecordedFiles calls fn for every file under the avatar and post
+// paths that is neither recorded as media or variant nor a default image
+func (server *Server) walkUnrecordedFiles(ctx context.Context, fn func(src string, fileName string, entry fs.DirEntry) error) error {
+	paths, err := server.store.ListMediaPaths(ctx)
+	if err != nil {
+		return err
+	}
+	known := map[string]bool{}
+	for _, p := range paths {
+		known[p] = true
+	}
+
+	for _, dir := range []string{server.config.AvatarPath, server.config.PostPath} {
+		root := path.Join(server.config.PublicPath, dir)
+		err = filepath.WalkDir(root, func(fileName string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				if errors.Is(err, fs.ErrNotExist) {
+					return nil
+				}
+				return err
+			}
+
+			rel, err := filepath.Rel(root, fileName)
+			if err != nil {
+				return err
+			}
+			src := path.Join(dir, filepath.ToSlash(rel))
+
+			// The default images are kept along with files and directories
+			// named after them, like default.png
+			if strings.HasPrefix(src, server.config.DefaultAvatar) || strings.HasPrefix(src, server.config.DefaultCover) {
+				if entry.IsDir() {
+					return fs.SkipDir
+				}
+				return nil
+			}
+			if entry.IsDir() || known[src] {
+				return nil
+			}
+			return fn(src, fileName, entry)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// backfillMedia records the images under the avatar and post paths that were
+// uploaded before the media library, so that they are listed, deleted and
+// swept like new uploads. Their owner is the user who refers to them.
+func (server *Server) backfillMedia(ctx context.Context) (int, error) {
+	count := 0
+	err := server.walkUnrecordedFiles(ctx, func(src string, fileName string, entry fs.DirEntry) error {
+		// Files that nothing refers to have no owner and are left alone
+		ownerID, err := server.store.GetMediaOwner(ctx, src)
+		if err == sql.ErrNoRows {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		arg, ok, err := mediaFileParams(fileName)
+		if err != nil || !ok {
+			return err
+		}
+		arg.OwnerID, arg.Path = ownerID, src
+
+		if _, err = server.store.CreateMedia(ctx, arg); err != nil {
+			return err
+		}
+		count++
+		return nil
+	})
+	return count, err
+}
+
+// mediaFileParams reads the type, size, hash and dimensions of an image
+// file, files that are not images of an allowed type are reported as not ok
+func mediaFileParams(fileName string) (db.CreateMediaParams, bool, error) {
+	var arg db.CreateMediaParams
+
+	f, err := os.Open(fileName)
+	if err != nil {
+		return arg, false, err
+	}
+	defer f.Close()
+
+	br := bufio.NewReaderSize(f, 512)
+	head, _ := br.Peek(512)
+	if arg.MimeType, _, err = sniffImage(head); err != nil {
+		return arg, false, nil
+	}
+
+	hash := sha256.New()
+	if arg.Size, err = io.Copy(hash, br); err != nil {
+		return arg, false, err
+	}
+	arg.Sha256 = hex.EncodeToString(hash.Sum(nil))
+
+	if _, err = f.Seek(0, io.SeekStart); err != nil {
+		return arg, false, err
+	}
+	var width, height int
+	if arg.MimeType == "image/avif" {
+		width, height, err = avifSize(f)
+	} else {
+		var config image.Config
+		config, _, err = image.DecodeConfig(f)
+		width, height = config.Width, config.Height
+	}
+	if err == nil {
+		arg.Width, arg.Height = int32(width), int32(height)
+	}
+	return arg, true, nil
+}
+
+// RunMediaSweeper sweeps orphaned media on start and on every interval.
+// Files that are not recorded as media are recorded first when something
+// refers to them, so that they go through the grace period and the dry run
+// of SweepMedia. Files that nothing refers to are never removed unrecorded.
+func (server *Server) RunMediaSweeper(ctx context.Context) {
+	ticker := time.NewTicker(server.config.MediaSweepInterval)
+	defer ticker.Stop()
+
+	sweep := func() {
+		count, err := server.backfillMedia(ctx)
+		if err != nil {
+			log.Println("failed to backfill media:", err)
+			return
+		}
+		if count > 0 {
+			log.Printf("recorded %d existing media files", count)
+		}
+
+		result, err := server.sweepMedia(ctx, false)
+		if err != nil {
+			log.Println("failed to sweep orphaned media:", err)
+			return
+		}
+		if len(result.Deleted) > 0 {
+			log.Printf("deleted %d orphaned media files", len(result.Deleted))
+		}
+
+		queued, err := server.requeueVariants(ctx)
+		if err != nil {
+			log.Println("failed to queue pending media variants:", err)
+			return
+		}
+		if queued > 0 {
+			log.Printf("queued variants of %d pending media files", queued)
+		}
+	}
+
+	sweep()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			sweep()
+		}
+	}
+}
+
+// ========================// SweepMedia //======================== //
+
+func (server *Server) SweepMedia(ctx context.Context, req *pb.SweepMediaRequest) (*pb.SweepMediaResponse, error) {
+	if _, gErr := server.grpcGuard(ctx, roleAdmin); gErr != nil {
+		return nil, gErr.GrpcErr()
+	}
+
+	result, err := server.sweepMedia(ctx, req.GetDryRun())
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to sweep media")
+	}
+
+	rsp := &pb.SweepMediaResponse{
+		Marked:   make([]*pb.Media, 0, len(result.Marked)),
+		Restored: result.Restored,
+		Deleted:  make([]*pb.Media, 0, len(result.Deleted)),
+		DryRun:   req.GetDryRun(),
+	}
+	for _, media := range result.Marked {
+		rsp.Marked = append(rsp.Marked, convertMedia(media))
+	}
+	for _, media := range result.Deleted {
+		rsp.Deleted = append(rsp.Deleted, convertMedia(media))
+		rsp.FreedSize += media.Size
+	}
+	for _, variant := range result.Variants {
+		rsp.FreedSize += variant.Size
+	}
+	return rsp, nil
+}
@@ -5,11 +5,16 @@ import (
 	"database/sql"
 	"fmt"
 	"log"
+	"strconv"
+	"strings"
 
 	"github.com/bwen19/blog/grpc/pb"
 	"github.com/bwen19/blog/psql/db"
 	"github.com/bwen19/blog/util"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/emptypb"
 )
@@ -50,14 +55,25 @@ func (server *Server) DeletePost(ctx context.Context, req *pb.DeletePostRequest)
 		return nil, status.Error(codes.InvalidArgument, err.Error())
 	}
 
-	arg := db.DeletePostParams{
-		ID:       req.PostId,
+	// Posts are moved to the trash and purged after the retention period
+	arg := db.TrashPostParams{
+		ID:       req.GetPostId(),
+		IsAdmin:  authUser.Role == "admin",
 		AuthorID: authUser.ID,
 	}
 
-	if err := server.store.DeletePost(ctx, arg); err != nil {
+	post, err := server.store.TrashPost(ctx, arg)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, status.Error(codes.NotFound, "post not found")
+		}
 		return nil, status.Error(codes.Internal, "failed to delete post")
 	}
+
+	if post.TrashStatus == "publish" {
+		server.sitemap.invalidate()
+		server.related.invalidate()
+	}
 	return &emptypb.Empty{}, nil
 }
 
@@ -73,61 +89,54 @@ func (server *Server) UpdatePost(ctx context.Context, req *pb.UpdatePostRequest)
 		return nil, status.Error(codes.InvalidArgument, err.Error())
 	}
 
-	arg := db.UpdatePostParams{
-		ID:         req.GetPostId(),
-		AuthorID:   authUser.ID,
-		Title:      sql.NullString{String: req.GetTitle(), Valid: req.Title != nil},
-		CoverImage: sql.NullString{String: req.GetCoverImage(), Valid: req.CoverImage != nil},
+	// Cover images are limited to the default cover, the current cover and
+	// media of the authors of the post
+	if req.CoverImage != nil && req.GetCoverImage() != server.config.DefaultCover {
+		arg := db.CanUsePostCoverParams{
+			PostID: req.GetPostId(),
+			Path:   req.GetCoverImage(),
+			UserID: authUser.ID,
+		}
+		ok, err := server.store.CanUsePostCover(ctx, arg)
+		if err != nil {
+			return nil, status.Error(codes.Internal, "failed to check cover image")
+		}
+		if !ok {
+			return nil, status.Error(codes.InvalidArgument, "coverImage: must be media of the post authors or the default cover")
+		}
 	}
 
-	newPost, err := server.store.UpdatePost(ctx, arg)
+	version, err := extractPostVersion(ctx, req)
 	if err != nil {
-		return nil, status.Error(codes.Internal, "failed to update post")
+		return nil, status.Errorf(codes.InvalidArgument, "version: %s", err.Error())
 	}
 
-	var newContent db.PostContent
-	if req.Content != nil {
-		arg := db.UpdatePostContentParams{
-			ID:       req.GetPostId(),
-			Content:  req.GetContent(),
-			AuthorID: authUser.ID,
-		}
-		if newContent, err = server.store.UpdatePostContent(ctx, arg); err != nil {
-			return nil, status.Error(codes.Internal, "failed to update post content")
-		}
+	arg := db.EditPostParams{
+		ID:          req.GetPostId(),
+		AuthorID:    authUser.ID,
+		Title:       sql.NullString{String: req.GetTitle(), Valid: req.Title != nil},
+		CoverImage:  sql.NullString{String: req.GetCoverImage(), Valid: req.CoverImage != nil},
+		Content:     sql.NullString{String: req.GetContent(), Valid: req.Content != nil},
+		CategoryIDs: req.GetCategoryIds(),
+		TagIDs:      req.GetTagIds(),
+		Version:     version,
 	}
 
-	var categories []db.Category
-	if req.CategoryIds != nil {
-		arg := db.SetPostCategoriesParams{
-			PostID:      newPost.ID,
-			CategoryIDs: req.GetCategoryIds(),
-		}
-		if categories, err = server.store.SetPostCategories(ctx, arg); err != nil {
-			return nil, status.Error(codes.Internal, "failed to set post categories")
-		}
-	} else {
-		if categories, err = server.store.GetPostCategories(ctx, newPost.ID); err != nil {
-			return nil, status.Error(codes.Internal, "failed to get post categories")
+	result, err := server.store.EditPost(ctx, arg)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			// Only drafts are updated in place, published posts get a pending revision
+			return server.updatePostRevision(ctx, req, authUser, version)
 		}
+		return nil, status.Error(codes.Internal, "failed to update post")
 	}
+	setPostETag(ctx, result.Post.Version)
 
-	var tags []db.Tag
 	if req.TagIds != nil {
-		arg := db.SetPostTagsParams{
-			PostID: newPost.ID,
-			TagIDs: req.GetTagIds(),
-		}
-		if tags, err = server.store.SetPostTags(ctx, arg); err != nil {
-			return nil, status.Error(codes.Internal, "failed to set post tags")
-		}
-	} else {
-		if tags, err = server.store.GetPostTags(ctx, newPost.ID); err != nil {
-			return nil, status.Error(codes.Internal, "failed to get post tags")
-		}
+		server.related.invalidate()
 	}
 
-	rsp := convertUpdatePost(newPost, newContent, categories, tags)
+	rsp := convertUpdatePost(result)
 	return rsp, nil
 }
 
@@ -148,9 +157,66 @@ func validateUpdatePostRequest(req *pb.UpdatePostRequest) error {
 	return nil
 }
 
+// extractPostVersion returns the version from the request body, or from the
+// If-Match header when the request comes through the HTTP gateway
+func extractPostVersion(ctx context.Context, req *pb.UpdatePostRequest) (int64, error) {
+	if req.GetVersion() != 0 {
+		if err := util.ValidateID(req.GetVersion()); err != nil {
+			return 0, err
+		}
+		return req.GetVersion(), nil
+	}
+
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get("grpcgateway-if-match"); len(values) > 0 {
+			etag := strings.TrimPrefix(strings.TrimSpace(values[0]), "W/")
+			version, err := strconv.ParseInt(strings.Trim(etag, "\""), 10, 64)
+			if err != nil || version < 1 {
+				return 0, fmt.Errorf("invalid If-Match header")
+			}
+			return version, nil
+		}
+	}
+	return 0, fmt.Errorf("must be provided in the body or If-Match header")
+}
+
+// setPostETag sends the post version back as an ETag header
+func setPostETag(ctx context.Context, version int64) {
+	if err := grpc.SetHeader(ctx, metadata.Pairs("etag", fmt.Sprintf("\"%d\"", version))); err != nil {
+		log.Println("failed to set etag header")
+	}
+}
+
+// stalePostError tells the editor which version is current so that it can merge changes
+func (server *Server) stalePostError(ctx context.Context, postID int64, authorID int64) error {
+	arg := db.GetPostVersionParams{
+		ID:       postID,
+		AuthorID: authorID,
+	}
+	version, err := server.store.GetPostVersion(ctx, arg)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return status.Error(codes.NotFound, "post not found")
+		}
+		return status.Error(codes.Internal, "failed to get post version")
+	}
+	setPostETag(ctx, version)
+
+	st := status.Newf(codes.Aborted, "post has been modified, current version is %d", version)
+	detail := &errdetails.ErrorInfo{
+		Reason:   "STALE_VERSION",
+		Domain:   "blog",
+		Metadata: map[string]string{"version": strconv.FormatInt(version, 10)},
+	}
+	if stWithDetail, err := st.WithDetails(detail); err == nil {
+		st = stWithDetail
+	}
+	return st.Err()
+}
+
 // ========================// SubmitPost //======================== //
 
-func (server *Server) SubmitPost(ctx context.Context, req *pb.SubmitPostRequest) (*emptypb.Empty, error) {
+func (server *Server) SubmitPost(ctx context.Context, req *pb.SubmitPostRequest) (*pb.SubmitPostResponse, error) {
 	authUser, gErr := server.grpcGuard(ctx, roleAuthor)
 	if gErr != nil {
 		return nil, gErr.GrpcErr()
@@ -161,42 +227,76 @@ func (server *Server) SubmitPost(ctx context.Context, req *pb.SubmitPostRequest)
 		return nil, status.Errorf(codes.InvalidArgument, "postID: %s", err.Error())
 	}
 
-	arg := db.UpdatePostStatusParams{
-		Ids:       postIDs,
-		Status:    "review",
-		OldStatus: []string{"draft", "revise"},
-		IsAdmin:   authUser.Role == "admin",
-		AuthorID:  authUser.ID,
+	// Published posts are submitted with their pending revisions
+	arg := db.BulkUpdatePostStatusParams{
+		UpdatePostStatusParams: db.UpdatePostStatusParams{
+			Ids:       postIDs,
+			Status:    "review",
+			OldStatus: []string{"draft", "revise"},
+			IsAdmin:   authUser.Role == "admin",
+			AuthorID:  authUser.ID,
+		},
+		SubmitRevisions: true,
+		AllOrNothing:    req.GetAllOrNothing(),
 	}
 
-	newPosts, err := server.store.UpdatePostStatus(ctx, arg)
-	if err != nil {
+	result, err := server.store.BulkUpdatePostStatus(ctx, arg)
+	rolledBack := err == db.ErrBatchRolledBack
+	if err != nil && !rolledBack {
 		return nil, status.Error(codes.Internal, "failed to submit posts")
 	}
 
+	results, err := server.postStatusResults(ctx, arg, result, rolledBack)
+	if err != nil {
+		return nil, err
+	}
+	rsp := &pb.SubmitPostResponse{Results: results, RolledBack: rolledBack}
+	if rolledBack {
+		return rsp, nil
+	}
+
+	newPosts := result.Posts
+	for _, revision := range result.Revisions {
+		newPosts = append(newPosts, db.Post{ID: revision.PostID, Title: revision.Title, ReviewerID: revision.ReviewerID})
+	}
+
+	// Resubmitted posts go back to their reviewer, new ones to all admins
+	var adminIDs []int64
 	for _, post := range newPosts {
-		arg := db.CreateNotificationParams{
-			UserID:  authUser.ID,
-			Kind:    "admin",
-			Title:   "New post submitted",
-			Content: fmt.Sprintf("Post entitled \"%s\" has been submitted", post.Title),
+		reviewerIDs := []int64{}
+		if post.ReviewerID.Valid {
+			reviewerIDs = append(reviewerIDs, post.ReviewerID.Int64)
+		} else {
+			if adminIDs == nil {
+				if adminIDs, err = server.store.ListAdminIDs(ctx); err != nil {
+					log.Println("failed to list admins for submitting post")
+				}
+			}
+			reviewerIDs = adminIDs
 		}
 
-		if err = server.store.CreateNotification(ctx, arg); err != nil {
-			log.Println("failed to create new notification for submitting post")
+		for _, reviewerID := range reviewerIDs {
+			arg := db.CreateNotificationParams{
+				UserID:  reviewerID,
+				Kind:    "system",
+				Title:   "New post submitted",
+				Content: fmt.Sprintf("Post entitled \"%s\" has been submitted by %s", post.Title, authUser.Username),
+			}
+
+			if err = server.store.CreateNotification(ctx, arg); err != nil {
+				log.Println("failed to create new notification for submitting post")
+			}
 		}
 	}
 
-	if len(newPosts) != len(postIDs) {
-		return nil, status.Error(codes.Internal, "some submissions failed")
-	}
-	return &emptypb.Empty{}, nil
+	return rsp, nil
 }
 
 // ========================// PublishPost //======================== //
 
-func (server *Server) PublishPost(ctx context.Context, req *pb.PublishPostRequest) (*emptypb.Empty, error) {
-	if _, gErr := server.grpcGuard(ctx, roleAdmin); gErr != nil {
+func (server *Server) PublishPost(ctx context.Context, req *pb.PublishPostRequest) (*pb.PublishPostResponse, error) {
+	authUser, gErr := server.grpcGuard(ctx, roleAdmin)
+	if gErr != nil {
 		return nil, gErr.GrpcErr()
 	}
 
@@ -204,25 +304,58 @@ func (server *Server) PublishPost(ctx context.Context, req *pb.PublishPostReques
 	if err != nil {
 		return nil, status.Errorf(codes.InvalidArgument, "postID: %s", err.Error())
 	}
+	if err := util.ValidateString(req.GetReason(), 1, 2000); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "reason: %s", err.Error())
+	}
 
-	arg := db.UpdatePostStatusParams{
-		Ids:       postIDs,
-		Status:    "publish",
-		OldStatus: []string{"review"},
-		IsAdmin:   true,
+	// Publishing approves the posts, so posts claimed by another reviewer
+	// are left to them
+	arg := db.BulkUpdatePostStatusParams{
+		UpdatePostStatusParams: db.UpdatePostStatusParams{
+			Ids:        postIDs,
+			Status:     "publish",
+			OldStatus:  []string{"review"},
+			IsAdmin:    true,
+			AuthorID:   authUser.ID,
+			CheckClaim: true,
+		},
+		AllOrNothing: req.GetAllOrNothing(),
 	}
 
-	newPosts, err := server.store.UpdatePostStatus(ctx, arg)
-	if err != nil {
+	result, err := server.store.BulkUpdatePostStatus(ctx, arg)
+	rolledBack := err == db.ErrBatchRolledBack
+	if err != nil && !rolledBack {
 		return nil, status.Error(codes.Internal, "failed to publish posts")
 	}
 
-	for _, post := range newPosts {
+	results, err := server.postStatusResults(ctx, arg, result, rolledBack)
+	if err != nil {
+		return nil, err
+	}
+	rsp := &pb.PublishPostResponse{Results: results, RolledBack: rolledBack}
+	if rolledBack || len(result.Posts) == 0 {
+		return rsp, nil
+	}
+
+	server.sitemap.invalidate()
+	server.related.invalidate()
+
+	for _, post := range result.Posts {
+		arg1 := db.CreateReviewNoteParams{
+			PostID:  post.ID,
+			UserID:  authUser.ID,
+			Kind:    "approve",
+			Content: req.GetReason(),
+		}
+		if _, err = server.store.CreateReviewNote(ctx, arg1); err != nil {
+			log.Println("failed to create review note for publishing post")
+		}
+
 		arg := db.CreateNotificationParams{
 			UserID:  post.AuthorID,
 			Kind:    "system",
 			Title:   "New post published",
-			Content: fmt.Sprintf("Congratulations! Post entitled \"%s\" has been published", post.Title),
+			Content: fmt.Sprintf("Congratulations! Post entitled \"%s\" has been published: %s", post.Title, req.GetReason()),
 		}
 
 		if err = server.store.CreateNotification(ctx, arg); err != nil {
@@ -230,16 +363,14 @@ func (server *Server) PublishPost(ctx context.Context, req *pb.PublishPostReques
 		}
 	}
 
-	if len(newPosts) != len(postIDs) {
-		return nil, status.Error(codes.Internal, "some publications failed")
-	}
-	return &emptypb.Empty{}, nil
+	return rsp, nil
 }
 
 // ========================// WithdrawPost //======================== //
 
-func (server *Server) WithdrawPost(ctx context.Context, req *pb.WithdrawPostRequest) (*emptypb.Empty, error) {
-	if _, gErr := server.grpcGuard(ctx, roleAdmin); gErr != nil {
+func (server *Server) WithdrawPost(ctx context.Context, req *pb.WithdrawPostRequest) (*pb.WithdrawPostResponse, error) {
+	authUser, gErr := server.grpcGuard(ctx, roleAdmin)
+	if gErr != nil {
 		return nil, gErr.GrpcErr()
 	}
 
@@ -247,38 +378,159 @@ func (server *Server) WithdrawPost(ctx context.Context, req *pb.WithdrawPostRequ
 	if err != nil {
 		return nil, status.Errorf(codes.InvalidArgument, "postID: %s", err.Error())
 	}
+	if err := util.ValidateString(req.GetReason(), 1, 2000); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "reason: %s", err.Error())
+	}
 
-	arg := db.UpdatePostStatusParams{
-		Ids:       postIDs,
-		Status:    "revise",
-		OldStatus: []string{"publish", "review"},
-		IsAdmin:   true,
+	arg := db.BulkUpdatePostStatusParams{
+		UpdatePostStatusParams: db.UpdatePostStatusParams{
+			Ids:       postIDs,
+			Status:    "revise",
+			OldStatus: []string{"publish", "review"},
+			IsAdmin:   true,
+		},
+		AllOrNothing: req.GetAllOrNothing(),
 	}
 
-	withdrawPosts, err := server.store.UpdatePostStatus(ctx, arg)
-	if err != nil {
+	result, err := server.store.BulkUpdatePostStatus(ctx, arg)
+	rolledBack := err == db.ErrBatchRolledBack
+	if err != nil && !rolledBack {
 		return nil, status.Error(codes.Internal, "failed to withdraw posts")
 	}
 
-	for _, post := range withdrawPosts {
-		arg := db.CreateNotificationParams{
-			UserID:  post.AuthorID,
-			Kind:    "system",
-			Title:   "Post withdrawn",
-			Content: fmt.Sprintf("Post \"%s\" has been withdrawn", post.Title),
+	results, err := server.postStatusResults(ctx, arg, result, rolledBack)
+	if err != nil {
+		return nil, err
+	}
+	rsp := &pb.WithdrawPostResponse{Results: results, RolledBack: rolledBack}
+	if rolledBack || len(result.Posts) == 0 {
+		return rsp, nil
+	}
+
+	server.sitemap.invalidate()
+	server.related.invalidate()
+
+	for _, post := range result.Posts {
+		arg := db.CreateReviewNoteParams{
+			PostID:  post.ID,
+			UserID:  authUser.ID,
+			Kind:    "withdraw",
+			Content: req.GetReason(),
+		}
+		if _, err = server.store.CreateReviewNote(ctx, arg); err != nil {
+			log.Println("failed to create review note for withdrawing post")
 		}
 
-		if err = server.store.CreateNotification(ctx, arg); err != nil {
-			log.Println("failed to create new notification for withdrawing post")
+		server.notifyPostAuthors(ctx, post.ID, post.AuthorID, "Post withdrawn",
+			fmt.Sprintf("Post \"%s\" has been withdrawn: %s", post.Title, req.GetReason()))
+	}
+
+	return rsp, nil
+}
+
+// postStatusResults reports for every post of a batch whether its status was
+// changed, and why not otherwise
+func (server *Server) postStatusResults(ctx context.Context, arg db.BulkUpdatePostStatusParams, result db.BulkUpdatePostStatusResult, rolledBack bool) ([]*pb.PostStatusResult, error) {
+	failedIDs := []int64{}
+	for _, postID := range arg.Ids {
+		if !result.Updated(postID) {
+			failedIDs = append(failedIDs, postID)
 		}
 	}
 
-	if len(withdrawPosts) != len(postIDs) {
-		return nil, status.Error(codes.Internal, "some withdrawal failed")
+	access := map[int64]db.ListPostAccessRow{}
+	if len(failedIDs) > 0 {
+		arg1 := db.ListPostAccessParams{
+			Ids:      failedIDs,
+			AuthorID: arg.AuthorID,
+		}
+		rows, err := server.store.ListPostAccess(ctx, arg1)
+		if err != nil {
+			return nil, status.Error(codes.Internal, "failed to check failed posts")
+		}
+		for _, row := range rows {
+			access[row.ID] = row
+		}
+	}
+
+	results := make([]*pb.PostStatusResult, 0, len(arg.Ids))
+	for _, postID := range arg.Ids {
+		res := &pb.PostStatusResult{PostId: postID}
+		if result.Updated(postID) {
+			if rolledBack {
+				res.Reason = "rolled back"
+			} else {
+				res.Success = true
+			}
+		} else if row, ok := access[postID]; !ok {
+			res.Reason = "not found"
+		} else if !arg.IsAdmin && !row.IsAuthor {
+			res.Reason = "not owner"
+		} else if arg.CheckClaim && row.Claimed && row.Status == "review" {
+			res.Reason = "claimed by another reviewer"
+		} else {
+			res.Reason = fmt.Sprintf("wrong status: %s", row.Status)
+		}
+		results = append(results, res)
+	}
+	return results, nil
+}
+
+// ========================// UpdatePostVisibility //======================== //
+
+var postVisibilities = []string{"public", "unlisted", "followers", "password"}
+
+func (server *Server) UpdatePostVisibility(ctx context.Context, req *pb.UpdatePostVisibilityRequest) (*emptypb.Empty, error) {
+	authUser, gErr := server.grpcGuard(ctx, roleAuthor)
+	if gErr != nil {
+		return nil, gErr.GrpcErr()
 	}
+
+	if err := validateUpdatePostVisibilityRequest(req); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	arg := db.UpdatePostVisibilityParams{
+		ID:         req.GetPostId(),
+		Visibility: req.GetVisibility(),
+		IsAdmin:    authUser.Role == "admin",
+		AuthorID:   authUser.ID,
+	}
+	if req.GetVisibility() == "password" {
+		hashedPassword, err := util.HashPassword(req.GetPassword())
+		if err != nil {
+			return nil, status.Error(codes.Internal, "failed to hash password")
+		}
+		arg.PasswordHash = hashedPassword
+	}
+
+	if _, err := server.store.UpdatePostVisibility(ctx, arg); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, status.Error(codes.NotFound, "post not found")
+		}
+		return nil, status.Error(codes.Internal, "failed to update post visibility")
+	}
+
+	server.sitemap.invalidate()
+	server.related.invalidate()
 	return &emptypb.Empty{}, nil
 }
 
+func validateUpdatePostVisibilityRequest(req *pb.UpdatePostVisibilityRequest) error {
+	if err := util.ValidateID(req.GetPostId()); err != nil {
+		return fmt.Errorf("postId: %s", err.Error())
+	}
+	if err := util.ValidateOneOf(req.GetVisibility(), postVisibilities); err != nil {
+		return fmt.Errorf("visibility: %s", err.Error())
+	}
+	if req.GetVisibility() == "password" {
+		if err := util.ValidateString(req.GetPassword(), 6, 50); err != nil {
+			return fmt.Errorf("password: %s", err.Error())
+		}
+	}
+	return nil
+}
+
 // ========================// UpdatePostLabel //======================== //
 
 func (server *Server) UpdatePostLabel(ctx context.Context, req *pb.UpdatePostLabelRequest) (*emptypb.Empty, error) {
@@ -320,6 +572,10 @@ func (server *Server) UpdatePostLabel(ctx context.Context, req *pb.UpdatePostLab
 			return nil, status.Error(codes.Internal, "failed to update post tags")
 		}
 	}
+
+	if req.CategoryIds != nil || req.TagIds != nil {
+		server.related.invalidate()
+	}
 	return &emptypb.Empty{}, nil
 }
 
@@ -393,7 +649,7 @@ func validateListPostsRequest(req *pb.ListPostsRequest) error {
 		return err
 	}
 	if req.Status != nil {
-		options = []string{"publish", "review", "revise", "draft"}
+		options = []string{"publish", "review", "revise", "draft", "trash"}
 		if err := util.ValidateOneOf(req.GetStatus(), options); err != nil {
 			return fmt.Errorf("status: %s", err.Error())
 		}
@@ -428,6 +684,7 @@ func (server *Server) GetPost(ctx context.Context, req *pb.GetPostRequest) (*pb.
 	if err != nil {
 		return nil, status.Error(codes.Internal, "failed to get post")
 	}
+	setPostETag(ctx, post.Version)
 	return convertGetPost(post), nil
 }
 
@@ -448,6 +705,11 @@ func (server *Server) GetFeaturedPosts(ctx context.Context, req *pb.GetFeaturedP
 // ========================// GetPosts //======================== //
 
 func (server *Server) GetPosts(ctx context.Context, req *pb.GetPostsRequest) (*pb.GetPostsResponse, error) {
+	authUser, gErr := server.grpcGuard(ctx, roleGhost)
+	if gErr != nil {
+		return nil, gErr.GrpcErr()
+	}
+
 	if err := validateGetPostsRequest(req); err != nil {
 		return nil, status.Error(codes.InvalidArgument, err.Error())
 	}
@@ -459,6 +721,8 @@ func (server *Server) GetPosts(ctx context.Context, req *pb.GetPostsRequest) (*p
 		PublishAtDesc: req.GetOrderBy() == "publishAt" && req.GetOrder() == "desc",
 		ViewCountAsc:  req.GetOrderBy() == "viewCount" && req.GetOrder() == "asc",
 		ViewCountDesc: req.GetOrderBy() == "viewCount" && req.GetOrder() == "desc",
+		HotScoreAsc:   req.GetOrderBy() == "hot" && req.GetOrder() == "asc",
+		HotScoreDesc:  req.GetOrderBy() == "hot" && req.GetOrder() == "desc",
 		AnyFeatured:   req.Featured == nil,
 		Featured:      req.GetFeatured(),
 		AnyAuthor:     req.AuthorId == nil,
@@ -469,17 +733,32 @@ func (server *Server) GetPosts(ctx context.Context, req *pb.GetPostsRequest) (*p
 		TagID:         req.GetTagId(),
 		AnyKeyword:    req.Keyword == nil,
 		Keyword:       "%" + req.GetKeyword() + "%",
+		SelfID:        authUser.ID,
 	}
 
 	posts, err := server.store.GetPosts(ctx, arg)
 	if err != nil {
 		return nil, status.Error(codes.Internal, "failed to list posts")
 	}
-	return convertGetPosts(posts), nil
+
+	images := make([]string, 0, 2*len(posts))
+	for _, post := range posts {
+		images = append(images, post.CoverImage, post.Avatar)
+		images = append(images, post.AuthorAvatars...)
+	}
+	variants := server.imageVariants(ctx, images)
+
+	rsp := convertGetPosts(posts)
+	for _, post := range rsp.Posts {
+		post.CoverVariants = variants[post.CoverImage]
+		post.Author.AvatarVariants = variants[post.Author.Avatar]
+		setAvatarVariants(post.Authors, variants)
+	}
+	return rsp, nil
 }
 
 func validateGetPostsRequest(req *pb.GetPostsRequest) error {
-	options := []string{"publishAt", "viewCount"}
+	options := []string{"publishAt", "viewCount", "hot"}
 	if err := util.ValidatePageOrder(req, options); err != nil {
 		return err
 	}
@@ -525,9 +804,126 @@ func (server *Server) ReadPost(ctx context.Context, req *pb.ReadPostRequest) (*p
 
 	post, err := server.store.ReadPost(ctx, arg)
 	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, status.Error(codes.NotFound, "post not found")
+		}
 		return nil, status.Error(codes.Internal, "failed to read post")
 	}
-	return convertReadPost(post), nil
+	access := postAccess{
+		status:       "publish",
+		visibility:   post.Visibility,
+		passwordHash: post.PasswordHash,
+		authorIDs:    post.AuthorIds,
+		followed:     post.Followed.Valid,
+	}
+	if err = checkPostVisibility(access, authUser, extractPostPassword(ctx)); err != nil {
+		return nil, err
+	}
+	server.recordPostView(ctx, post.ID, authUser)
+	rsp := convertReadPost(post)
+
+	images := contentImages(post.Content)
+	avatars := append([]string{post.Avatar}, post.AuthorAvatars...)
+	variants := server.imageVariants(ctx, append(avatars, images...))
+	rsp.Post.Images = make([]*pb.ImageSet, 0, len(images))
+	for _, image := range images {
+		if v, ok := variants[image]; ok {
+			rsp.Post.Images = append(rsp.Post.Images, &pb.ImageSet{Path: image, Variants: v})
+		}
+	}
+	rsp.Post.Author.AvatarVariants = variants[post.Avatar]
+	setAvatarVariants(rsp.Post.Authors, variants)
+
+	arg1 := db.GetPostSeriesParams{
+		PostID:  post.ID,
+		SelfID:  authUser.ID,
+		IsAdmin: authUser.Role == "admin",
+	}
+	series, err := server.store.GetPostSeries(ctx, arg1)
+	if err == nil {
+		rsp.Post.Series = convertSeriesContext(series)
+	} else if err != sql.ErrNoRows {
+		log.Println("failed to get series of post:", err)
+	}
+	return rsp, nil
+}
+
+// postAccess holds the fields of a post that decide who may read it
+type postAccess struct {
+	status       string
+	visibility   string
+	passwordHash string
+	authorIDs    []int64
+	followed     bool
+}
+
+// checkPostVisibility reports whether the user may read a post. Authors of
+// the post and admins can read posts of any status and visibility.
+func checkPostVisibility(post postAccess, authUser *db.User, password string) error {
+	if authUser.Role == "admin" {
+		return nil
+	}
+	for _, authorID := range post.authorIDs {
+		if authUser.ID != 0 && authorID == authUser.ID {
+			return nil
+		}
+	}
+
+	if post.status != "publish" {
+		return status.Error(codes.NotFound, "post not found")
+	}
+	switch post.visibility {
+	case "followers":
+		if !post.followed {
+			return status.Error(codes.PermissionDenied, "post is only visible to followers of the author")
+		}
+	case "password":
+		if password == "" {
+			return status.Error(codes.PermissionDenied, "post is protected by a password")
+		}
+		if err := util.CheckPassword(password, post.passwordHash); err != nil {
+			return status.Error(codes.PermissionDenied, "incorrect post password")
+		}
+	}
+	return nil
+}
+
+// checkPostAccess makes sure that the user may read a post before anything
+// that belongs to it, like its comments, is read or added
+func (server *Server) checkPostAccess(ctx context.Context, postID int64, authUser *db.User) error {
+	arg := db.GetPostAccessParams{
+		PostID: postID,
+		SelfID: authUser.ID,
+	}
+	post, err := server.store.GetPostAccess(ctx, arg)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return status.Error(codes.NotFound, "post not found")
+		}
+		return status.Error(codes.Internal, "failed to get post")
+	}
+
+	access := postAccess{
+		status:       post.Status,
+		visibility:   post.Visibility,
+		passwordHash: post.PasswordHash,
+		authorIDs:    post.AuthorIds,
+		followed:     post.Followed,
+	}
+	return checkPostVisibility(access, authUser, extractPostPassword(ctx))
+}
+
+// postPasswordHeader carries the password of a protected post, so that it is
+// not part of the URL. The gateway forwards it as metadata.
+const postPasswordHeader = "x-post-password"
+
+func extractPostPassword(ctx context.Context) string {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(postPasswordHeader); len(values) > 0 {
+			return values[0]
+		}
+	}
+	return ""
 }
 
 // ========================// StarPost //======================== //
@@ -562,3 +958,79 @@ func (server *Server) StarPost(ctx context.Context, req *pb.StarPostRequest) (*e
 	}
 	return &emptypb.Empty{}, nil
 }
+
+// ========================// ListStargazers //======================== //
+
+func (server *Server) ListStargazers(ctx context.Context, req *pb.ListStargazersRequest) (*pb.ListStargazersResponse, error) {
+	authUser, gErr := server.grpcGuard(ctx, roleGhost)
+	if gErr != nil {
+		return nil, gErr.GrpcErr()
+	}
+
+	if err := util.ValidateID(req.GetPostId()); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "postId: %s", err.Error())
+	}
+	if err := util.ValidatePage(req); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	// Users who hide their stars are only listed to themselves and admins
+	arg := db.ListStargazersParams{
+		Limit:   req.GetPageSize(),
+		Offset:  (req.GetPageId() - 1) * req.GetPageSize(),
+		PostID:  req.GetPostId(),
+		SelfID:  authUser.ID,
+		IsAdmin: authUser.Role == "admin",
+	}
+
+	users, err := server.store.ListStargazers(ctx, arg)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to list stargazers")
+	}
+	return convertListStargazers(users), nil
+}
+
+// ========================// ListStarredPosts //======================== //
+
+func (server *Server) ListStarredPosts(ctx context.Context, req *pb.ListStarredPostsRequest) (*pb.ListStarredPostsResponse, error) {
+	authUser, gErr := server.grpcGuard(ctx, roleGhost)
+	if gErr != nil {
+		return nil, gErr.GrpcErr()
+	}
+
+	if err := util.ValidateID(req.GetUserId()); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "userId: %s", err.Error())
+	}
+	if err := util.ValidatePage(req); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	user, err := server.store.GetUser(ctx, req.GetUserId())
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, status.Error(codes.NotFound, "user not found")
+		}
+		return nil, status.Error(codes.Internal, "failed to get user")
+	}
+	if user.Deleted {
+		return nil, status.Error(codes.NotFound, "user not found")
+	}
+
+	// Users can hide their stars from everyone except themselves and admins
+	if !user.PublicStars && user.ID != authUser.ID && authUser.Role != "admin" {
+		return nil, status.Error(codes.PermissionDenied, "stars of this user are private")
+	}
+
+	arg := db.ListStarredPostsParams{
+		Limit:  req.GetPageSize(),
+		Offset: (req.GetPageId() - 1) * req.GetPageSize(),
+		UserID: user.ID,
+		SelfID: authUser.ID,
+	}
+
+	posts, err := server.store.ListStarredPosts(ctx, arg)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to list starred posts")
+	}
+	return convertListStarredPosts(posts), nil
+}
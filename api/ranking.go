@@ -0,0 +1,64 @@
+package api
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/bwen19/blog/grpc/pb"
+	"github.com/bwen19/blog/psql/db"
+	"github.com/bwen19/blog/util"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// refreshRankings recomputes the hot scores with time-decayed views, stars and comments
+func (server *Server) refreshRankings(ctx context.Context) {
+	arg := db.RefreshPostRankingsParams{
+		HalfLife:      server.config.HotHalfLife.Hours(),
+		ViewWeight:    server.config.HotViewWeight,
+		StarWeight:    server.config.HotStarWeight,
+		CommentWeight: server.config.HotCommentWeight,
+		Since:         time.Now().Add(-server.config.HotWindow),
+	}
+	if _, err := server.store.UpdateHotRankings(ctx, arg); err != nil {
+		log.Println("failed to refresh hot rankings:", err)
+	}
+}
+
+// RunRankingRefresher refreshes the hot rankings on start and on every interval
+func (server *Server) RunRankingRefresher(ctx context.Context) {
+	ticker := time.NewTicker(server.config.HotRefreshInterval)
+	defer ticker.Stop()
+
+	server.refreshRankings(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			server.refreshRankings(ctx)
+		}
+	}
+}
+
+// ========================// GetTrendingTags //======================== //
+
+func (server *Server) GetTrendingTags(ctx context.Context, req *pb.GetTrendingTagsRequest) (*pb.GetTrendingTagsResponse, error) {
+	if err := util.ValidateNumber(int64(req.GetNum()), 1, 20); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "num: %s", err.Error())
+	}
+
+	tags, err := server.store.ListTrendingTags(ctx, req.GetNum())
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to list trending tags")
+	}
+
+	rsp := &pb.GetTrendingTagsResponse{
+		Tags: make([]*pb.Tag, 0, len(tags)),
+	}
+	for _, tag := range tags {
+		rsp.Tags = append(rsp.Tags, &pb.Tag{Id: tag.ID, Name: tag.Name})
+	}
+	return rsp, nil
+}
@@ -0,0 +1,134 @@
+package api
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/bwen19/blog/grpc/pb"
+	"github.com/bwen19/blog/psql/db"
+	"github.com/bwen19/blog/util"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Number of ranked candidates kept for each post, readers' exclusions are
+// applied on top of them
+const relatedCandidates = 50
+
+// Maximum number of posts kept in the cache before it starts over
+const relatedCacheSize = 10000
+
+// Weights of the signals that make two posts related
+const (
+	relatedTagWeight      = 2.0
+	relatedCategoryWeight = 1.0
+	relatedAuthorWeight   = 1.0
+	relatedTextWeight     = 1.0
+)
+
+type relatedEntry struct {
+	ids       []int64
+	expiresAt time.Time
+}
+
+// relatedCache keeps ranked candidates of related posts in memory
+type relatedCache struct {
+	mu      sync.Mutex
+	entries map[int64]relatedEntry
+}
+
+// Drop all cached candidates, a label change can affect any post
+func (cache *relatedCache) invalidate() {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	cache.entries = nil
+}
+
+func (cache *relatedCache) get(postID int64) ([]int64, bool) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	entry, ok := cache.entries[postID]
+	if !ok || time.Now().After(entry.expiresAt) {
+		return nil, false
+	}
+	return entry.ids, true
+}
+
+func (cache *relatedCache) set(postID int64, ids []int64, duration time.Duration) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	if cache.entries == nil || len(cache.entries) >= relatedCacheSize {
+		cache.entries = map[int64]relatedEntry{}
+	}
+	cache.entries[postID] = relatedEntry{
+		ids:       ids,
+		expiresAt: time.Now().Add(duration),
+	}
+}
+
+// Return the ranked candidates of related posts, computing them if necessary
+func (server *Server) relatedPostIDs(ctx context.Context, postID int64) ([]int64, error) {
+	if ids, ok := server.related.get(postID); ok {
+		return ids, nil
+	}
+
+	arg := db.ListRelatedPostIDsParams{
+		Num:            relatedCandidates,
+		PostID:         postID,
+		TagWeight:      relatedTagWeight,
+		CategoryWeight: relatedCategoryWeight,
+		AuthorWeight:   relatedAuthorWeight,
+	}
+	if server.config.RelatedTextMatch {
+		arg.TextWeight = relatedTextWeight
+	}
+
+	ids, err := server.store.ListRelatedPostIDs(ctx, arg)
+	if err != nil {
+		return nil, err
+	}
+
+	server.related.set(postID, ids, server.config.RelatedCacheDuration)
+	return ids, nil
+}
+
+// ========================// GetRelatedPosts //======================== //
+
+func (server *Server) GetRelatedPosts(ctx context.Context, req *pb.GetRelatedPostsRequest) (*pb.GetRelatedPostsResponse, error) {
+	authUser, gErr := server.grpcGuard(ctx, roleGhost)
+	if gErr != nil {
+		return nil, gErr.GrpcErr()
+	}
+
+	if err := util.ValidateID(req.GetPostId()); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "postId: %s", err.Error())
+	}
+	if err := util.ValidateNumber(int64(req.GetNum()), 1, 10); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "num: %s", err.Error())
+	}
+
+	ids, err := server.relatedPostIDs(ctx, req.GetPostId())
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to list related posts")
+	}
+	if len(ids) == 0 {
+		return &pb.GetRelatedPostsResponse{}, nil
+	}
+
+	// Guests have no reading history or stars, so nothing is excluded
+	arg := db.GetRelatedPostsParams{
+		Ids:       ids,
+		SelfID:    authUser.ID,
+		ReadSince: time.Now().Add(-server.config.RelatedReadWindow),
+		Num:       req.GetNum(),
+	}
+
+	posts, err := server.store.GetRelatedPosts(ctx, arg)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to get related posts")
+	}
+	return convertGetRelatedPosts(posts), nil
+}
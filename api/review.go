@@ -0,0 +1,379 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+
+	"github.com/bwen19/blog/grpc/pb"
+	"github.com/bwen19/blog/psql/db"
+	"github.com/bwen19/blog/util"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+// ========================// ListReviewQueue //======================== //
+
+func (server *Server) ListReviewQueue(ctx context.Context, req *pb.ListReviewQueueRequest) (*pb.ListReviewQueueResponse, error) {
+	authUser, gErr := server.grpcGuard(ctx, roleAdmin)
+	if gErr != nil {
+		return nil, gErr.GrpcErr()
+	}
+
+	if err := util.ValidatePage(req); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if req.Filter != nil {
+		if err := util.ValidateOneOf(req.GetFilter(), []string{"all", "unclaimed", "mine"}); err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "filter: %s", err.Error())
+		}
+	}
+
+	arg := db.ListReviewQueueParams{
+		Limit:       req.GetPageSize(),
+		Offset:      (req.GetPageId() - 1) * req.GetPageSize(),
+		Unclaimed:   req.GetFilter() == "unclaimed",
+		AnyReviewer: req.GetFilter() != "mine",
+		ReviewerID:  authUser.ID,
+	}
+
+	posts, err := server.store.ListReviewQueue(ctx, arg)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to list review queue")
+	}
+	return convertListReviewQueue(posts), nil
+}
+
+// ========================// ClaimPost //======================== //
+
+func (server *Server) ClaimPost(ctx context.Context, req *pb.ClaimPostRequest) (*emptypb.Empty, error) {
+	authUser, gErr := server.grpcGuard(ctx, roleAdmin)
+	if gErr != nil {
+		return nil, gErr.GrpcErr()
+	}
+
+	if err := util.ValidateID(req.GetPostId()); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "postId: %s", err.Error())
+	}
+
+	// Admins claim posts for themselves unless another reviewer is assigned
+	reviewerID := authUser.ID
+	if req.ReviewerId != nil && req.GetReviewerId() != authUser.ID {
+		if err := util.ValidateID(req.GetReviewerId()); err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "reviewerId: %s", err.Error())
+		}
+
+		reviewer, err := server.store.GetUser(ctx, req.GetReviewerId())
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return nil, status.Error(codes.NotFound, "reviewer not found")
+			}
+			return nil, status.Error(codes.Internal, "failed to get reviewer")
+		}
+		if reviewer.Deleted || reviewer.Role != "admin" {
+			return nil, status.Error(codes.FailedPrecondition, "only active admins can review posts")
+		}
+		reviewerID = reviewer.ID
+	}
+
+	// Posts claimed by another reviewer are left to them
+	arg1 := db.AssignPostReviewerParams{
+		ID:         req.GetPostId(),
+		ReviewerID: reviewerID,
+		ClaimerID:  authUser.ID,
+	}
+	post, err := server.store.AssignPostReviewer(ctx, arg1)
+	if err == sql.ErrNoRows {
+		// Published posts are claimed through their pending revision
+		arg := db.AssignRevisionReviewerParams{
+			PostID:     req.GetPostId(),
+			ReviewerID: reviewerID,
+			ClaimerID:  authUser.ID,
+		}
+		var revision db.PostRevision
+		revision, err = server.store.AssignRevisionReviewer(ctx, arg)
+		post.Title = revision.Title
+	}
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, status.Error(codes.NotFound, "post not found in review queue or claimed by another reviewer")
+		}
+		return nil, status.Error(codes.Internal, "failed to claim post")
+	}
+
+	if reviewerID != authUser.ID {
+		arg2 := db.CreateNotificationParams{
+			UserID:  reviewerID,
+			Kind:    "system",
+			Title:   "Review assigned",
+			Content: fmt.Sprintf("%s assigned post \"%s\" to you for review", authUser.Username, post.Title),
+		}
+		if err = server.store.CreateNotification(ctx, arg2); err != nil {
+			log.Println("failed to create new notification for assigning reviewer")
+		}
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+// ========================// CreateReviewNote //======================== //
+
+func (server *Server) CreateReviewNote(ctx context.Context, req *pb.CreateReviewNoteRequest) (*pb.CreateReviewNoteResponse, error) {
+	authUser, gErr := server.grpcGuard(ctx, roleAuthor)
+	if gErr != nil {
+		return nil, gErr.GrpcErr()
+	}
+
+	if err := validateCreateReviewNoteRequest(req); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	arg1 := db.GetPostParams{
+		PostID:   req.GetPostId(),
+		IsAdmin:  authUser.Role == "admin",
+		AuthorID: authUser.ID,
+	}
+	post, err := server.store.GetPost(ctx, arg1)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, status.Error(codes.NotFound, "post not found")
+		}
+		return nil, status.Error(codes.Internal, "failed to get post")
+	}
+
+	arg2 := db.CreateReviewNoteParams{
+		PostID:  post.ID,
+		UserID:  authUser.ID,
+		Kind:    "note",
+		Content: req.GetContent(),
+	}
+
+	// Anchors are rune offsets into the current content, the quoted text
+	// is kept so that the note still makes sense after later edits
+	if req.AnchorStart != nil {
+		content, err := server.reviewedContent(ctx, post)
+		if err != nil {
+			return nil, err
+		}
+
+		runes := []rune(content)
+		start, end := int(req.GetAnchorStart()), int(req.GetAnchorEnd())
+		if end > len(runes) {
+			return nil, status.Error(codes.InvalidArgument, "anchorEnd: out of content range")
+		}
+		arg2.AnchorStart = sql.NullInt32{Int32: req.GetAnchorStart(), Valid: true}
+		arg2.AnchorEnd = sql.NullInt32{Int32: req.GetAnchorEnd(), Valid: true}
+		arg2.AnchorText = string(runes[start:end])
+	}
+
+	note, err := server.store.CreateReviewNote(ctx, arg2)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to create review note")
+	}
+
+	// Notes from reviewers go to the authors and replies go to the reviewer
+	title := "New review note"
+	content := fmt.Sprintf("%s left a review note on post \"%s\"", authUser.Username, post.Title)
+	if authUser.Role != "admin" || post.AuthorID == authUser.ID {
+		if post.ReviewerID.Valid && post.ReviewerID.Int64 != authUser.ID {
+			arg3 := db.CreateNotificationParams{
+				UserID:  post.ReviewerID.Int64,
+				Kind:    "system",
+				Title:   title,
+				Content: content,
+			}
+			if err = server.store.CreateNotification(ctx, arg3); err != nil {
+				log.Println("failed to create new notification for review note")
+			}
+		}
+	} else {
+		server.notifyPostAuthors(ctx, post.ID, post.AuthorID, title, content)
+	}
+
+	rsp := &pb.CreateReviewNoteResponse{Note: convertReviewNote(note, authUser)}
+	return rsp, nil
+}
+
+// reviewedContent returns the content under review, which is the pending
+// revision for published posts
+func (server *Server) reviewedContent(ctx context.Context, post db.GetPostRow) (string, error) {
+	if post.Status != "publish" || post.RevisionStatus == "" {
+		return post.Content, nil
+	}
+
+	arg := db.GetPostRevisionParams{
+		PostID:  post.ID,
+		IsAdmin: true,
+	}
+	revision, err := server.store.GetPostRevision(ctx, arg)
+	if err != nil {
+		return "", status.Error(codes.Internal, "failed to get post revision")
+	}
+	return revision.Content, nil
+}
+
+func validateCreateReviewNoteRequest(req *pb.CreateReviewNoteRequest) error {
+	if err := util.ValidateID(req.GetPostId()); err != nil {
+		return fmt.Errorf("postId: %s", err.Error())
+	}
+	if err := util.ValidateString(req.GetContent(), 1, 2000); err != nil {
+		return fmt.Errorf("content: %s", err.Error())
+	}
+	if (req.AnchorStart == nil) != (req.AnchorEnd == nil) {
+		return fmt.Errorf("anchorStart and anchorEnd must be provided together")
+	}
+	if req.AnchorStart != nil {
+		if req.GetAnchorStart() < 0 {
+			return fmt.Errorf("anchorStart: must not be negative")
+		}
+		if req.GetAnchorEnd() <= req.GetAnchorStart() {
+			return fmt.Errorf("anchorEnd: must be greater than anchorStart")
+		}
+	}
+	return nil
+}
+
+// ========================// ListReviewNotes //======================== //
+
+func (server *Server) ListReviewNotes(ctx context.Context, req *pb.ListReviewNotesRequest) (*pb.ListReviewNotesResponse, error) {
+	authUser, gErr := server.grpcGuard(ctx, roleAuthor)
+	if gErr != nil {
+		return nil, gErr.GrpcErr()
+	}
+
+	if err := util.ValidateID(req.GetPostId()); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "postId: %s", err.Error())
+	}
+
+	// The review thread is only visible to the reviewers and the authors
+	arg := db.GetPostParams{
+		PostID:   req.GetPostId(),
+		IsAdmin:  authUser.Role == "admin",
+		AuthorID: authUser.ID,
+	}
+	if _, err := server.store.GetPost(ctx, arg); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, status.Error(codes.NotFound, "post not found")
+		}
+		return nil, status.Error(codes.Internal, "failed to get post")
+	}
+
+	notes, err := server.store.ListReviewNotes(ctx, req.GetPostId())
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to list review notes")
+	}
+	return convertListReviewNotes(notes), nil
+}
+
+// ========================// ReviewPost //======================== //
+
+func (server *Server) ReviewPost(ctx context.Context, req *pb.ReviewPostRequest) (*emptypb.Empty, error) {
+	authUser, gErr := server.grpcGuard(ctx, roleAdmin)
+	if gErr != nil {
+		return nil, gErr.GrpcErr()
+	}
+
+	if err := validateReviewPostRequest(req); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	arg := db.ReviewPostParams{
+		PostID:     req.GetPostId(),
+		ReviewerID: authUser.ID,
+		Reason:     req.GetReason(),
+	}
+	if req.GetDecision() == "approve" {
+		arg.Status = "publish"
+		arg.Kind = "approve"
+	} else {
+		arg.Status = "revise"
+		arg.Kind = "changes"
+	}
+
+	post, err := server.store.ReviewPost(ctx, arg)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			// Published posts are reviewed through their pending revision
+			return server.reviewPostRevision(ctx, authUser, arg)
+		}
+		return nil, status.Error(codes.Internal, "failed to review post")
+	}
+
+	if post.Status == "publish" {
+		server.sitemap.invalidate()
+		server.related.invalidate()
+		server.notifyPostAuthors(ctx, post.ID, post.AuthorID, "New post published",
+			fmt.Sprintf("Congratulations! Post entitled \"%s\" has been published: %s", post.Title, req.GetReason()))
+	} else {
+		server.notifyPostAuthors(ctx, post.ID, post.AuthorID, "Changes requested",
+			fmt.Sprintf("%s requested changes to post \"%s\": %s", authUser.Username, post.Title, req.GetReason()))
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+// reviewPostRevision reviews the pending revision of a published post, an
+// approved revision replaces the live content
+func (server *Server) reviewPostRevision(ctx context.Context, authUser *db.User, arg db.ReviewPostParams) (*emptypb.Empty, error) {
+	post, err := server.store.ReviewPostRevision(ctx, arg)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, status.Error(codes.NotFound, "post not found in review queue or claimed by another reviewer")
+		}
+		return nil, status.Error(codes.Internal, "failed to review post revision")
+	}
+
+	if arg.Status == "publish" {
+		server.sitemap.invalidate()
+		server.related.invalidate()
+		server.notifyPostAuthors(ctx, post.ID, post.AuthorID, "Revision published",
+			fmt.Sprintf("The revision of post \"%s\" has been published: %s", post.Title, arg.Reason))
+	} else {
+		server.notifyPostAuthors(ctx, post.ID, post.AuthorID, "Changes requested",
+			fmt.Sprintf("%s requested changes to the revision of post \"%s\": %s", authUser.Username, post.Title, arg.Reason))
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+func validateReviewPostRequest(req *pb.ReviewPostRequest) error {
+	if err := util.ValidateID(req.GetPostId()); err != nil {
+		return fmt.Errorf("postId: %s", err.Error())
+	}
+	if err := util.ValidateOneOf(req.GetDecision(), []string{"approve", "changes"}); err != nil {
+		return fmt.Errorf("decision: %s", err.Error())
+	}
+	if err := util.ValidateString(req.GetReason(), 1, 2000); err != nil {
+		return fmt.Errorf("reason: %s", err.Error())
+	}
+	return nil
+}
+
+// notifyPostAuthors sends a notification to the owner and all accepted collaborators of a post
+func (server *Server) notifyPostAuthors(ctx context.Context, postID int64, ownerID int64, title string, content string) {
+	userIDs := []int64{ownerID}
+
+	collaborators, err := server.store.ListPostCollaborators(ctx, postID)
+	if err != nil {
+		log.Println("failed to list collaborators for notification")
+	}
+	for _, collaborator := range collaborators {
+		if collaborator.Accepted && collaborator.UserID != ownerID {
+			userIDs = append(userIDs, collaborator.UserID)
+		}
+	}
+
+	for _, userID := range userIDs {
+		arg := db.CreateNotificationParams{
+			UserID:  userID,
+			Kind:    "system",
+			Title:   title,
+			Content: content,
+		}
+		if err = server.store.CreateNotification(ctx, arg); err != nil {
+			log.Println("failed to create new notification for post authors")
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/bwen19/blog/grpc/pb"
+	"github.com/bwen19/blog/psql/db"
+	"github.com/bwen19/blog/util"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+// updatePostRevision edits a published post through its pending revision,
+// so that the live version keeps serving readers until the revision is approved
+func (server *Server) updatePostRevision(ctx context.Context, req *pb.UpdatePostRequest, authUser *db.User, version int64) (*pb.UpdatePostResponse, error) {
+	arg := db.UpdatePostRevisionParams{
+		PostID:      req.GetPostId(),
+		AuthorID:    authUser.ID,
+		Title:       sql.NullString{String: req.GetTitle(), Valid: req.Title != nil},
+		CoverImage:  sql.NullString{String: req.GetCoverImage(), Valid: req.CoverImage != nil},
+		Content:     sql.NullString{String: req.GetContent(), Valid: req.Content != nil},
+		CategoryIds: req.GetCategoryIds(),
+		TagIds:      req.GetTagIds(),
+		Version:     version,
+	}
+
+	revision, err := server.store.UpdatePublishedPost(ctx, arg)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, server.stalePostError(ctx, req.GetPostId(), authUser.ID)
+		}
+		return nil, status.Error(codes.Internal, "failed to update post revision")
+	}
+	setPostETag(ctx, revision.Version)
+
+	pbPost, err := server.convertPostRevision(ctx, revision)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.UpdatePostResponse{Post: pbPost}, nil
+}
+
+// convertPostRevision returns the revision as a post with the names of its categories and tags
+func (server *Server) convertPostRevision(ctx context.Context, revision db.PostRevision) (*pb.Post, error) {
+	categories, err := server.store.GetCategoriesByIDs(ctx, revision.CategoryIds)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to get revision categories")
+	}
+
+	tags, err := server.store.GetTagsByIDs(ctx, revision.TagIds)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to get revision tags")
+	}
+
+	pbPost := &pb.Post{
+		Id:             revision.PostID,
+		Title:          revision.Title,
+		CoverImage:     revision.CoverImage,
+		Content:        revision.Content,
+		Categories:     convertCategories(categories),
+		Tags:           convertTags(tags),
+		Status:         "publish",
+		Version:        revision.Version,
+		RevisionStatus: revision.Status,
+	}
+	return pbPost, nil
+}
+
+// ========================// GetPostRevision //======================== //
+
+func (server *Server) GetPostRevision(ctx context.Context, req *pb.GetPostRevisionRequest) (*pb.GetPostRevisionResponse, error) {
+	authUser, gErr := server.grpcGuard(ctx, roleAuthor)
+	if gErr != nil {
+		return nil, gErr.GrpcErr()
+	}
+
+	if err := util.ValidateID(req.GetPostId()); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "postId: %s", err.Error())
+	}
+
+	arg := db.GetPostRevisionParams{
+		PostID:   req.GetPostId(),
+		IsAdmin:  authUser.Role == "admin",
+		AuthorID: authUser.ID,
+	}
+
+	revision, err := server.store.GetPostRevision(ctx, arg)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, status.Error(codes.NotFound, "post revision not found")
+		}
+		return nil, status.Error(codes.Internal, "failed to get post revision")
+	}
+	setPostETag(ctx, revision.Version)
+
+	pbPost, err := server.convertPostRevision(ctx, revision)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.GetPostRevisionResponse{Post: pbPost}, nil
+}
+
+// ========================// DiscardPostRevision //======================== //
+
+func (server *Server) DiscardPostRevision(ctx context.Context, req *pb.DiscardPostRevisionRequest) (*emptypb.Empty, error) {
+	authUser, gErr := server.grpcGuard(ctx, roleAuthor)
+	if gErr != nil {
+		return nil, gErr.GrpcErr()
+	}
+
+	if err := util.ValidateID(req.GetPostId()); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "postId: %s", err.Error())
+	}
+
+	arg := db.DeletePostRevisionParams{
+		PostID:   req.GetPostId(),
+		IsAdmin:  authUser.Role == "admin",
+		AuthorID: authUser.ID,
+	}
+
+	n, err := server.store.DeletePostRevision(ctx, arg)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to discard post revision")
+	}
+	if n == 0 {
+		return nil, status.Error(codes.NotFound, "post revision not found")
+	}
+	return &emptypb.Empty{}, nil
+}
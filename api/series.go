@@ -0,0 +1,282 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/bwen19/blog/grpc/pb"
+	"github.com/bwen19/blog/psql/db"
+	"github.com/bwen19/blog/util"
+	"github.com/lib/pq"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+// ========================// CreateSeries //======================== //
+
+func (server *Server) CreateSeries(ctx context.Context, req *pb.CreateSeriesRequest) (*pb.CreateSeriesResponse, error) {
+	authUser, gErr := server.grpcGuard(ctx, roleAuthor)
+	if gErr != nil {
+		return nil, gErr.GrpcErr()
+	}
+
+	if err := validateCreateSeriesRequest(req); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	arg := db.CreateSeriesParams{
+		AuthorID:    authUser.ID,
+		Title:       req.GetTitle(),
+		Description: req.GetDescription(),
+		CoverImage:  server.config.DefaultCover,
+	}
+	if req.CoverImage != nil {
+		arg.CoverImage = req.GetCoverImage()
+	}
+
+	series, err := server.store.CreateSeries(ctx, arg)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to create series")
+	}
+
+	rsp := &pb.CreateSeriesResponse{Series: convertSeries(series, 0)}
+	return rsp, nil
+}
+
+func validateCreateSeriesRequest(req *pb.CreateSeriesRequest) error {
+	if err := util.ValidateString(req.GetTitle(), 1, 200); err != nil {
+		return fmt.Errorf("title: %s", err.Error())
+	}
+	if err := util.ValidateString(req.GetDescription(), 0, 500); err != nil {
+		return fmt.Errorf("description: %s", err.Error())
+	}
+	if req.CoverImage != nil {
+		if err := util.ValidateString(req.GetCoverImage(), 1, 100); err != nil {
+			return fmt.Errorf("coverImage: %s", err.Error())
+		}
+	}
+	return nil
+}
+
+// ========================// DeleteSeries //======================== //
+
+func (server *Server) DeleteSeries(ctx context.Context, req *pb.DeleteSeriesRequest) (*emptypb.Empty, error) {
+	authUser, gErr := server.grpcGuard(ctx, roleAuthor)
+	if gErr != nil {
+		return nil, gErr.GrpcErr()
+	}
+
+	if err := util.ValidateID(req.GetSeriesId()); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "seriesId: %s", err.Error())
+	}
+
+	arg := db.DeleteSeriesParams{
+		ID:       req.GetSeriesId(),
+		IsAdmin:  authUser.Role == "admin",
+		AuthorID: authUser.ID,
+	}
+
+	nrows, err := server.store.DeleteSeries(ctx, arg)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to delete series")
+	}
+	if nrows == 0 {
+		return nil, status.Error(codes.NotFound, "series not found")
+	}
+	return &emptypb.Empty{}, nil
+}
+
+// ========================// ReorderSeries //======================== //
+
+func (server *Server) ReorderSeries(ctx context.Context, req *pb.ReorderSeriesRequest) (*emptypb.Empty, error) {
+	authUser, gErr := server.grpcGuard(ctx, roleAuthor)
+	if gErr != nil {
+		return nil, gErr.GrpcErr()
+	}
+
+	if err := util.ValidateID(req.GetSeriesId()); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "seriesId: %s", err.Error())
+	}
+	postIDs, err := util.ValidateRepeatedIDs(req.GetPostIds())
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "postId: %s", err.Error())
+	}
+
+	series, err := server.store.GetSeries(ctx, req.GetSeriesId())
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, status.Error(codes.NotFound, "series not found")
+		}
+		return nil, status.Error(codes.Internal, "failed to get series")
+	}
+	if series.AuthorID != authUser.ID {
+		return nil, status.Error(codes.PermissionDenied, "no permission to reorder this series")
+	}
+
+	arg1 := db.ListSeriesPostsParams{
+		SeriesID:  series.ID,
+		AnyStatus: true,
+	}
+	posts, err := server.store.ListSeriesPosts(ctx, arg1)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to list series posts")
+	}
+
+	// The new order must contain every post of the series exactly once,
+	// trashed posts are kept after them in case they are restored
+	if len(posts) != len(postIDs) {
+		return nil, status.Error(codes.InvalidArgument, "postIds: must contain all posts of the series")
+	}
+	dict := map[int64]bool{}
+	for _, post := range posts {
+		dict[post.ID] = true
+	}
+	for _, postID := range postIDs {
+		if !dict[postID] {
+			return nil, status.Errorf(codes.InvalidArgument, "postIds: post %d is not in the series", postID)
+		}
+	}
+
+	arg2 := db.UpdateSeriesPositionsParams{
+		SeriesID: series.ID,
+		PostIds:  postIDs,
+	}
+	if _, err = server.store.UpdateSeriesPositions(ctx, arg2); err != nil {
+		return nil, status.Error(codes.Internal, "failed to reorder series")
+	}
+	return &emptypb.Empty{}, nil
+}
+
+// ========================// AddSeriesPost //======================== //
+
+func (server *Server) AddSeriesPost(ctx context.Context, req *pb.AddSeriesPostRequest) (*emptypb.Empty, error) {
+	authUser, gErr := server.grpcGuard(ctx, roleAuthor)
+	if gErr != nil {
+		return nil, gErr.GrpcErr()
+	}
+
+	if err := util.ValidateID(req.GetSeriesId()); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "seriesId: %s", err.Error())
+	}
+	if err := util.ValidateID(req.GetPostId()); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "postId: %s", err.Error())
+	}
+
+	arg := db.AddSeriesPostParams{
+		SeriesID: req.GetSeriesId(),
+		PostID:   req.GetPostId(),
+		AuthorID: authUser.ID,
+	}
+
+	if _, err := server.store.AddSeriesPost(ctx, arg); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, status.Error(codes.NotFound, "series or post not found")
+		}
+		if pgErr, ok := err.(*pq.Error); ok {
+			switch pgErr.Code.Name() {
+			case "unique_violation":
+				return nil, status.Error(codes.AlreadyExists, "post already belongs to a series")
+			}
+		}
+		return nil, status.Error(codes.Internal, "failed to add post to series")
+	}
+	return &emptypb.Empty{}, nil
+}
+
+// ========================// RemoveSeriesPost //======================== //
+
+func (server *Server) RemoveSeriesPost(ctx context.Context, req *pb.RemoveSeriesPostRequest) (*emptypb.Empty, error) {
+	authUser, gErr := server.grpcGuard(ctx, roleAuthor)
+	if gErr != nil {
+		return nil, gErr.GrpcErr()
+	}
+
+	if err := util.ValidateID(req.GetSeriesId()); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "seriesId: %s", err.Error())
+	}
+	if err := util.ValidateID(req.GetPostId()); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "postId: %s", err.Error())
+	}
+
+	arg := db.DeleteSeriesPostParams{
+		SeriesID: req.GetSeriesId(),
+		PostID:   req.GetPostId(),
+		AuthorID: authUser.ID,
+	}
+
+	nrows, err := server.store.DeleteSeriesPost(ctx, arg)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to remove post from series")
+	}
+	if nrows == 0 {
+		return nil, status.Error(codes.NotFound, "post not found in series")
+	}
+	return &emptypb.Empty{}, nil
+}
+
+// ========================// ListSeries //======================== //
+
+func (server *Server) ListSeries(ctx context.Context, req *pb.ListSeriesRequest) (*pb.ListSeriesResponse, error) {
+	authUser, gErr := server.grpcGuard(ctx, roleGhost)
+	if gErr != nil {
+		return nil, gErr.GrpcErr()
+	}
+
+	if err := util.ValidatePage(req); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err := util.ValidateID(req.GetAuthorId()); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "authorId: %s", err.Error())
+	}
+
+	arg := db.ListSeriesParams{
+		Limit:    req.GetPageSize(),
+		Offset:   (req.GetPageId() - 1) * req.GetPageSize(),
+		AuthorID: req.GetAuthorId(),
+		SelfID:   authUser.ID,
+	}
+
+	series, err := server.store.ListSeries(ctx, arg)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to list series")
+	}
+	return convertListSeries(series), nil
+}
+
+// ========================// GetSeries //======================== //
+
+func (server *Server) GetSeries(ctx context.Context, req *pb.GetSeriesRequest) (*pb.GetSeriesResponse, error) {
+	authUser, gErr := server.grpcGuard(ctx, roleGhost)
+	if gErr != nil {
+		return nil, gErr.GrpcErr()
+	}
+
+	if err := util.ValidateID(req.GetSeriesId()); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "seriesId: %s", err.Error())
+	}
+
+	series, err := server.store.GetSeries(ctx, req.GetSeriesId())
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, status.Error(codes.NotFound, "series not found")
+		}
+		return nil, status.Error(codes.Internal, "failed to get series")
+	}
+
+	// Unpublished parts are only visible to the author, and the other
+	// parts to those who may read them
+	arg := db.ListSeriesPostsParams{
+		SeriesID:  series.ID,
+		AnyStatus: series.AuthorID == authUser.ID,
+		SelfID:    authUser.ID,
+		IsAdmin:   authUser.Role == "admin",
+	}
+	posts, err := server.store.ListSeriesPosts(ctx, arg)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to list series posts")
+	}
+
+	return convertGetSeries(series, posts), nil
+}
@@ -14,6 +14,11 @@ type Server struct {
 	config     util.Config
 	store      db.Store
 	tokenMaker util.TokenMaker
+	sitemap    *sitemapCache
+	views      *viewBuffer
+	related    *relatedCache
+
+	variantJobs chan variantJob
 }
 
 // Create a new gRPC server
@@ -27,6 +32,11 @@ func NewServer(config util.Config, store db.Store) (*Server, error) {
 		config:     config,
 		store:      store,
 		tokenMaker: tokenMaker,
+		sitemap:    &sitemapCache{},
+		views:      newViewBuffer(config.ViewBufferSize),
+		related:    &relatedCache{},
+
+		variantJobs: make(chan variantJob, maxPendingVariants),
 	}
 	return server, nil
 }
@@ -0,0 +1,216 @@
+package api
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Maximum number of urls allowed in a single sitemap file
+const sitemapMaxURLs = 50000
+
+const sitemapXMLNS = "http://www.sitemaps.org/schemas/sitemap/0.9"
+
+type sitemapURL struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
+}
+
+type sitemapURLSet struct {
+	XMLName xml.Name     `xml:"urlset"`
+	XMLNS   string       `xml:"xmlns,attr"`
+	URLs    []sitemapURL `xml:"url"`
+}
+
+type sitemapIndex struct {
+	XMLName  xml.Name     `xml:"sitemapindex"`
+	XMLNS    string       `xml:"xmlns,attr"`
+	Sitemaps []sitemapURL `xml:"sitemap"`
+}
+
+// sitemapCache keeps the rendered sitemap files in memory. The first file is
+// served as /sitemap.xml, the rest (if any) as /sitemaps/{n}.xml
+type sitemapCache struct {
+	mu        sync.Mutex
+	files     [][]byte
+	expiresAt time.Time
+}
+
+// Drop the cached files so that they are rebuilt on the next request
+func (cache *sitemapCache) invalidate() {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	cache.files = nil
+}
+
+// Return the cached sitemap files, rebuilding them if necessary
+func (server *Server) sitemapFiles(ctx context.Context) ([][]byte, error) {
+	cache := server.sitemap
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	if cache.files != nil && time.Now().Before(cache.expiresAt) {
+		return cache.files, nil
+	}
+
+	files, err := server.buildSitemap(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	cache.files = files
+	cache.expiresAt = time.Now().Add(server.config.SitemapCacheDuration)
+	return files, nil
+}
+
+// Build sitemap files of published posts, authors, categories and tags
+func (server *Server) buildSitemap(ctx context.Context) ([][]byte, error) {
+	site := strings.TrimRight(server.config.SiteURL, "/")
+	urls := []sitemapURL{{Loc: site + "/"}}
+
+	posts, err := server.store.ListSitemapPosts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, post := range posts {
+		urls = append(urls, sitemapURL{
+			Loc:     fmt.Sprintf("%s/post/%d", site, post.ID),
+			LastMod: post.Lastmod.UTC().Format(time.RFC3339),
+		})
+	}
+
+	authors, err := server.store.ListSitemapAuthors(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, author := range authors {
+		urls = append(urls, sitemapURL{
+			Loc:     fmt.Sprintf("%s/user/%d", site, author.ID),
+			LastMod: author.Lastmod.UTC().Format(time.RFC3339),
+		})
+	}
+
+	categories, err := server.store.ListSitemapCategories(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, category := range categories {
+		urls = append(urls, sitemapURL{
+			Loc:     fmt.Sprintf("%s/category/%d", site, category.ID),
+			LastMod: category.Lastmod.UTC().Format(time.RFC3339),
+		})
+	}
+
+	tags, err := server.store.ListSitemapTags(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, tag := range tags {
+		urls = append(urls, sitemapURL{
+			Loc:     fmt.Sprintf("%s/tag/%s", site, url.PathEscape(tag.Name)),
+			LastMod: tag.Lastmod.UTC().Format(time.RFC3339),
+		})
+	}
+
+	if len(urls) <= sitemapMaxURLs {
+		file, err := marshalSitemap(&sitemapURLSet{XMLNS: sitemapXMLNS, URLs: urls})
+		if err != nil {
+			return nil, err
+		}
+		return [][]byte{file}, nil
+	}
+
+	// Split urls into several sitemaps and list them in a sitemap index
+	files := [][]byte{nil}
+	index := &sitemapIndex{XMLNS: sitemapXMLNS}
+	now := time.Now().UTC().Format(time.RFC3339)
+	for start := 0; start < len(urls); start += sitemapMaxURLs {
+		end := start + sitemapMaxURLs
+		if end > len(urls) {
+			end = len(urls)
+		}
+
+		file, err := marshalSitemap(&sitemapURLSet{XMLNS: sitemapXMLNS, URLs: urls[start:end]})
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+
+		index.Sitemaps = append(index.Sitemaps, sitemapURL{
+			Loc:     fmt.Sprintf("%s/sitemaps/%d.xml", site, len(files)-1),
+			LastMod: now,
+		})
+	}
+
+	file, err := marshalSitemap(index)
+	if err != nil {
+		return nil, err
+	}
+	files[0] = file
+	return files, nil
+}
+
+func marshalSitemap(v interface{}) ([]byte, error) {
+	data, err := xml.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), data...), nil
+}
+
+// Handler of /sitemap.xml
+func (server *Server) HandleSitemap(w http.ResponseWriter, r *http.Request) {
+	server.writeSitemap(w, r, 0)
+}
+
+// Handler of /sitemaps/{n}.xml
+func (server *Server) HandleSitemapPart(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimPrefix(r.URL.Path, "/sitemaps/")
+	n, err := strconv.Atoi(strings.TrimSuffix(name, ".xml"))
+	if err != nil || !strings.HasSuffix(name, ".xml") || n < 1 {
+		http.NotFound(w, r)
+		return
+	}
+	server.writeSitemap(w, r, n)
+}
+
+func (server *Server) writeSitemap(w http.ResponseWriter, r *http.Request, n int) {
+	files, err := server.sitemapFiles(r.Context())
+	if err != nil {
+		log.Println("failed to build sitemap:", err)
+		http.Error(w, "failed to build sitemap", http.StatusInternalServerError)
+		return
+	}
+	if n >= len(files) {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	w.Write(files[n])
+}
+
+// Handler of /robots.txt
+func (server *Server) HandleRobots(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+
+	if server.config.RobotsPath != "" {
+		data, err := os.ReadFile(server.config.RobotsPath)
+		if err == nil {
+			w.Write(data)
+			return
+		}
+		log.Println("failed to read robots file:", err)
+	}
+
+	site := strings.TrimRight(server.config.SiteURL, "/")
+	fmt.Fprintf(w, "User-agent: *\nDisallow: /api/\nDisallow: /swagger/\n\nSitemap: %s/sitemap.xml\n", site)
+}
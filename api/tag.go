@@ -56,6 +56,7 @@ func (server *Server) DeleteTags(ctx context.Context, req *pb.DeleteTagsRequest)
 	if err != nil || len(tagIDs) != int(nrows) {
 		return nil, status.Error(codes.Internal, "failed to delete tags")
 	}
+	server.related.invalidate()
 	return &emptypb.Empty{}, nil
 }
 
@@ -0,0 +1,76 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"time"
+
+	"github.com/bwen19/blog/grpc/pb"
+	"github.com/bwen19/blog/psql/db"
+	"github.com/bwen19/blog/util"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+// ========================// RestorePost //======================== //
+
+func (server *Server) RestorePost(ctx context.Context, req *pb.RestorePostRequest) (*emptypb.Empty, error) {
+	authUser, gErr := server.grpcGuard(ctx, roleAuthor)
+	if gErr != nil {
+		return nil, gErr.GrpcErr()
+	}
+
+	if err := util.ValidateID(req.GetPostId()); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	arg := db.RestorePostParams{
+		ID:       req.GetPostId(),
+		IsAdmin:  authUser.Role == "admin",
+		AuthorID: authUser.ID,
+	}
+
+	post, err := server.store.RestorePost(ctx, arg)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, status.Error(codes.NotFound, "post not found in trash")
+		}
+		return nil, status.Error(codes.Internal, "failed to restore post")
+	}
+
+	if post.Status == "publish" {
+		server.sitemap.invalidate()
+		server.related.invalidate()
+	}
+	return &emptypb.Empty{}, nil
+}
+
+// purgeTrash permanently deletes the posts kept in the trash longer than the retention period
+func (server *Server) purgeTrash(ctx context.Context) {
+	n, err := server.store.PurgeTrashedPosts(ctx, time.Now().Add(-server.config.TrashRetention))
+	if err != nil {
+		log.Println("failed to purge trashed posts:", err)
+		return
+	}
+	if n > 0 {
+		log.Printf("purged %d trashed posts", n)
+	}
+}
+
+// RunTrashPurger purges the trash on start and on every interval
+func (server *Server) RunTrashPurger(ctx context.Context) {
+	ticker := time.NewTicker(server.config.TrashPurgeInterval)
+	defer ticker.Stop()
+
+	server.purgeTrash(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			server.purgeTrash(ctx)
+		}
+	}
+}
@@ -1,18 +1,43 @@
 package api
 
 import (
+	"context"
 	"database/sql"
-	"io"
+	"errors"
 	"log"
 	"net/http"
-	"os"
-	"path"
 
 	"github.com/bwen19/blog/grpc/pb"
 	"github.com/bwen19/blog/psql/db"
-	"github.com/bwen19/blog/util"
 )
 
+const (
+	maxAvatarSize    = 1 << 20
+	maxPostImageSize = 5 << 20
+
+	// multipartOverhead leaves room for boundaries, headers and other fields
+	// of the form besides the file itself
+	multipartOverhead = 64 << 10
+)
+
+// parseUploadForm parses a multipart form whose total size is capped by limit
+// plus a small overhead. It writes the error response and returns false on
+// failure.
+func parseUploadForm(w http.ResponseWriter, r *http.Request, limit int64) bool {
+	r.Body = http.MaxBytesReader(w, r.Body, limit+multipartOverhead)
+	if err := r.ParseMultipartForm(limit); err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			code, msg := mediaErrorStatus(err, limit)
+			httpError(w, code, msg)
+		} else {
+			httpError(w, http.StatusBadRequest, "failed to parse multipart form")
+		}
+		return false
+	}
+	return true
+}
+
 // Handler of file upload
 func (server *Server) HandleFileUpload(w http.ResponseWriter, r *http.Request, params map[string]string) {
 	param, ok := params["suffix"]
@@ -45,9 +70,7 @@ func (server *Server) UploadAvatar(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// maxMemory set as 1M for avatar
-	if err := r.ParseMultipartForm(1 << 20); err != nil {
-		httpError(w, http.StatusBadRequest, "failed to parse multipart form")
+	if !parseUploadForm(w, r, maxAvatarSize) {
 		return
 	}
 
@@ -58,21 +81,17 @@ func (server *Server) UploadAvatar(w http.ResponseWriter, r *http.Request) {
 	}
 	defer f.Close()
 
-	avatarSrc := path.Join(server.config.AvatarPath, util.RandomImageName())
-	fileName := path.Join(server.config.PublicPath, avatarSrc)
-
-	fn, err := os.OpenFile(fileName, os.O_WRONLY|os.O_CREATE, 0666)
+	media, err := server.saveMedia(r.Context(), f, server.config.AvatarPath, authUser.ID, "", maxAvatarSize, avatarVariants)
 	if err != nil {
-		httpError(w, http.StatusInternalServerError, "failed to save the new avatar file")
+		code, msg := mediaErrorStatus(err, maxAvatarSize)
+		httpError(w, code, msg)
 		return
 	}
-	defer fn.Close()
-	io.Copy(fn, f)
 
 	// update avatar src of user in database
 	arg := db.UpdateUserParams{
 		ID:     authUser.ID,
-		Avatar: sql.NullString{String: avatarSrc, Valid: true},
+		Avatar: sql.NullString{String: media.Path, Valid: true},
 	}
 
 	user, err := server.store.UpdateUser(r.Context(), arg)
@@ -82,32 +101,58 @@ func (server *Server) UploadAvatar(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if authUser.Avatar != server.config.DefaultAvatar {
-		oldFile := path.Join(server.config.PublicPath, authUser.Avatar)
-		if err = os.Remove(oldFile); err != nil {
-			log.Println("failed to remove old avatar file")
-		}
+		server.deleteOldAvatar(r.Context(), authUser)
 	}
 
+	// Variants of the avatar are written in the background
 	rsp := &UploadAvatarResponse{User: convertUser(user)}
 	httpResponse(w, rsp)
 }
 
+// deleteOldAvatar removes the replaced avatar of a user with its variants
+func (server *Server) deleteOldAvatar(ctx context.Context, authUser *db.User) {
+	paths := []string{authUser.Avatar}
+
+	arg1 := db.GetMediaByPathParams{
+		Path:    authUser.Avatar,
+		OwnerID: authUser.ID,
+	}
+	if media, err := server.store.GetMediaByPath(ctx, arg1); err == nil {
+		variants, err := server.store.ListMediaVariants(ctx, []int64{media.ID})
+		if err != nil {
+			log.Println("failed to list old avatar variants")
+		}
+		for _, variant := range variants {
+			paths = append(paths, variant.Path)
+		}
+	}
+
+	arg2 := db.DeleteMediaByPathParams{
+		Path:    authUser.Avatar,
+		OwnerID: authUser.ID,
+	}
+	if err := server.store.DeleteMediaByPath(ctx, arg2); err != nil {
+		log.Println("failed to delete old avatar media")
+	}
+	server.removeMediaFiles(paths...)
+}
+
 // -------------------------------------------------------------------
 // UploadPostImage
 type UploadPostImageResponse struct {
-	Image string `json:"image,omitempty"`
+	Image string    `json:"image,omitempty"`
+	Media *pb.Media `json:"media,omitempty"`
 }
 
 // UploadPostImage
 func (server *Server) UploadPostImage(w http.ResponseWriter, r *http.Request) {
-	if _, gErr := server.httpGuard(r, roleAuthor); gErr != nil {
+	authUser, gErr := server.httpGuard(r, roleAuthor)
+	if gErr != nil {
 		gErr.HttpErr(w)
 		return
 	}
 
-	// maxMemory set as 5M for post image
-	if err := r.ParseMultipartForm(5 << 20); err != nil {
-		httpError(w, http.StatusBadRequest, "failed to parse multipart form")
+	if !parseUploadForm(w, r, maxPostImageSize) {
 		return
 	}
 
@@ -118,17 +163,14 @@ func (server *Server) UploadPostImage(w http.ResponseWriter, r *http.Request) {
 	}
 	defer f.Close()
 
-	imageSrc := path.Join(server.config.PostPath, util.RandomImageName())
-	fileName := path.Join(server.config.PublicPath, imageSrc)
-
-	fn, err := os.OpenFile(fileName, os.O_WRONLY|os.O_CREATE, 0666)
+	media, err := server.saveMedia(r.Context(), f, server.config.PostPath, authUser.ID, r.FormValue("alt"), maxPostImageSize, postImageVariants)
 	if err != nil {
-		httpError(w, http.StatusInternalServerError, "failed to save the new image file")
+		code, msg := mediaErrorStatus(err, maxPostImageSize)
+		httpError(w, code, msg)
 		return
 	}
-	defer fn.Close()
-	io.Copy(fn, f)
 
-	rsp := &UploadPostImageResponse{Image: imageSrc}
+	// Variants of the image are written in the background
+	rsp := &UploadPostImageResponse{Image: media.Path, Media: convertMedia(media)}
 	httpResponse(w, rsp)
 }
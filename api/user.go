@@ -219,10 +219,11 @@ func (server *Server) ChangeProfile(ctx context.Context, req *pb.ChangeProfileRe
 	}
 
 	arg := db.UpdateUserParams{
-		ID:       req.GetUserId(),
-		Username: sql.NullString{String: req.GetUsername(), Valid: req.Username != nil},
-		Email:    sql.NullString{String: req.GetEmail(), Valid: req.Email != nil},
-		Intro:    sql.NullString{String: req.GetIntro(), Valid: req.Intro != nil},
+		ID:          req.GetUserId(),
+		Username:    sql.NullString{String: req.GetUsername(), Valid: req.Username != nil},
+		Email:       sql.NullString{String: req.GetEmail(), Valid: req.Email != nil},
+		Intro:       sql.NullString{String: req.GetIntro(), Valid: req.Intro != nil},
+		PublicStars: sql.NullBool{Bool: req.GetPublicStars(), Valid: req.PublicStars != nil},
 	}
 
 	user, err := server.store.UpdateUser(ctx, arg)
@@ -230,7 +231,7 @@ func (server *Server) ChangeProfile(ctx context.Context, req *pb.ChangeProfileRe
 		return nil, status.Error(codes.Internal, "failed to change password")
 	}
 
-	rsp := &pb.ChangeProfileResponse{User: convertUser(user)}
+	rsp := &pb.ChangeProfileResponse{User: server.userWithVariants(ctx, user)}
 	return rsp, nil
 }
 
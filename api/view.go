@@ -0,0 +1,254 @@
+package api
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bwen19/blog/psql/db"
+	"github.com/bwen19/blog/util"
+	"google.golang.org/grpc/metadata"
+)
+
+type postView struct {
+	postID  int64
+	userID  int64
+	visitor string
+}
+
+type bufferedView struct {
+	postView
+	source string
+}
+
+// viewBuffer collects post views in memory until they are flushed to the
+// database. While flushes fail it holds at most maxPendingFactor times its
+// limit, views beyond that are dropped and counted.
+type viewBuffer struct {
+	mu      sync.Mutex
+	pending map[postView]string
+	limit   int
+	dropped int
+	full    chan struct{}
+}
+
+const maxPendingFactor = 4
+
+func newViewBuffer(limit int) *viewBuffer {
+	return &viewBuffer{
+		pending: map[postView]string{},
+		limit:   limit,
+		full:    make(chan struct{}, 1),
+	}
+}
+
+// add records a view, repeated views of the same visitor collapse into
+// one that keeps the traffic source of the first view
+func (buf *viewBuffer) add(view postView, source string) {
+	buf.mu.Lock()
+	if _, ok := buf.pending[view]; !ok {
+		if len(buf.pending) < buf.limit*maxPendingFactor {
+			buf.pending[view] = source
+		} else {
+			buf.dropped++
+		}
+	}
+	full := len(buf.pending) >= buf.limit
+	buf.mu.Unlock()
+
+	if full {
+		select {
+		case buf.full <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// drain takes all pending views out of the buffer along with the number of
+// views dropped since the last drain
+func (buf *viewBuffer) drain() ([]bufferedView, int) {
+	buf.mu.Lock()
+	pending, dropped := buf.pending, buf.dropped
+	buf.pending = map[postView]string{}
+	buf.dropped = 0
+	buf.mu.Unlock()
+
+	views := make([]bufferedView, 0, len(pending))
+	for view, source := range pending {
+		views = append(views, bufferedView{postView: view, source: source})
+	}
+	return views, dropped
+}
+
+// restore puts views back after a failed flush so that they are retried,
+// as far as the buffer has room for them
+func (buf *viewBuffer) restore(views []bufferedView) {
+	buf.mu.Lock()
+	defer buf.mu.Unlock()
+	for _, view := range views {
+		if _, ok := buf.pending[view.postView]; ok {
+			continue
+		}
+		if len(buf.pending) < buf.limit*maxPendingFactor {
+			buf.pending[view.postView] = view.source
+		} else {
+			buf.dropped++
+		}
+	}
+}
+
+// recordPostView buffers a view of a visitor, known crawlers are ignored
+func (server *Server) recordPostView(ctx context.Context, postID int64, authUser *db.User) {
+	userAgent, clientIP := extractLoginInfo(ctx)
+	if util.IsCrawler(userAgent) {
+		return
+	}
+
+	view := postView{
+		postID:  postID,
+		userID:  authUser.ID,
+		visitor: visitorKey(authUser.ID, clientIP, userAgent),
+	}
+	server.views.add(view, server.trafficSource(extractReferer(ctx)))
+}
+
+func extractReferer(ctx context.Context) string {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if referers := md.Get("grpcgateway-referer"); len(referers) > 0 {
+			return referers[0]
+		}
+	}
+	return ""
+}
+
+// trafficSource reduces a referer to the host it came from
+func (server *Server) trafficSource(referer string) string {
+	if referer == "" {
+		return "direct"
+	}
+
+	refURL, err := url.Parse(referer)
+	if err != nil || refURL.Hostname() == "" {
+		return "unknown"
+	}
+	if siteURL, err := url.Parse(server.config.SiteURL); err == nil && siteURL.Hostname() == refURL.Hostname() {
+		return "internal"
+	}
+	return strings.TrimPrefix(strings.ToLower(refURL.Hostname()), "www.")
+}
+
+// visitorKey identifies signed-in users by ID and guests by a hash of IP and user agent
+func visitorKey(userID int64, clientIP string, userAgent string) string {
+	if userID != 0 {
+		return fmt.Sprintf("u:%d", userID)
+	}
+
+	if host, _, err := net.SplitHostPort(clientIP); err == nil {
+		clientIP = host
+	}
+	sum := sha256.Sum256([]byte(clientIP + "|" + userAgent))
+	return "g:" + hex.EncodeToString(sum[:])
+}
+
+// flushViews writes all buffered views in one batch. The batch only adds
+// deltas and deduplicates against the database, so replicas can flush
+// their own buffers concurrently.
+func (server *Server) flushViews(ctx context.Context) {
+	views, dropped := server.views.drain()
+	if dropped > 0 {
+		log.Printf("dropped %d post views because the view buffer was full", dropped)
+	}
+	if len(views) == 0 {
+		return
+	}
+
+	// Sort the batch so that concurrent flushes take row locks in the same order
+	sort.Slice(views, func(i, j int) bool {
+		if views[i].postID != views[j].postID {
+			return views[i].postID < views[j].postID
+		}
+		return views[i].visitor < views[j].visitor
+	})
+
+	arg := db.FlushPostViewsParams{
+		PostIds:  make([]int64, 0, len(views)),
+		Visitors: make([]string, 0, len(views)),
+		Sources:  make([]string, 0, len(views)),
+		Since:    time.Now().Add(-server.config.ViewDedupWindow),
+	}
+	for _, view := range views {
+		arg.PostIds = append(arg.PostIds, view.postID)
+		arg.Visitors = append(arg.Visitors, view.visitor)
+		arg.Sources = append(arg.Sources, view.source)
+	}
+
+	if _, err := server.store.FlushPostViews(ctx, arg); err != nil {
+		log.Println("failed to flush post views:", err)
+		server.views.restore(views)
+		return
+	}
+
+	// Signed-in readers also keep a reading history
+	arg2 := db.FlushPostReadsParams{}
+	for _, view := range views {
+		if view.userID != 0 {
+			arg2.UserIds = append(arg2.UserIds, view.userID)
+			arg2.PostIds = append(arg2.PostIds, view.postID)
+		}
+	}
+	if len(arg2.UserIds) > 0 {
+		if err := server.store.FlushPostReads(ctx, arg2); err != nil {
+			log.Println("failed to flush post reads:", err)
+		}
+	}
+}
+
+// RunViewFlusher flushes buffered views on every interval or when the buffer
+// is full until the context is done
+func (server *Server) RunViewFlusher(ctx context.Context) {
+	ticker := time.NewTicker(server.config.ViewFlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			server.flushViews(ctx)
+		case <-server.views.full:
+			server.flushViews(ctx)
+		}
+	}
+}
+
+// FlushViews writes the views that are still buffered, it is called once more
+// on shutdown after the servers stopped taking requests
+func (server *Server) FlushViews(ctx context.Context) {
+	server.flushViews(ctx)
+}
+
+// PruneViewers removes visitor records that are older than the dedup window
+func (server *Server) PruneViewers(ctx context.Context) {
+	ticker := time.NewTicker(server.config.ViewDedupWindow)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			before := time.Now().Add(-server.config.ViewDedupWindow)
+			if _, err := server.store.DeletePostViewers(ctx, before); err != nil {
+				log.Println("failed to prune post viewers:", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package imaging
+
+import (
+	"image"
+
+	"golang.org/x/image/draw"
+)
+
+// Fit scales an image down to the given width and keeps its aspect ratio.
+// Images that are not wider than width are only copied.
+func Fit(src image.Image, width int) *image.NRGBA {
+	b := src.Bounds()
+	if b.Dx() <= width {
+		return scale(src, b, b.Dx(), b.Dy())
+	}
+
+	height := (b.Dy()*width + b.Dx()/2) / b.Dx()
+	if height < 1 {
+		height = 1
+	}
+	return scale(src, b, width, height)
+}
+
+// Square crops the center square of an image and scales it to size
+func Square(src image.Image, size int) *image.NRGBA {
+	b := src.Bounds()
+	side := b.Dx()
+	if b.Dy() < side {
+		side = b.Dy()
+	}
+
+	x0 := b.Min.X + (b.Dx()-side)/2
+	y0 := b.Min.Y + (b.Dy()-side)/2
+	crop := image.Rect(x0, y0, x0+side, y0+side)
+
+	if side < size {
+		size = side
+	}
+	return scale(src, crop, size, size)
+}
+
+func scale(src image.Image, sr image.Rectangle, width, height int) *image.NRGBA {
+	dst := image.NewNRGBA(image.Rect(0, 0, width, height))
+	if sr.Dx() == width && sr.Dy() == height {
+		draw.Draw(dst, dst.Bounds(), src, sr.Min, draw.Src)
+	} else {
+		draw.CatmullRom.Scale(dst, dst.Bounds(), src, sr, draw.Src, nil)
+	}
+	return dst
+}
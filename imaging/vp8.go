@@ -0,0 +1,825 @@
+package imaging
+
+import (
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	"io"
+	"math"
+)
+
+// EncodeWebPLossy writes an image in the lossy WebP format at a quality from
+// 1 to 100. The encoder predicts every macroblock as a whole and leaves out
+// the loop filter, but fits the token probabilities to the image, which keeps
+// photos well below the size of a JPEG. Transparent pixels are flattened onto
+// white, so it is meant for opaque images.
+func EncodeWebPLossy(w io.Writer, m image.Image, quality int) error {
+	b := m.Bounds()
+	if b.Dx() < 1 || b.Dy() < 1 || b.Dx() >= 1<<14 || b.Dy() >= 1<<14 {
+		return errors.New("webp: invalid image size")
+	}
+	if quality < 1 || quality > 100 {
+		return errors.New("webp: invalid quality")
+	}
+
+	e := newVP8Encoder(m, quantIndex(quality))
+	return writeRIFF(w, "VP8 ", e.encode(b.Dx(), b.Dy()))
+}
+
+// quantIndex maps a quality to the quantizer index of the frame, following
+// the curve of libwebp so that qualities mean about the same
+func quantIndex(quality int) int {
+	q := float64(quality) / 100
+	linear := 2*q - 1
+	if q < 0.75 {
+		linear = q * 2 / 3
+	}
+	return int(127 * (1 - math.Cbrt(linear)))
+}
+
+// Planes of the token probabilities, of which the encoder only uses the
+// ones for whole macroblock prediction
+const (
+	planeY1WithY2 = iota
+	planeY2
+	planeUV
+	planeY1SansY2
+	vp8Planes
+)
+
+const (
+	vp8Bands    = 8
+	vp8Contexts = 3
+	vp8Probs    = 11
+)
+
+// Prediction modes of a macroblock
+const (
+	predDC = iota
+	predTM
+	predVE
+	predHE
+	vp8Modes
+)
+
+var (
+	// vp8Zigzag maps the coding order of coefficients to their position
+	vp8Zigzag = [16]uint8{0, 1, 4, 8, 5, 2, 3, 6, 9, 12, 13, 10, 7, 11, 14, 15}
+	// vp8Band is the band of the probabilities for each coefficient
+	vp8Band = [17]uint8{0, 1, 2, 3, 6, 4, 5, 6, 6, 6, 6, 6, 6, 6, 6, 7, 0}
+	// vp8CatProb are the probabilities of the extra bits of large values
+	vp8CatProb = [4][]uint8{
+		{173, 148, 140},
+		{176, 155, 140, 135},
+		{180, 157, 141, 134, 130},
+		{254, 254, 243, 230, 196, 177, 153, 140, 133, 130, 129},
+	}
+	dcQuant = [128]int32{
+		4, 5, 6, 7, 8, 9, 10, 10, 11, 12, 13, 14, 15, 16, 17, 17,
+		18, 19, 20, 20, 21, 21, 22, 22, 23, 23, 24, 25, 25, 26, 27, 28,
+		29, 30, 31, 32, 33, 34, 35, 36, 37, 37, 38, 39, 40, 41, 42, 43,
+		44, 45, 46, 46, 47, 48, 49, 50, 51, 52, 53, 54, 55, 56, 57, 58,
+		59, 60, 61, 62, 63, 64, 65, 66, 67, 68, 69, 70, 71, 72, 73, 74,
+		75, 76, 76, 77, 78, 79, 80, 81, 82, 83, 84, 85, 86, 87, 88, 89,
+		91, 93, 95, 96, 98, 100, 101, 102, 104, 106, 108, 110, 112, 114, 116, 118,
+		122, 124, 126, 128, 130, 132, 134, 136, 138, 140, 143, 145, 148, 151, 154, 157,
+	}
+	acQuant = [128]int32{
+		4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17, 18, 19,
+		20, 21, 22, 23, 24, 25, 26, 27, 28, 29, 30, 31, 32, 33, 34, 35,
+		36, 37, 38, 39, 40, 41, 42, 43, 44, 45, 46, 47, 48, 49, 50, 51,
+		52, 53, 54, 55, 56, 57, 58, 60, 62, 64, 66, 68, 70, 72, 74, 76,
+		78, 80, 82, 84, 86, 88, 90, 92, 94, 96, 98, 100, 102, 104, 106, 108,
+		110, 112, 114, 116, 119, 122, 125, 128, 131, 134, 137, 140, 143, 146, 149, 152,
+		155, 158, 161, 164, 167, 170, 173, 177, 181, 185, 189, 193, 197, 201, 205, 209,
+		213, 217, 221, 225, 229, 234, 239, 245, 249, 254, 259, 264, 269, 274, 279, 284,
+	}
+)
+
+// maxLevel is the largest quantized coefficient the encoder writes
+const maxLevel = 2047
+
+type coeffProbs [vp8Planes][vp8Bands][vp8Contexts][vp8Probs]uint8
+
+// vp8Quant is the step size of the DC and AC coefficients of a block type,
+// with the rounding bias in 1/256 of a step
+type vp8Quant struct {
+	dc, ac         int32
+	dcBias, acBias int32
+}
+
+// quantize replaces the coefficients of a block from index first in coding
+// order with their quantized levels, and the coefficients with the values
+// the decoder gets back from them
+func (q *vp8Quant) quantize(coeffs *[16]int32, levels *[16]int16, first int) {
+	for i := first; i < 16; i++ {
+		z := vp8Zigzag[i]
+		step, bias := q.ac, q.acBias
+		if z == 0 {
+			step, bias = q.dc, q.dcBias
+		}
+		c := coeffs[z]
+		level := (abs32(c)*256 + bias*step) / (step * 256)
+		if level > maxLevel {
+			level = maxLevel
+		}
+		if c < 0 {
+			level = -level
+		}
+		levels[i] = int16(level)
+		coeffs[z] = int32(int16(level * step))
+	}
+}
+
+// vp8Macroblock is a coded macroblock
+type vp8Macroblock struct {
+	yMode, uvMode uint8
+	// levels are the quantized coefficients in coding order of the 16 luma
+	// blocks, the 4 blocks of each chroma plane and the luma DCs
+	levels [25][16]int16
+}
+
+func (mb *vp8Macroblock) empty() bool {
+	for i := range mb.levels {
+		if mb.levels[i] != [16]int16{} {
+			return false
+		}
+	}
+	return true
+}
+
+type vp8Encoder struct {
+	mbw, mbh int
+	qIndex   int
+	y1       vp8Quant
+	y2       vp8Quant
+	uv       vp8Quant
+	// Source and reconstructed planes, padded to whole macroblocks
+	y, u, v          []uint8
+	recY, recU, recV []uint8
+	mbs              []vp8Macroblock
+}
+
+func newVP8Encoder(m image.Image, qIndex int) *vp8Encoder {
+	b := m.Bounds()
+	width, height := b.Dx(), b.Dy()
+	e := &vp8Encoder{
+		mbw:    (width + 15) / 16,
+		mbh:    (height + 15) / 16,
+		qIndex: qIndex,
+		y1:     vp8Quant{dc: dcQuant[qIndex], ac: acQuant[qIndex], dcBias: 96, acBias: 110},
+		y2:     vp8Quant{dc: dcQuant[qIndex] * 2, ac: acQuant[qIndex] * 155 / 100, dcBias: 96, acBias: 108},
+		uv:     vp8Quant{dc: dcQuant[min(qIndex, 117)], ac: acQuant[qIndex], dcBias: 110, acBias: 115},
+	}
+	if e.y2.ac < 8 {
+		e.y2.ac = 8
+	}
+	e.mbs = make([]vp8Macroblock, e.mbw*e.mbh)
+
+	rgba := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(rgba, rgba.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(rgba, rgba.Bounds(), m, b.Min, draw.Over)
+
+	yStride, uvStride := e.mbw*16, e.mbw*8
+	e.y = make([]uint8, yStride*e.mbh*16)
+	e.u = make([]uint8, uvStride*e.mbh*8)
+	e.v = make([]uint8, uvStride*e.mbh*8)
+	e.recY = make([]uint8, len(e.y))
+	e.recU = make([]uint8, len(e.u))
+	e.recV = make([]uint8, len(e.v))
+
+	// Limited range BT.601 as in libwebp, with the edges repeated into the
+	// padding
+	pixel := func(x, y int) (r, g, b int) {
+		i := min(y, height-1)*rgba.Stride + min(x, width-1)*4
+		return int(rgba.Pix[i]), int(rgba.Pix[i+1]), int(rgba.Pix[i+2])
+	}
+	for y := 0; y < e.mbh*16; y++ {
+		for x := 0; x < yStride; x++ {
+			r, g, b := pixel(x, y)
+			e.y[y*yStride+x] = uint8((16839*r + 33059*g + 6420*b + 16<<16 + 1<<15) >> 16)
+		}
+	}
+	for y := 0; y < e.mbh*8; y++ {
+		for x := 0; x < uvStride; x++ {
+			var r, g, b int
+			for _, d := range [4][2]int{{0, 0}, {1, 0}, {0, 1}, {1, 1}} {
+				pr, pg, pb := pixel(2*x+d[0], 2*y+d[1])
+				r, g, b = r+pr, g+pg, b+pb
+			}
+			e.u[y*uvStride+x] = clamp((-9719*r - 19081*g + 28800*b + 128<<18 + 1<<17) >> 18)
+			e.v[y*uvStride+x] = clamp((28800*r - 24116*g - 4684*b + 128<<18 + 1<<17) >> 18)
+		}
+	}
+	return e
+}
+
+// encode codes the image into a key frame
+func (e *vp8Encoder) encode(width, height int) []byte {
+	for mby := 0; mby < e.mbh; mby++ {
+		for mbx := 0; mbx < e.mbw; mbx++ {
+			e.encodeMacroblock(mbx, mby)
+		}
+	}
+	return e.frame(width, height)
+}
+
+// encodeMacroblock picks the prediction modes of a macroblock, quantizes its
+// residuals and reconstructs it the way a decoder will, so that the next
+// macroblocks predict from the same pixels
+func (e *vp8Encoder) encodeMacroblock(mbx, mby int) {
+	mb := &e.mbs[mby*e.mbw+mbx]
+
+	stride := e.mbw * 16
+	origin := mby*16*stride + mbx*16
+	luma := predTarget{src: e.y[origin:], edges: blockEdges(e.recY, stride, 16, mbx, mby)}
+	mb.yMode = bestMode(16, stride, &luma)
+
+	var coeffs [16][16]int32
+	var dcs [16]int32
+	for n := range coeffs {
+		offset, predOffset := n/4*4*stride+n%4*4, n/4*4*16+n%4*4
+		forwardDCT(&coeffs[n], e.y[origin+offset:], stride, luma.pred[predOffset:], 16)
+		dcs[n] = coeffs[n][0]
+	}
+	wht := forwardWHT(&dcs)
+	e.y2.quantize(&wht, &mb.levels[24], 0)
+	dcs = inverseWHT(&wht)
+	for n := range coeffs {
+		offset, predOffset := n/4*4*stride+n%4*4, n/4*4*16+n%4*4
+		e.y1.quantize(&coeffs[n], &mb.levels[n], 1)
+		coeffs[n][0] = dcs[n]
+		inverseDCT(&coeffs[n], luma.pred[predOffset:], 16, e.recY[origin+offset:], stride)
+	}
+
+	stride = e.mbw * 8
+	origin = mby*8*stride + mbx*8
+	chroma := [2]predTarget{
+		{src: e.u[origin:], edges: blockEdges(e.recU, stride, 8, mbx, mby)},
+		{src: e.v[origin:], edges: blockEdges(e.recV, stride, 8, mbx, mby)},
+	}
+	mb.uvMode = bestMode(8, stride, &chroma[0], &chroma[1])
+	for c, rec := range [2][]uint8{e.recU, e.recV} {
+		for n := 0; n < 4; n++ {
+			offset, predOffset := n/2*4*stride+n%2*4, n/2*4*8+n%2*4
+			block := &coeffs[n]
+			forwardDCT(block, chroma[c].src[offset:], stride, chroma[c].pred[predOffset:], 8)
+			e.uv.quantize(block, &mb.levels[16+4*c+n], 0)
+			inverseDCT(block, chroma[c].pred[predOffset:], 8, rec[origin+offset:], stride)
+		}
+	}
+}
+
+// edgePixels are the reconstructed pixels above and left of a macroblock,
+// with the constants of the format outside the image
+type edgePixels struct {
+	top, left       [16]uint8
+	topLeft         uint8
+	hasTop, hasLeft bool
+}
+
+func blockEdges(rec []uint8, stride, size, mbx, mby int) edgePixels {
+	e := edgePixels{hasTop: mby > 0, hasLeft: mbx > 0}
+	x0, y0 := mbx*size, mby*size
+	for i := 0; i < size; i++ {
+		e.top[i], e.left[i] = 0x7f, 0x81
+		if e.hasTop {
+			e.top[i] = rec[(y0-1)*stride+x0+i]
+		}
+		if e.hasLeft {
+			e.left[i] = rec[(y0+i)*stride+x0-1]
+		}
+	}
+	switch {
+	case !e.hasTop:
+		e.topLeft = 0x7f
+	case !e.hasLeft:
+		e.topLeft = 0x81
+	default:
+		e.topLeft = rec[(y0-1)*stride+x0-1]
+	}
+	return e
+}
+
+// predTarget is a block of a plane to predict, with its source pixels from
+// the top left corner and the prediction of the chosen mode
+type predTarget struct {
+	src   []uint8
+	edges edgePixels
+	pred  [256]uint8
+}
+
+// bestMode returns the mode whose prediction is closest to the source of
+// all targets, and fills their predictions with it
+func bestMode(size, stride int, targets ...*predTarget) uint8 {
+	best, bestCost := uint8(predDC), math.MaxInt
+	var pred [256]uint8
+	for mode := uint8(predDC); mode < vp8Modes; mode++ {
+		cost := 0
+		for _, t := range targets {
+			predictBlock(pred[:], size, mode, &t.edges)
+			for y := 0; y < size; y++ {
+				for x := 0; x < size; x++ {
+					cost += abs(int(t.src[y*stride+x]) - int(pred[y*size+x]))
+				}
+			}
+		}
+		if cost < bestCost {
+			best, bestCost = mode, cost
+		}
+	}
+	for _, t := range targets {
+		predictBlock(t.pred[:], size, best, &t.edges)
+	}
+	return best
+}
+
+// predictBlock fills a block of size by size pixels with the prediction of a mode
+func predictBlock(pred []uint8, size int, mode uint8, e *edgePixels) {
+	switch mode {
+	case predDC:
+		sum, n := 0, 0
+		if e.hasTop {
+			for _, p := range e.top[:size] {
+				sum += int(p)
+			}
+			n += size
+		}
+		if e.hasLeft {
+			for _, p := range e.left[:size] {
+				sum += int(p)
+			}
+			n += size
+		}
+		dc := uint8(0x80)
+		if n > 0 {
+			dc = uint8((sum + n/2) / n)
+		}
+		for i := range pred[:size*size] {
+			pred[i] = dc
+		}
+	case predTM:
+		for y := 0; y < size; y++ {
+			for x := 0; x < size; x++ {
+				pred[y*size+x] = clamp(int(e.left[y]) + int(e.top[x]) - int(e.topLeft))
+			}
+		}
+	case predVE:
+		for y := 0; y < size; y++ {
+			copy(pred[y*size:y*size+size], e.top[:size])
+		}
+	case predHE:
+		for y := 0; y < size; y++ {
+			for x := 0; x < size; x++ {
+				pred[y*size+x] = e.left[y]
+			}
+		}
+	}
+}
+
+// forwardDCT transforms the difference of a 4x4 block and its prediction,
+// as in libwebp
+func forwardDCT(out *[16]int32, src []uint8, srcStride int, pred []uint8, predStride int) {
+	var tmp [16]int32
+	for i := 0; i < 4; i++ {
+		d0 := int32(src[i*srcStride+0]) - int32(pred[i*predStride+0])
+		d1 := int32(src[i*srcStride+1]) - int32(pred[i*predStride+1])
+		d2 := int32(src[i*srcStride+2]) - int32(pred[i*predStride+2])
+		d3 := int32(src[i*srcStride+3]) - int32(pred[i*predStride+3])
+		a0, a1, a2, a3 := d0+d3, d1+d2, d1-d2, d0-d3
+		tmp[0+i*4] = (a0 + a1) * 8
+		tmp[1+i*4] = (a2*2217 + a3*5352 + 1812) >> 9
+		tmp[2+i*4] = (a0 - a1) * 8
+		tmp[3+i*4] = (a3*2217 - a2*5352 + 937) >> 9
+	}
+	for i := 0; i < 4; i++ {
+		a0, a1 := tmp[0+i]+tmp[12+i], tmp[4+i]+tmp[8+i]
+		a2, a3 := tmp[4+i]-tmp[8+i], tmp[0+i]-tmp[12+i]
+		out[0+i] = (a0 + a1 + 7) >> 4
+		out[4+i] = (a2*2217 + a3*5352 + 12000) >> 16
+		if a3 != 0 {
+			out[4+i]++
+		}
+		out[8+i] = (a0 - a1 + 7) >> 4
+		out[12+i] = (a3*2217 - a2*5352 + 51000) >> 16
+	}
+}
+
+// inverseDCT adds the inverse transform of a block to its prediction, with
+// the same arithmetic as the decoder
+func inverseDCT(in *[16]int32, pred []uint8, predStride int, dst []uint8, dstStride int) {
+	const (
+		c1 = 85627
+		c2 = 35468
+	)
+	var m [4][4]int32
+	for i := 0; i < 4; i++ {
+		a := in[i] + in[8+i]
+		b := in[i] - in[8+i]
+		c := (in[4+i]*c2)>>16 - (in[12+i]*c1)>>16
+		d := (in[4+i]*c1)>>16 + (in[12+i]*c2)>>16
+		m[i] = [4]int32{a + d, b + c, b - c, a - d}
+	}
+	for j := 0; j < 4; j++ {
+		dc := m[0][j] + 4
+		a := dc + m[2][j]
+		b := dc - m[2][j]
+		c := (m[1][j]*c2)>>16 - (m[3][j]*c1)>>16
+		d := (m[1][j]*c1)>>16 + (m[3][j]*c2)>>16
+		for i, r := range [4]int32{a + d, b + c, b - c, a - d} {
+			dst[j*dstStride+i] = clamp(int(pred[j*predStride+i]) + int(r>>3))
+		}
+	}
+}
+
+// forwardWHT transforms the DCs of the 16 luma blocks, as in libwebp
+func forwardWHT(in *[16]int32) [16]int32 {
+	var tmp, out [16]int32
+	for i := 0; i < 4; i++ {
+		a0, a1 := in[i*4+0]+in[i*4+2], in[i*4+1]+in[i*4+3]
+		a2, a3 := in[i*4+1]-in[i*4+3], in[i*4+0]-in[i*4+2]
+		tmp[0+i*4] = a0 + a1
+		tmp[1+i*4] = a3 + a2
+		tmp[2+i*4] = a3 - a2
+		tmp[3+i*4] = a0 - a1
+	}
+	for i := 0; i < 4; i++ {
+		a0, a1 := tmp[0+i]+tmp[8+i], tmp[4+i]+tmp[12+i]
+		a2, a3 := tmp[4+i]-tmp[12+i], tmp[0+i]-tmp[8+i]
+		out[0+i] = (a0 + a1) >> 1
+		out[4+i] = (a3 + a2) >> 1
+		out[8+i] = (a3 - a2) >> 1
+		out[12+i] = (a0 - a1) >> 1
+	}
+	return out
+}
+
+// inverseWHT returns the DCs of the 16 luma blocks, with the same arithmetic
+// as the decoder
+func inverseWHT(in *[16]int32) [16]int32 {
+	var m, out [16]int32
+	for i := 0; i < 4; i++ {
+		a0, a1 := in[0+i]+in[12+i], in[4+i]+in[8+i]
+		a2, a3 := in[4+i]-in[8+i], in[0+i]-in[12+i]
+		m[0+i] = a0 + a1
+		m[8+i] = a0 - a1
+		m[4+i] = a3 + a2
+		m[12+i] = a3 - a2
+	}
+	for i := 0; i < 4; i++ {
+		dc := m[0+i*4] + 3
+		a0, a1 := dc+m[3+i*4], m[1+i*4]+m[2+i*4]
+		a2, a3 := m[1+i*4]-m[2+i*4], dc-m[3+i*4]
+		out[i*4+0] = int32(int16((a0 + a1) >> 3))
+		out[i*4+1] = int32(int16((a3 + a2) >> 3))
+		out[i*4+2] = int32(int16((a0 - a1) >> 3))
+		out[i*4+3] = int32(int16((a3 - a2) >> 3))
+	}
+	return out
+}
+
+// frame codes the macroblocks into a key frame, with the token
+// probabilities fitted to the coefficients
+func (e *vp8Encoder) frame(width, height int) []byte {
+	skipped := 0
+	for i := range e.mbs {
+		if e.mbs[i].empty() {
+			skipped++
+		}
+	}
+	useSkip := skipped > 0
+
+	var stats coeffStats
+	e.writeTokens(&stats, useSkip)
+	probs := defaultCoeffProb
+
+	fp := &boolEncoder{}
+	fp.init()
+	fp.writeLiteral(0, 1) // Color space
+	fp.writeLiteral(0, 1) // Clamping
+	fp.writeLiteral(0, 1) // Segmentation
+	fp.writeLiteral(0, 1) // Filter type
+	fp.writeLiteral(0, 6) // Filter level
+	fp.writeLiteral(0, 3) // Sharpness
+	fp.writeLiteral(0, 1) // Filter deltas
+	fp.writeLiteral(0, 2) // One token partition
+	fp.writeLiteral(uint32(e.qIndex), 7)
+	for i := 0; i < 5; i++ {
+		fp.writeLiteral(0, 1) // Quantizer deltas
+	}
+	fp.writeLiteral(0, 1) // Refresh entropy probabilities
+	for i := range probs {
+		for j := range probs[i] {
+			for k := range probs[i][j] {
+				for l := range probs[i][j][k] {
+					upd := coeffUpdateProb[i][j][k][l]
+					p, ok := stats.fit(i, j, k, l, probs[i][j][k][l], upd)
+					fp.writeBool(upd, ok)
+					if ok {
+						probs[i][j][k][l] = p
+						fp.writeLiteral(uint32(p), 8)
+					}
+				}
+			}
+		}
+	}
+
+	var skipProb uint8
+	fp.writeBool(128, useSkip)
+	if useSkip {
+		skipProb = uint8(clampInt((len(e.mbs)-skipped)*256/len(e.mbs), 1, 255))
+		fp.writeLiteral(uint32(skipProb), 8)
+	}
+	for i := range e.mbs {
+		mb := &e.mbs[i]
+		if useSkip {
+			fp.writeBool(skipProb, mb.empty())
+		}
+		fp.writeBool(145, true) // Whole macroblock prediction
+		switch mb.yMode {
+		case predDC:
+			fp.writeBool(156, false)
+			fp.writeBool(163, false)
+		case predVE:
+			fp.writeBool(156, false)
+			fp.writeBool(163, true)
+		case predHE:
+			fp.writeBool(156, true)
+			fp.writeBool(128, false)
+		case predTM:
+			fp.writeBool(156, true)
+			fp.writeBool(128, true)
+		}
+		fp.writeBool(142, mb.uvMode != predDC)
+		if mb.uvMode != predDC {
+			fp.writeBool(114, mb.uvMode != predVE)
+			if mb.uvMode != predVE {
+				fp.writeBool(183, mb.uvMode == predTM)
+			}
+		}
+	}
+	first := fp.flush()
+
+	tp := &boolEncoder{}
+	tp.init()
+	e.writeTokens(&tokenWriter{enc: tp, probs: &probs}, useSkip)
+	tokens := tp.flush()
+
+	data := make([]byte, 10, 10+len(first)+len(tokens))
+	tag := uint32(len(first))<<5 | 1<<4 // Key frame, version 0, shown
+	data[0], data[1], data[2] = byte(tag), byte(tag>>8), byte(tag>>16)
+	data[3], data[4], data[5] = 0x9d, 0x01, 0x2a
+	binary.LittleEndian.PutUint16(data[6:], uint16(width))
+	binary.LittleEndian.PutUint16(data[8:], uint16(height))
+	data = append(data, first...)
+	return append(data, tokens...)
+}
+
+// coeffSink takes the bits of the coefficient tokens, either coded with the
+// token probability at an index of a context or with a fixed probability
+type coeffSink interface {
+	token(plane, band, ctx, index int, bit bool)
+	literal(prob uint8, bit bool)
+}
+
+// writeTokens codes the coefficients of all macroblocks, keeping track of
+// which neighbouring blocks have any
+func (e *vp8Encoder) writeTokens(s coeffSink, useSkip bool) {
+	// Luma, U and V blocks along each edge, then the luma DCs
+	top := make([][9]uint8, e.mbw)
+	for mby := 0; mby < e.mbh; mby++ {
+		var left [9]uint8
+		for mbx := 0; mbx < e.mbw; mbx++ {
+			mb := &e.mbs[mby*e.mbw+mbx]
+			if useSkip && mb.empty() {
+				left, top[mbx] = [9]uint8{}, [9]uint8{}
+				continue
+			}
+			up := &top[mbx]
+			nz := writeCoeffs(s, planeY2, int(left[8]+up[8]), &mb.levels[24], 0)
+			left[8], up[8] = nz, nz
+			for y := 0; y < 4; y++ {
+				nz := left[y]
+				for x := 0; x < 4; x++ {
+					nz = writeCoeffs(s, planeY1WithY2, int(nz+up[x]), &mb.levels[y*4+x], 1)
+					up[x] = nz
+				}
+				left[y] = nz
+			}
+			for c := 4; c < 8; c += 2 {
+				for y := 0; y < 2; y++ {
+					nz := left[c+y]
+					for x := 0; x < 2; x++ {
+						nz = writeCoeffs(s, planeUV, int(nz+up[c+x]), &mb.levels[16+(c-4)*2+y*2+x], 0)
+						up[c+x] = nz
+					}
+					left[c+y] = nz
+				}
+			}
+		}
+	}
+}
+
+// writeCoeffs codes the levels of a block from index first, and returns 1
+// when any of them is not zero
+func writeCoeffs(s coeffSink, plane, ctx int, levels *[16]int16, first int) uint8 {
+	last := -1
+	for i := 15; i >= first; i-- {
+		if levels[i] != 0 {
+			last = i
+			break
+		}
+	}
+	band := int(vp8Band[first])
+	if last < 0 {
+		s.token(plane, band, ctx, 0, false)
+		return 0
+	}
+	s.token(plane, band, ctx, 0, true)
+	for i := first; i <= last; i++ {
+		v := int(levels[i])
+		if v == 0 {
+			s.token(plane, band, ctx, 1, false)
+			band, ctx = int(vp8Band[i+1]), 0
+			continue
+		}
+		s.token(plane, band, ctx, 1, true)
+		writeLevel(s, plane, band, ctx, abs(v))
+		band, ctx = int(vp8Band[i+1]), 2
+		if abs(v) == 1 {
+			ctx = 1
+		}
+		s.literal(128, v < 0)
+		if i == 15 {
+			break
+		}
+		s.token(plane, band, ctx, 0, i < last)
+	}
+	return 1
+}
+
+// writeLevel codes the size of a coefficient that is not zero
+func writeLevel(s coeffSink, plane, band, ctx, v int) {
+	token := func(index int, bit bool) {
+		s.token(plane, band, ctx, index, bit)
+	}
+	if v == 1 {
+		token(2, false)
+		return
+	}
+	token(2, true)
+	switch {
+	case v <= 4:
+		token(3, false)
+		token(4, v != 2)
+		if v != 2 {
+			token(5, v == 4)
+		}
+	case v <= 10:
+		token(3, true)
+		token(6, false)
+		token(7, v > 6)
+		if v <= 6 {
+			s.literal(159, v == 6)
+		} else {
+			s.literal(165, (v-7)>>1 == 1)
+			s.literal(145, (v-7)&1 == 1)
+		}
+	default:
+		token(3, true)
+		token(6, true)
+		cat := 3
+		for i, limit := range []int{18, 34, 66} {
+			if v <= limit {
+				cat = i
+				break
+			}
+		}
+		token(8, cat>>1 == 1)
+		token(9+cat>>1, cat&1 == 1)
+		extra, probs := v-(3+8<<cat), vp8CatProb[cat]
+		for i, p := range probs {
+			s.literal(p, extra>>(len(probs)-1-i)&1 == 1)
+		}
+	}
+}
+
+// tokenWriter codes tokens with a set of token probabilities
+type tokenWriter struct {
+	enc   *boolEncoder
+	probs *coeffProbs
+}
+
+func (w *tokenWriter) token(plane, band, ctx, index int, bit bool) {
+	w.enc.writeBool(w.probs[plane][band][ctx][index], bit)
+}
+
+func (w *tokenWriter) literal(prob uint8, bit bool) {
+	w.enc.writeBool(prob, bit)
+}
+
+// coeffStats counts the zeros and ones coded with each token probability
+type coeffStats [vp8Planes][vp8Bands][vp8Contexts][vp8Probs][2]int
+
+func (s *coeffStats) token(plane, band, ctx, index int, bit bool) {
+	if bit {
+		s[plane][band][ctx][index][1]++
+	} else {
+		s[plane][band][ctx][index][0]++
+	}
+}
+
+func (s *coeffStats) literal(prob uint8, bit bool) {}
+
+// fit returns the probability that fits the counted bits, and whether it
+// saves more than it costs to update from the old one
+func (s *coeffStats) fit(plane, band, ctx, index int, old, upd uint8) (uint8, bool) {
+	n := s[plane][band][ctx][index]
+	if n[0]+n[1] == 0 {
+		return old, false
+	}
+	p := uint8(clampInt(n[0]*256/(n[0]+n[1]), 1, 255))
+	saved := bitCost(old, n) - bitCost(p, n)
+	cost := 8 + bitCost(upd, [2]int{0, 1}) - bitCost(upd, [2]int{1, 0})
+	return p, saved > cost
+}
+
+// bitCost is the number of bits to code zeros and ones with a probability
+func bitCost(prob uint8, n [2]int) float64 {
+	p := float64(prob) / 256
+	return -float64(n[0])*math.Log2(p) - float64(n[1])*math.Log2(1-p)
+}
+
+// boolEncoder is the boolean entropy encoder of section 7 of RFC 6386
+type boolEncoder struct {
+	out      []byte
+	rng      uint32
+	bottom   uint32
+	bitCount int
+}
+
+func (e *boolEncoder) init() {
+	e.rng, e.bitCount = 255, 24
+}
+
+func (e *boolEncoder) writeBool(prob uint8, bit bool) {
+	split := 1 + (e.rng-1)*uint32(prob)>>8
+	if bit {
+		e.bottom += split
+		e.rng -= split
+	} else {
+		e.rng = split
+	}
+	for e.rng < 128 {
+		e.rng <<= 1
+		if e.bottom&(1<<31) != 0 {
+			// Carry into the bytes already written
+			i := len(e.out) - 1
+			for ; e.out[i] == 0xff; i-- {
+				e.out[i] = 0
+			}
+			e.out[i]++
+		}
+		e.bottom <<= 1
+		e.bitCount--
+		if e.bitCount == 0 {
+			e.out = append(e.out, byte(e.bottom>>24))
+			e.bottom &= 1<<24 - 1
+			e.bitCount = 8
+		}
+	}
+}
+
+// writeLiteral writes the n low bits of v from the highest, each with an
+// even probability
+func (e *boolEncoder) writeLiteral(v uint32, n int) {
+	for i := n - 1; i >= 0; i-- {
+		e.writeBool(128, v>>i&1 == 1)
+	}
+}
+
+func (e *boolEncoder) flush() []byte {
+	for i := 0; i < 32; i++ {
+		e.writeBool(128, false)
+	}
+	return e.out
+}
+
+func abs32(v int32) int32 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+func clampInt(v, lo, hi int) int {
+	if v < lo {
+		return lo
+	}
+	if v > hi {
+		return hi
+	}
+	return v
+}
@@ -0,0 +1,146 @@
+package imaging
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/image/webp"
+)
+
+// photoImage has smooth shading with some grain, like a photo
+func photoImage(width, height int) *image.NRGBA {
+	rng := rand.New(rand.NewSource(2))
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			fx, fy := float64(x)/float64(width), float64(y)/float64(height)
+			r := 128 + 100*math.Sin(fx*7+fy*3) + float64(rng.Intn(12))
+			g := 128 + 90*math.Cos(fx*4-fy*5) + float64(rng.Intn(12))
+			b := 100 + 80*math.Sin(fx*fy*20) + float64(rng.Intn(12))
+			img.SetNRGBA(x, y, color.NRGBA{R: clamp(int(r)), G: clamp(int(g)), B: clamp(int(b)), A: 255})
+		}
+	}
+	return img
+}
+
+func opaqueNoiseImage(width, height int) *image.NRGBA {
+	img := noiseImage(width, height)
+	for i := 3; i < len(img.Pix); i += 4 {
+		img.Pix[i] = 0xff
+	}
+	return img
+}
+
+// lumaPSNR compares the luma of an image to the decoded luma of a WebP
+func lumaPSNR(t *testing.T, want image.Image, data []byte) float64 {
+	got, err := webp.Decode(bytes.NewReader(data))
+	require.NoError(t, err)
+	ycbcr, ok := got.(*image.YCbCr)
+	require.True(t, ok)
+
+	b := want.Bounds()
+	var sum float64
+	for y := 0; y < b.Dy(); y++ {
+		for x := 0; x < b.Dx(); x++ {
+			c := color.RGBAModel.Convert(want.At(b.Min.X+x, b.Min.Y+y)).(color.RGBA)
+			luma := (16839*int(c.R) + 33059*int(c.G) + 6420*int(c.B) + 16<<16 + 1<<15) >> 16
+			d := float64(luma) - float64(ycbcr.Y[ycbcr.YOffset(x, y)])
+			sum += d * d
+		}
+	}
+	return 10 * math.Log10(255*255*float64(b.Dx()*b.Dy())/sum)
+}
+
+func TestEncodeWebPLossyRoundTrip(t *testing.T) {
+	testCases := []struct {
+		name string
+		img  image.Image
+	}{
+		{"Photo", photoImage(333, 211)},
+		{"Graphic", graphicImage(320, 240, false)},
+		{"Noise", opaqueNoiseImage(37, 45)},
+		{"Pixel", opaqueNoiseImage(1, 1)},
+		{"SubImage", photoImage(200, 100).SubImage(image.Rect(13, 7, 150, 90))},
+		{"Gray", image.NewGray(image.Rect(0, 0, 17, 5))},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			for _, quality := range []int{1, 80, 100} {
+				b := tc.img.Bounds()
+				e := newVP8Encoder(tc.img, quantIndex(quality))
+				var buf bytes.Buffer
+				require.NoError(t, writeRIFF(&buf, "VP8 ", e.encode(b.Dx(), b.Dy())))
+
+				config, err := webp.DecodeConfig(bytes.NewReader(buf.Bytes()))
+				require.NoError(t, err)
+				require.Equal(t, b.Dx(), config.Width)
+				require.Equal(t, b.Dy(), config.Height)
+
+				// The decoder must predict from the same pixels as the encoder
+				got, err := webp.Decode(bytes.NewReader(buf.Bytes()))
+				require.NoError(t, err)
+				ycbcr := got.(*image.YCbCr)
+				for y := 0; y < b.Dy(); y++ {
+					row := e.recY[y*e.mbw*16 : y*e.mbw*16+b.Dx()]
+					require.Equal(t, row, ycbcr.Y[ycbcr.YOffset(0, y):ycbcr.YOffset(b.Dx()-1, y)+1])
+				}
+				for y := 0; y < (b.Dy()+1)/2; y++ {
+					width := (b.Dx() + 1) / 2
+					i := ycbcr.COffset(0, 2*y)
+					require.Equal(t, e.recU[y*e.mbw*8:y*e.mbw*8+width], ycbcr.Cb[i:i+width])
+					require.Equal(t, e.recV[y*e.mbw*8:y*e.mbw*8+width], ycbcr.Cr[i:i+width])
+				}
+			}
+		})
+	}
+}
+
+func TestEncodeWebPLossyQuality(t *testing.T) {
+	img := photoImage(640, 480)
+
+	var lastSize int
+	var lastPSNR float64
+	for _, quality := range []int{30, 60, 80, 95} {
+		var webpBuf, jpegBuf bytes.Buffer
+		require.NoError(t, EncodeWebPLossy(&webpBuf, img, quality))
+		require.NoError(t, jpeg.Encode(&jpegBuf, img, &jpeg.Options{Quality: quality}))
+
+		// Photos come out smaller than a JPEG of the same quality
+		require.Less(t, webpBuf.Len(), jpegBuf.Len())
+		psnr := lumaPSNR(t, img, webpBuf.Bytes())
+		require.Greater(t, psnr, 35.0)
+
+		// and higher qualities cost more bytes for less loss
+		require.Greater(t, webpBuf.Len(), lastSize)
+		require.Greater(t, psnr, lastPSNR)
+		lastSize, lastPSNR = webpBuf.Len(), psnr
+	}
+}
+
+func TestEncodeWebPLossyTransparent(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 16, 16))
+
+	var buf bytes.Buffer
+	require.NoError(t, EncodeWebPLossy(&buf, img, 80))
+	got, err := webp.Decode(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+
+	// Transparent pixels are flattened onto white
+	require.Equal(t, uint8(235), got.(*image.YCbCr).Y[0])
+}
+
+func TestEncodeWebPLossyInvalid(t *testing.T) {
+	var buf bytes.Buffer
+	img := photoImage(8, 8)
+	require.Error(t, EncodeWebPLossy(&buf, image.NewNRGBA(image.Rect(0, 0, 0, 10)), 80))
+	require.Error(t, EncodeWebPLossy(&buf, image.NewNRGBA(image.Rect(0, 0, 1<<14, 1)), 80))
+	require.Error(t, EncodeWebPLossy(&buf, img, 0))
+	require.Error(t, EncodeWebPLossy(&buf, img, 101))
+	require.Zero(t, buf.Len())
+}
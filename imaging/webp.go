@@ -0,0 +1,578 @@
+package imaging
+
+import (
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/draw"
+	"io"
+	"sort"
+)
+
+// EncodeWebP writes an image in the lossless WebP format. The encoder only
+// uses the subtract green and predictor transforms with a single set of
+// prefix codes, which keeps it small while still compressing photos and
+// graphics reasonably well.
+func EncodeWebP(w io.Writer, m image.Image) error {
+	b := m.Bounds()
+	if b.Dx() < 1 || b.Dy() < 1 || b.Dx() > 1<<14 || b.Dy() > 1<<14 {
+		return errors.New("webp: invalid image size")
+	}
+
+	nrgba, ok := m.(*image.NRGBA)
+	if !ok || nrgba.Bounds().Min != (image.Point{}) {
+		nrgba = image.NewNRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+		draw.Draw(nrgba, nrgba.Bounds(), m, b.Min, draw.Src)
+	}
+	width, height := b.Dx(), b.Dy()
+
+	pix := make([]uint32, width*height)
+	opaque := true
+	for y := 0; y < height; y++ {
+		row := nrgba.Pix[y*nrgba.Stride : y*nrgba.Stride+4*width]
+		for x := 0; x < width; x++ {
+			r, g, b, a := row[4*x], row[4*x+1], row[4*x+2], row[4*x+3]
+			if a != 0xff {
+				opaque = false
+			}
+			pix[y*width+x] = argb(a, r, g, b)
+		}
+	}
+
+	bw := &bitWriter{}
+	bw.write(0x2f, 8)
+	bw.write(uint32(width-1), 14)
+	bw.write(uint32(height-1), 14)
+	if opaque {
+		bw.write(0, 1)
+	} else {
+		bw.write(1, 1)
+	}
+	bw.write(0, 3)
+
+	// Subtract green
+	bw.write(1, 1)
+	bw.write(2, 2)
+	subtractGreen(pix)
+
+	// Predictor on tiles of 1<<predictorBits pixels
+	bw.write(1, 1)
+	bw.write(0, 2)
+	bw.write(predictorBits-2, 3)
+	modes := predict(pix, width, height)
+	writeImage(bw, modes, (width+1<<predictorBits-1)>>predictorBits, false)
+
+	bw.write(0, 1)
+	writeImage(bw, pix, width, true)
+	return writeRIFF(w, "VP8L", bw.flush())
+}
+
+// writeRIFF writes a WebP file of a single chunk
+func writeRIFF(w io.Writer, fourCC string, data []byte) error {
+	chunkSize := len(data)
+	padded := chunkSize + chunkSize&1
+	header := make([]byte, 20)
+	copy(header[0:], "RIFF")
+	binary.LittleEndian.PutUint32(header[4:], uint32(12+padded))
+	copy(header[8:], "WEBP"+fourCC)
+	binary.LittleEndian.PutUint32(header[16:], uint32(chunkSize))
+
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+	if chunkSize != padded {
+		data = append(data, 0)
+	}
+	_, err := w.Write(data)
+	return err
+}
+
+func argb(a, r, g, b uint8) uint32 {
+	return uint32(a)<<24 | uint32(r)<<16 | uint32(g)<<8 | uint32(b)
+}
+
+func subtractGreen(pix []uint32) {
+	for i, p := range pix {
+		g := uint8(p >> 8)
+		r := uint8(p>>16) - g
+		b := uint8(p) - g
+		pix[i] = p&0xff00ff00 | uint32(r)<<16 | uint32(b)
+	}
+}
+
+const predictorBits = 4
+
+// predictorModes are the modes tried for every tile. Modes using the top
+// right pixel are left out as they wrap around at the right edge.
+var predictorModes = []uint32{1, 2, 7, 11, 12, 13}
+
+// predict replaces pixels with their residuals against the best predictor
+// mode of each tile, and returns the image of the chosen modes
+func predict(pix []uint32, width, height int) []uint32 {
+	size := 1 << predictorBits
+	tilesX := (width + size - 1) / size
+	tilesY := (height + size - 1) / size
+	modes := make([]uint32, tilesX*tilesY)
+
+	// Predictions use the original pixels, so residuals go to a new slice
+	res := make([]uint32, len(pix))
+	for ty := 0; ty < tilesY; ty++ {
+		for tx := 0; tx < tilesX; tx++ {
+			x0, y0 := tx*size, ty*size
+			x1, y1 := min(x0+size, width), min(y0+size, height)
+
+			best, bestCost := predictorModes[0], -1
+			for _, mode := range predictorModes {
+				cost := 0
+				for y := y0; y < y1; y++ {
+					for x := x0; x < x1; x++ {
+						cost += residualCost(pix[y*width+x], predictPixel(pix, width, x, y, mode))
+					}
+				}
+				if bestCost < 0 || cost < bestCost {
+					best, bestCost = mode, cost
+				}
+			}
+
+			modes[ty*tilesX+tx] = best << 8
+			for y := y0; y < y1; y++ {
+				for x := x0; x < x1; x++ {
+					i := y*width + x
+					res[i] = subPixels(pix[i], predictPixel(pix, width, x, y, best))
+				}
+			}
+		}
+	}
+	copy(pix, res)
+	return modes
+}
+
+func predictPixel(pix []uint32, width, x, y int, mode uint32) uint32 {
+	switch {
+	case x == 0 && y == 0:
+		return 0xff000000
+	case y == 0:
+		return pix[x-1]
+	case x == 0:
+		return pix[(y-1)*width]
+	}
+
+	l := pix[y*width+x-1]
+	t := pix[(y-1)*width+x]
+	tl := pix[(y-1)*width+x-1]
+	switch mode {
+	case 1:
+		return l
+	case 2:
+		return t
+	case 7:
+		return mapChannels(func(c [3]uint8) uint8 { return avg2(c[0], c[1]) }, l, t, tl)
+	case 11:
+		pl, pt := 0, 0
+		for shift := 0; shift < 32; shift += 8 {
+			cl, ct, ctl := int(uint8(l>>shift)), int(uint8(t>>shift)), int(uint8(tl>>shift))
+			pl += abs(ctl - ct)
+			pt += abs(ctl - cl)
+		}
+		if pl < pt {
+			return l
+		}
+		return t
+	case 12:
+		return mapChannels(func(c [3]uint8) uint8 {
+			return clamp(int(c[0]) + int(c[1]) - int(c[2]))
+		}, l, t, tl)
+	case 13:
+		return mapChannels(func(c [3]uint8) uint8 {
+			a := int(avg2(c[0], c[1]))
+			return clamp(a + (a-int(c[2]))/2)
+		}, l, t, tl)
+	}
+	return l
+}
+
+func mapChannels(f func([3]uint8) uint8, l, t, tl uint32) uint32 {
+	var p uint32
+	for shift := 0; shift < 32; shift += 8 {
+		c := [3]uint8{uint8(l >> shift), uint8(t >> shift), uint8(tl >> shift)}
+		p |= uint32(f(c)) << shift
+	}
+	return p
+}
+
+func subPixels(a, b uint32) uint32 {
+	var p uint32
+	for shift := 0; shift < 32; shift += 8 {
+		p |= uint32(uint8(a>>shift)-uint8(b>>shift)) << shift
+	}
+	return p
+}
+
+// residualCost estimates how well a prediction compresses by the size of
+// its residual in every channel
+func residualCost(p, pred uint32) int {
+	d := subPixels(p, pred)
+	cost := 0
+	for shift := 0; shift < 32; shift += 8 {
+		cost += abs(int(int8(d >> shift)))
+	}
+	return cost
+}
+
+func avg2(a, b uint8) uint8 {
+	return uint8((int(a) + int(b)) / 2)
+}
+
+func clamp(v int) uint8 {
+	if v < 0 {
+		return 0
+	}
+	if v > 255 {
+		return 255
+	}
+	return uint8(v)
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// backRef is a literal pixel, or a copy of length pixels at a distance
+// code when length is not zero
+type backRef struct {
+	pixel    uint32
+	length   int
+	distCode int
+}
+
+// minRunLength is the shortest run worth a backward reference
+const minRunLength = 3
+
+// findRuns replaces runs of pixels repeating the previous pixel or the
+// pixel above with backward references
+func findRuns(pix []uint32, width int) []backRef {
+	refs := make([]backRef, 0, len(pix))
+	for i := 0; i < len(pix); {
+		left, top := 0, 0
+		if i > 0 {
+			for i+left < len(pix) && left < 4096 && pix[i+left] == pix[i+left-1] {
+				left++
+			}
+		}
+		if i >= width {
+			for i+top < len(pix) && top < 4096 && pix[i+top] == pix[i+top-width] {
+				top++
+			}
+		}
+
+		switch {
+		case left >= top && left >= minRunLength:
+			refs = append(refs, backRef{length: left, distCode: 2})
+			i += left
+		case top > left && top >= minRunLength:
+			refs = append(refs, backRef{length: top, distCode: 1})
+			i += top
+		default:
+			refs = append(refs, backRef{pixel: pix[i]})
+			i++
+		}
+	}
+	return refs
+}
+
+// prefixValue splits a length or distance code into its prefix symbol and
+// extra bits
+func prefixValue(v int) (symbol, extraBits, extra int) {
+	v--
+	if v < 4 {
+		return v, 0, 0
+	}
+	hb := 0
+	for v>>(hb+1) != 0 {
+		hb++
+	}
+	second := v >> (hb - 1) & 1
+	extraBits = hb - 1
+	return 2*hb + second, extraBits, v & (1<<extraBits - 1)
+}
+
+// writeImage writes pixels coded with a single prefix code group. Only the
+// main image has the meta prefix codes bit.
+func writeImage(bw *bitWriter, pix []uint32, width int, main bool) {
+	bw.write(0, 1) // no color cache
+	if main {
+		bw.write(0, 1) // no meta prefix codes
+	}
+
+	refs := findRuns(pix, width)
+	green := make([]uint32, 256+24)
+	red := make([]uint32, 256)
+	blue := make([]uint32, 256)
+	alpha := make([]uint32, 256)
+	distance := make([]uint32, 40)
+	for _, ref := range refs {
+		if ref.length > 0 {
+			lengthSymbol, _, _ := prefixValue(ref.length)
+			distSymbol, _, _ := prefixValue(ref.distCode)
+			green[256+lengthSymbol]++
+			distance[distSymbol]++
+			continue
+		}
+		p := ref.pixel
+		alpha[p>>24]++
+		red[uint8(p>>16)]++
+		green[uint8(p>>8)]++
+		blue[uint8(p)]++
+	}
+
+	codes := [5]*prefixCode{}
+	for i, hist := range [][]uint32{green, red, blue, alpha, distance} {
+		codes[i] = newPrefixCode(hist, 15)
+		codes[i].writeTo(bw)
+	}
+
+	for _, ref := range refs {
+		if ref.length > 0 {
+			symbol, extraBits, extra := prefixValue(ref.length)
+			codes[0].writeCode(bw, 256+symbol)
+			bw.write(uint32(extra), uint(extraBits))
+			symbol, extraBits, extra = prefixValue(ref.distCode)
+			codes[4].writeCode(bw, symbol)
+			bw.write(uint32(extra), uint(extraBits))
+			continue
+		}
+		p := ref.pixel
+		codes[0].writeCode(bw, int(uint8(p>>8)))
+		codes[1].writeCode(bw, int(uint8(p>>16)))
+		codes[2].writeCode(bw, int(uint8(p)))
+		codes[3].writeCode(bw, int(p>>24))
+	}
+}
+
+// prefixCode is a canonical Huffman code. A code with a single symbol uses
+// zero bits per symbol.
+type prefixCode struct {
+	lengths []uint8
+	codes   []uint16
+	symbols int
+}
+
+var codeLengthCodeOrder = [19]int{
+	17, 18, 0, 1, 2, 3, 4, 5, 16, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15,
+}
+
+func newPrefixCode(hist []uint32, maxLength int) *prefixCode {
+	c := &prefixCode{lengths: huffmanLengths(hist, maxLength)}
+	for _, l := range c.lengths {
+		if l > 0 {
+			c.symbols++
+		}
+	}
+
+	// Canonical codes in order of length and symbol
+	var count [16]uint16
+	for _, l := range c.lengths {
+		count[l]++
+	}
+	count[0] = 0
+	var next [16]uint16
+	code := uint16(0)
+	for l := 1; l < 16; l++ {
+		code = (code + count[l-1]) << 1
+		next[l] = code
+	}
+	c.codes = make([]uint16, len(c.lengths))
+	for s, l := range c.lengths {
+		if l > 0 {
+			c.codes[s] = next[l]
+			next[l]++
+		}
+	}
+	return c
+}
+
+func (c *prefixCode) writeCode(bw *bitWriter, s int) {
+	if c.symbols <= 1 {
+		return
+	}
+	// Codes are read from the most significant bit
+	l := c.lengths[s]
+	code, rev := c.codes[s], uint32(0)
+	for i := uint8(0); i < l; i++ {
+		rev = rev<<1 | uint32(code>>i&1)
+	}
+	bw.write(rev, uint(l))
+}
+
+func (c *prefixCode) writeTo(bw *bitWriter) {
+	symbol := 0
+	for s, l := range c.lengths {
+		if l > 0 {
+			symbol = s
+		}
+	}
+	if c.symbols <= 1 && symbol < 256 {
+		// Simple code of one symbol
+		bw.write(1, 1)
+		bw.write(0, 1)
+		if symbol < 2 {
+			bw.write(0, 1)
+			bw.write(uint32(symbol), 1)
+		} else {
+			bw.write(1, 1)
+			bw.write(uint32(symbol), 8)
+		}
+		return
+	}
+
+	// Code lengths as literals, with runs of zeros for unused symbols
+	type token struct{ symbol, extra, extraBits int }
+	var tokens []token
+	for i := 0; i < len(c.lengths); {
+		if c.lengths[i] != 0 {
+			tokens = append(tokens, token{int(c.lengths[i]), 0, 0})
+			i++
+			continue
+		}
+		run := 1
+		for i+run < len(c.lengths) && c.lengths[i+run] == 0 && run < 138 {
+			run++
+		}
+		switch {
+		case run < 3:
+			for j := 0; j < run; j++ {
+				tokens = append(tokens, token{0, 0, 0})
+			}
+		case run <= 10:
+			tokens = append(tokens, token{17, run - 3, 3})
+		default:
+			tokens = append(tokens, token{18, run - 11, 7})
+		}
+		i += run
+	}
+
+	hist := make([]uint32, 19)
+	for _, t := range tokens {
+		hist[t.symbol]++
+	}
+	lengthCode := newPrefixCode(hist, 7)
+
+	n := 4
+	for i, s := range codeLengthCodeOrder {
+		if lengthCode.lengths[s] > 0 && i+1 > n {
+			n = i + 1
+		}
+	}
+	bw.write(0, 1)
+	bw.write(uint32(n-4), 4)
+	for _, s := range codeLengthCodeOrder[:n] {
+		bw.write(uint32(lengthCode.lengths[s]), 3)
+	}
+	bw.write(0, 1) // lengths of all symbols follow
+
+	for _, t := range tokens {
+		lengthCode.writeCode(bw, t.symbol)
+		if t.extraBits > 0 {
+			bw.write(uint32(t.extra), uint(t.extraBits))
+		}
+	}
+}
+
+// huffmanLengths computes code lengths limited to maxLength. Counts are
+// flattened and the tree rebuilt until it is shallow enough.
+func huffmanLengths(hist []uint32, maxLength int) []uint8 {
+	lengths := make([]uint8, len(hist))
+	for countMin := uint32(1); ; countMin *= 2 {
+		type node struct {
+			weight      uint32
+			symbol      int
+			left, right int
+		}
+		var nodes []node
+		for s, n := range hist {
+			if n > 0 {
+				if n < countMin {
+					n = countMin
+				}
+				nodes = append(nodes, node{n, s, -1, -1})
+			}
+		}
+		switch len(nodes) {
+		case 0:
+			return lengths
+		case 1:
+			lengths[nodes[0].symbol] = 1
+			return lengths
+		}
+		sort.SliceStable(nodes, func(i, j int) bool { return nodes[i].weight < nodes[j].weight })
+
+		// Two queues: sorted leaves and merged nodes in order of creation
+		leaves := len(nodes)
+		li, mi := 0, leaves
+		pop := func() int {
+			if li < leaves && (mi >= len(nodes) || nodes[li].weight <= nodes[mi].weight) {
+				li++
+				return li - 1
+			}
+			mi++
+			return mi - 1
+		}
+		for len(nodes) < 2*leaves-1 {
+			a, b := pop(), pop()
+			nodes = append(nodes, node{nodes[a].weight + nodes[b].weight, -1, a, b})
+		}
+
+		depth := make([]int, len(nodes))
+		tooDeep := false
+		for i := len(nodes) - 1; i >= 0; i-- {
+			if nodes[i].left >= 0 {
+				depth[nodes[i].left] = depth[i] + 1
+				depth[nodes[i].right] = depth[i] + 1
+			} else if depth[i] > maxLength {
+				tooDeep = true
+			}
+		}
+		if tooDeep {
+			continue
+		}
+		for i := 0; i < leaves; i++ {
+			lengths[nodes[i].symbol] = uint8(depth[i])
+		}
+		return lengths
+	}
+}
+
+// bitWriter packs bits starting from the least significant bit
+type bitWriter struct {
+	buf   []byte
+	bits  uint64
+	nBits uint
+}
+
+func (bw *bitWriter) write(v uint32, n uint) {
+	bw.bits |= uint64(v) << bw.nBits
+	bw.nBits += n
+	for bw.nBits >= 8 {
+		bw.buf = append(bw.buf, byte(bw.bits))
+		bw.bits >>= 8
+		bw.nBits -= 8
+	}
+}
+
+func (bw *bitWriter) flush() []byte {
+	if bw.nBits > 0 {
+		bw.buf = append(bw.buf, byte(bw.bits))
+		bw.bits, bw.nBits = 0, 0
+	}
+	return bw.buf
+}
@@ -0,0 +1,116 @@
+package imaging
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/image/webp"
+)
+
+// graphicImage draws flat shapes over a gradient, like a screenshot or a
+// diagram, with a transparent border when alpha is set
+func graphicImage(width, height int, alpha bool) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			c := color.NRGBA{R: uint8(x * 255 / width), G: uint8(y * 255 / height), B: 160, A: 255}
+			switch {
+			case (x/40+y/40)%5 == 0:
+				c = color.NRGBA{R: 250, G: 250, B: 250, A: 255}
+			case x > width/3 && x < width/2 && y > height/4:
+				c = color.NRGBA{R: 20, G: 90, B: 200, A: 255}
+			}
+			if alpha && (x < 8 || y < 8) {
+				c.A = uint8(x * y)
+			}
+			img.SetNRGBA(x, y, c)
+		}
+	}
+	return img
+}
+
+// noiseImage has random pixels, which no encoder can compress
+func noiseImage(width, height int) *image.NRGBA {
+	rng := rand.New(rand.NewSource(1))
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	rng.Read(img.Pix)
+	return img
+}
+
+func requireSamePixels(t *testing.T, want image.Image, got image.Image) {
+	b := want.Bounds()
+	require.Equal(t, b.Dx(), got.Bounds().Dx())
+	require.Equal(t, b.Dy(), got.Bounds().Dy())
+
+	g := got.Bounds().Min
+	for y := 0; y < b.Dy(); y++ {
+		for x := 0; x < b.Dx(); x++ {
+			w := color.NRGBAModel.Convert(want.At(b.Min.X+x, b.Min.Y+y)).(color.NRGBA)
+			c := color.NRGBAModel.Convert(got.At(g.X+x, g.Y+y)).(color.NRGBA)
+			// Colors of fully transparent pixels do not matter
+			if w.A == 0 && c.A == 0 {
+				continue
+			}
+			require.Equal(t, w, c, "pixel at %d,%d", x, y)
+		}
+	}
+}
+
+func TestEncodeWebPRoundTrip(t *testing.T) {
+	testCases := []struct {
+		name string
+		img  image.Image
+	}{
+		{"Opaque", graphicImage(320, 240, false)},
+		{"Alpha", graphicImage(97, 61, true)},
+		{"Noise", noiseImage(50, 33)},
+		{"Pixel", noiseImage(1, 1)},
+		{"SubImage", graphicImage(200, 100, true).SubImage(image.Rect(13, 7, 150, 90))},
+		{"Gray", image.NewGray(image.Rect(0, 0, 17, 5))},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			require.NoError(t, EncodeWebP(&buf, tc.img))
+
+			config, err := webp.DecodeConfig(bytes.NewReader(buf.Bytes()))
+			require.NoError(t, err)
+			require.Equal(t, tc.img.Bounds().Dx(), config.Width)
+			require.Equal(t, tc.img.Bounds().Dy(), config.Height)
+
+			got, err := webp.Decode(bytes.NewReader(buf.Bytes()))
+			require.NoError(t, err)
+			requireSamePixels(t, tc.img, got)
+		})
+	}
+}
+
+func TestEncodeWebPSize(t *testing.T) {
+	img := graphicImage(640, 480, false)
+
+	var webpBuf, pngBuf bytes.Buffer
+	require.NoError(t, EncodeWebP(&webpBuf, img))
+	require.NoError(t, png.Encode(&pngBuf, img))
+
+	// Graphics compress well below their raw pixels, and below PNG
+	require.Less(t, webpBuf.Len(), len(img.Pix)/20)
+	require.Less(t, webpBuf.Len(), pngBuf.Len())
+
+	// Noise cannot be compressed, but the overhead stays small
+	noise := noiseImage(128, 128)
+	webpBuf.Reset()
+	require.NoError(t, EncodeWebP(&webpBuf, noise))
+	require.Less(t, webpBuf.Len(), len(noise.Pix)*11/10)
+}
+
+func TestEncodeWebPInvalidSize(t *testing.T) {
+	var buf bytes.Buffer
+	require.Error(t, EncodeWebP(&buf, image.NewNRGBA(image.Rect(0, 0, 0, 10))))
+	require.Error(t, EncodeWebP(&buf, image.NewNRGBA(image.Rect(0, 0, 1<<14+1, 1))))
+	require.Zero(t, buf.Len())
+}
@@ -0,0 +1,188 @@
+package importer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ghostID accepts both the numeric IDs of old Ghost versions and the
+// object IDs of newer ones
+type ghostID string
+
+func (id *ghostID) UnmarshalJSON(data []byte) error {
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	switch v := value.(type) {
+	case string:
+		*id = ghostID(v)
+	case float64:
+		*id = ghostID(fmt.Sprintf("%.0f", v))
+	}
+	return nil
+}
+
+type ghostData struct {
+	Posts []struct {
+		ID           ghostID `json:"id"`
+		Title        string  `json:"title"`
+		HTML         string  `json:"html"`
+		Markdown     string  `json:"markdown"`
+		FeatureImage string  `json:"feature_image"`
+		Image        string  `json:"image"`
+		Status       string  `json:"status"`
+		Type         string  `json:"type"`
+		Page         bool    `json:"page"`
+		AuthorID     ghostID `json:"author_id"`
+		CreatedAt    string  `json:"created_at"`
+		UpdatedAt    string  `json:"updated_at"`
+		PublishedAt  string  `json:"published_at"`
+	} `json:"posts"`
+	Users []struct {
+		ID    ghostID `json:"id"`
+		Name  string  `json:"name"`
+		Slug  string  `json:"slug"`
+		Email string  `json:"email"`
+	} `json:"users"`
+	Tags []struct {
+		ID   ghostID `json:"id"`
+		Name string  `json:"name"`
+	} `json:"tags"`
+	PostsTags []struct {
+		PostID    ghostID `json:"post_id"`
+		TagID     ghostID `json:"tag_id"`
+		SortOrder int     `json:"sort_order"`
+	} `json:"posts_tags"`
+	PostsAuthors []struct {
+		PostID    ghostID `json:"post_id"`
+		AuthorID  ghostID `json:"author_id"`
+		SortOrder int     `json:"sort_order"`
+	} `json:"posts_authors"`
+}
+
+type ghostExport struct {
+	DB []struct {
+		Data ghostData `json:"data"`
+	} `json:"db"`
+	Data *ghostData `json:"data"`
+}
+
+// ParseGhost reads a Ghost JSON export. Ghost has no categories and keeps
+// comments outside of its exports, so only users, tags and posts are read.
+// Media URLs are resolved against baseURL.
+func ParseGhost(r io.Reader, baseURL string) (*Site, error) {
+	var export ghostExport
+	if err := json.NewDecoder(r).Decode(&export); err != nil {
+		return nil, fmt.Errorf("invalid Ghost export: %w", err)
+	}
+
+	var data ghostData
+	switch {
+	case len(export.DB) > 0:
+		data = export.DB[0].Data
+	case export.Data != nil:
+		data = *export.Data
+	default:
+		return nil, fmt.Errorf("invalid Ghost export: no data found")
+	}
+
+	baseURL = strings.TrimRight(baseURL, "/")
+	site := &Site{BaseURL: baseURL}
+
+	for _, user := range data.Users {
+		email := strings.ToLower(strings.TrimSpace(user.Email))
+		site.addUser(User{
+			Key:      string(user.ID),
+			Username: Username(user.Slug, email),
+			Email:    email,
+		})
+	}
+
+	tags := map[ghostID]string{}
+	for _, tag := range data.Tags {
+		// Internal tags of Ghost start with a hash and are not shown to readers
+		if strings.HasPrefix(tag.Name, "#") {
+			continue
+		}
+		tags[tag.ID] = strings.TrimSpace(tag.Name)
+		site.Tags = appendName(site.Tags, tag.Name)
+	}
+
+	sort.SliceStable(data.PostsTags, func(i, j int) bool {
+		return data.PostsTags[i].SortOrder < data.PostsTags[j].SortOrder
+	})
+	postTags := map[ghostID][]string{}
+	for _, pt := range data.PostsTags {
+		if name, ok := tags[pt.TagID]; ok {
+			postTags[pt.PostID] = appendName(postTags[pt.PostID], name)
+		}
+	}
+
+	// The primary author is the first one of posts_authors on Ghost 1.22+
+	authors := map[ghostID]ghostID{}
+	sort.SliceStable(data.PostsAuthors, func(i, j int) bool {
+		return data.PostsAuthors[i].SortOrder < data.PostsAuthors[j].SortOrder
+	})
+	for _, pa := range data.PostsAuthors {
+		if _, ok := authors[pa.PostID]; !ok {
+			authors[pa.PostID] = pa.AuthorID
+		}
+	}
+
+	for _, gp := range data.Posts {
+		if gp.Page || (gp.Type != "" && gp.Type != "post") {
+			continue
+		}
+
+		authorID, ok := authors[gp.ID]
+		if !ok {
+			authorID = gp.AuthorID
+		}
+
+		post := Post{
+			ID:         string(gp.ID),
+			AuthorKey:  site.addUser(User{Key: string(authorID), Author: true}),
+			Title:      strings.TrimSpace(gp.Title),
+			CoverImage: ghostURL(gp.FeatureImage+gp.Image, baseURL),
+			PublishAt:  parseGhostDate(gp.PublishedAt, gp.CreatedAt),
+			UpdateAt:   parseGhostDate(gp.UpdatedAt, gp.CreatedAt),
+			Tags:       postTags[gp.ID],
+		}
+		if gp.HTML != "" {
+			post.Content = HTMLToMarkdown(gp.HTML)
+		} else {
+			post.Content = strings.TrimSpace(gp.Markdown)
+		}
+		post.Content = strings.ReplaceAll(post.Content, "__GHOST_URL__", baseURL)
+
+		switch gp.Status {
+		case "published":
+			post.Status = "publish"
+		case "draft", "scheduled":
+			post.Status = "draft"
+		default:
+			continue
+		}
+		site.Posts = append(site.Posts, post)
+	}
+	return site, nil
+}
+
+func ghostURL(url string, baseURL string) string {
+	return strings.ReplaceAll(url, "__GHOST_URL__", baseURL)
+}
+
+func parseGhostDate(value string, fallback string) time.Time {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t
+	}
+	if t, err := time.Parse(time.RFC3339, fallback); err == nil {
+		return t
+	}
+	return time.Now()
+}
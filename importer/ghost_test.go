@@ -0,0 +1,162 @@
+package importer
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+const ghostFixture = `{
+  "db": [{
+    "data": {
+      "posts": [
+        {
+          "id": "5f1a",
+          "title": " Hello Ghost ",
+          "html": "<p>Intro with <img src=\"__GHOST_URL__/content/images/a.png\" alt=\"a\"></p>",
+          "feature_image": "__GHOST_URL__/content/images/cover.jpg",
+          "status": "published",
+          "type": "post",
+          "author_id": "u2",
+          "created_at": "2021-01-01T08:00:00.000Z",
+          "updated_at": "2021-03-04T05:06:07.000Z",
+          "published_at": "2021-01-02T10:00:00.000Z"
+        },
+        {
+          "id": "5f1b",
+          "title": "Scheduled",
+          "markdown": "  Plain **markdown**  ",
+          "status": "scheduled",
+          "author_id": "u2",
+          "created_at": "2021-02-01T08:00:00.000Z",
+          "updated_at": null,
+          "published_at": null
+        },
+        {"id": "5f1c", "title": "About", "status": "published", "type": "page", "author_id": "u1"},
+        {"id": "5f1d", "title": "Old page", "status": "published", "page": true, "author_id": "u1"},
+        {"id": "5f1e", "title": "Sent", "status": "sent", "type": "post", "author_id": "u1"}
+      ],
+      "users": [
+        {"id": "u1", "name": "Alice", "slug": "alice", "email": "Alice@Example.com"},
+        {"id": "u2", "name": "Bob", "slug": "b", "email": "bob.smith@example.com"}
+      ],
+      "tags": [
+        {"id": "t1", "name": "News"},
+        {"id": "t2", "name": "#internal"},
+        {"id": "t3", "name": " Go "}
+      ],
+      "posts_tags": [
+        {"post_id": "5f1a", "tag_id": "t1", "sort_order": 1},
+        {"post_id": "5f1a", "tag_id": "t2", "sort_order": 2},
+        {"post_id": "5f1a", "tag_id": "t3", "sort_order": 0}
+      ],
+      "posts_authors": [
+        {"post_id": "5f1a", "author_id": "u2", "sort_order": 1},
+        {"post_id": "5f1a", "author_id": "u1", "sort_order": 0}
+      ]
+    }
+  }]
+}`
+
+func TestParseGhost(t *testing.T) {
+	site, err := ParseGhost(strings.NewReader(ghostFixture), "https://old.example.com/")
+	require.NoError(t, err)
+
+	require.Equal(t, "https://old.example.com", site.BaseURL)
+	require.Equal(t, []User{
+		{Key: "u1", Username: "alice", Email: "alice@example.com", Author: true},
+		{Key: "u2", Username: "bob-smith", Email: "bob.smith@example.com", Author: true},
+	}, site.Users)
+
+	// Internal tags are left out, and Ghost has no categories
+	require.Equal(t, []string{"News", "Go"}, site.Tags)
+	require.Empty(t, site.Categories)
+
+	// Pages and posts that were only sent as newsletters are left out
+	require.Len(t, site.Posts, 2)
+	post := site.Posts[0]
+	require.Equal(t, "5f1a", post.ID)
+	require.Equal(t, "u1", post.AuthorKey)
+	require.Equal(t, "Hello Ghost", post.Title)
+	require.Equal(t, "publish", post.Status)
+	require.Equal(t, "Intro with ![a](https://old.example.com/content/images/a.png)", post.Content)
+	require.Equal(t, "https://old.example.com/content/images/cover.jpg", post.CoverImage)
+	require.Equal(t, []string{"Go", "News"}, post.Tags)
+	require.Equal(t, time.Date(2021, 1, 2, 10, 0, 0, 0, time.UTC), post.PublishAt)
+	require.Equal(t, time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC), post.UpdateAt)
+	require.Empty(t, post.Comments)
+
+	// Posts without posts_authors fall back to author_id, and unpublished
+	// posts to the time they were created
+	scheduled := site.Posts[1]
+	require.Equal(t, "u2", scheduled.AuthorKey)
+	require.Equal(t, "draft", scheduled.Status)
+	require.Equal(t, "Plain **markdown**", scheduled.Content)
+	require.Equal(t, time.Date(2021, 2, 1, 8, 0, 0, 0, time.UTC), scheduled.PublishAt)
+	require.Equal(t, scheduled.PublishAt, scheduled.UpdateAt)
+	require.Empty(t, scheduled.Tags)
+}
+
+func TestParseGhostFormats(t *testing.T) {
+	testCases := []struct {
+		name   string
+		export string
+		postID string
+		tags   []string
+	}{
+		{
+			name: "Database",
+			export: `{"db": [{"data": {
+				"posts": [{"id": "a1", "title": "Post", "status": "published", "author_id": "u1"}],
+				"tags": [{"id": "t1", "name": "News"}],
+				"posts_tags": [{"post_id": "a1", "tag_id": "t1"}]
+			}}]}`,
+			postID: "a1",
+			tags:   []string{"News"},
+		},
+		{
+			name: "Data",
+			export: `{"data": {
+				"posts": [{"id": "a1", "title": "Post", "status": "published", "author_id": "u1"}]
+			}}`,
+			postID: "a1",
+		},
+		{
+			name: "NumericIDs",
+			export: `{"db": [{"data": {
+				"posts": [{"id": 12, "title": "Post", "status": "published", "author_id": 1}],
+				"tags": [{"id": 3, "name": "News"}],
+				"posts_tags": [{"post_id": 12, "tag_id": 3}]
+			}}]}`,
+			postID: "12",
+			tags:   []string{"News"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			site, err := ParseGhost(strings.NewReader(tc.export), "")
+			require.NoError(t, err)
+			require.Len(t, site.Posts, 1)
+			require.Equal(t, tc.postID, site.Posts[0].ID)
+			require.Equal(t, tc.tags, site.Posts[0].Tags)
+		})
+	}
+}
+
+func TestParseGhostInvalid(t *testing.T) {
+	testCases := []struct {
+		name   string
+		export string
+	}{
+		{"Syntax", `{"db": [`},
+		{"NoData", `{"meta": {}}`},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := ParseGhost(strings.NewReader(tc.export), "")
+			require.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,191 @@
+package importer
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+var (
+	spaceRun   = regexp.MustCompile(`[ \t\r\n]+`)
+	blankLines = regexp.MustCompile(`\n[ \t]*\n(\s*\n)*`)
+)
+
+// HTMLToMarkdown converts the HTML body of a post to Markdown. Elements
+// without a Markdown equivalent, like tables and embeds, are kept as HTML.
+func HTMLToMarkdown(src string) string {
+	body := &html.Node{Type: html.ElementNode, Data: "body", DataAtom: atom.Body}
+	nodes, err := html.ParseFragment(strings.NewReader(src), body)
+	if err != nil {
+		return src
+	}
+
+	var sb strings.Builder
+	for _, node := range nodes {
+		sb.WriteString(convertNode(node))
+	}
+	return strings.TrimSpace(blankLines.ReplaceAllString(sb.String(), "\n\n"))
+}
+
+func convertChildren(node *html.Node) string {
+	var sb strings.Builder
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		sb.WriteString(convertNode(child))
+	}
+	return sb.String()
+}
+
+func convertNode(node *html.Node) string {
+	switch node.Type {
+	case html.TextNode:
+		return spaceRun.ReplaceAllString(node.Data, " ")
+	case html.ElementNode:
+	default:
+		return ""
+	}
+
+	switch node.DataAtom {
+	case atom.Script, atom.Style, atom.Noscript:
+		return ""
+	case atom.P, atom.Div, atom.Section, atom.Article, atom.Figure, atom.Header, atom.Footer:
+		return block(convertChildren(node))
+	case atom.Figcaption:
+		if caption := strings.TrimSpace(convertChildren(node)); caption != "" {
+			return block("_" + caption + "_")
+		}
+		return ""
+	case atom.Br:
+		return "  \n"
+	case atom.Hr:
+		return block("---")
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+		level := int(node.Data[1] - '0')
+		return block(strings.Repeat("#", level) + " " + strings.TrimSpace(convertChildren(node)))
+	case atom.Strong, atom.B:
+		return wrapInline(convertChildren(node), "**")
+	case atom.Em, atom.I:
+		return wrapInline(convertChildren(node), "_")
+	case atom.Del, atom.S, atom.Strike:
+		return wrapInline(convertChildren(node), "~~")
+	case atom.Code:
+		return "`" + textContent(node) + "`"
+	case atom.Pre:
+		return block("```" + codeLanguage(node) + "\n" + strings.TrimRight(textContent(node), "\n") + "\n```")
+	case atom.A:
+		text := strings.TrimSpace(convertChildren(node))
+		href := attr(node, "href")
+		if href == "" {
+			return text
+		}
+		return fmt.Sprintf("[%s](%s)", text, href)
+	case atom.Img:
+		src := attr(node, "src")
+		if src == "" {
+			return ""
+		}
+		return fmt.Sprintf("![%s](%s)", attr(node, "alt"), src)
+	case atom.Blockquote:
+		lines := strings.Split(strings.TrimSpace(blankLines.ReplaceAllString(convertChildren(node), "\n\n")), "\n")
+		for i, line := range lines {
+			lines[i] = strings.TrimRight("> "+line, " ")
+		}
+		return block(strings.Join(lines, "\n"))
+	case atom.Ul, atom.Ol:
+		return block(convertList(node))
+	case atom.Table, atom.Iframe, atom.Video, atom.Audio:
+		var sb strings.Builder
+		if err := html.Render(&sb, node); err != nil {
+			return ""
+		}
+		return block(sb.String())
+	}
+	return convertChildren(node)
+}
+
+func convertList(list *html.Node) string {
+	lines := []string{}
+	index := 1
+	for item := list.FirstChild; item != nil; item = item.NextSibling {
+		if item.Type != html.ElementNode || item.DataAtom != atom.Li {
+			continue
+		}
+
+		marker := "- "
+		if list.DataAtom == atom.Ol {
+			marker = fmt.Sprintf("%d. ", index)
+			index++
+		}
+
+		// Items are kept tight, nested blocks are indented under the marker
+		content := strings.TrimSpace(blankLines.ReplaceAllString(convertChildren(item), "\n"))
+		indent := strings.Repeat(" ", len(marker))
+		for i, line := range strings.Split(content, "\n") {
+			if i == 0 {
+				lines = append(lines, marker+line)
+			} else {
+				lines = append(lines, indent+line)
+			}
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func block(content string) string {
+	content = strings.TrimSpace(content)
+	if content == "" {
+		return ""
+	}
+	return "\n\n" + content + "\n\n"
+}
+
+// wrapInline adds emphasis markers around the text but outside of its spaces
+func wrapInline(text string, marker string) string {
+	trimmed := strings.TrimSpace(text)
+	if trimmed == "" {
+		return text
+	}
+	leading := text[:strings.Index(text, trimmed)]
+	trailing := text[len(leading)+len(trimmed):]
+	return leading + marker + trimmed + marker + trailing
+}
+
+func textContent(node *html.Node) string {
+	if node.Type == html.TextNode {
+		return node.Data
+	}
+	var sb strings.Builder
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		if child.Type == html.ElementNode && child.DataAtom == atom.Br {
+			sb.WriteString("\n")
+			continue
+		}
+		sb.WriteString(textContent(child))
+	}
+	return sb.String()
+}
+
+// codeLanguage reads the language-* class of a code block
+func codeLanguage(pre *html.Node) string {
+	classes := attr(pre, "class")
+	if code := pre.FirstChild; code != nil && code.DataAtom == atom.Code {
+		classes += " " + attr(code, "class")
+	}
+	for _, class := range strings.Fields(classes) {
+		if strings.HasPrefix(class, "language-") {
+			return strings.TrimPrefix(class, "language-")
+		}
+	}
+	return ""
+}
+
+func attr(node *html.Node, key string) string {
+	for _, a := range node.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
@@ -0,0 +1,33 @@
+package importer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTMLToMarkdown(t *testing.T) {
+	testCases := []struct {
+		name string
+		html string
+		want string
+	}{
+		{"Paragraphs", "<p>One\n  two</p><p>Three</p>", "One two\n\nThree"},
+		{"Headings", "<h2>Title</h2><p>Text</p>", "## Title\n\nText"},
+		{"Emphasis", "<p><strong>bold </strong><em>it</em> <del>old</del></p>", "**bold** _it_ ~~old~~"},
+		{"Link", `<a href="https://example.com">site</a>`, "[site](https://example.com)"},
+		{"Image", `<figure><img src="/a.png" alt="A"><figcaption>Caption</figcaption></figure>`, "![A](/a.png)\n\n_Caption_"},
+		{"Code", `<p>Run <code>go test</code></p><pre><code class="language-go">x := 1
+</code></pre>`, "Run `go test`\n\n```go\nx := 1\n```"},
+		{"Lists", "<ul><li>a</li><li>b</li></ul><ol><li>one</li><li>two</li></ol>", "- a\n- b\n\n1. one\n2. two"},
+		{"Quote", "<blockquote><p>One</p><p>Two</p></blockquote>", "> One\n>\n> Two"},
+		{"Break", "<p>a<br>b</p>", "a  \nb"},
+		{"Table", "<table><tr><td>x</td></tr></table>", "<table><tbody><tr><td>x</td></tr></tbody></table>"},
+		{"Script", "<script>alert(1)</script><p>Text</p>", "Text"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.want, HTMLToMarkdown(tc.html))
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package importer
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Site is the content of an exported blog in a form independent of the
+// engine it was exported from
+type Site struct {
+	// BaseURL is the address of the old blog, used to resolve media URLs
+	BaseURL    string
+	Users      []User
+	Categories []string
+	Tags       []string
+	Posts      []Post
+}
+
+// User is a post author or commenter of the old blog. Users are matched
+// to existing accounts by email.
+type User struct {
+	Key      string
+	Username string
+	Email    string
+	Author   bool
+}
+
+type Post struct {
+	ID         string
+	AuthorKey  string
+	Title      string
+	Content    string
+	CoverImage string
+	Status     string
+	PublishAt  time.Time
+	UpdateAt   time.Time
+	Categories []string
+	Tags       []string
+	Comments   []Comment
+}
+
+// Comment is a comment of a post, ParentID is empty for top-level comments
+type Comment struct {
+	ID       string
+	ParentID string
+	UserKey  string
+	Content  string
+	CreateAt time.Time
+}
+
+// addUser registers a user once per key and returns the key
+func (site *Site) addUser(user User) string {
+	for i := range site.Users {
+		if site.Users[i].Key == user.Key {
+			site.Users[i].Author = site.Users[i].Author || user.Author
+			return user.Key
+		}
+	}
+	site.Users = append(site.Users, user)
+	return user.Key
+}
+
+// sortComments orders comments by time so that parents come before replies
+func sortComments(comments []Comment) {
+	sort.SliceStable(comments, func(i, j int) bool {
+		if !comments[i].CreateAt.Equal(comments[j].CreateAt) {
+			return comments[i].CreateAt.Before(comments[j].CreateAt)
+		}
+		a, _ := strconv.ParseInt(comments[i].ID, 10, 64)
+		b, _ := strconv.ParseInt(comments[j].ID, 10, 64)
+		return a < b
+	})
+}
+
+var usernameReplacer = regexp.MustCompile(`[^a-zA-Z0-9_-]+`)
+
+// Username turns a login or display name into a valid username
+func Username(name string, email string) string {
+	username := strings.Trim(usernameReplacer.ReplaceAllString(name, "-"), "-")
+	if len(username) < 3 {
+		username = strings.Trim(usernameReplacer.ReplaceAllString(strings.Split(email, "@")[0], "-"), "-")
+	}
+	for len(username) < 3 {
+		username += "_"
+	}
+	if len(username) > 40 {
+		username = username[:40]
+	}
+	return username
+}
+
+// Media file extensions that are copied to the local upload store
+var mediaExtensions = map[string]bool{
+	".jpg": true, ".jpeg": true, ".png": true, ".gif": true,
+	".webp": true, ".svg": true, ".bmp": true, ".avif": true,
+}
+
+var markdownLink = regexp.MustCompile(`\]\(([^()\s]+)`)
+
+// RewriteMedia replaces the media URLs of Markdown links and images with
+// the result of replace. URLs for which replace returns an empty string
+// are kept.
+func RewriteMedia(content string, replace func(string) string) string {
+	return markdownLink.ReplaceAllStringFunc(content, func(match string) string {
+		url := match[2:]
+		if !IsMedia(url) {
+			return match
+		}
+		if local := replace(url); local != "" {
+			return "](" + local
+		}
+		return match
+	})
+}
+
+// IsMedia reports whether a URL points to an uploaded media file
+func IsMedia(url string) bool {
+	path := strings.ToLower(url)
+	if i := strings.IndexAny(path, "?#"); i >= 0 {
+		path = path[:i]
+	}
+	if i := strings.LastIndex(path, "."); i >= 0 && !strings.Contains(path[i:], "/") {
+		return mediaExtensions[path[i:]]
+	}
+	return false
+}
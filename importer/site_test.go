@@ -0,0 +1,105 @@
+package importer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRewriteMedia(t *testing.T) {
+	// The replacement copies the file and returns its new path, or an empty
+	// string when it cannot be copied
+	replace := func(url string) string {
+		if strings.Contains(url, "missing") {
+			return ""
+		}
+		return "/image/post/img-1-1" + url[strings.LastIndex(url, "."):]
+	}
+
+	testCases := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{
+			name:    "Absolute",
+			content: "![a](https://old.example.com/uploads/a.png)",
+			want:    "![a](/image/post/img-1-1.png)",
+		},
+		{
+			name:    "Relative",
+			content: "![b](/wp-content/uploads/b.JPG \"Title\")",
+			want:    "![b](/image/post/img-1-1.JPG \"Title\")",
+		},
+		{
+			name:    "RelativeToPost",
+			content: "![c](images/c.webp)",
+			want:    "![c](/image/post/img-1-1.webp)",
+		},
+		{
+			name:    "Linked",
+			content: "[![d](https://old.example.com/d.gif)](https://old.example.com/d.gif?size=full)",
+			want:    "[![d](/image/post/img-1-1.gif)](/image/post/img-1-1.gif?size=full)",
+		},
+		{
+			name:    "NotMedia",
+			content: "[post](https://old.example.com/2020/01/hello/) and [file](/files/doc.pdf)",
+			want:    "[post](https://old.example.com/2020/01/hello/) and [file](/files/doc.pdf)",
+		},
+		{
+			name:    "NotCopied",
+			content: "![e](https://old.example.com/missing.png)",
+			want:    "![e](https://old.example.com/missing.png)",
+		},
+		{
+			name:    "NotALink",
+			content: "See https://old.example.com/a.png",
+			want:    "See https://old.example.com/a.png",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.want, RewriteMedia(tc.content, replace))
+		})
+	}
+}
+
+func TestIsMedia(t *testing.T) {
+	testCases := []struct {
+		url  string
+		want bool
+	}{
+		{"https://old.example.com/a.png", true},
+		{"/uploads/photo.JPEG?w=300#top", true},
+		{"images/b.avif", true},
+		{"https://old.example.com/a.png/", false},
+		{"https://old.example.com/post.html", false},
+		{"https://old.example.com/v1.2/post", false},
+		{"https://old.example.com", false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.url, func(t *testing.T) {
+			require.Equal(t, tc.want, IsMedia(tc.url))
+		})
+	}
+}
+
+func TestUsername(t *testing.T) {
+	testCases := []struct {
+		name     string
+		email    string
+		username string
+	}{
+		{"alice", "alice@example.com", "alice"},
+		{"Jane Doe", "jane@example.com", "Jane-Doe"},
+		{"Jo", "joanna.smith@example.com", "joanna-smith"},
+		{"", "x@example.com", "x__"},
+		{strings.Repeat("a", 50), "a@example.com", strings.Repeat("a", 40)},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.username, func(t *testing.T) {
+			require.Equal(t, tc.username, Username(tc.name, tc.email))
+		})
+	}
+}
@@ -0,0 +1,218 @@
+package importer
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const wpDateLayout = "2006-01-02 15:04:05"
+
+type wxrExport struct {
+	Channel struct {
+		BaseBlogURL string `xml:"base_blog_url"`
+		Authors     []struct {
+			Login       string `xml:"author_login"`
+			Email       string `xml:"author_email"`
+			DisplayName string `xml:"author_display_name"`
+		} `xml:"author"`
+		Categories []struct {
+			Name string `xml:"cat_name"`
+		} `xml:"category"`
+		Tags []struct {
+			Name string `xml:"tag_name"`
+		} `xml:"tag"`
+		Items []wxrItem `xml:"item"`
+	} `xml:"channel"`
+}
+
+type wxrItem struct {
+	Title         string `xml:"title"`
+	Creator       string `xml:"creator"`
+	Content       string `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
+	PostID        string `xml:"post_id"`
+	PostDate      string `xml:"post_date"`
+	PostDateGMT   string `xml:"post_date_gmt"`
+	ModifiedGMT   string `xml:"post_modified_gmt"`
+	Status        string `xml:"status"`
+	PostType      string `xml:"post_type"`
+	AttachmentURL string `xml:"attachment_url"`
+	PostMeta      []struct {
+		Key   string `xml:"meta_key"`
+		Value string `xml:"meta_value"`
+	} `xml:"postmeta"`
+	Terms []struct {
+		Domain string `xml:"domain,attr"`
+		Name   string `xml:",chardata"`
+	} `xml:"category"`
+	Comments []struct {
+		ID          string `xml:"comment_id"`
+		Author      string `xml:"comment_author"`
+		AuthorEmail string `xml:"comment_author_email"`
+		DateGMT     string `xml:"comment_date_gmt"`
+		Content     string `xml:"comment_content"`
+		Approved    string `xml:"comment_approved"`
+		Type        string `xml:"comment_type"`
+		Parent      string `xml:"comment_parent"`
+	} `xml:"comment"`
+}
+
+// ParseWordPress reads a WordPress WXR export. Pages, attachments, trashed
+// posts and unapproved comments are left out.
+func ParseWordPress(r io.Reader) (*Site, error) {
+	var export wxrExport
+	if err := xml.NewDecoder(r).Decode(&export); err != nil {
+		return nil, fmt.Errorf("invalid WXR file: %w", err)
+	}
+	channel := export.Channel
+
+	site := &Site{BaseURL: strings.TrimRight(channel.BaseBlogURL, "/")}
+
+	emails := map[string]string{}
+	for _, author := range channel.Authors {
+		email := strings.ToLower(strings.TrimSpace(author.Email))
+		emails[email] = author.Login
+		site.addUser(User{
+			Key:      author.Login,
+			Username: Username(author.Login, email),
+			Email:    email,
+		})
+	}
+	for _, category := range channel.Categories {
+		site.Categories = appendName(site.Categories, category.Name)
+	}
+	for _, tag := range channel.Tags {
+		site.Tags = appendName(site.Tags, tag.Name)
+	}
+
+	attachments := map[string]string{}
+	for _, item := range channel.Items {
+		if item.PostType == "attachment" {
+			attachments[item.PostID] = item.AttachmentURL
+		}
+	}
+
+	for _, item := range channel.Items {
+		if item.PostType != "post" {
+			continue
+		}
+
+		post := Post{
+			ID:        item.PostID,
+			AuthorKey: site.addUser(User{Key: item.Creator, Author: true}),
+			Title:     strings.TrimSpace(item.Title),
+			Content:   HTMLToMarkdown(wpautop(item.Content)),
+			PublishAt: parseWPDate(item.PostDateGMT, item.PostDate),
+			UpdateAt:  parseWPDate(item.ModifiedGMT, item.PostDate),
+		}
+		switch item.Status {
+		case "publish":
+			post.Status = "publish"
+		case "draft", "pending", "future", "private":
+			post.Status = "draft"
+		default:
+			continue
+		}
+
+		for _, meta := range item.PostMeta {
+			if meta.Key == "_thumbnail_id" {
+				post.CoverImage = attachments[meta.Value]
+			}
+		}
+		for _, term := range item.Terms {
+			switch term.Domain {
+			case "category":
+				post.Categories = appendName(post.Categories, term.Name)
+				site.Categories = appendName(site.Categories, term.Name)
+			case "post_tag":
+				post.Tags = appendName(post.Tags, term.Name)
+				site.Tags = appendName(site.Tags, term.Name)
+			}
+		}
+
+		for _, wc := range item.Comments {
+			if wc.Approved != "1" || (wc.Type != "" && wc.Type != "comment") {
+				continue
+			}
+
+			// Commenters are matched to authors by email, guests without
+			// an email cannot be imported
+			comment := Comment{
+				ID:       wc.ID,
+				Content:  HTMLToMarkdown(wpautop(wc.Content)),
+				CreateAt: parseWPDate(wc.DateGMT, ""),
+			}
+			if wc.Parent != "0" {
+				comment.ParentID = wc.Parent
+			}
+			if email := strings.ToLower(strings.TrimSpace(wc.AuthorEmail)); email != "" {
+				if login, ok := emails[email]; ok {
+					comment.UserKey = login
+				} else {
+					comment.UserKey = site.addUser(User{
+						Key:      "email:" + email,
+						Username: Username(wc.Author, email),
+						Email:    email,
+					})
+				}
+			}
+			post.Comments = append(post.Comments, comment)
+		}
+		sortComments(post.Comments)
+
+		site.Posts = append(site.Posts, post)
+	}
+	return site, nil
+}
+
+func parseWPDate(gmt string, local string) time.Time {
+	if t, err := time.Parse(wpDateLayout, gmt); err == nil {
+		return t
+	}
+	if t, err := time.Parse(wpDateLayout, local); err == nil {
+		return t
+	}
+	return time.Now()
+}
+
+var (
+	shortcode   = regexp.MustCompile(`\[/?(caption|gallery|embed)[^\]]*\]`)
+	blockTag    = regexp.MustCompile(`(?i)^<(p|div|h[1-6]|ul|ol|pre|blockquote|table|figure|hr|!--)`)
+	wpParagraph = regexp.MustCompile(`\n\s*\n`)
+)
+
+// wpautop adds the paragraphs that WordPress renders from blank lines
+func wpautop(content string) string {
+	content = strings.ReplaceAll(content, "\r\n", "\n")
+	content = shortcode.ReplaceAllString(content, "")
+
+	var sb strings.Builder
+	for _, part := range wpParagraph.Split(content, -1) {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		if blockTag.MatchString(part) {
+			sb.WriteString(part + "\n")
+		} else {
+			sb.WriteString("<p>" + strings.ReplaceAll(part, "\n", "<br>") + "</p>\n")
+		}
+	}
+	return sb.String()
+}
+
+func appendName(names []string, name string) []string {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return names
+	}
+	for _, n := range names {
+		if n == name {
+			return names
+		}
+	}
+	return append(names, name)
+}
@@ -0,0 +1,264 @@
+package importer
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+const wxrFixture = `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0"
+  xmlns:content="http://purl.org/rss/1.0/modules/content/"
+  xmlns:dc="http://purl.org/dc/elements/1.1/"
+  xmlns:wp="http://wordpress.org/export/1.2/">
+<channel>
+  <wp:base_blog_url>https://old.example.com/</wp:base_blog_url>
+  <wp:author>
+    <wp:author_login>alice</wp:author_login>
+    <wp:author_email>Alice@Example.com</wp:author_email>
+    <wp:author_display_name>Alice</wp:author_display_name>
+  </wp:author>
+  <wp:category><wp:cat_name>News</wp:cat_name></wp:category>
+  <wp:tag><wp:tag_name>go</wp:tag_name></wp:tag>
+  <item>
+    <title>cover.jpg</title>
+    <wp:post_id>10</wp:post_id>
+    <wp:post_type>attachment</wp:post_type>
+    <wp:attachment_url>https://old.example.com/wp-content/uploads/cover.jpg</wp:attachment_url>
+  </item>
+  <item>
+    <title> Hello World </title>
+    <dc:creator>alice</dc:creator>
+    <content:encoded><![CDATA[First paragraph.
+
+Second with <img src="/wp-content/uploads/a.png" alt="a">]]></content:encoded>
+    <wp:post_id>1</wp:post_id>
+    <wp:post_date>2020-01-02 10:00:00</wp:post_date>
+    <wp:post_date_gmt>2020-01-02 09:00:00</wp:post_date_gmt>
+    <wp:post_modified_gmt>2020-02-03 04:05:06</wp:post_modified_gmt>
+    <wp:status>publish</wp:status>
+    <wp:post_type>post</wp:post_type>
+    <wp:postmeta><wp:meta_key>_thumbnail_id</wp:meta_key><wp:meta_value>10</wp:meta_value></wp:postmeta>
+    <category domain="category" nicename="news"><![CDATA[News]]></category>
+    <category domain="category" nicename="travel"><![CDATA[Travel]]></category>
+    <category domain="post_tag" nicename="go"><![CDATA[go]]></category>
+    <category domain="post_tag" nicename="web"><![CDATA[ web ]]></category>
+    <wp:comment>
+      <wp:comment_id>6</wp:comment_id>
+      <wp:comment_author>Bob</wp:comment_author>
+      <wp:comment_author_email>bob@example.com</wp:comment_author_email>
+      <wp:comment_date_gmt>2020-01-03 11:00:00</wp:comment_date_gmt>
+      <wp:comment_content>Reply from a guest</wp:comment_content>
+      <wp:comment_approved>1</wp:comment_approved>
+      <wp:comment_type>comment</wp:comment_type>
+      <wp:comment_parent>5</wp:comment_parent>
+    </wp:comment>
+    <wp:comment>
+      <wp:comment_id>5</wp:comment_id>
+      <wp:comment_author>Alice</wp:comment_author>
+      <wp:comment_author_email>alice@example.com</wp:comment_author_email>
+      <wp:comment_date_gmt>2020-01-03 10:00:00</wp:comment_date_gmt>
+      <wp:comment_content>Comment of the author</wp:comment_content>
+      <wp:comment_approved>1</wp:comment_approved>
+      <wp:comment_type></wp:comment_type>
+      <wp:comment_parent>0</wp:comment_parent>
+    </wp:comment>
+    <wp:comment>
+      <wp:comment_id>13</wp:comment_id>
+      <wp:comment_author>Bob</wp:comment_author>
+      <wp:comment_author_email>BOB@example.com</wp:comment_author_email>
+      <wp:comment_date_gmt>2020-01-03 12:00:00</wp:comment_date_gmt>
+      <wp:comment_content>Another one</wp:comment_content>
+      <wp:comment_approved>1</wp:comment_approved>
+      <wp:comment_parent>0</wp:comment_parent>
+    </wp:comment>
+    <wp:comment>
+      <wp:comment_id>7</wp:comment_id>
+      <wp:comment_author>Alice</wp:comment_author>
+      <wp:comment_author_email>alice@example.com</wp:comment_author_email>
+      <wp:comment_date_gmt>2020-01-03 12:00:00</wp:comment_date_gmt>
+      <wp:comment_content>Reply to the reply</wp:comment_content>
+      <wp:comment_approved>1</wp:comment_approved>
+      <wp:comment_parent>6</wp:comment_parent>
+    </wp:comment>
+    <wp:comment>
+      <wp:comment_id>4</wp:comment_id>
+      <wp:comment_author>Anonymous</wp:comment_author>
+      <wp:comment_author_email></wp:comment_author_email>
+      <wp:comment_date_gmt>2020-01-03 08:00:00</wp:comment_date_gmt>
+      <wp:comment_content>Guest without an email</wp:comment_content>
+      <wp:comment_approved>1</wp:comment_approved>
+      <wp:comment_parent>0</wp:comment_parent>
+    </wp:comment>
+    <wp:comment>
+      <wp:comment_id>8</wp:comment_id>
+      <wp:comment_author>Spammer</wp:comment_author>
+      <wp:comment_author_email>spam@example.com</wp:comment_author_email>
+      <wp:comment_date_gmt>2020-01-03 13:00:00</wp:comment_date_gmt>
+      <wp:comment_content>Buy now</wp:comment_content>
+      <wp:comment_approved>spam</wp:comment_approved>
+      <wp:comment_parent>0</wp:comment_parent>
+    </wp:comment>
+    <wp:comment>
+      <wp:comment_id>9</wp:comment_id>
+      <wp:comment_author>Other blog</wp:comment_author>
+      <wp:comment_author_email>blog@example.org</wp:comment_author_email>
+      <wp:comment_date_gmt>2020-01-03 14:00:00</wp:comment_date_gmt>
+      <wp:comment_content>Linked here</wp:comment_content>
+      <wp:comment_approved>1</wp:comment_approved>
+      <wp:comment_type>pingback</wp:comment_type>
+      <wp:comment_parent>0</wp:comment_parent>
+    </wp:comment>
+  </item>
+  <item>
+    <title>Waiting</title>
+    <dc:creator>carol</dc:creator>
+    <content:encoded><![CDATA[<p>Draft</p>]]></content:encoded>
+    <wp:post_id>2</wp:post_id>
+    <wp:post_date>2021-05-06 07:08:09</wp:post_date>
+    <wp:post_date_gmt>0000-00-00 00:00:00</wp:post_date_gmt>
+    <wp:post_modified_gmt>0000-00-00 00:00:00</wp:post_modified_gmt>
+    <wp:status>pending</wp:status>
+    <wp:post_type>post</wp:post_type>
+  </item>
+  <item>
+    <title>Trashed</title>
+    <dc:creator>alice</dc:creator>
+    <wp:post_id>3</wp:post_id>
+    <wp:status>trash</wp:status>
+    <wp:post_type>post</wp:post_type>
+  </item>
+  <item>
+    <title>About</title>
+    <dc:creator>alice</dc:creator>
+    <wp:post_id>11</wp:post_id>
+    <wp:status>publish</wp:status>
+    <wp:post_type>page</wp:post_type>
+  </item>
+</channel>
+</rss>`
+
+func TestParseWordPress(t *testing.T) {
+	site, err := ParseWordPress(strings.NewReader(wxrFixture))
+	require.NoError(t, err)
+
+	require.Equal(t, "https://old.example.com", site.BaseURL)
+	// Commenters are matched to authors by email, and authors missing from
+	// the author list have no email
+	require.Equal(t, []User{
+		{Key: "alice", Username: "alice", Email: "alice@example.com", Author: true},
+		{Key: "email:bob@example.com", Username: "Bob", Email: "bob@example.com"},
+		{Key: "carol", Author: true},
+	}, site.Users)
+
+	// Terms of posts are added to the ones of the channel once
+	require.Equal(t, []string{"News", "Travel"}, site.Categories)
+	require.Equal(t, []string{"go", "web"}, site.Tags)
+
+	// Pages, attachments and trashed posts are left out
+	require.Len(t, site.Posts, 2)
+	post := site.Posts[0]
+	require.Equal(t, "1", post.ID)
+	require.Equal(t, "alice", post.AuthorKey)
+	require.Equal(t, "Hello World", post.Title)
+	require.Equal(t, "publish", post.Status)
+	require.Equal(t, "First paragraph.\n\nSecond with ![a](/wp-content/uploads/a.png)", post.Content)
+	require.Equal(t, "https://old.example.com/wp-content/uploads/cover.jpg", post.CoverImage)
+	require.Equal(t, []string{"News", "Travel"}, post.Categories)
+	require.Equal(t, []string{"go", "web"}, post.Tags)
+	require.Equal(t, time.Date(2020, 1, 2, 9, 0, 0, 0, time.UTC), post.PublishAt)
+	require.Equal(t, time.Date(2020, 2, 3, 4, 5, 6, 0, time.UTC), post.UpdateAt)
+
+	// Drafts without GMT dates fall back to the local date
+	draft := site.Posts[1]
+	require.Equal(t, "draft", draft.Status)
+	require.Equal(t, "carol", draft.AuthorKey)
+	require.Equal(t, time.Date(2021, 5, 6, 7, 8, 9, 0, time.UTC), draft.PublishAt)
+	require.Equal(t, draft.PublishAt, draft.UpdateAt)
+	require.Empty(t, draft.Comments)
+}
+
+func TestParseWordPressComments(t *testing.T) {
+	site, err := ParseWordPress(strings.NewReader(wxrFixture))
+	require.NoError(t, err)
+	comments := site.Posts[0].Comments
+
+	// Unapproved comments and pingbacks are left out, the rest are sorted
+	// by time and then by ID, so that parents come before their replies
+	testCases := []struct {
+		id       string
+		parentID string
+		userKey  string
+		createAt time.Time
+	}{
+		{"4", "", "", time.Date(2020, 1, 3, 8, 0, 0, 0, time.UTC)},
+		{"5", "", "alice", time.Date(2020, 1, 3, 10, 0, 0, 0, time.UTC)},
+		{"6", "5", "email:bob@example.com", time.Date(2020, 1, 3, 11, 0, 0, 0, time.UTC)},
+		{"7", "6", "alice", time.Date(2020, 1, 3, 12, 0, 0, 0, time.UTC)},
+		{"13", "", "email:bob@example.com", time.Date(2020, 1, 3, 12, 0, 0, 0, time.UTC)},
+	}
+	require.Len(t, comments, len(testCases))
+	for i, tc := range testCases {
+		t.Run(tc.id, func(t *testing.T) {
+			require.Equal(t, tc.id, comments[i].ID)
+			require.Equal(t, tc.parentID, comments[i].ParentID)
+			require.Equal(t, tc.userKey, comments[i].UserKey)
+			require.Equal(t, tc.createAt, comments[i].CreateAt)
+		})
+	}
+}
+
+func TestParseWordPressStatus(t *testing.T) {
+	testCases := []struct {
+		status string
+		want   string
+	}{
+		{"publish", "publish"},
+		{"draft", "draft"},
+		{"pending", "draft"},
+		{"future", "draft"},
+		{"private", "draft"},
+		{"trash", ""},
+		{"auto-draft", ""},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.status, func(t *testing.T) {
+			export := `<rss><channel><item>
+				<title>Post</title><post_id>1</post_id><post_type>post</post_type>
+				<status>` + tc.status + `</status>
+			</item></channel></rss>`
+			site, err := ParseWordPress(strings.NewReader(export))
+			require.NoError(t, err)
+			if tc.want == "" {
+				require.Empty(t, site.Posts)
+				return
+			}
+			require.Len(t, site.Posts, 1)
+			require.Equal(t, tc.want, site.Posts[0].Status)
+		})
+	}
+}
+
+func TestParseWordPressInvalid(t *testing.T) {
+	_, err := ParseWordPress(strings.NewReader("<rss><channel>"))
+	require.Error(t, err)
+}
+
+func TestWPAutoP(t *testing.T) {
+	testCases := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"Paragraphs", "One\r\n\r\nTwo\nlines", "<p>One</p>\n<p>Two<br>lines</p>\n"},
+		{"Blocks", "<h2>Title</h2>\n\nText", "<h2>Title</h2>\n<p>Text</p>\n"},
+		{"Shortcodes", `[caption id="1"]<img src="a.png">[/caption]`, "<p><img src=\"a.png\"></p>\n"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.want, wpautop(tc.content))
+		})
+	}
+}
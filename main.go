@@ -3,14 +3,24 @@ package main
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"flag"
+	"fmt"
 	"log"
 	"net"
 	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/bwen19/blog/api"
 	"github.com/bwen19/blog/grpc/pb"
 	_ "github.com/bwen19/blog/grpc/statik"
+	"github.com/bwen19/blog/mdsync"
 	"github.com/bwen19/blog/psql/db"
+	"github.com/bwen19/blog/staticsite"
 	"github.com/bwen19/blog/util"
 
 	"github.com/golang-migrate/migrate/v4"
@@ -38,15 +48,50 @@ func main() {
 	runDBMigration(config.MigrationURL, config.DBSource)
 
 	store := db.NewStore(conn)
+	if len(os.Args) > 1 && os.Args[1] == "sync" {
+		runMarkdownSync(store, config.DefaultCover, os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "static" {
+		runStaticExport(config, store, os.Args[2:])
+		return
+	}
+
 	server, err := api.NewServer(config, store)
 	if err != nil {
 		log.Fatal("cannot create api server:", err)
 	}
 
-	go runGatewayServer(config, server)
-	runGrpcServer(config, server)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	go server.PruneViewers(ctx)
+	go server.RunRankingRefresher(ctx)
+	go server.RunTrashPurger(ctx)
+	go server.RunMediaSweeper(ctx)
+	go server.RunViewFlusher(ctx)
+	go server.RunVariantWriter(ctx)
+
+	grpcServer := runGrpcServer(config, server)
+	httpServer := runGatewayServer(config, server)
+	<-ctx.Done()
+
+	// Stop taking requests first, so that no view is buffered after the
+	// final flush
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err = httpServer.Shutdown(shutdownCtx); err != nil {
+		log.Println("failed to shut down HTTP gateway server:", err)
+	}
+	grpcServer.GracefulStop()
+
+	server.FlushViews(context.Background())
+	log.Println("server stopped")
 }
 
+// shutdownTimeout limits the time given to pending HTTP requests on shutdown
+const shutdownTimeout = 10 * time.Second
+
 func runDBMigration(migrationURL string, dbSource string) {
 	migration, err := migrate.New(migrationURL, dbSource)
 	if err != nil {
@@ -60,7 +105,97 @@ func runDBMigration(migrationURL string, dbSource string) {
 	log.Println("db migrated successfully")
 }
 
-func runGrpcServer(config util.Config, server *api.Server) {
+// runMarkdownSync exports posts to or imports posts from a directory of
+// Markdown files: blog sync export|import [-dir posts] [-author username]
+func runMarkdownSync(store db.Store, defaultCover string, args []string) {
+	flags := flag.NewFlagSet("sync", flag.ExitOnError)
+	dir := flags.String("dir", "posts", "directory of the Markdown files")
+	author := flags.String("author", "", "username of the author whose posts are synced")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: blog sync export|import [-dir posts] [-author username]")
+		flags.PrintDefaults()
+	}
+
+	if len(args) == 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+	command := args[0]
+	flags.Parse(args[1:])
+
+	ctx := context.Background()
+	var authorID int64
+	if *author != "" {
+		user, err := store.GetUserByUsername(ctx, *author)
+		if err != nil {
+			log.Fatal("cannot find author:", err)
+		}
+		authorID = user.ID
+	}
+
+	syncer := mdsync.NewSyncer(store, *dir, authorID, defaultCover)
+	var report *mdsync.Report
+	var err error
+	switch command {
+	case "export":
+		report, err = syncer.Export(ctx)
+	case "import":
+		report, err = syncer.Import(ctx)
+	default:
+		flags.Usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		log.Fatal("failed to sync posts:", err)
+	}
+
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		log.Fatal("cannot marshal sync report:", err)
+	}
+	fmt.Println(string(data))
+
+	if len(report.Conflicts) > 0 {
+		os.Exit(1)
+	}
+}
+
+// runStaticExport renders the published posts to a static site:
+// blog static [-out dist] [-templates dir] [-full]
+func runStaticExport(config util.Config, store db.Store, args []string) {
+	flags := flag.NewFlagSet("static", flag.ExitOnError)
+	out := flags.String("out", "dist", "output directory of the site")
+	templates := flags.String("templates", "", "directory of templates that replace the built-in ones")
+	full := flags.Bool("full", false, "render all posts instead of the changed ones")
+	flags.Parse(args)
+
+	opts := staticsite.Options{
+		OutDir:      *out,
+		TemplateDir: *templates,
+		PublicPath:  config.PublicPath,
+		SiteURL:     config.SiteURL,
+		SiteTitle:   config.SiteTitle,
+		Full:        *full,
+	}
+	builder, err := staticsite.NewBuilder(store, opts)
+	if err != nil {
+		log.Fatal("cannot create static site builder:", err)
+	}
+
+	report, err := builder.Build(context.Background())
+	if err != nil {
+		log.Fatal("failed to build static site:", err)
+	}
+
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		log.Fatal("cannot marshal build report:", err)
+	}
+	fmt.Println(string(data))
+}
+
+// runGrpcServer starts serving gRPC requests in the background
+func runGrpcServer(config util.Config, server *api.Server) *grpc.Server {
 	grpcServer := grpc.NewServer()
 
 	pb.RegisterBlogServer(grpcServer, server)
@@ -72,15 +207,17 @@ func runGrpcServer(config util.Config, server *api.Server) {
 	}
 
 	log.Printf("start gRPC server at %s", listener.Addr().String())
-	err = grpcServer.Serve(listener)
-	if err != nil {
-		log.Fatal("cannot start gRPC server:", err)
-	}
+	go func() {
+		if err := grpcServer.Serve(listener); err != nil {
+			log.Fatal("cannot start gRPC server:", err)
+		}
+	}()
+	return grpcServer
 }
 
-func runGatewayServer(config util.Config, server *api.Server) {
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
+// runGatewayServer starts serving HTTP requests in the background
+func runGatewayServer(config util.Config, server *api.Server) *http.Server {
+	ctx := context.Background()
 
 	options := runtime.WithMarshalerOption(runtime.MIMEWildcard, &runtime.JSONPb{
 		MarshalOptions: protojson.MarshalOptions{
@@ -91,7 +228,23 @@ func runGatewayServer(config util.Config, server *api.Server) {
 		},
 	})
 
-	grpcMux := runtime.NewServeMux(options)
+	// Forward ETag as a plain HTTP header for If-Match requests
+	headerMatcher := runtime.WithOutgoingHeaderMatcher(func(key string) (string, bool) {
+		if key == "etag" {
+			return "ETag", true
+		}
+		return runtime.MetadataHeaderPrefix + key, true
+	})
+
+	// Forward the password of protected posts, which is kept out of URLs
+	incomingMatcher := runtime.WithIncomingHeaderMatcher(func(key string) (string, bool) {
+		if strings.EqualFold(key, "X-Post-Password") {
+			return "x-post-password", true
+		}
+		return runtime.DefaultHeaderMatcher(key)
+	})
+
+	grpcMux := runtime.NewServeMux(options, headerMatcher, incomingMatcher)
 
 	err := pb.RegisterBlogHandlerServer(ctx, grpcMux, server)
 	if err != nil {
@@ -103,8 +256,16 @@ func runGatewayServer(config util.Config, server *api.Server) {
 		log.Fatal("cannot register file upload handler:", err)
 	}
 
+	err = grpcMux.HandlePath("POST", "/api/import/{format}", server.HandleImport)
+	if err != nil {
+		log.Fatal("cannot register import handler:", err)
+	}
+
 	mux := http.NewServeMux()
 	mux.Handle("/", grpcMux)
+	mux.HandleFunc("/sitemap.xml", server.HandleSitemap)
+	mux.HandleFunc("/sitemaps/", server.HandleSitemapPart)
+	mux.HandleFunc("/robots.txt", server.HandleRobots)
 
 	statikFs, err := fs.New()
 	if err != nil {
@@ -119,8 +280,12 @@ func runGatewayServer(config util.Config, server *api.Server) {
 		log.Fatal("cannot create listener:", err)
 	}
 
+	httpServer := &http.Server{Handler: mux}
 	log.Printf("start HTTP server at %s", listener.Addr().String())
-	if err = http.Serve(listener, mux); err != nil {
-		log.Fatal("cannot start HTTP gateway server:", err)
-	}
+	go func() {
+		if err := httpServer.Serve(listener); err != nil && err != http.ErrServerClosed {
+			log.Fatal("cannot start HTTP gateway server:", err)
+		}
+	}()
+	return httpServer
 }
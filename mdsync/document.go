@@ -0,0 +1,133 @@
+package mdsync
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const frontMatterDelim = "---"
+
+// FrontMatter is the YAML header of a post file. ID, Version and Checksum
+// are written by the sync and tell which post the file belongs to and
+// whether the file or the post changed since they were last in sync.
+type FrontMatter struct {
+	ID         int64     `yaml:"id,omitempty"`
+	Title      string    `yaml:"title"`
+	Slug       string    `yaml:"slug,omitempty"`
+	Status     string    `yaml:"status,omitempty"`
+	Date       time.Time `yaml:"date,omitempty"`
+	Lastmod    time.Time `yaml:"lastmod,omitempty"`
+	Cover      string    `yaml:"cover,omitempty"`
+	Categories []string  `yaml:"categories,omitempty"`
+	Tags       []string  `yaml:"tags,omitempty"`
+	Version    int64     `yaml:"version,omitempty"`
+	Checksum   string    `yaml:"checksum,omitempty"`
+}
+
+// Document is a post as a Markdown file
+type Document struct {
+	FrontMatter
+	Body string
+}
+
+// ParseDocument reads a Markdown file with a YAML front matter
+func ParseDocument(data []byte) (*Document, error) {
+	text := strings.ReplaceAll(string(data), "\r\n", "\n")
+	if !strings.HasPrefix(text, frontMatterDelim+"\n") {
+		return nil, fmt.Errorf("missing front matter")
+	}
+
+	rest := text[len(frontMatterDelim)+1:]
+	end := strings.Index(rest, "\n"+frontMatterDelim+"\n")
+	if end < 0 {
+		if !strings.HasSuffix(rest, "\n"+frontMatterDelim) {
+			return nil, fmt.Errorf("unterminated front matter")
+		}
+		end = len(rest) - len(frontMatterDelim) - 1
+	}
+
+	doc := &Document{}
+	if err := yaml.Unmarshal([]byte(rest[:end]), &doc.FrontMatter); err != nil {
+		return nil, fmt.Errorf("invalid front matter: %w", err)
+	}
+
+	body := rest[end+1+len(frontMatterDelim):]
+	doc.Body = strings.TrimSpace(body)
+	if strings.TrimSpace(doc.Title) == "" {
+		return nil, fmt.Errorf("front matter: title is required")
+	}
+	return doc, nil
+}
+
+// Render writes the document as a Markdown file with a YAML front matter
+func (doc *Document) Render() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString(frontMatterDelim + "\n")
+
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&doc.FrontMatter); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+
+	buf.WriteString(frontMatterDelim + "\n\n")
+	buf.WriteString(doc.Body)
+	buf.WriteString("\n")
+	return buf.Bytes(), nil
+}
+
+// Sum hashes the fields that are synced, so that a file and a post can be
+// compared no matter how the file is formatted
+func (doc *Document) Sum() string {
+	categories := append([]string{}, doc.Categories...)
+	sort.Strings(categories)
+	tags := append([]string{}, doc.Tags...)
+	sort.Strings(tags)
+
+	date := ""
+	if !doc.Date.IsZero() {
+		date = doc.Date.UTC().Format(time.RFC3339)
+	}
+
+	h := sha256.New()
+	for _, field := range []string{
+		strings.TrimSpace(doc.Title),
+		strings.TrimSpace(doc.Cover),
+		doc.Status,
+		date,
+		strings.Join(categories, "\n"),
+		strings.Join(tags, "\n"),
+		strings.TrimSpace(doc.Body),
+	} {
+		h.Write([]byte(field))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+// Changed reports whether the file was edited since it was last synced
+func (doc *Document) Changed() bool {
+	return doc.Checksum != doc.Sum()
+}
+
+var slugReplacer = regexp.MustCompile(`[^\p{L}\p{N}]+`)
+
+// Slugify turns a title into the name of a post file
+func Slugify(title string) string {
+	slug := strings.Trim(slugReplacer.ReplaceAllString(strings.ToLower(title), "-"), "-")
+	if len([]rune(slug)) > 60 {
+		slug = strings.TrimRight(string([]rune(slug)[:60]), "-")
+	}
+	return slug
+}
@@ -0,0 +1,103 @@
+package mdsync
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func randomDocument() *Document {
+	return &Document{
+		FrontMatter: FrontMatter{
+			ID:         7,
+			Title:      "Hello World",
+			Status:     "draft",
+			Date:       time.Date(2022, 5, 1, 8, 0, 0, 0, time.UTC),
+			Cover:      "/image/post/cover.jpg",
+			Categories: []string{"go", "web"},
+			Tags:       []string{"grpc", "api"},
+			Version:    3,
+		},
+		Body: "Some content",
+	}
+}
+
+func TestDocumentSum(t *testing.T) {
+	doc := randomDocument()
+	sum := doc.Sum()
+	require.Len(t, sum, 16)
+
+	// Formatting and sync state do not change the sum
+	other := randomDocument()
+	other.Title = "  Hello World "
+	other.Body = "\nSome content\n\n"
+	other.Categories = []string{"web", "go"}
+	other.Tags = []string{"api", "grpc"}
+	other.Date = doc.Date.In(time.FixedZone("CST", 8*3600))
+	other.Version = 5
+	other.Checksum = "stale"
+	require.Equal(t, sum, other.Sum())
+
+	// Every synced field does
+	changes := []func(doc *Document){
+		func(doc *Document) { doc.Title = "Hello Go" },
+		func(doc *Document) { doc.Cover = "" },
+		func(doc *Document) { doc.Status = "revise" },
+		func(doc *Document) { doc.Date = doc.Date.Add(time.Hour) },
+		func(doc *Document) { doc.Categories = []string{"go"} },
+		func(doc *Document) { doc.Tags = []string{"grpc", "api", "rest"} },
+		func(doc *Document) { doc.Body = "Other content" },
+	}
+	for _, change := range changes {
+		other := randomDocument()
+		change(other)
+		require.NotEqual(t, sum, other.Sum())
+	}
+
+	// Fields are separated, so that text cannot move between them
+	a := &Document{FrontMatter: FrontMatter{Title: "ab"}, Body: "c"}
+	b := &Document{FrontMatter: FrontMatter{Title: "a"}, Body: "bc"}
+	require.NotEqual(t, a.Sum(), b.Sum())
+}
+
+func TestDocumentChanged(t *testing.T) {
+	doc := randomDocument()
+	require.True(t, doc.Changed())
+
+	doc.Checksum = doc.Sum()
+	require.False(t, doc.Changed())
+
+	doc.Body += " edited"
+	require.True(t, doc.Changed())
+}
+
+func TestDocumentRender(t *testing.T) {
+	doc := randomDocument()
+	doc.Checksum = doc.Sum()
+
+	data, err := doc.Render()
+	require.NoError(t, err)
+
+	parsed, err := ParseDocument(data)
+	require.NoError(t, err)
+	require.Equal(t, doc.FrontMatter, parsed.FrontMatter)
+	require.Equal(t, doc.Body, parsed.Body)
+	require.False(t, parsed.Changed())
+}
+
+func TestParseDocument(t *testing.T) {
+	_, err := ParseDocument([]byte("title: Hello\n"))
+	require.EqualError(t, err, "missing front matter")
+
+	_, err = ParseDocument([]byte("---\ntitle: Hello\n"))
+	require.EqualError(t, err, "unterminated front matter")
+
+	_, err = ParseDocument([]byte("---\nstatus: draft\n---\nbody"))
+	require.EqualError(t, err, "front matter: title is required")
+
+	doc, err := ParseDocument([]byte("---\r\ntitle: Hello\r\n---"))
+	require.NoError(t, err)
+	require.Equal(t, "Hello", doc.Title)
+	require.Empty(t, doc.Body)
+}
@@ -0,0 +1,432 @@
+package mdsync
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bwen19/blog/psql/db"
+)
+
+// editableStatus lists the status that posts can be created with and edited
+// in by a sync. Posts are submitted and published on the blog, so the status
+// of existing posts is never changed, and posts in review or published only
+// change through the review of the blog.
+var editableStatus = map[string]bool{
+	"draft":  true,
+	"revise": true,
+}
+
+// Report summarizes a sync. Conflicts are files that were left alone
+// because both the file and the post changed, or the post cannot be
+// changed by a sync.
+type Report struct {
+	Exported  int      `json:"exported"`
+	Created   int      `json:"created"`
+	Updated   int      `json:"updated"`
+	Pulled    int      `json:"pulled"`
+	Unchanged int      `json:"unchanged"`
+	Conflicts []string `json:"conflicts"`
+	Warnings  []string `json:"warnings"`
+}
+
+func (report *Report) conflict(path string, format string, args ...interface{}) {
+	report.Conflicts = append(report.Conflicts, path+": "+fmt.Sprintf(format, args...))
+}
+
+func (report *Report) warn(path string, format string, args ...interface{}) {
+	report.Warnings = append(report.Warnings, path+": "+fmt.Sprintf(format, args...))
+}
+
+// Syncer keeps a directory of Markdown files and the posts of the blog in sync
+type Syncer struct {
+	store db.Store
+	dir   string
+	// authorID limits the sync to the posts of an author and owns new posts,
+	// zero exports the posts of all authors
+	authorID int64
+	// defaultCover is the cover of files without one
+	defaultCover string
+}
+
+func NewSyncer(store db.Store, dir string, authorID int64, defaultCover string) *Syncer {
+	return &Syncer{
+		store:        store,
+		dir:          dir,
+		authorID:     authorID,
+		defaultCover: defaultCover,
+	}
+}
+
+// localFile is a post file found in the directory
+type localFile struct {
+	path string
+	doc  *Document
+}
+
+// Export writes the posts to the directory. Files with changes that were
+// not imported yet are not overwritten.
+func (s *Syncer) Export(ctx context.Context) (*Report, error) {
+	report := &Report{Conflicts: []string{}, Warnings: []string{}}
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return nil, err
+	}
+
+	files, _, err := s.scan(report)
+	if err != nil {
+		return nil, err
+	}
+
+	arg := db.ListSyncPostIDsParams{
+		AnyAuthor: s.authorID == 0,
+		AuthorID:  s.authorID,
+	}
+	ids, err := s.store.ListSyncPostIDs(ctx, arg)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, id := range ids {
+		post, err := s.postDocument(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+
+		file, ok := files[id]
+		if !ok {
+			file = localFile{path: s.newPath(post)}
+		} else if file.doc.Sum() == post.Sum() && file.doc.Checksum == post.Checksum && file.doc.Version == post.Version {
+			report.Unchanged++
+			continue
+		} else if file.doc.Changed() && file.doc.Sum() != post.Sum() {
+			report.conflict(file.path, "file has changes that are not imported")
+			continue
+		}
+
+		if err = writeDocument(file.path, post); err != nil {
+			return nil, err
+		}
+		report.Exported++
+	}
+	return report, nil
+}
+
+// Import saves the files of the directory as posts. Files without ID create
+// new posts, edited files update their post and files of posts that changed
+// on the blog are updated from the post.
+func (s *Syncer) Import(ctx context.Context) (*Report, error) {
+	report := &Report{Conflicts: []string{}, Warnings: []string{}}
+
+	files, newFiles, err := s.scan(report)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, file := range newFiles {
+		if s.authorID == 0 {
+			report.warn(file.path, "an author is required to create posts")
+			continue
+		}
+
+		arg, ok, err := s.syncParams(ctx, file, nil, report)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		arg.AuthorID = s.authorID
+
+		if err = s.save(ctx, file.path, arg); err != nil {
+			return nil, err
+		}
+		report.Created++
+	}
+
+	ids := make([]int64, 0, len(files))
+	for id := range files {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	for _, id := range ids {
+		file := files[id]
+		post, err := s.postDocument(ctx, id)
+		if err == sql.ErrNoRows {
+			report.conflict(file.path, "post %d does not exist", id)
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		switch importAction(file.doc, post.Document) {
+		case actionRefresh:
+			if err = writeDocument(file.path, post); err != nil {
+				return nil, err
+			}
+			report.Unchanged++
+		case actionUnchanged:
+			report.Unchanged++
+		case actionConflict:
+			report.conflict(file.path, "both the file and post %d changed", id)
+		case actionPush:
+			if s.authorID != 0 && post.authorID != s.authorID {
+				report.warn(file.path, "post %d belongs to another author", id)
+				continue
+			}
+			if !editableStatus[post.Status] {
+				report.conflict(file.path, "post %d is in %s and can only be changed on the blog", id, post.Status)
+				continue
+			}
+
+			arg, ok, err := s.syncParams(ctx, file, post.Document, report)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				continue
+			}
+			arg.ID = id
+			arg.Version = post.Version
+
+			if err = s.save(ctx, file.path, arg); err == sql.ErrNoRows {
+				report.conflict(file.path, "post %d changed during the sync", id)
+				continue
+			} else if err != nil {
+				return nil, err
+			}
+			report.Updated++
+		case actionPull:
+			if err = writeDocument(file.path, post); err != nil {
+				return nil, err
+			}
+			report.Pulled++
+		}
+	}
+	return report, nil
+}
+
+type syncAction int
+
+const (
+	actionUnchanged syncAction = iota
+	// Same content on both sides, only the sync state of the file is outdated
+	actionRefresh
+	// Both the file and the post changed
+	actionConflict
+	// Only the file changed and is saved to the post
+	actionPush
+	// Only the post changed and is written to the file
+	actionPull
+)
+
+// importAction decides how a file is synced with its post on import
+func importAction(file *Document, post *Document) syncAction {
+	fileChanged := file.Changed()
+	postChanged := file.Version != post.Version || file.Checksum != post.Checksum
+
+	switch {
+	case file.Sum() == post.Sum():
+		if fileChanged || postChanged {
+			return actionRefresh
+		}
+		return actionUnchanged
+	case fileChanged && postChanged:
+		return actionConflict
+	case fileChanged:
+		return actionPush
+	default:
+		return actionPull
+	}
+}
+
+// scan reads the Markdown files of the directory, keyed by post ID
+func (s *Syncer) scan(report *Report) (map[int64]localFile, []localFile, error) {
+	files := map[int64]localFile{}
+	newFiles := []localFile{}
+
+	err := filepath.WalkDir(s.dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".md") {
+			return nil
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		doc, err := ParseDocument(data)
+		if err != nil {
+			report.warn(path, "%s", err.Error())
+			return nil
+		}
+
+		file := localFile{path: path, doc: doc}
+		if doc.ID == 0 {
+			newFiles = append(newFiles, file)
+		} else if other, ok := files[doc.ID]; ok {
+			report.conflict(path, "post %d is also in %s", doc.ID, other.path)
+		} else {
+			files[doc.ID] = file
+		}
+		return nil
+	})
+	return files, newFiles, err
+}
+
+// postFile is the document of a post as it is stored on the blog
+type postFile struct {
+	*Document
+	authorID int64
+}
+
+func (s *Syncer) postDocument(ctx context.Context, id int64) (postFile, error) {
+	arg := db.GetPostParams{
+		PostID:  id,
+		IsAdmin: true,
+	}
+	post, err := s.store.GetPost(ctx, arg)
+	if err != nil {
+		return postFile{}, err
+	}
+
+	doc := &Document{
+		FrontMatter: FrontMatter{
+			ID:         post.ID,
+			Title:      post.Title,
+			Slug:       Slugify(post.Title),
+			Status:     post.Status,
+			Date:       post.PublishAt.UTC().Truncate(time.Second),
+			Lastmod:    post.UpdateAt.UTC().Truncate(time.Second),
+			Cover:      post.CoverImage,
+			Categories: post.CategoryNames,
+			Tags:       post.TagNames,
+			Version:    post.Version,
+		},
+		Body: strings.TrimSpace(post.Content),
+	}
+	doc.Checksum = doc.Sum()
+	return postFile{Document: doc, authorID: post.AuthorID}, nil
+}
+
+// syncParams validates a file and resolves its categories and tags. Unknown
+// categories are dropped while unknown tags are created. Files of existing
+// posts are given along with the post, whose status is kept.
+func (s *Syncer) syncParams(ctx context.Context, file localFile, post *Document, report *Report) (db.SyncPostParams, bool, error) {
+	doc := file.doc
+	arg := db.SyncPostParams{
+		Title:       strings.TrimSpace(doc.Title),
+		CoverImage:  strings.TrimSpace(doc.Cover),
+		Content:     doc.Body,
+		Status:      doc.Status,
+		PublishAt:   doc.Date,
+		CategoryIDs: []int64{},
+		TagIDs:      []int64{},
+	}
+
+	if post != nil {
+		if arg.Status != "" && arg.Status != post.Status {
+			report.warn(file.path, "status %q is ignored, post %d stays %s", arg.Status, post.ID, post.Status)
+		}
+		arg.Status = post.Status
+	} else if arg.Status == "" {
+		arg.Status = "draft"
+	} else if !editableStatus[arg.Status] {
+		report.warn(file.path, "new posts can only be created as draft or revise, got %q", arg.Status)
+		return arg, false, nil
+	}
+	if arg.PublishAt.IsZero() {
+		arg.PublishAt = time.Now()
+	}
+
+	// Covers are limited like on the blog, to the default cover, the current
+	// cover and media of the authors of the post
+	if arg.CoverImage == "" {
+		arg.CoverImage = s.defaultCover
+	}
+	if arg.CoverImage != s.defaultCover && (post == nil || arg.CoverImage != post.Cover) {
+		arg1 := db.CanUsePostCoverParams{
+			Path:   arg.CoverImage,
+			UserID: s.authorID,
+		}
+		if post != nil {
+			arg1.PostID = post.ID
+		}
+		ok, err := s.store.CanUsePostCover(ctx, arg1)
+		if err != nil {
+			return arg, false, err
+		}
+		if !ok {
+			report.warn(file.path, "cover %q must be media of the post authors or the default cover", arg.CoverImage)
+			return arg, false, nil
+		}
+	}
+
+	for _, name := range doc.Categories {
+		category, err := s.store.GetCategoryByName(ctx, name)
+		if err == sql.ErrNoRows {
+			report.warn(file.path, "unknown category %q", name)
+			continue
+		} else if err != nil {
+			return arg, false, err
+		}
+		arg.CategoryIDs = append(arg.CategoryIDs, category.ID)
+	}
+
+	for _, name := range doc.Tags {
+		tag, err := s.store.GetTagsByName(ctx, name)
+		if err == sql.ErrNoRows {
+			tag, err = s.store.CreateTag(ctx, name)
+		}
+		if err != nil {
+			return arg, false, err
+		}
+		arg.TagIDs = append(arg.TagIDs, tag.ID)
+	}
+	return arg, true, nil
+}
+
+// save stores the post and writes it back, so that the file gets the ID
+// and version of the post
+func (s *Syncer) save(ctx context.Context, path string, arg db.SyncPostParams) error {
+	post, err := s.store.SyncPost(ctx, arg)
+	if err != nil {
+		return err
+	}
+
+	doc, err := s.postDocument(ctx, post.ID)
+	if err != nil {
+		return err
+	}
+	return writeDocument(path, doc)
+}
+
+// newPath names the file of an exported post after its slug
+func (s *Syncer) newPath(post postFile) string {
+	slug := post.Slug
+	if slug == "" {
+		slug = fmt.Sprintf("post-%d", post.ID)
+	}
+
+	path := filepath.Join(s.dir, slug+".md")
+	if _, err := os.Stat(path); err == nil {
+		path = filepath.Join(s.dir, fmt.Sprintf("%s-%d.md", slug, post.ID))
+	}
+	return path
+}
+
+func writeDocument(path string, post postFile) error {
+	data, err := post.Render()
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
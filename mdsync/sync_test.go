@@ -0,0 +1,160 @@
+package mdsync
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bwen19/blog/psql/db"
+	"github.com/stretchr/testify/require"
+)
+
+// syncedPair returns a file and its post as they are right after a sync
+func syncedPair() (*Document, *Document) {
+	post := randomDocument()
+	post.Checksum = post.Sum()
+
+	file := *post
+	return &file, post
+}
+
+func TestImportAction(t *testing.T) {
+	file, post := syncedPair()
+	require.Equal(t, actionUnchanged, importAction(file, post))
+
+	// Only the file changed
+	file, post = syncedPair()
+	file.Body = "Edited in the file"
+	require.Equal(t, actionPush, importAction(file, post))
+
+	// Only the post changed
+	file, post = syncedPair()
+	post.Body = "Edited on the blog"
+	post.Version++
+	post.Checksum = post.Sum()
+	require.Equal(t, actionPull, importAction(file, post))
+
+	// Both changed
+	file.Body = "Edited in the file"
+	require.Equal(t, actionConflict, importAction(file, post))
+
+	// Both changed the same way
+	file.Body = post.Body
+	require.Equal(t, actionRefresh, importAction(file, post))
+
+	// The post was saved again without a change of content
+	file, post = syncedPair()
+	post.Version++
+	require.Equal(t, actionRefresh, importAction(file, post))
+}
+
+func TestSyncParamsStatus(t *testing.T) {
+	// Documents without categories and tags need no store
+	s := &Syncer{}
+
+	// New posts are created as drafts or revisions
+	testCases := map[string]bool{"": true, "draft": true, "revise": true, "review": false, "publish": false}
+	for status, ok := range testCases {
+		report := &Report{}
+		doc := &Document{FrontMatter: FrontMatter{Title: "New", Status: status}}
+
+		arg, valid, err := s.syncParams(context.Background(), localFile{path: "new.md", doc: doc}, nil, report)
+		require.NoError(t, err)
+		require.Equal(t, ok, valid, status)
+		if ok {
+			require.Contains(t, editableStatus, arg.Status)
+			require.Empty(t, report.Warnings)
+		} else {
+			require.Len(t, report.Warnings, 1)
+		}
+	}
+
+	// Existing posts keep their status
+	post := &Document{FrontMatter: FrontMatter{ID: 7, Title: "Post", Status: "review"}}
+	doc := &Document{FrontMatter: FrontMatter{ID: 7, Title: "Post", Status: "publish"}}
+
+	report := &Report{}
+	arg, valid, err := s.syncParams(context.Background(), localFile{path: "post.md", doc: doc}, post, report)
+	require.NoError(t, err)
+	require.True(t, valid)
+	require.Equal(t, "review", arg.Status)
+	require.Len(t, report.Warnings, 1)
+
+	doc.Status = ""
+	report = &Report{}
+	arg, valid, err = s.syncParams(context.Background(), localFile{path: "post.md", doc: doc}, post, report)
+	require.NoError(t, err)
+	require.True(t, valid)
+	require.Equal(t, "review", arg.Status)
+	require.Empty(t, report.Warnings)
+}
+
+// fakeStore serves posts from memory and records the synced ones
+type fakeStore struct {
+	db.Store
+	posts  map[int64]db.GetPostRow
+	synced []db.SyncPostParams
+}
+
+func (s *fakeStore) GetPost(ctx context.Context, arg db.GetPostParams) (db.GetPostRow, error) {
+	return s.posts[arg.PostID], nil
+}
+
+func (s *fakeStore) SyncPost(ctx context.Context, arg db.SyncPostParams) (db.Post, error) {
+	s.synced = append(s.synced, arg)
+
+	post := s.posts[arg.ID]
+	post.Title, post.Content = arg.Title, arg.Content
+	post.Version++
+	s.posts[arg.ID] = post
+	return db.Post{ID: post.ID, Version: post.Version}, nil
+}
+
+func TestImportStatus(t *testing.T) {
+	store := &fakeStore{posts: map[int64]db.GetPostRow{}}
+	dir := t.TempDir()
+	s := NewSyncer(store, dir, 0, "/image/post/default")
+
+	// Files of all posts are edited after the export
+	statuses := []string{"draft", "revise", "review", "publish"}
+	for i, status := range statuses {
+		id := int64(i + 1)
+		store.posts[id] = db.GetPostRow{
+			ID:         id,
+			AuthorID:   1,
+			Title:      "Post in " + status,
+			CoverImage: "/image/post/default",
+			Status:     status,
+			UpdateAt:   time.Now(),
+			PublishAt:  time.Now(),
+			Version:    1,
+			Content:    "Content on the blog",
+		}
+
+		post, err := s.postDocument(context.Background(), id)
+		require.NoError(t, err)
+		post.Body = "Edited in the file"
+		require.NoError(t, writeDocument(filepath.Join(dir, status+".md"), post))
+	}
+
+	report, err := s.Import(context.Background())
+	require.NoError(t, err)
+
+	// Only drafts and revised posts are saved, the others are conflicts
+	require.Equal(t, 2, report.Updated)
+	require.Len(t, report.Conflicts, 2)
+	require.Len(t, store.synced, 2)
+	for _, arg := range store.synced {
+		require.Contains(t, editableStatus, arg.Status)
+		require.Equal(t, "Edited in the file", arg.Content)
+	}
+	require.Equal(t, "Content on the blog", store.posts[3].Content)
+	require.Equal(t, "Content on the blog", store.posts[4].Content)
+
+	// The files in conflict are left alone
+	data, err := os.ReadFile(filepath.Join(dir, "publish.md"))
+	require.NoError(t, err)
+	require.Contains(t, string(data), "Edited in the file")
+}
@@ -0,0 +1,266 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.15.0
+// source: analytics.sql
+
+package db
+
+import (
+	"context"
+	"time"
+)
+
+const listDailyStats = `-- name: ListDailyStats :many
+WITH Post_CTE AS (
+  SELECT id FROM posts
+  WHERE ($1::bool OR author_id = $2::bigint OR id = ANY(
+      SELECT post_id FROM post_collaborators
+      WHERE user_id = $2::bigint AND accepted = true
+    ))
+    AND status <> 'trash'
+),
+Day_CTE AS (
+  SELECT generate_series($3::date, $4::date, interval '1 day')::date AS day
+),
+View_CTE AS (
+  SELECT day, sum(views) AS views
+  FROM post_daily_views
+  WHERE post_id IN (SELECT id FROM Post_CTE)
+    AND day BETWEEN $3::date AND $4::date
+  GROUP BY day
+),
+Star_CTE AS (
+  SELECT create_at::date AS day, count(*) AS stars
+  FROM post_stars
+  WHERE post_id IN (SELECT id FROM Post_CTE)
+    AND create_at::date BETWEEN $3::date AND $4::date
+  GROUP BY create_at::date
+),
+Comment_CTE AS (
+  SELECT create_at::date AS day, count(*) AS comments
+  FROM comments
+  WHERE post_id IN (SELECT id FROM Post_CTE)
+    AND create_at::date BETWEEN $3::date AND $4::date
+  GROUP BY create_at::date
+),
+Follower_CTE AS (
+  SELECT create_at::date AS day, count(*) AS followers
+  FROM follows
+  WHERE ($1::bool OR user_id = $2::bigint)
+    AND create_at::date BETWEEN $3::date AND $4::date
+  GROUP BY create_at::date
+)
+SELECT dc.day,
+    coalesce(vc.views, 0)::bigint AS views,
+    coalesce(sc.stars, 0)::bigint AS stars,
+    coalesce(cc.comments, 0)::bigint AS comments,
+    coalesce(fc.followers, 0)::bigint AS followers
+FROM Day_CTE dc
+LEFT JOIN View_CTE vc ON vc.day = dc.day
+LEFT JOIN Star_CTE sc ON sc.day = dc.day
+LEFT JOIN Comment_CTE cc ON cc.day = dc.day
+LEFT JOIN Follower_CTE fc ON fc.day = dc.day
+ORDER BY dc.day ASC
+`
+
+type ListDailyStatsParams struct {
+	SiteWide bool      `json:"site_wide"`
+	AuthorID int64     `json:"author_id"`
+	StartDay time.Time `json:"start_day"`
+	EndDay   time.Time `json:"end_day"`
+}
+
+type ListDailyStatsRow struct {
+	Day       time.Time `json:"day"`
+	Views     int64     `json:"views"`
+	Stars     int64     `json:"stars"`
+	Comments  int64     `json:"comments"`
+	Followers int64     `json:"followers"`
+}
+
+func (q *Queries) ListDailyStats(ctx context.Context, arg ListDailyStatsParams) ([]ListDailyStatsRow, error) {
+	rows, err := q.db.QueryContext(ctx, listDailyStats,
+		arg.SiteWide,
+		arg.AuthorID,
+		arg.StartDay,
+		arg.EndDay,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListDailyStatsRow{}
+	for rows.Next() {
+		var i ListDailyStatsRow
+		if err := rows.Scan(
+			&i.Day,
+			&i.Views,
+			&i.Stars,
+			&i.Comments,
+			&i.Followers,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listTopPosts = `-- name: ListTopPosts :many
+WITH View_CTE AS (
+  SELECT post_id, sum(views) AS views
+  FROM post_daily_views
+  WHERE day BETWEEN $4::date AND $5::date
+  GROUP BY post_id
+),
+Star_CTE AS (
+  SELECT post_id, count(*) AS stars
+  FROM post_stars
+  WHERE create_at::date BETWEEN $4::date AND $5::date
+  GROUP BY post_id
+),
+Comment_CTE AS (
+  SELECT post_id, count(*) AS comments
+  FROM comments
+  WHERE create_at::date BETWEEN $4::date AND $5::date
+  GROUP BY post_id
+)
+SELECT p.id, p.title, p.author_id, u.username, u.avatar,
+    coalesce(vc.views, 0)::bigint AS views,
+    coalesce(sc.stars, 0)::bigint AS stars,
+    coalesce(cc.comments, 0)::bigint AS comments
+FROM posts p
+JOIN users u ON u.id = p.author_id
+LEFT JOIN View_CTE vc ON vc.post_id = p.id
+LEFT JOIN Star_CTE sc ON sc.post_id = p.id
+LEFT JOIN Comment_CTE cc ON cc.post_id = p.id
+WHERE p.status = 'publish'
+  AND ($1::bool OR p.author_id = $2::bigint OR p.id = ANY(
+    SELECT post_id FROM post_collaborators
+    WHERE user_id = $2::bigint AND accepted = true
+  ))
+ORDER BY views DESC, stars DESC, comments DESC, p.id DESC
+LIMIT $3::int
+`
+
+type ListTopPostsParams struct {
+	SiteWide bool      `json:"site_wide"`
+	AuthorID int64     `json:"author_id"`
+	Num      int32     `json:"num"`
+	StartDay time.Time `json:"start_day"`
+	EndDay   time.Time `json:"end_day"`
+}
+
+type ListTopPostsRow struct {
+	ID       int64  `json:"id"`
+	Title    string `json:"title"`
+	AuthorID int64  `json:"author_id"`
+	Username string `json:"username"`
+	Avatar   string `json:"avatar"`
+	Views    int64  `json:"views"`
+	Stars    int64  `json:"stars"`
+	Comments int64  `json:"comments"`
+}
+
+func (q *Queries) ListTopPosts(ctx context.Context, arg ListTopPostsParams) ([]ListTopPostsRow, error) {
+	rows, err := q.db.QueryContext(ctx, listTopPosts,
+		arg.SiteWide,
+		arg.AuthorID,
+		arg.Num,
+		arg.StartDay,
+		arg.EndDay,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListTopPostsRow{}
+	for rows.Next() {
+		var i ListTopPostsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Title,
+			&i.AuthorID,
+			&i.Username,
+			&i.Avatar,
+			&i.Views,
+			&i.Stars,
+			&i.Comments,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listTrafficSources = `-- name: ListTrafficSources :many
+SELECT source, sum(views)::bigint AS views
+FROM post_daily_sources
+WHERE day BETWEEN $1::date AND $2::date
+  AND post_id IN (
+    SELECT id FROM posts
+    WHERE ($3::bool OR author_id = $4::bigint OR id = ANY(
+        SELECT post_id FROM post_collaborators
+        WHERE user_id = $4::bigint AND accepted = true
+      ))
+      AND status <> 'trash'
+  )
+GROUP BY source
+ORDER BY views DESC, source ASC
+LIMIT $5::int
+`
+
+type ListTrafficSourcesParams struct {
+	StartDay time.Time `json:"start_day"`
+	EndDay   time.Time `json:"end_day"`
+	SiteWide bool      `json:"site_wide"`
+	AuthorID int64     `json:"author_id"`
+	Num      int32     `json:"num"`
+}
+
+type ListTrafficSourcesRow struct {
+	Source string `json:"source"`
+	Views  int64  `json:"views"`
+}
+
+func (q *Queries) ListTrafficSources(ctx context.Context, arg ListTrafficSourcesParams) ([]ListTrafficSourcesRow, error) {
+	rows, err := q.db.QueryContext(ctx, listTrafficSources,
+		arg.StartDay,
+		arg.EndDay,
+		arg.SiteWide,
+		arg.AuthorID,
+		arg.Num,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListTrafficSourcesRow{}
+	for rows.Next() {
+		var i ListTrafficSourcesRow
+		if err := rows.Scan(&i.Source, &i.Views); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
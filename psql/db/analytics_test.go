@@ -0,0 +1,127 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/bwen19/blog/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuthorAnalytics(t *testing.T) {
+	author := createRandomUser(t)
+	reader := createRandomUser(t)
+	post := createRandomPost(t, author)
+
+	arg1 := UpdatePostStatusParams{
+		Ids:       []int64{post.ID},
+		Status:    "publish",
+		OldStatus: []string{"draft"},
+		IsAdmin:   true,
+	}
+	_, err := testStore.UpdatePostStatus(context.Background(), arg1)
+	require.NoError(t, err)
+
+	arg2 := FlushPostViewsParams{
+		PostIds:  []int64{post.ID, post.ID},
+		Visitors: []string{util.RandomString(10), util.RandomString(10)},
+		Sources:  []string{"direct", "google.com"},
+		Since:    time.Now().Add(-time.Hour),
+	}
+	_, err = testStore.FlushPostViews(context.Background(), arg2)
+	require.NoError(t, err)
+
+	err = testStore.CreatePostStar(context.Background(), CreatePostStarParams{
+		PostID: post.ID,
+		UserID: reader.ID,
+	})
+	require.NoError(t, err)
+
+	createRandomFollow(t, author.ID, reader.ID)
+
+	startDay := time.Now().AddDate(0, 0, -2)
+	endDay := time.Now().AddDate(0, 0, 1)
+
+	arg3 := ListDailyStatsParams{
+		AuthorID: author.ID,
+		StartDay: startDay,
+		EndDay:   endDay,
+	}
+	stats, err := testStore.ListDailyStats(context.Background(), arg3)
+	require.NoError(t, err)
+	require.Len(t, stats, 4)
+
+	var views, stars, followers int64
+	for _, stat := range stats {
+		views += stat.Views
+		stars += stat.Stars
+		followers += stat.Followers
+	}
+	require.Equal(t, int64(2), views)
+	require.Equal(t, int64(1), stars)
+	require.Equal(t, int64(1), followers)
+
+	arg4 := ListTopPostsParams{
+		AuthorID: author.ID,
+		Num:      5,
+		StartDay: startDay,
+		EndDay:   endDay,
+	}
+	posts, err := testStore.ListTopPosts(context.Background(), arg4)
+	require.NoError(t, err)
+	require.Len(t, posts, 1)
+	require.Equal(t, post.ID, posts[0].ID)
+	require.Equal(t, int64(2), posts[0].Views)
+
+	arg5 := ListTrafficSourcesParams{
+		AuthorID: author.ID,
+		Num:      5,
+		StartDay: startDay,
+		EndDay:   endDay,
+	}
+	sources, err := testStore.ListTrafficSources(context.Background(), arg5)
+	require.NoError(t, err)
+	require.Len(t, sources, 2)
+}
+
+func TestCollaboratorAnalytics(t *testing.T) {
+	author := createRandomUser(t)
+	editor := createRandomUser(t)
+	post := createRandomPost(t, author)
+	createRandomCollaborator(t, post.ID, editor.ID)
+
+	arg1 := UpdatePostStatusParams{
+		Ids:       []int64{post.ID},
+		Status:    "publish",
+		OldStatus: []string{"draft"},
+		IsAdmin:   true,
+	}
+	_, err := testStore.UpdatePostStatus(context.Background(), arg1)
+	require.NoError(t, err)
+
+	startDay := time.Now().AddDate(0, 0, -2)
+	endDay := time.Now().AddDate(0, 0, 1)
+
+	arg2 := ListTopPostsParams{
+		AuthorID: editor.ID,
+		Num:      5,
+		StartDay: startDay,
+		EndDay:   endDay,
+	}
+	posts, err := testStore.ListTopPosts(context.Background(), arg2)
+	require.NoError(t, err)
+	require.Empty(t, posts)
+
+	_, err = testStore.AcceptPostCollaborator(context.Background(), AcceptPostCollaboratorParams{
+		PostID: post.ID,
+		UserID: editor.ID,
+	})
+	require.NoError(t, err)
+
+	posts, err = testStore.ListTopPosts(context.Background(), arg2)
+	require.NoError(t, err)
+	require.Len(t, posts, 1)
+	require.Equal(t, post.ID, posts[0].ID)
+	require.Equal(t, author.ID, posts[0].AuthorID)
+}
@@ -0,0 +1,300 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.15.0
+// source: bookmark.sql
+
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const createReadingList = `-- name: CreateReadingList :one
+INSERT INTO reading_lists (user_id, name, position)
+SELECT $1::bigint, $2::varchar, coalesce((
+    SELECT max(position) FROM reading_lists
+    WHERE user_id = $1::bigint
+  ), 0) + 1
+RETURNING id, user_id, name, position, create_at
+`
+
+type CreateReadingListParams struct {
+	UserID int64  `json:"user_id"`
+	Name   string `json:"name"`
+}
+
+func (q *Queries) CreateReadingList(ctx context.Context, arg CreateReadingListParams) (ReadingList, error) {
+	row := q.db.QueryRowContext(ctx, createReadingList, arg.UserID, arg.Name)
+	var i ReadingList
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Name,
+		&i.Position,
+		&i.CreateAt,
+	)
+	return i, err
+}
+
+const deleteBookmark = `-- name: DeleteBookmark :execrows
+DELETE FROM bookmarks
+WHERE user_id = $1::bigint AND post_id = $2::bigint
+`
+
+type DeleteBookmarkParams struct {
+	UserID int64 `json:"user_id"`
+	PostID int64 `json:"post_id"`
+}
+
+func (q *Queries) DeleteBookmark(ctx context.Context, arg DeleteBookmarkParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteBookmark, arg.UserID, arg.PostID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deleteReadingList = `-- name: DeleteReadingList :execrows
+DELETE FROM reading_lists
+WHERE id = $1::bigint AND user_id = $2::bigint
+`
+
+type DeleteReadingListParams struct {
+	ID     int64 `json:"id"`
+	UserID int64 `json:"user_id"`
+}
+
+func (q *Queries) DeleteReadingList(ctx context.Context, arg DeleteReadingListParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteReadingList, arg.ID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const listBookmarks = `-- name: ListBookmarks :many
+WITH Data_CTE AS (
+  SELECT b.post_id, b.note, b.create_at FROM bookmarks b
+  JOIN posts p ON p.id = b.post_id AND p.status <> 'trash'
+  WHERE b.list_id = $3::bigint AND b.user_id = $4::bigint
+    AND (p.visibility = ANY('{public, password}'::varchar[])
+      OR (p.visibility = 'followers' AND (p.author_id = $4::bigint OR p.author_id = ANY(
+        SELECT user_id FROM follows
+        WHERE follower_id = $4::bigint
+      ))))
+),
+Count_CTE AS (
+  SELECT count(*) total FROM Data_CTE
+)
+SELECT dc.post_id, dc.note, dc.create_at, cnt.total,
+    p.title, p.cover_image, p.status, p.publish_at,
+    p.author_id, u.username, u.avatar
+FROM Data_CTE dc
+CROSS JOIN Count_CTE cnt
+JOIN posts p ON p.id = dc.post_id
+JOIN users u ON u.id = p.author_id
+ORDER BY dc.create_at DESC, dc.post_id DESC
+LIMIT $1
+OFFSET $2
+`
+
+type ListBookmarksParams struct {
+	Limit  int32 `json:"limit"`
+	Offset int32 `json:"offset"`
+	ListID int64 `json:"list_id"`
+	UserID int64 `json:"user_id"`
+}
+
+type ListBookmarksRow struct {
+	PostID     int64     `json:"post_id"`
+	Note       string    `json:"note"`
+	CreateAt   time.Time `json:"create_at"`
+	Total      int64     `json:"total"`
+	Title      string    `json:"title"`
+	CoverImage string    `json:"cover_image"`
+	Status     string    `json:"status"`
+	PublishAt  time.Time `json:"publish_at"`
+	AuthorID   int64     `json:"author_id"`
+	Username   string    `json:"username"`
+	Avatar     string    `json:"avatar"`
+}
+
+func (q *Queries) ListBookmarks(ctx context.Context, arg ListBookmarksParams) ([]ListBookmarksRow, error) {
+	rows, err := q.db.QueryContext(ctx, listBookmarks,
+		arg.Limit,
+		arg.Offset,
+		arg.ListID,
+		arg.UserID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListBookmarksRow{}
+	for rows.Next() {
+		var i ListBookmarksRow
+		if err := rows.Scan(
+			&i.PostID,
+			&i.Note,
+			&i.CreateAt,
+			&i.Total,
+			&i.Title,
+			&i.CoverImage,
+			&i.Status,
+			&i.PublishAt,
+			&i.AuthorID,
+			&i.Username,
+			&i.Avatar,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listReadingLists = `-- name: ListReadingLists :many
+SELECT rl.id, rl.user_id, rl.name, rl.position, rl.create_at,
+    (SELECT count(*) FROM bookmarks bk
+      WHERE bk.list_id = rl.id) bookmark_count
+FROM reading_lists rl
+WHERE rl.user_id = $1::bigint
+ORDER BY rl.position ASC, rl.id ASC
+`
+
+type ListReadingListsRow struct {
+	ID            int64     `json:"id"`
+	UserID        int64     `json:"user_id"`
+	Name          string    `json:"name"`
+	Position      int32     `json:"position"`
+	CreateAt      time.Time `json:"create_at"`
+	BookmarkCount int64     `json:"bookmark_count"`
+}
+
+func (q *Queries) ListReadingLists(ctx context.Context, userID int64) ([]ListReadingListsRow, error) {
+	rows, err := q.db.QueryContext(ctx, listReadingLists, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListReadingListsRow{}
+	for rows.Next() {
+		var i ListReadingListsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Name,
+			&i.Position,
+			&i.CreateAt,
+			&i.BookmarkCount,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const updateReadingList = `-- name: UpdateReadingList :one
+UPDATE reading_lists SET name = $1::varchar
+WHERE id = $2::bigint AND user_id = $3::bigint
+RETURNING id, user_id, name, position, create_at
+`
+
+type UpdateReadingListParams struct {
+	Name   string `json:"name"`
+	ID     int64  `json:"id"`
+	UserID int64  `json:"user_id"`
+}
+
+func (q *Queries) UpdateReadingList(ctx context.Context, arg UpdateReadingListParams) (ReadingList, error) {
+	row := q.db.QueryRowContext(ctx, updateReadingList, arg.Name, arg.ID, arg.UserID)
+	var i ReadingList
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Name,
+		&i.Position,
+		&i.CreateAt,
+	)
+	return i, err
+}
+
+const updateReadingListPositions = `-- name: UpdateReadingListPositions :execrows
+UPDATE reading_lists
+SET position = array_position($1::bigint[], id)
+WHERE user_id = $2::bigint
+  AND id = ANY($1::bigint[])
+`
+
+type UpdateReadingListPositionsParams struct {
+	ListIds []int64 `json:"list_ids"`
+	UserID  int64   `json:"user_id"`
+}
+
+func (q *Queries) UpdateReadingListPositions(ctx context.Context, arg UpdateReadingListPositionsParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, updateReadingListPositions, pq.Array(arg.ListIds), arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const upsertBookmark = `-- name: UpsertBookmark :one
+INSERT INTO bookmarks (user_id, post_id, list_id, note)
+SELECT rl.user_id, p.id, rl.id, $1::varchar
+FROM reading_lists rl
+JOIN posts p ON p.id = $2::bigint AND p.status = 'publish'
+WHERE rl.id = $3::bigint AND rl.user_id = $4::bigint
+  AND (p.visibility = ANY('{public, password}'::varchar[])
+    OR (p.visibility = 'followers' AND (p.author_id = $4::bigint OR p.author_id = ANY(
+      SELECT user_id FROM follows
+      WHERE follower_id = $4::bigint
+    ))))
+ON CONFLICT (user_id, post_id) DO UPDATE
+SET list_id = excluded.list_id,
+  note = CASE WHEN $5::bool THEN excluded.note ELSE bookmarks.note END
+RETURNING user_id, post_id, list_id, note, create_at
+`
+
+type UpsertBookmarkParams struct {
+	Note    string `json:"note"`
+	PostID  int64  `json:"post_id"`
+	ListID  int64  `json:"list_id"`
+	UserID  int64  `json:"user_id"`
+	HasNote bool   `json:"has_note"`
+}
+
+func (q *Queries) UpsertBookmark(ctx context.Context, arg UpsertBookmarkParams) (Bookmark, error) {
+	row := q.db.QueryRowContext(ctx, upsertBookmark,
+		arg.Note,
+		arg.PostID,
+		arg.ListID,
+		arg.UserID,
+		arg.HasNote,
+	)
+	var i Bookmark
+	err := row.Scan(
+		&i.UserID,
+		&i.PostID,
+		&i.ListID,
+		&i.Note,
+		&i.CreateAt,
+	)
+	return i, err
+}
@@ -0,0 +1,177 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/bwen19/blog/util"
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomReadingList(t *testing.T, user User) ReadingList {
+	arg := CreateReadingListParams{
+		UserID: user.ID,
+		Name:   util.RandomString(8),
+	}
+
+	list, err := testStore.CreateReadingList(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, arg.UserID, list.UserID)
+	require.Equal(t, arg.Name, list.Name)
+	require.NotZero(t, list.ID)
+
+	return list
+}
+
+func TestReadingLists(t *testing.T) {
+	user := createRandomUser(t)
+	list1 := createRandomReadingList(t, user)
+	list2 := createRandomReadingList(t, user)
+	require.Equal(t, list1.Position+1, list2.Position)
+
+	_, err := testStore.CreateReadingList(context.Background(), CreateReadingListParams{
+		UserID: user.ID,
+		Name:   list1.Name,
+	})
+	require.Error(t, err)
+
+	arg1 := UpdateReadingListPositionsParams{
+		UserID:  user.ID,
+		ListIds: []int64{list2.ID, list1.ID},
+	}
+	nrows, err := testStore.UpdateReadingListPositions(context.Background(), arg1)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), nrows)
+
+	lists, err := testStore.ListReadingLists(context.Background(), user.ID)
+	require.NoError(t, err)
+	require.Len(t, lists, 2)
+	require.Equal(t, list2.ID, lists[0].ID)
+
+	arg2 := DeleteReadingListParams{
+		ID:     list1.ID,
+		UserID: createRandomUser(t).ID,
+	}
+	nrows, err = testStore.DeleteReadingList(context.Background(), arg2)
+	require.NoError(t, err)
+	require.Zero(t, nrows)
+}
+
+func TestBookmarks(t *testing.T) {
+	user := createRandomUser(t)
+	list1 := createRandomReadingList(t, user)
+	list2 := createRandomReadingList(t, user)
+
+	post := createRandomPost(t, user)
+	arg1 := UpsertBookmarkParams{
+		UserID:  user.ID,
+		PostID:  post.ID,
+		ListID:  list1.ID,
+		HasNote: true,
+		Note:    util.RandomText(5),
+	}
+	_, err := testStore.UpsertBookmark(context.Background(), arg1)
+	require.EqualError(t, err, sql.ErrNoRows.Error())
+
+	arg2 := UpdatePostStatusParams{
+		Ids:       []int64{post.ID},
+		Status:    "publish",
+		OldStatus: []string{"draft"},
+		IsAdmin:   true,
+	}
+	_, err = testStore.UpdatePostStatus(context.Background(), arg2)
+	require.NoError(t, err)
+
+	bookmark, err := testStore.UpsertBookmark(context.Background(), arg1)
+	require.NoError(t, err)
+	require.Equal(t, list1.ID, bookmark.ListID)
+	require.Equal(t, arg1.Note, bookmark.Note)
+
+	// Moving a bookmark keeps its note
+	arg1.ListID = list2.ID
+	arg1.HasNote = false
+	bookmark, err = testStore.UpsertBookmark(context.Background(), arg1)
+	require.NoError(t, err)
+	require.Equal(t, list2.ID, bookmark.ListID)
+	require.NotEmpty(t, bookmark.Note)
+
+	arg3 := ListBookmarksParams{
+		Limit:  5,
+		ListID: list2.ID,
+		UserID: user.ID,
+	}
+	bookmarks, err := testStore.ListBookmarks(context.Background(), arg3)
+	require.NoError(t, err)
+	require.Len(t, bookmarks, 1)
+	require.Equal(t, post.ID, bookmarks[0].PostID)
+
+	arg4 := ReadPostParams{
+		PostID: post.ID,
+		SelfID: user.ID,
+	}
+	readPost, err := testStore.ReadPost(context.Background(), arg4)
+	require.NoError(t, err)
+	require.Equal(t, list2.ID, readPost.BookmarkListID.Int64)
+
+	arg5 := DeleteBookmarkParams{
+		UserID: user.ID,
+		PostID: post.ID,
+	}
+	nrows, err := testStore.DeleteBookmark(context.Background(), arg5)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), nrows)
+}
+
+func TestBookmarkFollowersPost(t *testing.T) {
+	author := createRandomUser(t)
+	user := createRandomUser(t)
+	list := createRandomReadingList(t, user)
+
+	post := createRandomPost(t, author)
+	_, err := testStore.UpdatePostStatus(context.Background(), UpdatePostStatusParams{
+		Ids:       []int64{post.ID},
+		Status:    "publish",
+		OldStatus: []string{"draft"},
+		IsAdmin:   true,
+	})
+	require.NoError(t, err)
+	_, err = testStore.UpdatePostVisibility(context.Background(), UpdatePostVisibilityParams{
+		ID:         post.ID,
+		Visibility: "followers",
+		AuthorID:   author.ID,
+	})
+	require.NoError(t, err)
+
+	arg1 := UpsertBookmarkParams{
+		UserID: user.ID,
+		PostID: post.ID,
+		ListID: list.ID,
+	}
+	_, err = testStore.UpsertBookmark(context.Background(), arg1)
+	require.EqualError(t, err, sql.ErrNoRows.Error())
+
+	createRandomFollow(t, author.ID, user.ID)
+	_, err = testStore.UpsertBookmark(context.Background(), arg1)
+	require.NoError(t, err)
+
+	arg2 := ListBookmarksParams{
+		Limit:  5,
+		ListID: list.ID,
+		UserID: user.ID,
+	}
+	bookmarks, err := testStore.ListBookmarks(context.Background(), arg2)
+	require.NoError(t, err)
+	require.Len(t, bookmarks, 1)
+
+	// Bookmarks of posts that are no longer visible are hidden
+	_, err = testStore.UpdatePostVisibility(context.Background(), UpdatePostVisibilityParams{
+		ID:         post.ID,
+		Visibility: "unlisted",
+		AuthorID:   author.ID,
+	})
+	require.NoError(t, err)
+	bookmarks, err = testStore.ListBookmarks(context.Background(), arg2)
+	require.NoError(t, err)
+	require.Empty(t, bookmarks)
+}
@@ -130,6 +130,35 @@ func (q *Queries) GetCategories(ctx context.Context) ([]Category, error) {
 	return items, nil
 }
 
+const getCategoriesByIDs = `-- name: GetCategoriesByIDs :many
+SELECT id, name FROM categories
+WHERE id = ANY($1::bigint[])
+ORDER BY id ASC
+`
+
+func (q *Queries) GetCategoriesByIDs(ctx context.Context, ids []int64) ([]Category, error) {
+	rows, err := q.db.QueryContext(ctx, getCategoriesByIDs, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Category{}
+	for rows.Next() {
+		var i Category
+		if err := rows.Scan(&i.ID, &i.Name); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getPostCategories = `-- name: GetPostCategories :many
 SELECT id, name FROM categories
 WHERE id = ANY(
@@ -0,0 +1,160 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.15.0
+// source: collaborator.sql
+
+package db
+
+import (
+	"context"
+	"time"
+)
+
+const acceptPostCollaborator = `-- name: AcceptPostCollaborator :one
+UPDATE post_collaborators SET accepted = true
+WHERE post_id = $1::bigint AND user_id = $2::bigint
+  AND accepted = false
+RETURNING post_id, user_id, role, accepted, create_at
+`
+
+type AcceptPostCollaboratorParams struct {
+	PostID int64 `json:"post_id"`
+	UserID int64 `json:"user_id"`
+}
+
+func (q *Queries) AcceptPostCollaborator(ctx context.Context, arg AcceptPostCollaboratorParams) (PostCollaborator, error) {
+	row := q.db.QueryRowContext(ctx, acceptPostCollaborator, arg.PostID, arg.UserID)
+	var i PostCollaborator
+	err := row.Scan(
+		&i.PostID,
+		&i.UserID,
+		&i.Role,
+		&i.Accepted,
+		&i.CreateAt,
+	)
+	return i, err
+}
+
+const createPostCollaborator = `-- name: CreatePostCollaborator :one
+INSERT INTO post_collaborators (post_id, user_id, role, accepted)
+VALUES ($1, $2, $3, $4) RETURNING post_id, user_id, role, accepted, create_at
+`
+
+type CreatePostCollaboratorParams struct {
+	PostID   int64  `json:"post_id"`
+	UserID   int64  `json:"user_id"`
+	Role     string `json:"role"`
+	Accepted bool   `json:"accepted"`
+}
+
+func (q *Queries) CreatePostCollaborator(ctx context.Context, arg CreatePostCollaboratorParams) (PostCollaborator, error) {
+	row := q.db.QueryRowContext(ctx, createPostCollaborator,
+		arg.PostID,
+		arg.UserID,
+		arg.Role,
+		arg.Accepted,
+	)
+	var i PostCollaborator
+	err := row.Scan(
+		&i.PostID,
+		&i.UserID,
+		&i.Role,
+		&i.Accepted,
+		&i.CreateAt,
+	)
+	return i, err
+}
+
+const deletePostCollaborator = `-- name: DeletePostCollaborator :execrows
+DELETE FROM post_collaborators
+WHERE post_id = $1::bigint AND user_id = $2::bigint
+  AND role <> 'owner'
+`
+
+type DeletePostCollaboratorParams struct {
+	PostID int64 `json:"post_id"`
+	UserID int64 `json:"user_id"`
+}
+
+func (q *Queries) DeletePostCollaborator(ctx context.Context, arg DeletePostCollaboratorParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deletePostCollaborator, arg.PostID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const getPostCollaborator = `-- name: GetPostCollaborator :one
+SELECT post_id, user_id, role, accepted, create_at FROM post_collaborators
+WHERE post_id = $1::bigint AND user_id = $2::bigint
+LIMIT 1
+`
+
+type GetPostCollaboratorParams struct {
+	PostID int64 `json:"post_id"`
+	UserID int64 `json:"user_id"`
+}
+
+func (q *Queries) GetPostCollaborator(ctx context.Context, arg GetPostCollaboratorParams) (PostCollaborator, error) {
+	row := q.db.QueryRowContext(ctx, getPostCollaborator, arg.PostID, arg.UserID)
+	var i PostCollaborator
+	err := row.Scan(
+		&i.PostID,
+		&i.UserID,
+		&i.Role,
+		&i.Accepted,
+		&i.CreateAt,
+	)
+	return i, err
+}
+
+const listPostCollaborators = `-- name: ListPostCollaborators :many
+SELECT pcb.post_id, pcb.user_id, pcb.role, pcb.accepted, pcb.create_at, u.username, u.avatar
+FROM post_collaborators pcb
+JOIN users u ON u.id = pcb.user_id
+WHERE pcb.post_id = $1::bigint
+ORDER BY
+  CASE WHEN pcb.role = 'owner' THEN 0 ELSE 1 END ASC,
+  pcb.create_at ASC
+`
+
+type ListPostCollaboratorsRow struct {
+	PostID   int64     `json:"post_id"`
+	UserID   int64     `json:"user_id"`
+	Role     string    `json:"role"`
+	Accepted bool      `json:"accepted"`
+	CreateAt time.Time `json:"create_at"`
+	Username string    `json:"username"`
+	Avatar   string    `json:"avatar"`
+}
+
+func (q *Queries) ListPostCollaborators(ctx context.Context, postID int64) ([]ListPostCollaboratorsRow, error) {
+	rows, err := q.db.QueryContext(ctx, listPostCollaborators, postID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListPostCollaboratorsRow{}
+	for rows.Next() {
+		var i ListPostCollaboratorsRow
+		if err := rows.Scan(
+			&i.PostID,
+			&i.UserID,
+			&i.Role,
+			&i.Accepted,
+			&i.CreateAt,
+			&i.Username,
+			&i.Avatar,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
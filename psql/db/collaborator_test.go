@@ -0,0 +1,140 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomCollaborator(t *testing.T, postID int64, userID int64) PostCollaborator {
+	arg := CreatePostCollaboratorParams{
+		PostID:   postID,
+		UserID:   userID,
+		Role:     "editor",
+		Accepted: false,
+	}
+
+	collaborator, err := testStore.CreatePostCollaborator(context.Background(), arg)
+	require.NoError(t, err)
+	require.NotEmpty(t, collaborator)
+
+	require.Equal(t, arg.PostID, collaborator.PostID)
+	require.Equal(t, arg.UserID, collaborator.UserID)
+	require.Equal(t, "editor", collaborator.Role)
+	require.False(t, collaborator.Accepted)
+
+	return collaborator
+}
+
+func TestCreatePostOwner(t *testing.T) {
+	user := createRandomUser(t)
+	post := createRandomPost(t, user)
+
+	arg := GetPostCollaboratorParams{
+		PostID: post.ID,
+		UserID: user.ID,
+	}
+	owner, err := testStore.GetPostCollaborator(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, "owner", owner.Role)
+	require.True(t, owner.Accepted)
+
+	arg2 := DeletePostCollaboratorParams{
+		PostID: post.ID,
+		UserID: user.ID,
+	}
+	nrows, err := testStore.DeletePostCollaborator(context.Background(), arg2)
+	require.NoError(t, err)
+	require.Zero(t, nrows)
+}
+
+func TestCollaboratorEditPost(t *testing.T) {
+	owner := createRandomUser(t)
+	editor := createRandomUser(t)
+	post := createRandomPost(t, owner)
+	createRandomCollaborator(t, post.ID, editor.ID)
+
+	arg1 := UpdatePostParams{
+		ID:       post.ID,
+		Title:    sql.NullString{String: "test", Valid: true},
+		AuthorID: editor.ID,
+		Version:  post.Version,
+	}
+	_, err := testStore.UpdatePost(context.Background(), arg1)
+	require.Error(t, err)
+	require.EqualError(t, err, sql.ErrNoRows.Error())
+
+	arg2 := AcceptPostCollaboratorParams{
+		PostID: post.ID,
+		UserID: editor.ID,
+	}
+	collaborator, err := testStore.AcceptPostCollaborator(context.Background(), arg2)
+	require.NoError(t, err)
+	require.True(t, collaborator.Accepted)
+
+	post2, err := testStore.UpdatePost(context.Background(), arg1)
+	require.NoError(t, err)
+	require.Equal(t, arg1.Title.String, post2.Title)
+	require.Equal(t, owner.ID, post2.AuthorID)
+
+	arg3 := TrashPostParams{
+		ID:       post.ID,
+		AuthorID: editor.ID,
+	}
+	_, err = testStore.TrashPost(context.Background(), arg3)
+	require.EqualError(t, err, sql.ErrNoRows.Error())
+
+	arg4 := GetPostParams{
+		PostID:   post.ID,
+		AuthorID: editor.ID,
+	}
+	post3, err := testStore.GetPost(context.Background(), arg4)
+	require.NoError(t, err)
+	require.Equal(t, post.ID, post3.ID)
+
+	collaborators, err := testStore.ListPostCollaborators(context.Background(), post.ID)
+	require.NoError(t, err)
+	require.Len(t, collaborators, 2)
+	require.Equal(t, owner.ID, collaborators[0].UserID)
+	require.Equal(t, editor.ID, collaborators[1].UserID)
+}
+
+func TestReadPostAuthors(t *testing.T) {
+	owner := createRandomUser(t)
+	editor := createRandomUser(t)
+	post := createRandomPost(t, owner)
+	createRandomCollaborator(t, post.ID, editor.ID)
+
+	arg1 := AcceptPostCollaboratorParams{
+		PostID: post.ID,
+		UserID: editor.ID,
+	}
+	_, err := testStore.AcceptPostCollaborator(context.Background(), arg1)
+	require.NoError(t, err)
+
+	arg2 := UpdatePostStatusParams{
+		Ids:       []int64{post.ID},
+		Status:    "review",
+		OldStatus: []string{"draft"},
+		AuthorID:  editor.ID,
+	}
+	posts, err := testStore.UpdatePostStatus(context.Background(), arg2)
+	require.NoError(t, err)
+	require.Len(t, posts, 1)
+
+	arg2.Status = "publish"
+	arg2.OldStatus = []string{"review"}
+	arg2.IsAdmin = true
+	_, err = testStore.UpdatePostStatus(context.Background(), arg2)
+	require.NoError(t, err)
+
+	arg3 := ReadPostParams{
+		PostID: post.ID,
+	}
+	post2, err := testStore.ReadPost(context.Background(), arg3)
+	require.NoError(t, err)
+	require.Equal(t, []int64{owner.ID, editor.ID}, post2.AuthorIds)
+	require.Equal(t, []string{owner.Username, editor.Username}, post2.AuthorNames)
+}
@@ -0,0 +1,98 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/bwen19/blog/util"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+)
+
+var errFault = errors.New("injected fault")
+
+// newFaultStore returns a store whose connections fail the query of the given
+// name, or the commit of a transaction for COMMIT, with errFault
+func newFaultStore(t *testing.T, query string) Store {
+	config, err := util.LoadConfig("../..")
+	require.NoError(t, err)
+
+	connector, err := pq.NewConnector(config.DBSource)
+	require.NoError(t, err)
+
+	faultDB := sql.OpenDB(&faultConnector{Connector: connector, query: query})
+	t.Cleanup(func() { faultDB.Close() })
+	return NewStore(faultDB)
+}
+
+type faultConnector struct {
+	driver.Connector
+	query string
+}
+
+func (c *faultConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &faultConn{Conn: conn, query: c.query}, nil
+}
+
+// faultConn passes statements on to a pq connection unless they are the
+// query that should fail, sqlc names each query in a leading comment
+type faultConn struct {
+	driver.Conn
+	query string
+}
+
+func (c *faultConn) check(query string) error {
+	if strings.HasPrefix(query, "-- name: "+c.query+" ") {
+		return errFault
+	}
+	return nil
+}
+
+func (c *faultConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	if err := c.check(query); err != nil {
+		return nil, err
+	}
+	return c.Conn.(driver.ConnPrepareContext).PrepareContext(ctx, query)
+}
+
+func (c *faultConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if err := c.check(query); err != nil {
+		return nil, err
+	}
+	return c.Conn.(driver.QueryerContext).QueryContext(ctx, query, args)
+}
+
+func (c *faultConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if err := c.check(query); err != nil {
+		return nil, err
+	}
+	return c.Conn.(driver.ExecerContext).ExecContext(ctx, query, args)
+}
+
+func (c *faultConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	tx, err := c.Conn.(driver.ConnBeginTx).BeginTx(ctx, opts)
+	if err != nil || c.query != "COMMIT" {
+		return tx, err
+	}
+	return faultTx{tx}, nil
+}
+
+// faultTx rolls back instead of committing
+type faultTx struct {
+	driver.Tx
+}
+
+func (tx faultTx) Commit() error {
+	if err := tx.Tx.Rollback(); err != nil {
+		return err
+	}
+	return errFault
+}
@@ -0,0 +1,165 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.15.0
+// source: feed.sql
+
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const getFeed = `-- name: GetFeed :many
+WITH Following_CTE AS (
+  SELECT user_id FROM follows
+  WHERE follower_id = $1::bigint
+),
+Post_CTE AS (
+  SELECT id, title, author_id, cover_image, view_count, publish_at, visibility
+  FROM posts
+  WHERE status = 'publish'
+    AND (visibility = ANY('{public, password}'::varchar[])
+      OR (visibility = 'followers' AND author_id = ANY(SELECT user_id FROM Following_CTE)))
+    AND (author_id = ANY(SELECT user_id FROM Following_CTE) OR id = ANY(
+      SELECT post_id FROM post_collaborators
+      WHERE user_id = ANY(SELECT user_id FROM Following_CTE) AND accepted = true
+    ))
+    AND (NOT $2::bool OR (publish_at, id) < ($3::timestamptz, $4::bigint))
+  ORDER BY publish_at DESC, id DESC
+  LIMIT $5::int
+),
+Tag_CTE AS (
+  SELECT pt.post_id,
+      array_agg(t.id)::bigint[] tag_ids,
+      array_agg(t.name)::varchar[] tag_names
+  FROM post_tags pt
+  JOIN tags t
+    ON pt.tag_id = t.id
+    AND pt.post_id = ANY(SELECT id FROM Post_CTE)
+  GROUP BY pt.post_id
+),
+Author_CTE AS (
+  SELECT pcb.post_id,
+      array_agg(u.id ORDER BY pcb.role = 'owner' DESC, pcb.create_at)::bigint[] author_ids,
+      array_agg(u.username ORDER BY pcb.role = 'owner' DESC, pcb.create_at)::varchar[] author_names,
+      array_agg(u.avatar ORDER BY pcb.role = 'owner' DESC, pcb.create_at)::varchar[] author_avatars
+  FROM post_collaborators pcb
+  JOIN users u
+    ON pcb.user_id = u.id
+    AND pcb.accepted = true
+    AND pcb.post_id = ANY(SELECT id FROM Post_CTE)
+  GROUP BY pcb.post_id
+)
+SELECT p.id, p.title, p.author_id, p.cover_image, p.view_count, p.publish_at, p.visibility, u.username, u.avatar,
+    tc.tag_ids, tc.tag_names, ac.author_ids,
+    ac.author_names, ac.author_avatars,
+    (SELECT count(*) FROM comments cm
+      WHERE cm.post_id = p.id) comment_count,
+    (SELECT count(*) FROM post_stars ps
+      WHERE ps.post_id = p.id) star_count
+FROM Post_CTE p
+JOIN users u ON u.id = p.author_id
+LEFT JOIN Tag_CTE tc ON tc.post_id = p.id
+LEFT JOIN Author_CTE ac ON ac.post_id = p.id
+ORDER BY p.publish_at DESC, p.id DESC
+`
+
+type GetFeedParams struct {
+	SelfID     int64     `json:"self_id"`
+	HasCursor  bool      `json:"has_cursor"`
+	CursorTime time.Time `json:"cursor_time"`
+	CursorID   int64     `json:"cursor_id"`
+	Num        int32     `json:"num"`
+}
+
+type GetFeedRow struct {
+	ID            int64     `json:"id"`
+	Title         string    `json:"title"`
+	AuthorID      int64     `json:"author_id"`
+	CoverImage    string    `json:"cover_image"`
+	ViewCount     int64     `json:"view_count"`
+	PublishAt     time.Time `json:"publish_at"`
+	Visibility    string    `json:"visibility"`
+	Username      string    `json:"username"`
+	Avatar        string    `json:"avatar"`
+	TagIds        []int64   `json:"tag_ids"`
+	TagNames      []string  `json:"tag_names"`
+	AuthorIds     []int64   `json:"author_ids"`
+	AuthorNames   []string  `json:"author_names"`
+	AuthorAvatars []string  `json:"author_avatars"`
+	CommentCount  int64     `json:"comment_count"`
+	StarCount     int64     `json:"star_count"`
+}
+
+func (q *Queries) GetFeed(ctx context.Context, arg GetFeedParams) ([]GetFeedRow, error) {
+	rows, err := q.db.QueryContext(ctx, getFeed,
+		arg.SelfID,
+		arg.HasCursor,
+		arg.CursorTime,
+		arg.CursorID,
+		arg.Num,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []GetFeedRow{}
+	for rows.Next() {
+		var i GetFeedRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Title,
+			&i.AuthorID,
+			&i.CoverImage,
+			&i.ViewCount,
+			&i.PublishAt,
+			&i.Visibility,
+			&i.Username,
+			&i.Avatar,
+			pq.Array(&i.TagIds),
+			pq.Array(&i.TagNames),
+			pq.Array(&i.AuthorIds),
+			pq.Array(&i.AuthorNames),
+			pq.Array(&i.AuthorAvatars),
+			&i.CommentCount,
+			&i.StarCount,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getFeedVisit = `-- name: GetFeedVisit :one
+SELECT visit_at FROM feed_visits
+WHERE user_id = $1
+`
+
+func (q *Queries) GetFeedVisit(ctx context.Context, userID int64) (time.Time, error) {
+	row := q.db.QueryRowContext(ctx, getFeedVisit, userID)
+	var visit_at time.Time
+	err := row.Scan(&visit_at)
+	return visit_at, err
+}
+
+const upsertFeedVisit = `-- name: UpsertFeedVisit :exec
+INSERT INTO feed_visits (user_id, visit_at)
+VALUES ($1, now())
+ON CONFLICT (user_id) DO UPDATE
+SET visit_at = excluded.visit_at
+`
+
+func (q *Queries) UpsertFeedVisit(ctx context.Context, userID int64) error {
+	_, err := q.db.ExecContext(ctx, upsertFeedVisit, userID)
+	return err
+}
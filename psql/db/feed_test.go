@@ -0,0 +1,58 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetFeed(t *testing.T) {
+	reader := createRandomUser(t)
+	author1 := createRandomUser(t)
+	author2 := createRandomUser(t)
+	createRandomFollow(t, author1.ID, reader.ID)
+
+	postIDs := []int64{}
+	for _, author := range []User{author1, author1, author1, author2} {
+		post := createRandomPost(t, author)
+		postIDs = append(postIDs, post.ID)
+	}
+
+	arg1 := UpdatePostStatusParams{
+		Ids:       postIDs,
+		Status:    "publish",
+		OldStatus: []string{"draft"},
+		IsAdmin:   true,
+	}
+	_, err := testStore.UpdatePostStatus(context.Background(), arg1)
+	require.NoError(t, err)
+
+	arg2 := GetFeedParams{
+		SelfID: reader.ID,
+		Num:    2,
+	}
+	posts, err := testStore.GetFeed(context.Background(), arg2)
+	require.NoError(t, err)
+	require.Len(t, posts, 2)
+	require.Equal(t, author1.ID, posts[0].AuthorID)
+
+	arg2.HasCursor = true
+	arg2.CursorTime = posts[1].PublishAt
+	arg2.CursorID = posts[1].ID
+	posts, err = testStore.GetFeed(context.Background(), arg2)
+	require.NoError(t, err)
+	require.Len(t, posts, 1)
+	require.NotEqual(t, postIDs[3], posts[0].ID)
+
+	_, err = testStore.GetFeedVisit(context.Background(), reader.ID)
+	require.EqualError(t, err, sql.ErrNoRows.Error())
+
+	err = testStore.UpsertFeedVisit(context.Background(), reader.ID)
+	require.NoError(t, err)
+
+	visitAt, err := testStore.GetFeedVisit(context.Background(), reader.ID)
+	require.NoError(t, err)
+	require.NotZero(t, visitAt)
+}
@@ -0,0 +1,168 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.15.0
+// source: import.sql
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+const createImportRecord = `-- name: CreateImportRecord :exec
+INSERT INTO import_records (source, kind, external_id, local_id)
+VALUES ($1, $2, $3, $4)
+`
+
+type CreateImportRecordParams struct {
+	Source     string `json:"source"`
+	Kind       string `json:"kind"`
+	ExternalID string `json:"external_id"`
+	LocalID    int64  `json:"local_id"`
+}
+
+func (q *Queries) CreateImportRecord(ctx context.Context, arg CreateImportRecordParams) error {
+	_, err := q.db.ExecContext(ctx, createImportRecord,
+		arg.Source,
+		arg.Kind,
+		arg.ExternalID,
+		arg.LocalID,
+	)
+	return err
+}
+
+const getCategoryByName = `-- name: GetCategoryByName :one
+SELECT id, name FROM categories
+WHERE name = $1::varchar LIMIT 1
+`
+
+func (q *Queries) GetCategoryByName(ctx context.Context, name string) (Category, error) {
+	row := q.db.QueryRowContext(ctx, getCategoryByName, name)
+	var i Category
+	err := row.Scan(&i.ID, &i.Name)
+	return i, err
+}
+
+const getComment = `-- name: GetComment :one
+SELECT id, post_id, user_id, parent_id, reply_user_id, content, create_at FROM comments
+WHERE id = $1 LIMIT 1
+`
+
+func (q *Queries) GetComment(ctx context.Context, id int64) (Comment, error) {
+	row := q.db.QueryRowContext(ctx, getComment, id)
+	var i Comment
+	err := row.Scan(
+		&i.ID,
+		&i.PostID,
+		&i.UserID,
+		&i.ParentID,
+		&i.ReplyUserID,
+		&i.Content,
+		&i.CreateAt,
+	)
+	return i, err
+}
+
+const getImportRecord = `-- name: GetImportRecord :one
+SELECT local_id FROM import_records
+WHERE source = $1 AND kind = $2 AND external_id = $3
+`
+
+type GetImportRecordParams struct {
+	Source     string `json:"source"`
+	Kind       string `json:"kind"`
+	ExternalID string `json:"external_id"`
+}
+
+func (q *Queries) GetImportRecord(ctx context.Context, arg GetImportRecordParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, getImportRecord, arg.Source, arg.Kind, arg.ExternalID)
+	var local_id int64
+	err := row.Scan(&local_id)
+	return local_id, err
+}
+
+const importComment = `-- name: ImportComment :one
+INSERT INTO comments (
+    post_id, user_id, parent_id, reply_user_id, content, create_at
+)
+VALUES ($1, $2, $3, $4, $5, $6) RETURNING id, post_id, user_id, parent_id, reply_user_id, content, create_at
+`
+
+type ImportCommentParams struct {
+	PostID      int64         `json:"post_id"`
+	UserID      int64         `json:"user_id"`
+	ParentID    sql.NullInt64 `json:"parent_id"`
+	ReplyUserID sql.NullInt64 `json:"reply_user_id"`
+	Content     string        `json:"content"`
+	CreateAt    time.Time     `json:"create_at"`
+}
+
+func (q *Queries) ImportComment(ctx context.Context, arg ImportCommentParams) (Comment, error) {
+	row := q.db.QueryRowContext(ctx, importComment,
+		arg.PostID,
+		arg.UserID,
+		arg.ParentID,
+		arg.ReplyUserID,
+		arg.Content,
+		arg.CreateAt,
+	)
+	var i Comment
+	err := row.Scan(
+		&i.ID,
+		&i.PostID,
+		&i.UserID,
+		&i.ParentID,
+		&i.ReplyUserID,
+		&i.Content,
+		&i.CreateAt,
+	)
+	return i, err
+}
+
+const importPost = `-- name: ImportPost :one
+INSERT INTO posts (
+    author_id, title, cover_image, status, update_at, publish_at
+)
+VALUES ($1, $2, $3, $4, $5, $6) RETURNING id, author_id, title, cover_image, status, featured, view_count, update_at, publish_at, reviewer_id, version, visibility, password_hash, trash_status, delete_at
+`
+
+type ImportPostParams struct {
+	AuthorID   int64     `json:"author_id"`
+	Title      string    `json:"title"`
+	CoverImage string    `json:"cover_image"`
+	Status     string    `json:"status"`
+	UpdateAt   time.Time `json:"update_at"`
+	PublishAt  time.Time `json:"publish_at"`
+}
+
+func (q *Queries) ImportPost(ctx context.Context, arg ImportPostParams) (Post, error) {
+	row := q.db.QueryRowContext(ctx, importPost,
+		arg.AuthorID,
+		arg.Title,
+		arg.CoverImage,
+		arg.Status,
+		arg.UpdateAt,
+		arg.PublishAt,
+	)
+	var i Post
+	err := row.Scan(
+		&i.ID,
+		&i.AuthorID,
+		&i.Title,
+		&i.CoverImage,
+		&i.Status,
+		&i.Featured,
+		&i.ViewCount,
+		&i.UpdateAt,
+		&i.PublishAt,
+		&i.ReviewerID,
+		&i.Version,
+		&i.Visibility,
+		&i.PasswordHash,
+		&i.TrashStatus,
+		&i.DeleteAt,
+	)
+	return i, err
+}
@@ -0,0 +1,78 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/bwen19/blog/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImportNewPost(t *testing.T) {
+	user := createRandomUser(t)
+	category := createRandomCategory(t)
+	tag := createRandomTag(t)
+
+	publishAt := time.Now().Add(-48 * time.Hour).Truncate(time.Second).UTC()
+	arg := ImportNewPostParams{
+		Source:      "wordpress:" + util.RandomString(8),
+		ExternalID:  util.RandomNumString(5),
+		AuthorID:    user.ID,
+		Title:       util.RandomString(10),
+		CoverImage:  util.RandomString(10),
+		Content:     util.RandomText(20),
+		Status:      "publish",
+		UpdateAt:    publishAt,
+		PublishAt:   publishAt,
+		CategoryIDs: []int64{category.ID},
+		TagIDs:      []int64{tag.ID},
+	}
+
+	post, err := testStore.ImportNewPost(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, arg.AuthorID, post.AuthorID)
+	require.Equal(t, arg.Title, post.Title)
+	require.Equal(t, arg.Status, post.Status)
+	require.WithinDuration(t, arg.PublishAt, post.PublishAt, time.Second)
+
+	arg1 := GetImportRecordParams{
+		Source:     arg.Source,
+		Kind:       "post",
+		ExternalID: arg.ExternalID,
+	}
+	postID, err := testStore.GetImportRecord(context.Background(), arg1)
+	require.NoError(t, err)
+	require.Equal(t, post.ID, postID)
+
+	// Importing the same post twice is rejected by the record
+	_, err = testStore.ImportNewPost(context.Background(), arg)
+	require.Error(t, err)
+
+	arg1.Kind = "comment"
+	_, err = testStore.GetImportRecord(context.Background(), arg1)
+	require.EqualError(t, err, sql.ErrNoRows.Error())
+
+	createAt := publishAt.Add(time.Hour)
+	arg2 := ImportNewCommentParams{
+		Source:     arg.Source,
+		ExternalID: util.RandomNumString(5),
+		ImportCommentParams: ImportCommentParams{
+			PostID:   post.ID,
+			UserID:   user.ID,
+			Content:  util.RandomText(5),
+			CreateAt: createAt,
+		},
+	}
+	comment, err := testStore.ImportNewComment(context.Background(), arg2)
+	require.NoError(t, err)
+	require.Equal(t, post.ID, comment.PostID)
+	require.False(t, comment.ParentID.Valid)
+	require.WithinDuration(t, createAt, comment.CreateAt, time.Second)
+
+	arg1.ExternalID = arg2.ExternalID
+	commentID, err := testStore.GetImportRecord(context.Background(), arg1)
+	require.NoError(t, err)
+	require.Equal(t, comment.ID, commentID)
+}
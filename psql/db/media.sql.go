@@ -0,0 +1,683 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.15.0
+// source: media.sql
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const canUsePostCover = `-- name: CanUsePostCover :one
+SELECT EXISTS (
+  SELECT 1 FROM posts
+  WHERE id = $1::bigint AND cover_image = $2::varchar
+  UNION ALL
+  SELECT 1 FROM post_revisions
+  WHERE post_id = $1::bigint AND cover_image = $2::varchar
+  UNION ALL
+  SELECT 1 FROM media m
+  WHERE m.path = $2::varchar AND (m.owner_id = $3::bigint OR m.owner_id = ANY(
+    SELECT user_id FROM post_collaborators
+    WHERE post_id = $1::bigint AND accepted = true
+  ))
+)::bool
+`
+
+type CanUsePostCoverParams struct {
+	PostID int64  `json:"post_id"`
+	Path   string `json:"path"`
+	UserID int64  `json:"user_id"`
+}
+
+func (q *Queries) CanUsePostCover(ctx context.Context, arg CanUsePostCoverParams) (bool, error) {
+	row := q.db.QueryRowContext(ctx, canUsePostCover, arg.PostID, arg.Path, arg.UserID)
+	var column_1 bool
+	err := row.Scan(&column_1)
+	return column_1, err
+}
+
+const claimMediaVariants = `-- name: ClaimMediaVariants :execrows
+UPDATE media SET variants_pending = false
+WHERE id = $1::bigint AND variants_pending
+`
+
+func (q *Queries) ClaimMediaVariants(ctx context.Context, id int64) (int64, error) {
+	result, err := q.db.ExecContext(ctx, claimMediaVariants, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const createMedia = `-- name: CreateMedia :one
+INSERT INTO media (
+    owner_id, path, size, mime_type, width, height, sha256, alt_text
+)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id, owner_id, path, size, mime_type, width, height, sha256, alt_text, create_at, orphan_at, variants_pending
+`
+
+type CreateMediaParams struct {
+	OwnerID  int64  `json:"owner_id"`
+	Path     string `json:"path"`
+	Size     int64  `json:"size"`
+	MimeType string `json:"mime_type"`
+	Width    int32  `json:"width"`
+	Height   int32  `json:"height"`
+	Sha256   string `json:"sha256"`
+	AltText  string `json:"alt_text"`
+}
+
+func (q *Queries) CreateMedia(ctx context.Context, arg CreateMediaParams) (Media, error) {
+	row := q.db.QueryRowContext(ctx, createMedia,
+		arg.OwnerID,
+		arg.Path,
+		arg.Size,
+		arg.MimeType,
+		arg.Width,
+		arg.Height,
+		arg.Sha256,
+		arg.AltText,
+	)
+	var i Media
+	err := row.Scan(
+		&i.ID,
+		&i.OwnerID,
+		&i.Path,
+		&i.Size,
+		&i.MimeType,
+		&i.Width,
+		&i.Height,
+		&i.Sha256,
+		&i.AltText,
+		&i.CreateAt,
+		&i.OrphanAt,
+		&i.VariantsPending,
+	)
+	return i, err
+}
+
+const createMediaVariant = `-- name: CreateMediaVariant :one
+INSERT INTO media_variants (
+    media_id, path, size, mime_type, width, height
+)
+VALUES ($1, $2, $3, $4, $5, $6) RETURNING id, media_id, path, size, mime_type, width, height
+`
+
+type CreateMediaVariantParams struct {
+	MediaID  int64  `json:"media_id"`
+	Path     string `json:"path"`
+	Size     int64  `json:"size"`
+	MimeType string `json:"mime_type"`
+	Width    int32  `json:"width"`
+	Height   int32  `json:"height"`
+}
+
+func (q *Queries) CreateMediaVariant(ctx context.Context, arg CreateMediaVariantParams) (MediaVariant, error) {
+	row := q.db.QueryRowContext(ctx, createMediaVariant,
+		arg.MediaID,
+		arg.Path,
+		arg.Size,
+		arg.MimeType,
+		arg.Width,
+		arg.Height,
+	)
+	var i MediaVariant
+	err := row.Scan(
+		&i.ID,
+		&i.MediaID,
+		&i.Path,
+		&i.Size,
+		&i.MimeType,
+		&i.Width,
+		&i.Height,
+	)
+	return i, err
+}
+
+const deleteMedia = `-- name: DeleteMedia :one
+DELETE FROM media m
+WHERE m.id = $1::bigint AND m.owner_id = $2::bigint
+  AND NOT EXISTS (SELECT 1 FROM posts WHERE cover_image = m.path)
+  AND NOT EXISTS (SELECT 1 FROM series WHERE cover_image = m.path)
+  AND NOT EXISTS (SELECT 1 FROM users WHERE avatar = m.path)
+  AND NOT EXISTS (SELECT 1 FROM post_contents WHERE strpos(content, m.path) > 0)
+  AND NOT EXISTS (
+    SELECT 1 FROM post_revisions
+    WHERE cover_image = m.path OR strpos(content, m.path) > 0
+  )
+RETURNING id, owner_id, path, size, mime_type, width, height, sha256, alt_text, create_at, orphan_at, variants_pending
+`
+
+type DeleteMediaParams struct {
+	ID      int64 `json:"id"`
+	OwnerID int64 `json:"owner_id"`
+}
+
+func (q *Queries) DeleteMedia(ctx context.Context, arg DeleteMediaParams) (Media, error) {
+	row := q.db.QueryRowContext(ctx, deleteMedia, arg.ID, arg.OwnerID)
+	var i Media
+	err := row.Scan(
+		&i.ID,
+		&i.OwnerID,
+		&i.Path,
+		&i.Size,
+		&i.MimeType,
+		&i.Width,
+		&i.Height,
+		&i.Sha256,
+		&i.AltText,
+		&i.CreateAt,
+		&i.OrphanAt,
+		&i.VariantsPending,
+	)
+	return i, err
+}
+
+const deleteMediaByPath = `-- name: DeleteMediaByPath :exec
+DELETE FROM media
+WHERE path = $1::varchar AND owner_id = $2::bigint
+`
+
+type DeleteMediaByPathParams struct {
+	Path    string `json:"path"`
+	OwnerID int64  `json:"owner_id"`
+}
+
+func (q *Queries) DeleteMediaByPath(ctx context.Context, arg DeleteMediaByPathParams) error {
+	_, err := q.db.ExecContext(ctx, deleteMediaByPath, arg.Path, arg.OwnerID)
+	return err
+}
+
+const deleteOrphanedMedia = `-- name: DeleteOrphanedMedia :many
+DELETE FROM media
+WHERE id = ANY($1::bigint[])
+  AND orphan_at < $2::timestamptz
+RETURNING id, owner_id, path, size, mime_type, width, height, sha256, alt_text, create_at, orphan_at, variants_pending
+`
+
+type DeleteOrphanedMediaParams struct {
+	Ids          []int64   `json:"ids"`
+	OrphanBefore time.Time `json:"orphan_before"`
+}
+
+func (q *Queries) DeleteOrphanedMedia(ctx context.Context, arg DeleteOrphanedMediaParams) ([]Media, error) {
+	rows, err := q.db.QueryContext(ctx, deleteOrphanedMedia, pq.Array(arg.Ids), arg.OrphanBefore)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Media{}
+	for rows.Next() {
+		var i Media
+		if err := rows.Scan(
+			&i.ID,
+			&i.OwnerID,
+			&i.Path,
+			&i.Size,
+			&i.MimeType,
+			&i.Width,
+			&i.Height,
+			&i.Sha256,
+			&i.AltText,
+			&i.CreateAt,
+			&i.OrphanAt,
+			&i.VariantsPending,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getMedia = `-- name: GetMedia :one
+SELECT id, owner_id, path, size, mime_type, width, height, sha256, alt_text, create_at, orphan_at, variants_pending FROM media
+WHERE id = $1::bigint AND owner_id = $2::bigint
+`
+
+type GetMediaParams struct {
+	ID      int64 `json:"id"`
+	OwnerID int64 `json:"owner_id"`
+}
+
+func (q *Queries) GetMedia(ctx context.Context, arg GetMediaParams) (Media, error) {
+	row := q.db.QueryRowContext(ctx, getMedia, arg.ID, arg.OwnerID)
+	var i Media
+	err := row.Scan(
+		&i.ID,
+		&i.OwnerID,
+		&i.Path,
+		&i.Size,
+		&i.MimeType,
+		&i.Width,
+		&i.Height,
+		&i.Sha256,
+		&i.AltText,
+		&i.CreateAt,
+		&i.OrphanAt,
+		&i.VariantsPending,
+	)
+	return i, err
+}
+
+const getMediaByPath = `-- name: GetMediaByPath :one
+SELECT id, owner_id, path, size, mime_type, width, height, sha256, alt_text, create_at, orphan_at, variants_pending FROM media
+WHERE path = $1::varchar AND owner_id = $2::bigint
+`
+
+type GetMediaByPathParams struct {
+	Path    string `json:"path"`
+	OwnerID int64  `json:"owner_id"`
+}
+
+func (q *Queries) GetMediaByPath(ctx context.Context, arg GetMediaByPathParams) (Media, error) {
+	row := q.db.QueryRowContext(ctx, getMediaByPath, arg.Path, arg.OwnerID)
+	var i Media
+	err := row.Scan(
+		&i.ID,
+		&i.OwnerID,
+		&i.Path,
+		&i.Size,
+		&i.MimeType,
+		&i.Width,
+		&i.Height,
+		&i.Sha256,
+		&i.AltText,
+		&i.CreateAt,
+		&i.OrphanAt,
+		&i.VariantsPending,
+	)
+	return i, err
+}
+
+const getMediaOwner = `-- name: GetMediaOwner :one
+SELECT owner_id FROM (
+  SELECT id owner_id, 1 rank FROM users
+  WHERE avatar = $1::varchar
+  UNION ALL
+  SELECT author_id, 2 FROM posts
+  WHERE cover_image = $1::varchar
+  UNION ALL
+  SELECT author_id, 3 FROM series
+  WHERE cover_image = $1::varchar
+  UNION ALL
+  SELECT p.author_id, 4 FROM post_contents pc
+  JOIN posts p ON p.id = pc.id
+  WHERE strpos(pc.content, $1::varchar) > 0
+  UNION ALL
+  SELECT p.author_id, 5 FROM post_revisions r
+  JOIN posts p ON p.id = r.post_id
+  WHERE r.cover_image = $1::varchar OR strpos(r.content, $1::varchar) > 0
+) o
+ORDER BY rank
+LIMIT 1
+`
+
+func (q *Queries) GetMediaOwner(ctx context.Context, path string) (int64, error) {
+	row := q.db.QueryRowContext(ctx, getMediaOwner, path)
+	var owner_id int64
+	err := row.Scan(&owner_id)
+	return owner_id, err
+}
+
+const listMedia = `-- name: ListMedia :many
+WITH Data_CTE AS (
+  SELECT id, owner_id, path, size, mime_type, width, height, sha256, alt_text, create_at, orphan_at, variants_pending FROM media
+  WHERE owner_id = $3::bigint
+    AND ($4::bool OR alt_text ILIKE $5::varchar OR path ILIKE $5::varchar)
+    AND ($6::bool OR mime_type LIKE $7::varchar)
+),
+Count_CTE AS (
+  SELECT count(*) total FROM Data_CTE
+)
+SELECT dc.id, dc.owner_id, dc.path, dc.size, dc.mime_type, dc.width, dc.height, dc.sha256, dc.alt_text, dc.create_at, dc.orphan_at, dc.variants_pending, cnt.total
+FROM Data_CTE dc
+CROSS JOIN Count_CTE cnt
+ORDER BY dc.create_at DESC, dc.id DESC
+LIMIT $1
+OFFSET $2
+`
+
+type ListMediaParams struct {
+	Limit      int32  `json:"limit"`
+	Offset     int32  `json:"offset"`
+	OwnerID    int64  `json:"owner_id"`
+	AnyKeyword bool   `json:"any_keyword"`
+	Keyword    string `json:"keyword"`
+	AnyType    bool   `json:"any_type"`
+	MimeType   string `json:"mime_type"`
+}
+
+type ListMediaRow struct {
+	ID              int64        `json:"id"`
+	OwnerID         int64        `json:"owner_id"`
+	Path            string       `json:"path"`
+	Size            int64        `json:"size"`
+	MimeType        string       `json:"mime_type"`
+	Width           int32        `json:"width"`
+	Height          int32        `json:"height"`
+	Sha256          string       `json:"sha256"`
+	AltText         string       `json:"alt_text"`
+	CreateAt        time.Time    `json:"create_at"`
+	OrphanAt        sql.NullTime `json:"orphan_at"`
+	VariantsPending bool         `json:"variants_pending"`
+	Total           int64        `json:"total"`
+}
+
+func (q *Queries) ListMedia(ctx context.Context, arg ListMediaParams) ([]ListMediaRow, error) {
+	rows, err := q.db.QueryContext(ctx, listMedia,
+		arg.Limit,
+		arg.Offset,
+		arg.OwnerID,
+		arg.AnyKeyword,
+		arg.Keyword,
+		arg.AnyType,
+		arg.MimeType,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListMediaRow{}
+	for rows.Next() {
+		var i ListMediaRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.OwnerID,
+			&i.Path,
+			&i.Size,
+			&i.MimeType,
+			&i.Width,
+			&i.Height,
+			&i.Sha256,
+			&i.AltText,
+			&i.CreateAt,
+			&i.OrphanAt,
+			&i.VariantsPending,
+			&i.Total,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listMediaPaths = `-- name: ListMediaPaths :many
+SELECT path FROM media
+UNION ALL
+SELECT path FROM media_variants
+`
+
+func (q *Queries) ListMediaPaths(ctx context.Context) ([]string, error) {
+	rows, err := q.db.QueryContext(ctx, listMediaPaths)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []string{}
+	for rows.Next() {
+		var path string
+		if err := rows.Scan(&path); err != nil {
+			return nil, err
+		}
+		items = append(items, path)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listMediaVariants = `-- name: ListMediaVariants :many
+SELECT id, media_id, path, size, mime_type, width, height FROM media_variants
+WHERE media_id = ANY($1::bigint[])
+ORDER BY media_id, mime_type, width
+`
+
+func (q *Queries) ListMediaVariants(ctx context.Context, mediaIds []int64) ([]MediaVariant, error) {
+	rows, err := q.db.QueryContext(ctx, listMediaVariants, pq.Array(mediaIds))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []MediaVariant{}
+	for rows.Next() {
+		var i MediaVariant
+		if err := rows.Scan(
+			&i.ID,
+			&i.MediaID,
+			&i.Path,
+			&i.Size,
+			&i.MimeType,
+			&i.Width,
+			&i.Height,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listUnreferencedMedia = `-- name: ListUnreferencedMedia :many
+SELECT m.id FROM media m
+WHERE m.path <> ALL($1::varchar[])
+  AND NOT EXISTS (SELECT 1 FROM posts WHERE cover_image = m.path)
+  AND NOT EXISTS (SELECT 1 FROM series WHERE cover_image = m.path)
+  AND NOT EXISTS (SELECT 1 FROM users WHERE avatar = m.path)
+  AND NOT EXISTS (SELECT 1 FROM post_contents WHERE strpos(content, m.path) > 0)
+  AND NOT EXISTS (
+    SELECT 1 FROM post_revisions
+    WHERE cover_image = m.path OR strpos(content, m.path) > 0
+  )
+`
+
+func (q *Queries) ListUnreferencedMedia(ctx context.Context, keepPaths []string) ([]int64, error) {
+	rows, err := q.db.QueryContext(ctx, listUnreferencedMedia, pq.Array(keepPaths))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []int64{}
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listVariantsByPaths = `-- name: ListVariantsByPaths :many
+SELECT m.path AS media_path, v.id, v.media_id, v.path, v.size, v.mime_type, v.width, v.height
+FROM media_variants v
+JOIN media m ON m.id = v.media_id
+WHERE m.path = ANY($1::varchar[])
+ORDER BY m.path, v.mime_type, v.width
+`
+
+type ListVariantsByPathsRow struct {
+	MediaPath string `json:"media_path"`
+	ID        int64  `json:"id"`
+	MediaID   int64  `json:"media_id"`
+	Path      string `json:"path"`
+	Size      int64  `json:"size"`
+	MimeType  string `json:"mime_type"`
+	Width     int32  `json:"width"`
+	Height    int32  `json:"height"`
+}
+
+func (q *Queries) ListVariantsByPaths(ctx context.Context, paths []string) ([]ListVariantsByPathsRow, error) {
+	rows, err := q.db.QueryContext(ctx, listVariantsByPaths, pq.Array(paths))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListVariantsByPathsRow{}
+	for rows.Next() {
+		var i ListVariantsByPathsRow
+		if err := rows.Scan(
+			&i.MediaPath,
+			&i.ID,
+			&i.MediaID,
+			&i.Path,
+			&i.Size,
+			&i.MimeType,
+			&i.Width,
+			&i.Height,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listVariantsPendingMedia = `-- name: ListVariantsPendingMedia :many
+SELECT id, owner_id, path, size, mime_type, width, height, sha256, alt_text, create_at, orphan_at, variants_pending FROM media
+WHERE variants_pending
+ORDER BY id
+LIMIT $1
+`
+
+func (q *Queries) ListVariantsPendingMedia(ctx context.Context, limit int32) ([]Media, error) {
+	rows, err := q.db.QueryContext(ctx, listVariantsPendingMedia, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Media{}
+	for rows.Next() {
+		var i Media
+		if err := rows.Scan(
+			&i.ID,
+			&i.OwnerID,
+			&i.Path,
+			&i.Size,
+			&i.MimeType,
+			&i.Width,
+			&i.Height,
+			&i.Sha256,
+			&i.AltText,
+			&i.CreateAt,
+			&i.OrphanAt,
+			&i.VariantsPending,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const markMediaVariantsPending = `-- name: MarkMediaVariantsPending :exec
+UPDATE media SET variants_pending = true
+WHERE id = $1::bigint
+`
+
+func (q *Queries) MarkMediaVariantsPending(ctx context.Context, id int64) error {
+	_, err := q.db.ExecContext(ctx, markMediaVariantsPending, id)
+	return err
+}
+
+const markOrphanedMedia = `-- name: MarkOrphanedMedia :many
+UPDATE media SET orphan_at = now()
+WHERE id = ANY($1::bigint[]) AND orphan_at IS NULL
+RETURNING id, owner_id, path, size, mime_type, width, height, sha256, alt_text, create_at, orphan_at, variants_pending
+`
+
+func (q *Queries) MarkOrphanedMedia(ctx context.Context, ids []int64) ([]Media, error) {
+	rows, err := q.db.QueryContext(ctx, markOrphanedMedia, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Media{}
+	for rows.Next() {
+		var i Media
+		if err := rows.Scan(
+			&i.ID,
+			&i.OwnerID,
+			&i.Path,
+			&i.Size,
+			&i.MimeType,
+			&i.Width,
+			&i.Height,
+			&i.Sha256,
+			&i.AltText,
+			&i.CreateAt,
+			&i.OrphanAt,
+			&i.VariantsPending,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const unmarkReferencedMedia = `-- name: UnmarkReferencedMedia :execrows
+UPDATE media SET orphan_at = NULL
+WHERE orphan_at IS NOT NULL AND id <> ALL($1::bigint[])
+`
+
+func (q *Queries) UnmarkReferencedMedia(ctx context.Context, ids []int64) (int64, error) {
+	result, err := q.db.ExecContext(ctx, unmarkReferencedMedia, pq.Array(ids))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,333 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/bwen19/blog/util"
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomMedia(t *testing.T, user User, mimeType string) Media {
+	arg := CreateMediaParams{
+		OwnerID:  user.ID,
+		Path:     "/image/post/" + util.RandomImageName(),
+		Size:     util.RandomInt(1, 1<<20),
+		MimeType: mimeType,
+		Width:    640,
+		Height:   480,
+		Sha256:   util.RandomString(64),
+		AltText:  util.RandomText(5),
+	}
+
+	media, err := testStore.CreateMedia(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, arg.OwnerID, media.OwnerID)
+	require.Equal(t, arg.Path, media.Path)
+	require.Equal(t, arg.Size, media.Size)
+	require.Equal(t, arg.MimeType, media.MimeType)
+	require.Equal(t, arg.Sha256, media.Sha256)
+	require.Equal(t, arg.AltText, media.AltText)
+	require.NotZero(t, media.CreateAt)
+
+	return media
+}
+
+func TestListMedia(t *testing.T) {
+	user := createRandomUser(t)
+	image := createRandomMedia(t, user, "image/png")
+	createRandomMedia(t, user, "application/pdf")
+
+	arg := ListMediaParams{
+		Limit:      10,
+		OwnerID:    user.ID,
+		AnyKeyword: true,
+		AnyType:    true,
+	}
+	media, err := testStore.ListMedia(context.Background(), arg)
+	require.NoError(t, err)
+	require.Len(t, media, 2)
+	require.Equal(t, int64(2), media[0].Total)
+
+	arg.AnyType = false
+	arg.MimeType = "image/%"
+	media, err = testStore.ListMedia(context.Background(), arg)
+	require.NoError(t, err)
+	require.Len(t, media, 1)
+	require.Equal(t, image.ID, media[0].ID)
+
+	arg.AnyType = true
+	arg.AnyKeyword = false
+	arg.Keyword = "%" + image.AltText + "%"
+	media, err = testStore.ListMedia(context.Background(), arg)
+	require.NoError(t, err)
+	require.Len(t, media, 1)
+	require.Equal(t, image.ID, media[0].ID)
+
+	other := createRandomUser(t)
+	_, err = testStore.GetMediaByPath(context.Background(), GetMediaByPathParams{Path: image.Path, OwnerID: other.ID})
+	require.EqualError(t, err, sql.ErrNoRows.Error())
+}
+
+func TestDeleteMedia(t *testing.T) {
+	user := createRandomUser(t)
+	post := createRandomPost(t, user)
+	media := createRandomMedia(t, user, "image/jpeg")
+
+	arg1 := UpdatePostParams{
+		ID:         post.ID,
+		CoverImage: sql.NullString{String: media.Path, Valid: true},
+		AuthorID:   user.ID,
+		Version:    post.Version,
+	}
+	_, err := testStore.UpdatePost(context.Background(), arg1)
+	require.NoError(t, err)
+
+	// Media used as a cover image is kept
+	arg2 := DeleteMediaParams{
+		ID:      media.ID,
+		OwnerID: user.ID,
+	}
+	_, err = testStore.DeleteMedia(context.Background(), arg2)
+	require.EqualError(t, err, sql.ErrNoRows.Error())
+
+	arg1.CoverImage = sql.NullString{String: "/image/post/default", Valid: true}
+	arg1.Version++
+	_, err = testStore.UpdatePost(context.Background(), arg1)
+	require.NoError(t, err)
+
+	// So is media used in the content of a post
+	arg3 := UpdatePostContentParams{
+		ID:       post.ID,
+		Content:  "![image](" + media.Path + ")",
+		AuthorID: user.ID,
+	}
+	_, err = testStore.UpdatePostContent(context.Background(), arg3)
+	require.NoError(t, err)
+
+	_, err = testStore.DeleteMedia(context.Background(), arg2)
+	require.EqualError(t, err, sql.ErrNoRows.Error())
+
+	owner, err := testStore.GetMediaOwner(context.Background(), media.Path)
+	require.NoError(t, err)
+	require.Equal(t, user.ID, owner)
+
+	arg3.Content = util.RandomText(10)
+	_, err = testStore.UpdatePostContent(context.Background(), arg3)
+	require.NoError(t, err)
+
+	deleted, err := testStore.DeleteMedia(context.Background(), arg2)
+	require.NoError(t, err)
+	require.Equal(t, media.ID, deleted.ID)
+
+	_, err = testStore.GetMedia(context.Background(), GetMediaParams{ID: media.ID, OwnerID: user.ID})
+	require.EqualError(t, err, sql.ErrNoRows.Error())
+}
+
+func TestGetMediaOwnerRevision(t *testing.T) {
+	author := createRandomUser(t)
+	post := createRandomPublishedPost(t, author)
+
+	// Legacy files referenced only by a pending revision belong to the author
+	cover := "/image/post/" + util.RandomImageName() + ".jpg"
+	image := "/image/post/" + util.RandomImageName() + ".png"
+	arg := UpdatePostRevisionParams{
+		PostID:     post.ID,
+		AuthorID:   author.ID,
+		CoverImage: sql.NullString{String: cover, Valid: true},
+		Content:    sql.NullString{String: "![image](" + image + ")", Valid: true},
+		Version:    post.Version,
+	}
+	_, err := testStore.UpdatePublishedPost(context.Background(), arg)
+	require.NoError(t, err)
+
+	for _, p := range []string{cover, image} {
+		owner, err := testStore.GetMediaOwner(context.Background(), p)
+		require.NoError(t, err)
+		require.Equal(t, author.ID, owner)
+	}
+
+	_, err = testStore.GetMediaOwner(context.Background(), "/image/post/"+util.RandomImageName()+".png")
+	require.EqualError(t, err, sql.ErrNoRows.Error())
+}
+
+func containsMedia(media []Media, id int64) bool {
+	for _, m := range media {
+		if m.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+func TestSweepMedia(t *testing.T) {
+	user := createRandomUser(t)
+	post := createRandomPost(t, user)
+	used := createRandomMedia(t, user, "image/png")
+	orphan := createRandomMedia(t, user, "image/png")
+
+	arg1 := UpdatePostContentParams{
+		ID:       post.ID,
+		Content:  "![image](" + used.Path + ")",
+		AuthorID: user.ID,
+	}
+	_, err := testStore.UpdatePostContent(context.Background(), arg1)
+	require.NoError(t, err)
+
+	// New orphans are only marked during the grace period
+	arg2 := SweepMediaParams{
+		KeepPaths:    []string{"/image/avatar/default", "/image/post/default"},
+		OrphanBefore: time.Now().Add(-time.Hour),
+	}
+	result, err := testStore.SweepMedia(context.Background(), arg2)
+	require.NoError(t, err)
+	require.True(t, containsMedia(result.Marked, orphan.ID))
+	require.False(t, containsMedia(result.Marked, used.ID))
+	require.False(t, containsMedia(result.Deleted, orphan.ID))
+
+	arg2.OrphanBefore = time.Now().Add(time.Hour)
+	arg2.DryRun = true
+	result, err = testStore.SweepMedia(context.Background(), arg2)
+	require.NoError(t, err)
+	require.True(t, containsMedia(result.Deleted, orphan.ID))
+
+	media, err := testStore.GetMedia(context.Background(), GetMediaParams{ID: orphan.ID, OwnerID: user.ID})
+	require.NoError(t, err)
+	require.True(t, media.OrphanAt.Valid)
+
+	arg2.DryRun = false
+	result, err = testStore.SweepMedia(context.Background(), arg2)
+	require.NoError(t, err)
+	require.True(t, containsMedia(result.Deleted, orphan.ID))
+	require.False(t, containsMedia(result.Deleted, used.ID))
+
+	_, err = testStore.GetMedia(context.Background(), GetMediaParams{ID: orphan.ID, OwnerID: user.ID})
+	require.EqualError(t, err, sql.ErrNoRows.Error())
+}
+
+func TestAddMediaVariants(t *testing.T) {
+	user := createRandomUser(t)
+	name := "/image/post/" + util.RandomImageName()
+
+	media, err := testStore.CreateMedia(context.Background(), CreateMediaParams{
+		OwnerID:  user.ID,
+		Path:     name + ".png",
+		Size:     util.RandomInt(1, 1<<20),
+		MimeType: "image/png",
+		Width:    1600,
+		Height:   1200,
+		Sha256:   util.RandomString(64),
+	})
+	require.NoError(t, err)
+
+	arg := AddMediaVariantsParams{
+		MediaID: media.ID,
+		Variants: []CreateMediaVariantParams{
+			{Path: name + "-320w.png", Size: 100, MimeType: "image/png", Width: 320, Height: 240},
+			{Path: name + "-320w.webp", Size: 80, MimeType: "image/webp", Width: 320, Height: 240},
+		},
+	}
+	variants, err := testStore.AddMediaVariants(context.Background(), arg)
+	require.NoError(t, err)
+	require.Len(t, variants, 2)
+	for _, variant := range variants {
+		require.Equal(t, media.ID, variant.MediaID)
+	}
+
+	rows, err := testStore.ListVariantsByPaths(context.Background(), []string{media.Path})
+	require.NoError(t, err)
+	require.Len(t, rows, 2)
+	require.Equal(t, media.Path, rows[0].MediaPath)
+
+	// A duplicated variant path rolls back the other variants
+	arg.Variants = []CreateMediaVariantParams{
+		{Path: name + "-640w.png", Size: 200, MimeType: "image/png", Width: 640, Height: 480},
+		arg.Variants[0],
+	}
+	_, err = testStore.AddMediaVariants(context.Background(), arg)
+	require.Error(t, err)
+
+	rows, err = testStore.ListVariantsByPaths(context.Background(), []string{media.Path})
+	require.NoError(t, err)
+	require.Len(t, rows, 2)
+
+	// Variants are deleted with their media, and cannot be added afterwards
+	_, err = testStore.DeleteMedia(context.Background(), DeleteMediaParams{ID: media.ID, OwnerID: user.ID})
+	require.NoError(t, err)
+
+	variants, err = testStore.ListMediaVariants(context.Background(), []int64{media.ID})
+	require.NoError(t, err)
+	require.Empty(t, variants)
+
+	arg.Variants = arg.Variants[:1]
+	_, err = testStore.AddMediaVariants(context.Background(), arg)
+	require.Error(t, err)
+}
+
+func TestCanUsePostCover(t *testing.T) {
+	author := createRandomUser(t)
+	collaborator := createRandomUser(t)
+	other := createRandomUser(t)
+	post := createRandomPost(t, author)
+	createRandomCollaborator(t, post.ID, collaborator.ID)
+
+	media1 := createRandomMedia(t, collaborator, "image/jpeg")
+	media2 := createRandomMedia(t, other, "image/jpeg")
+
+	// Media of collaborators can be used once they accepted
+	arg := CanUsePostCoverParams{
+		PostID: post.ID,
+		Path:   media1.Path,
+		UserID: author.ID,
+	}
+	ok, err := testStore.CanUsePostCover(context.Background(), arg)
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	_, err = testStore.AcceptPostCollaborator(context.Background(), AcceptPostCollaboratorParams{
+		PostID: post.ID,
+		UserID: collaborator.ID,
+	})
+	require.NoError(t, err)
+
+	ok, err = testStore.CanUsePostCover(context.Background(), arg)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	arg.Path = media2.Path
+	ok, err = testStore.CanUsePostCover(context.Background(), arg)
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	// The current cover can be kept no matter who owns it
+	arg.Path = post.CoverImage
+	ok, err = testStore.CanUsePostCover(context.Background(), arg)
+	require.NoError(t, err)
+	require.True(t, ok)
+}
+
+func TestVariantsPendingMedia(t *testing.T) {
+	user := createRandomUser(t)
+	media := createRandomMedia(t, user, "image/png")
+	require.False(t, media.VariantsPending)
+
+	require.NoError(t, testStore.MarkMediaVariantsPending(context.Background(), media.ID))
+	pending, err := testStore.ListVariantsPendingMedia(context.Background(), 1000)
+	require.NoError(t, err)
+	found := false
+	for _, m := range pending {
+		require.True(t, m.VariantsPending)
+		found = found || m.ID == media.ID
+	}
+	require.True(t, found)
+
+	// Only one writer claims the variants of a pending media
+	claimed, err := testStore.ClaimMediaVariants(context.Background(), media.ID)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), claimed)
+	claimed, err = testStore.ClaimMediaVariants(context.Background(), media.ID)
+	require.NoError(t, err)
+	require.Zero(t, claimed)
+}
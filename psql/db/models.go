@@ -11,6 +11,14 @@ import (
 	"github.com/google/uuid"
 )
 
+type Bookmark struct {
+	UserID   int64     `json:"user_id"`
+	PostID   int64     `json:"post_id"`
+	ListID   int64     `json:"list_id"`
+	Note     string    `json:"note"`
+	CreateAt time.Time `json:"create_at"`
+}
+
 type Category struct {
 	ID   int64  `json:"id"`
 	Name string `json:"name"`
@@ -31,12 +39,50 @@ type CommentStar struct {
 	UserID    int64 `json:"user_id"`
 }
 
+type FeedVisit struct {
+	UserID  int64     `json:"user_id"`
+	VisitAt time.Time `json:"visit_at"`
+}
+
 type Follow struct {
 	UserID     int64     `json:"user_id"`
 	FollowerID int64     `json:"follower_id"`
 	CreateAt   time.Time `json:"create_at"`
 }
 
+type ImportRecord struct {
+	Source     string    `json:"source"`
+	Kind       string    `json:"kind"`
+	ExternalID string    `json:"external_id"`
+	LocalID    int64     `json:"local_id"`
+	CreateAt   time.Time `json:"create_at"`
+}
+
+type Media struct {
+	ID              int64        `json:"id"`
+	OwnerID         int64        `json:"owner_id"`
+	Path            string       `json:"path"`
+	Size            int64        `json:"size"`
+	MimeType        string       `json:"mime_type"`
+	Width           int32        `json:"width"`
+	Height          int32        `json:"height"`
+	Sha256          string       `json:"sha256"`
+	AltText         string       `json:"alt_text"`
+	CreateAt        time.Time    `json:"create_at"`
+	OrphanAt        sql.NullTime `json:"orphan_at"`
+	VariantsPending bool         `json:"variants_pending"`
+}
+
+type MediaVariant struct {
+	ID       int64  `json:"id"`
+	MediaID  int64  `json:"media_id"`
+	Path     string `json:"path"`
+	Size     int64  `json:"size"`
+	MimeType string `json:"mime_type"`
+	Width    int32  `json:"width"`
+	Height   int32  `json:"height"`
+}
+
 type Notification struct {
 	ID       int64     `json:"id"`
 	UserID   int64     `json:"user_id"`
@@ -48,15 +94,21 @@ type Notification struct {
 }
 
 type Post struct {
-	ID         int64     `json:"id"`
-	AuthorID   int64     `json:"author_id"`
-	Title      string    `json:"title"`
-	CoverImage string    `json:"cover_image"`
-	Status     string    `json:"status"`
-	Featured   bool      `json:"featured"`
-	ViewCount  int64     `json:"view_count"`
-	UpdateAt   time.Time `json:"update_at"`
-	PublishAt  time.Time `json:"publish_at"`
+	ID           int64         `json:"id"`
+	AuthorID     int64         `json:"author_id"`
+	Title        string        `json:"title"`
+	CoverImage   string        `json:"cover_image"`
+	Status       string        `json:"status"`
+	Featured     bool          `json:"featured"`
+	ViewCount    int64         `json:"view_count"`
+	UpdateAt     time.Time     `json:"update_at"`
+	PublishAt    time.Time     `json:"publish_at"`
+	ReviewerID   sql.NullInt64 `json:"reviewer_id"`
+	Version      int64         `json:"version"`
+	Visibility   string        `json:"visibility"`
+	PasswordHash string        `json:"password_hash"`
+	TrashStatus  string        `json:"trash_status"`
+	DeleteAt     sql.NullTime  `json:"delete_at"`
 }
 
 type PostCategory struct {
@@ -64,11 +116,57 @@ type PostCategory struct {
 	CategoryID int64 `json:"category_id"`
 }
 
+type PostCollaborator struct {
+	PostID   int64     `json:"post_id"`
+	UserID   int64     `json:"user_id"`
+	Role     string    `json:"role"`
+	Accepted bool      `json:"accepted"`
+	CreateAt time.Time `json:"create_at"`
+}
+
 type PostContent struct {
 	ID      int64  `json:"id"`
 	Content string `json:"content"`
 }
 
+type PostDailySource struct {
+	PostID int64     `json:"post_id"`
+	Day    time.Time `json:"day"`
+	Source string    `json:"source"`
+	Views  int64     `json:"views"`
+}
+
+type PostDailyView struct {
+	PostID int64     `json:"post_id"`
+	Day    time.Time `json:"day"`
+	Views  int64     `json:"views"`
+}
+
+type PostRanking struct {
+	PostID   int64     `json:"post_id"`
+	Score    float64   `json:"score"`
+	UpdateAt time.Time `json:"update_at"`
+}
+
+type PostRead struct {
+	UserID int64     `json:"user_id"`
+	PostID int64     `json:"post_id"`
+	ReadAt time.Time `json:"read_at"`
+}
+
+type PostRevision struct {
+	PostID      int64         `json:"post_id"`
+	Title       string        `json:"title"`
+	CoverImage  string        `json:"cover_image"`
+	Content     string        `json:"content"`
+	CategoryIds []int64       `json:"category_ids"`
+	TagIds      []int64       `json:"tag_ids"`
+	Status      string        `json:"status"`
+	ReviewerID  sql.NullInt64 `json:"reviewer_id"`
+	Version     int64         `json:"version"`
+	UpdateAt    time.Time     `json:"update_at"`
+}
+
 type PostStar struct {
 	PostID   int64     `json:"post_id"`
 	UserID   int64     `json:"user_id"`
@@ -80,6 +178,48 @@ type PostTag struct {
 	TagID  int64 `json:"tag_id"`
 }
 
+type PostViewer struct {
+	PostID  int64     `json:"post_id"`
+	Visitor string    `json:"visitor"`
+	ViewAt  time.Time `json:"view_at"`
+	Source  string    `json:"source"`
+}
+
+type ReadingList struct {
+	ID       int64     `json:"id"`
+	UserID   int64     `json:"user_id"`
+	Name     string    `json:"name"`
+	Position int32     `json:"position"`
+	CreateAt time.Time `json:"create_at"`
+}
+
+type ReviewNote struct {
+	ID          int64         `json:"id"`
+	PostID      int64         `json:"post_id"`
+	UserID      int64         `json:"user_id"`
+	Kind        string        `json:"kind"`
+	Content     string        `json:"content"`
+	AnchorStart sql.NullInt32 `json:"anchor_start"`
+	AnchorEnd   sql.NullInt32 `json:"anchor_end"`
+	AnchorText  string        `json:"anchor_text"`
+	CreateAt    time.Time     `json:"create_at"`
+}
+
+type Series struct {
+	ID          int64     `json:"id"`
+	AuthorID    int64     `json:"author_id"`
+	Title       string    `json:"title"`
+	Description string    `json:"description"`
+	CoverImage  string    `json:"cover_image"`
+	CreateAt    time.Time `json:"create_at"`
+}
+
+type SeriesPost struct {
+	SeriesID int64 `json:"series_id"`
+	PostID   int64 `json:"post_id"`
+	Position int32 `json:"position"`
+}
+
 type Session struct {
 	ID           uuid.UUID `json:"id"`
 	UserID       int64     `json:"user_id"`
@@ -105,4 +245,5 @@ type User struct {
 	Role           string    `json:"role"`
 	Deleted        bool      `json:"deleted"`
 	CreateAt       time.Time `json:"create_at"`
+	PublicStars    bool      `json:"public_stars"`
 }
@@ -15,7 +15,7 @@ import (
 
 const createPost = `-- name: CreatePost :one
 INSERT INTO posts (author_id, title, cover_image)
-VALUES ($1, $2, $3) RETURNING id, author_id, title, cover_image, status, featured, view_count, update_at, publish_at
+VALUES ($1, $2, $3) RETURNING id, author_id, title, cover_image, status, featured, view_count, update_at, publish_at, reviewer_id, version, visibility, password_hash, trash_status, delete_at
 `
 
 type CreatePostParams struct {
@@ -37,6 +37,12 @@ func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (Post, e
 		&i.ViewCount,
 		&i.UpdateAt,
 		&i.PublishAt,
+		&i.ReviewerID,
+		&i.Version,
+		&i.Visibility,
+		&i.PasswordHash,
+		&i.TrashStatus,
+		&i.DeleteAt,
 	)
 	return i, err
 }
@@ -73,22 +79,6 @@ func (q *Queries) CreatePostStar(ctx context.Context, arg CreatePostStarParams)
 	return err
 }
 
-const deletePost = `-- name: DeletePost :exec
-DELETE FROM posts
-WHERE id = $1::bigint AND author_id = $2::bigint
-  AND status = ANY('{draft, revise}'::varchar[])
-`
-
-type DeletePostParams struct {
-	ID       int64 `json:"id"`
-	AuthorID int64 `json:"author_id"`
-}
-
-func (q *Queries) DeletePost(ctx context.Context, arg DeletePostParams) error {
-	_, err := q.db.ExecContext(ctx, deletePost, arg.ID, arg.AuthorID)
-	return err
-}
-
 const deletePostStar = `-- name: DeletePostStar :exec
 DELETE FROM post_stars
 WHERE post_id = $1 AND user_id = $2
@@ -114,6 +104,7 @@ SELECT p.id, p.title, p.cover_image, p.view_count,
 FROM posts p
 JOIN users u ON u.id = p.author_id
 WHERE featured = true AND status = 'publish'
+  AND visibility = ANY('{public, password}'::varchar[])
 ORDER BY random()
 LIMIT $1
 `
@@ -184,14 +175,18 @@ Tag_CTE AS (
     ON pt.tag_id = t.id AND pt.post_id = $1::bigint
   GROUP BY pt.post_id
 )
-SELECT p.id, p.author_id, p.title, p.cover_image, p.status, p.featured, p.view_count, p.update_at, p.publish_at, pc.content, cc.category_ids, cc.category_names,
-    tc.tag_ids, tc.tag_names
+SELECT p.id, p.author_id, p.title, p.cover_image, p.status, p.featured, p.view_count, p.update_at, p.publish_at, p.reviewer_id, p.version, p.visibility, p.password_hash, p.trash_status, p.delete_at, pc.content, cc.category_ids, cc.category_names,
+    tc.tag_ids, tc.tag_names, coalesce(pr.status, '')::varchar revision_status
 FROM posts p
 JOIN post_contents pc ON pc.id = p.id
 LEFT JOIN Category_CTE cc ON cc.post_id = p.id
 LEFT JOIN Tag_CTE tc ON tc.post_id = p.id
-WHERE p.id = $1::bigint
-  AND ($2::bool OR author_id = $3::bigint)
+LEFT JOIN post_revisions pr ON pr.post_id = p.id
+WHERE p.id = $1::bigint AND p.status <> 'trash'
+  AND ($2::bool OR author_id = $3::bigint OR p.id = ANY(
+    SELECT post_id FROM post_collaborators
+    WHERE user_id = $3::bigint AND accepted = true
+  ))
 LIMIT 1
 `
 
@@ -202,20 +197,27 @@ type GetPostParams struct {
 }
 
 type GetPostRow struct {
-	ID            int64     `json:"id"`
-	AuthorID      int64     `json:"author_id"`
-	Title         string    `json:"title"`
-	CoverImage    string    `json:"cover_image"`
-	Status        string    `json:"status"`
-	Featured      bool      `json:"featured"`
-	ViewCount     int64     `json:"view_count"`
-	UpdateAt      time.Time `json:"update_at"`
-	PublishAt     time.Time `json:"publish_at"`
-	Content       string    `json:"content"`
-	CategoryIds   []int64   `json:"category_ids"`
-	CategoryNames []string  `json:"category_names"`
-	TagIds        []int64   `json:"tag_ids"`
-	TagNames      []string  `json:"tag_names"`
+	ID             int64         `json:"id"`
+	AuthorID       int64         `json:"author_id"`
+	Title          string        `json:"title"`
+	CoverImage     string        `json:"cover_image"`
+	Status         string        `json:"status"`
+	Featured       bool          `json:"featured"`
+	ViewCount      int64         `json:"view_count"`
+	UpdateAt       time.Time     `json:"update_at"`
+	PublishAt      time.Time     `json:"publish_at"`
+	ReviewerID     sql.NullInt64 `json:"reviewer_id"`
+	Version        int64         `json:"version"`
+	Visibility     string        `json:"visibility"`
+	PasswordHash   string        `json:"password_hash"`
+	TrashStatus    string        `json:"trash_status"`
+	DeleteAt       sql.NullTime  `json:"delete_at"`
+	Content        string        `json:"content"`
+	CategoryIds    []int64       `json:"category_ids"`
+	CategoryNames  []string      `json:"category_names"`
+	TagIds         []int64       `json:"tag_ids"`
+	TagNames       []string      `json:"tag_names"`
+	RevisionStatus string        `json:"revision_status"`
 }
 
 func (q *Queries) GetPost(ctx context.Context, arg GetPostParams) (GetPostRow, error) {
@@ -231,43 +233,159 @@ func (q *Queries) GetPost(ctx context.Context, arg GetPostParams) (GetPostRow, e
 		&i.ViewCount,
 		&i.UpdateAt,
 		&i.PublishAt,
+		&i.ReviewerID,
+		&i.Version,
+		&i.Visibility,
+		&i.PasswordHash,
+		&i.TrashStatus,
+		&i.DeleteAt,
 		&i.Content,
 		pq.Array(&i.CategoryIds),
 		pq.Array(&i.CategoryNames),
 		pq.Array(&i.TagIds),
 		pq.Array(&i.TagNames),
+		&i.RevisionStatus,
+	)
+	return i, err
+}
+
+const getPostAccess = `-- name: GetPostAccess :one
+SELECT p.id, p.status, p.visibility, p.password_hash,
+  coalesce((
+    SELECT array_agg(user_id) FROM post_collaborators
+    WHERE post_id = p.id AND accepted = true
+  ), '{}')::bigint[] author_ids,
+  EXISTS (
+    SELECT 1 FROM follows
+    WHERE user_id = p.author_id AND follower_id = $1::bigint
+  )::bool followed
+FROM posts p
+WHERE p.id = $2::bigint AND p.status <> 'trash'
+`
+
+type GetPostAccessParams struct {
+	SelfID int64 `json:"self_id"`
+	PostID int64 `json:"post_id"`
+}
+
+type GetPostAccessRow struct {
+	ID           int64   `json:"id"`
+	Status       string  `json:"status"`
+	Visibility   string  `json:"visibility"`
+	PasswordHash string  `json:"password_hash"`
+	AuthorIds    []int64 `json:"author_ids"`
+	Followed     bool    `json:"followed"`
+}
+
+func (q *Queries) GetPostAccess(ctx context.Context, arg GetPostAccessParams) (GetPostAccessRow, error) {
+	row := q.db.QueryRowContext(ctx, getPostAccess, arg.SelfID, arg.PostID)
+	var i GetPostAccessRow
+	err := row.Scan(
+		&i.ID,
+		&i.Status,
+		&i.Visibility,
+		&i.PasswordHash,
+		pq.Array(&i.AuthorIds),
+		&i.Followed,
+	)
+	return i, err
+}
+
+const getPostByID = `-- name: GetPostByID :one
+SELECT id, author_id, title, cover_image, status, featured, view_count, update_at, publish_at, reviewer_id, version, visibility, password_hash, trash_status, delete_at FROM posts
+WHERE id = $1
+`
+
+func (q *Queries) GetPostByID(ctx context.Context, id int64) (Post, error) {
+	row := q.db.QueryRowContext(ctx, getPostByID, id)
+	var i Post
+	err := row.Scan(
+		&i.ID,
+		&i.AuthorID,
+		&i.Title,
+		&i.CoverImage,
+		&i.Status,
+		&i.Featured,
+		&i.ViewCount,
+		&i.UpdateAt,
+		&i.PublishAt,
+		&i.ReviewerID,
+		&i.Version,
+		&i.Visibility,
+		&i.PasswordHash,
+		&i.TrashStatus,
+		&i.DeleteAt,
 	)
 	return i, err
 }
 
+const getPostVersion = `-- name: GetPostVersion :one
+SELECT coalesce(r.version, p.version)::bigint AS version
+FROM posts p
+LEFT JOIN post_revisions r ON r.post_id = p.id
+WHERE p.id = $1
+  AND (p.author_id = $2::bigint OR p.id = ANY(
+    SELECT post_id FROM post_collaborators
+    WHERE user_id = $2::bigint AND accepted = true
+  ))
+  AND (p.status = ANY('{draft, revise}'::varchar[])
+    OR p.status = 'publish' AND (r.status IS NULL OR r.status = ANY('{draft, revise}'::varchar[])))
+`
+
+type GetPostVersionParams struct {
+	ID       int64 `json:"id"`
+	AuthorID int64 `json:"author_id"`
+}
+
+func (q *Queries) GetPostVersion(ctx context.Context, arg GetPostVersionParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, getPostVersion, arg.ID, arg.AuthorID)
+	var version int64
+	err := row.Scan(&version)
+	return version, err
+}
+
 const getPosts = `-- name: GetPosts :many
 WITH Data_CTE AS (
   SELECT id, title, author_id, cover_image,
-      featured, view_count, publish_at
+      featured, view_count, publish_at, visibility,
+      coalesce((
+        SELECT score FROM post_rankings pr
+        WHERE pr.post_id = posts.id
+      ), 0)::float8 hot_score
   FROM posts
   WHERE status = 'publish'
-    AND ($3::bool OR featured = $4::bool)
-    AND ($5::bool OR author_id = $6::bigint)
-    AND ($7::bool OR id = ANY(
+    AND (visibility = ANY('{public, password}'::varchar[])
+      OR (visibility = 'followers' AND (author_id = $3::bigint OR author_id = ANY(
+        SELECT user_id FROM follows
+        WHERE follower_id = $3::bigint
+      ))))
+    AND ($4::bool OR featured = $5::bool)
+    AND ($6::bool OR author_id = $7::bigint OR id = ANY(
+      SELECT post_id FROM post_collaborators
+      WHERE user_id = $7::bigint AND accepted = true
+    ))
+    AND ($8::bool OR id = ANY(
       SELECT post_id FROM post_categories
-      WHERE category_id = $8::bigint
+      WHERE category_id = $9::bigint
     ))
-    AND ($9::bool OR id = ANY(
+    AND ($10::bool OR id = ANY(
       SELECT post_id FROM post_tags
-      WHERE tag_id = $10::bigint
+      WHERE tag_id = $11::bigint
     ))
-    AND ($11::bool OR title LIKE $12::varchar)
+    AND ($12::bool OR title LIKE $13::varchar)
 ),
 Count_CTE AS (
   SELECT count(*) total FROM Data_CTE
 ),
 Post_CTE AS (
-  SELECT id, title, author_id, cover_image, featured, view_count, publish_at FROM Data_CTE
+  SELECT id, title, author_id, cover_image, featured, view_count, publish_at, visibility, hot_score FROM Data_CTE
   ORDER BY
-    CASE WHEN $13::bool THEN publish_at END ASC,
-    CASE WHEN $14::bool THEN publish_at END DESC,
-    CASE WHEN $15::bool THEN view_count END ASC,
-    CASE WHEN $16::bool THEN view_count END DESC,
+    CASE WHEN $14::bool THEN publish_at END ASC,
+    CASE WHEN $15::bool THEN publish_at END DESC,
+    CASE WHEN $16::bool THEN view_count END ASC,
+    CASE WHEN $17::bool THEN view_count END DESC,
+    CASE WHEN $18::bool THEN hot_score END ASC,
+    CASE WHEN $19::bool THEN hot_score END DESC,
     id ASC
   LIMIT $1
   OFFSET $2
@@ -281,9 +399,23 @@ Tag_CTE AS (
     ON pt.tag_id = t.id
     AND pt.post_id = ANY(SELECT id FROM Post_CTE)
   GROUP BY pt.post_id
+),
+Author_CTE AS (
+  SELECT pcb.post_id,
+      array_agg(u.id ORDER BY pcb.role = 'owner' DESC, pcb.create_at)::bigint[] author_ids,
+      array_agg(u.username ORDER BY pcb.role = 'owner' DESC, pcb.create_at)::varchar[] author_names,
+      array_agg(u.avatar ORDER BY pcb.role = 'owner' DESC, pcb.create_at)::varchar[] author_avatars
+  FROM post_collaborators pcb
+  JOIN users u
+    ON pcb.user_id = u.id
+    AND pcb.accepted = true
+    AND pcb.post_id = ANY(SELECT id FROM Post_CTE)
+  GROUP BY pcb.post_id
 )
-SELECT p.id, p.title, p.author_id, p.cover_image, p.featured, p.view_count, p.publish_at, cnt.total, u.username, u.avatar,
-    tc.tag_ids, tc.tag_names,
+SELECT p.id, p.title, p.author_id, p.cover_image, p.featured, p.view_count, p.publish_at, p.visibility, p.hot_score, cnt.total, u.username, u.avatar,
+    tc.tag_ids, tc.tag_names, ac.author_ids,
+    ac.author_names, ac.author_avatars,
+    bk.list_id bookmark_list_id, st.user_id starred,
     (SELECT count(*) FROM comments cm
       WHERE cm.post_id = p.id) comment_count,
     (SELECT count(*) FROM post_stars ps
@@ -292,11 +424,17 @@ FROM Post_CTE p
 CROSS JOIN Count_CTE cnt
 JOIN users u ON u.id = p.author_id
 LEFT JOIN Tag_CTE tc ON tc.post_id = p.id
+LEFT JOIN Author_CTE ac ON ac.post_id = p.id
+LEFT JOIN bookmarks bk
+  ON bk.post_id = p.id AND bk.user_id = $3::bigint
+LEFT JOIN post_stars st
+  ON st.post_id = p.id AND st.user_id = $3::bigint
 `
 
 type GetPostsParams struct {
 	Limit         int32  `json:"limit"`
 	Offset        int32  `json:"offset"`
+	SelfID        int64  `json:"self_id"`
 	AnyFeatured   bool   `json:"any_featured"`
 	Featured      bool   `json:"featured"`
 	AnyAuthor     bool   `json:"any_author"`
@@ -311,29 +449,39 @@ type GetPostsParams struct {
 	PublishAtDesc bool   `json:"publish_at_desc"`
 	ViewCountAsc  bool   `json:"view_count_asc"`
 	ViewCountDesc bool   `json:"view_count_desc"`
+	HotScoreAsc   bool   `json:"hot_score_asc"`
+	HotScoreDesc  bool   `json:"hot_score_desc"`
 }
 
 type GetPostsRow struct {
-	ID           int64     `json:"id"`
-	Title        string    `json:"title"`
-	AuthorID     int64     `json:"author_id"`
-	CoverImage   string    `json:"cover_image"`
-	Featured     bool      `json:"featured"`
-	ViewCount    int64     `json:"view_count"`
-	PublishAt    time.Time `json:"publish_at"`
-	Total        int64     `json:"total"`
-	Username     string    `json:"username"`
-	Avatar       string    `json:"avatar"`
-	TagIds       []int64   `json:"tag_ids"`
-	TagNames     []string  `json:"tag_names"`
-	CommentCount int64     `json:"comment_count"`
-	StarCount    int64     `json:"star_count"`
+	ID             int64         `json:"id"`
+	Title          string        `json:"title"`
+	AuthorID       int64         `json:"author_id"`
+	CoverImage     string        `json:"cover_image"`
+	Featured       bool          `json:"featured"`
+	ViewCount      int64         `json:"view_count"`
+	PublishAt      time.Time     `json:"publish_at"`
+	Visibility     string        `json:"visibility"`
+	HotScore       float64       `json:"hot_score"`
+	Total          int64         `json:"total"`
+	Username       string        `json:"username"`
+	Avatar         string        `json:"avatar"`
+	TagIds         []int64       `json:"tag_ids"`
+	TagNames       []string      `json:"tag_names"`
+	AuthorIds      []int64       `json:"author_ids"`
+	AuthorNames    []string      `json:"author_names"`
+	AuthorAvatars  []string      `json:"author_avatars"`
+	BookmarkListID sql.NullInt64 `json:"bookmark_list_id"`
+	Starred        sql.NullInt64 `json:"starred"`
+	CommentCount   int64         `json:"comment_count"`
+	StarCount      int64         `json:"star_count"`
 }
 
 func (q *Queries) GetPosts(ctx context.Context, arg GetPostsParams) ([]GetPostsRow, error) {
 	rows, err := q.db.QueryContext(ctx, getPosts,
 		arg.Limit,
 		arg.Offset,
+		arg.SelfID,
 		arg.AnyFeatured,
 		arg.Featured,
 		arg.AnyAuthor,
@@ -348,6 +496,8 @@ func (q *Queries) GetPosts(ctx context.Context, arg GetPostsParams) ([]GetPostsR
 		arg.PublishAtDesc,
 		arg.ViewCountAsc,
 		arg.ViewCountDesc,
+		arg.HotScoreAsc,
+		arg.HotScoreDesc,
 	)
 	if err != nil {
 		return nil, err
@@ -364,11 +514,18 @@ func (q *Queries) GetPosts(ctx context.Context, arg GetPostsParams) ([]GetPostsR
 			&i.Featured,
 			&i.ViewCount,
 			&i.PublishAt,
+			&i.Visibility,
+			&i.HotScore,
 			&i.Total,
 			&i.Username,
 			&i.Avatar,
 			pq.Array(&i.TagIds),
 			pq.Array(&i.TagNames),
+			pq.Array(&i.AuthorIds),
+			pq.Array(&i.AuthorNames),
+			pq.Array(&i.AuthorAvatars),
+			&i.BookmarkListID,
+			&i.Starred,
 			&i.CommentCount,
 			&i.StarCount,
 		); err != nil {
@@ -385,18 +542,72 @@ func (q *Queries) GetPosts(ctx context.Context, arg GetPostsParams) ([]GetPostsR
 	return items, nil
 }
 
+const listPostAccess = `-- name: ListPostAccess :many
+SELECT p.id, p.status,
+  (p.author_id = $1::bigint OR p.id = ANY(
+    SELECT post_id FROM post_collaborators
+    WHERE user_id = $1::bigint AND accepted = true
+  ))::bool is_author,
+  (p.reviewer_id IS NOT NULL AND p.reviewer_id <> $1::bigint)::bool claimed
+FROM posts p
+WHERE p.id = ANY($2::bigint[]) AND p.status <> 'trash'
+`
+
+type ListPostAccessParams struct {
+	AuthorID int64   `json:"author_id"`
+	Ids      []int64 `json:"ids"`
+}
+
+type ListPostAccessRow struct {
+	ID       int64  `json:"id"`
+	Status   string `json:"status"`
+	IsAuthor bool   `json:"is_author"`
+	Claimed  bool   `json:"claimed"`
+}
+
+func (q *Queries) ListPostAccess(ctx context.Context, arg ListPostAccessParams) ([]ListPostAccessRow, error) {
+	rows, err := q.db.QueryContext(ctx, listPostAccess, arg.AuthorID, pq.Array(arg.Ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListPostAccessRow{}
+	for rows.Next() {
+		var i ListPostAccessRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Status,
+			&i.IsAuthor,
+			&i.Claimed,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listPosts = `-- name: ListPosts :many
 WITH Data_CTE AS (
-  SELECT id, author_id, title, cover_image, status, featured, view_count, update_at, publish_at FROM posts
-  WHERE ($3::bool OR author_id = $4::bigint)
-    AND ($5::bool OR status = $6::varchar)
+  SELECT id, author_id, title, cover_image, status, featured, view_count, update_at, publish_at, reviewer_id, version, visibility, password_hash, trash_status, delete_at FROM posts
+  WHERE ($3::bool OR author_id = $4::bigint OR id = ANY(
+      SELECT post_id FROM post_collaborators
+      WHERE user_id = $4::bigint AND accepted = true
+    ))
+    AND ($5::bool AND status <> 'trash' OR status = $6::varchar)
     AND ($7::bool OR title LIKE $8::varchar)
 ),
 Count_CTE AS (
   SELECT count(*) total FROM Data_CTE
 ),
 Post_CTE AS (
-  SELECT id, author_id, title, cover_image, status, featured, view_count, update_at, publish_at FROM Data_CTE
+  SELECT id, author_id, title, cover_image, status, featured, view_count, update_at, publish_at, reviewer_id, version, visibility, password_hash, trash_status, delete_at FROM Data_CTE
   ORDER BY
     CASE WHEN $9::bool THEN update_at END ASC,
     CASE WHEN $10::bool THEN update_at END DESC,
@@ -428,7 +639,7 @@ Tag_CTE AS (
     AND pt.post_id = ANY(SELECT id FROM Post_CTE)
   GROUP BY pt.post_id
 )
-SELECT p.id, p.author_id, p.title, p.cover_image, p.status, p.featured, p.view_count, p.update_at, p.publish_at, cnt.total, u.username, u.avatar,
+SELECT p.id, p.author_id, p.title, p.cover_image, p.status, p.featured, p.view_count, p.update_at, p.publish_at, p.reviewer_id, p.version, p.visibility, p.password_hash, p.trash_status, p.delete_at, cnt.total, u.username, u.avatar,
       cc.category_ids, cc.category_names,
       tc.tag_ids, tc.tag_names
 FROM Post_CTE p
@@ -456,22 +667,28 @@ type ListPostsParams struct {
 }
 
 type ListPostsRow struct {
-	ID            int64     `json:"id"`
-	AuthorID      int64     `json:"author_id"`
-	Title         string    `json:"title"`
-	CoverImage    string    `json:"cover_image"`
-	Status        string    `json:"status"`
-	Featured      bool      `json:"featured"`
-	ViewCount     int64     `json:"view_count"`
-	UpdateAt      time.Time `json:"update_at"`
-	PublishAt     time.Time `json:"publish_at"`
-	Total         int64     `json:"total"`
-	Username      string    `json:"username"`
-	Avatar        string    `json:"avatar"`
-	CategoryIds   []int64   `json:"category_ids"`
-	CategoryNames []string  `json:"category_names"`
-	TagIds        []int64   `json:"tag_ids"`
-	TagNames      []string  `json:"tag_names"`
+	ID            int64         `json:"id"`
+	AuthorID      int64         `json:"author_id"`
+	Title         string        `json:"title"`
+	CoverImage    string        `json:"cover_image"`
+	Status        string        `json:"status"`
+	Featured      bool          `json:"featured"`
+	ViewCount     int64         `json:"view_count"`
+	UpdateAt      time.Time     `json:"update_at"`
+	PublishAt     time.Time     `json:"publish_at"`
+	ReviewerID    sql.NullInt64 `json:"reviewer_id"`
+	Version       int64         `json:"version"`
+	Visibility    string        `json:"visibility"`
+	PasswordHash  string        `json:"password_hash"`
+	TrashStatus   string        `json:"trash_status"`
+	DeleteAt      sql.NullTime  `json:"delete_at"`
+	Total         int64         `json:"total"`
+	Username      string        `json:"username"`
+	Avatar        string        `json:"avatar"`
+	CategoryIds   []int64       `json:"category_ids"`
+	CategoryNames []string      `json:"category_names"`
+	TagIds        []int64       `json:"tag_ids"`
+	TagNames      []string      `json:"tag_names"`
 }
 
 func (q *Queries) ListPosts(ctx context.Context, arg ListPostsParams) ([]ListPostsRow, error) {
@@ -508,6 +725,12 @@ func (q *Queries) ListPosts(ctx context.Context, arg ListPostsParams) ([]ListPos
 			&i.ViewCount,
 			&i.UpdateAt,
 			&i.PublishAt,
+			&i.ReviewerID,
+			&i.Version,
+			&i.Visibility,
+			&i.PasswordHash,
+			&i.TrashStatus,
+			&i.DeleteAt,
 			&i.Total,
 			&i.Username,
 			&i.Avatar,
@@ -529,11 +752,23 @@ func (q *Queries) ListPosts(ctx context.Context, arg ListPostsParams) ([]ListPos
 	return items, nil
 }
 
+const purgeTrashedPosts = `-- name: PurgeTrashedPosts :execrows
+DELETE FROM posts
+WHERE status = 'trash' AND delete_at < $1::timestamptz
+`
+
+func (q *Queries) PurgeTrashedPosts(ctx context.Context, deleteBefore time.Time) (int64, error) {
+	result, err := q.db.ExecContext(ctx, purgeTrashedPosts, deleteBefore)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 const readPost = `-- name: ReadPost :one
 WITH Post_CTE AS (
-  UPDATE posts SET view_count = view_count + 1
+  SELECT id, author_id, title, cover_image, status, featured, view_count, update_at, publish_at, reviewer_id, version, visibility, password_hash, trash_status, delete_at FROM posts
   WHERE id = $1::bigint AND status = 'publish'
-  RETURNING id, author_id, title, cover_image, status, featured, view_count, update_at, publish_at
 ),
 Category_CTE AS (
   SELECT pc.post_id,
@@ -552,11 +787,26 @@ Tag_CTE AS (
   JOIN tags t
     ON pt.tag_id = t.id AND pt.post_id = $1::bigint
   GROUP BY pt.post_id
+),
+Author_CTE AS (
+  SELECT pcb.post_id,
+      array_agg(u.id ORDER BY pcb.role = 'owner' DESC, pcb.create_at)::bigint[] author_ids,
+      array_agg(u.username ORDER BY pcb.role = 'owner' DESC, pcb.create_at)::varchar[] author_names,
+      array_agg(u.avatar ORDER BY pcb.role = 'owner' DESC, pcb.create_at)::varchar[] author_avatars
+  FROM post_collaborators pcb
+  JOIN users u
+    ON pcb.user_id = u.id
+    AND pcb.accepted = true
+    AND pcb.post_id = $1::bigint
+  GROUP BY pcb.post_id
 )
 SELECT p.id, p.title, pc.content, p.view_count, p.publish_at,
-    p.author_id, u.username, u.avatar, u.intro,
+    p.visibility, p.password_hash, p.author_id, u.username,
+    u.avatar, u.intro,
     cc.category_ids, cc.category_names, tc.tag_ids,
-    tc.tag_names, fu.follower_id followed,
+    tc.tag_names, ac.author_ids, ac.author_names,
+    ac.author_avatars, fu.follower_id followed,
+    bk.list_id bookmark_list_id, st.user_id starred,
     (SELECT count(*) FROM follows f
       WHERE f.user_id = p.author_id) follower_count,
     (SELECT count(*) FROM follows f
@@ -568,8 +818,13 @@ JOIN post_contents pc ON pc.id = p.id
 JOIN users u ON u.id = p.author_id
 LEFT JOIN Category_CTE cc ON cc.post_id = p.id
 LEFT JOIN Tag_CTE tc ON tc.post_id = p.id
+LEFT JOIN Author_CTE ac ON ac.post_id = p.id
 LEFT JOIN follows fu
   ON fu.user_id = p.author_id AND fu.follower_id = $2::bigint
+LEFT JOIN bookmarks bk
+  ON bk.post_id = p.id AND bk.user_id = $2::bigint
+LEFT JOIN post_stars st
+  ON st.post_id = p.id AND st.user_id = $2::bigint
 `
 
 type ReadPostParams struct {
@@ -583,6 +838,8 @@ type ReadPostRow struct {
 	Content        string        `json:"content"`
 	ViewCount      int64         `json:"view_count"`
 	PublishAt      time.Time     `json:"publish_at"`
+	Visibility     string        `json:"visibility"`
+	PasswordHash   string        `json:"password_hash"`
 	AuthorID       int64         `json:"author_id"`
 	Username       string        `json:"username"`
 	Avatar         string        `json:"avatar"`
@@ -591,7 +848,12 @@ type ReadPostRow struct {
 	CategoryNames  []string      `json:"category_names"`
 	TagIds         []int64       `json:"tag_ids"`
 	TagNames       []string      `json:"tag_names"`
+	AuthorIds      []int64       `json:"author_ids"`
+	AuthorNames    []string      `json:"author_names"`
+	AuthorAvatars  []string      `json:"author_avatars"`
 	Followed       sql.NullInt64 `json:"followed"`
+	BookmarkListID sql.NullInt64 `json:"bookmark_list_id"`
+	Starred        sql.NullInt64 `json:"starred"`
 	FollowerCount  int64         `json:"follower_count"`
 	FollowingCount int64         `json:"following_count"`
 	StarCount      int64         `json:"star_count"`
@@ -606,6 +868,8 @@ func (q *Queries) ReadPost(ctx context.Context, arg ReadPostParams) (ReadPostRow
 		&i.Content,
 		&i.ViewCount,
 		&i.PublishAt,
+		&i.Visibility,
+		&i.PasswordHash,
 		&i.AuthorID,
 		&i.Username,
 		&i.Avatar,
@@ -614,7 +878,12 @@ func (q *Queries) ReadPost(ctx context.Context, arg ReadPostParams) (ReadPostRow
 		pq.Array(&i.CategoryNames),
 		pq.Array(&i.TagIds),
 		pq.Array(&i.TagNames),
+		pq.Array(&i.AuthorIds),
+		pq.Array(&i.AuthorNames),
+		pq.Array(&i.AuthorAvatars),
 		&i.Followed,
+		&i.BookmarkListID,
+		&i.Starred,
 		&i.FollowerCount,
 		&i.FollowingCount,
 		&i.StarCount,
@@ -622,15 +891,96 @@ func (q *Queries) ReadPost(ctx context.Context, arg ReadPostParams) (ReadPostRow
 	return i, err
 }
 
+const restorePost = `-- name: RestorePost :one
+UPDATE posts
+SET status = trash_status, trash_status = '', delete_at = NULL
+WHERE id = $1::bigint AND status = 'trash'
+  AND ($2::bool OR author_id = $3::bigint)
+RETURNING id, author_id, title, cover_image, status, featured, view_count, update_at, publish_at, reviewer_id, version, visibility, password_hash, trash_status, delete_at
+`
+
+type RestorePostParams struct {
+	ID       int64 `json:"id"`
+	IsAdmin  bool  `json:"is_admin"`
+	AuthorID int64 `json:"author_id"`
+}
+
+func (q *Queries) RestorePost(ctx context.Context, arg RestorePostParams) (Post, error) {
+	row := q.db.QueryRowContext(ctx, restorePost, arg.ID, arg.IsAdmin, arg.AuthorID)
+	var i Post
+	err := row.Scan(
+		&i.ID,
+		&i.AuthorID,
+		&i.Title,
+		&i.CoverImage,
+		&i.Status,
+		&i.Featured,
+		&i.ViewCount,
+		&i.UpdateAt,
+		&i.PublishAt,
+		&i.ReviewerID,
+		&i.Version,
+		&i.Visibility,
+		&i.PasswordHash,
+		&i.TrashStatus,
+		&i.DeleteAt,
+	)
+	return i, err
+}
+
+const trashPost = `-- name: TrashPost :one
+UPDATE posts
+SET trash_status = status, status = 'trash', delete_at = now()
+WHERE id = $1::bigint AND status <> 'trash'
+  AND ($2::bool OR (author_id = $3::bigint
+    AND status = ANY('{draft, revise}'::varchar[])))
+RETURNING id, author_id, title, cover_image, status, featured, view_count, update_at, publish_at, reviewer_id, version, visibility, password_hash, trash_status, delete_at
+`
+
+type TrashPostParams struct {
+	ID       int64 `json:"id"`
+	IsAdmin  bool  `json:"is_admin"`
+	AuthorID int64 `json:"author_id"`
+}
+
+func (q *Queries) TrashPost(ctx context.Context, arg TrashPostParams) (Post, error) {
+	row := q.db.QueryRowContext(ctx, trashPost, arg.ID, arg.IsAdmin, arg.AuthorID)
+	var i Post
+	err := row.Scan(
+		&i.ID,
+		&i.AuthorID,
+		&i.Title,
+		&i.CoverImage,
+		&i.Status,
+		&i.Featured,
+		&i.ViewCount,
+		&i.UpdateAt,
+		&i.PublishAt,
+		&i.ReviewerID,
+		&i.Version,
+		&i.Visibility,
+		&i.PasswordHash,
+		&i.TrashStatus,
+		&i.DeleteAt,
+	)
+	return i, err
+}
+
 const updatePost = `-- name: UpdatePost :one
 UPDATE posts
 SET
   title = coalesce($2, title),
   cover_image = coalesce($3, cover_image),
-  update_at = now()
-WHERE id = $1 AND author_id = $4::bigint
+  update_at = now(),
+  version = version + 1
+WHERE id = $1
+  AND (author_id = $4::bigint OR id = ANY(
+    SELECT post_id FROM post_collaborators
+    WHERE user_id = $4::bigint AND accepted = true
+  ))
   AND status = ANY('{draft, revise}'::varchar[])
-RETURNING id, author_id, title, cover_image, status, featured, view_count, update_at, publish_at
+  AND version = $5::bigint
+RETURNING id, author_id, title, cover_image, status, featured, view_count, update_at, publish_at, reviewer_id, version, visibility, password_hash, trash_status, delete_at
 `
 
 type UpdatePostParams struct {
@@ -638,6 +988,7 @@ type UpdatePostParams struct {
 	Title      sql.NullString `json:"title"`
 	CoverImage sql.NullString `json:"cover_image"`
 	AuthorID   int64          `json:"author_id"`
+	Version    int64          `json:"version"`
 }
 
 func (q *Queries) UpdatePost(ctx context.Context, arg UpdatePostParams) (Post, error) {
@@ -646,6 +997,7 @@ func (q *Queries) UpdatePost(ctx context.Context, arg UpdatePostParams) (Post, e
 		arg.Title,
 		arg.CoverImage,
 		arg.AuthorID,
+		arg.Version,
 	)
 	var i Post
 	err := row.Scan(
@@ -658,6 +1010,12 @@ func (q *Queries) UpdatePost(ctx context.Context, arg UpdatePostParams) (Post, e
 		&i.ViewCount,
 		&i.UpdateAt,
 		&i.PublishAt,
+		&i.ReviewerID,
+		&i.Version,
+		&i.Visibility,
+		&i.PasswordHash,
+		&i.TrashStatus,
+		&i.DeleteAt,
 	)
 	return i, err
 }
@@ -668,7 +1026,10 @@ SET content = $2
 WHERE id = (
   SELECT p.id FROM posts p
   WHERE p.id = $1
-    AND author_id = $3::bigint
+    AND (author_id = $3::bigint OR p.id = ANY(
+      SELECT post_id FROM post_collaborators
+      WHERE user_id = $3::bigint AND accepted = true
+    ))
     AND status = ANY('{draft, revise}'::varchar[])
 ) RETURNING id, content
 `
@@ -704,16 +1065,22 @@ func (q *Queries) UpdatePostFeature(ctx context.Context, arg UpdatePostFeaturePa
 const updatePostStatus = `-- name: UpdatePostStatus :many
 UPDATE posts SET status = $1::varchar
 WHERE id = ANY($2::bigint[]) AND status = ANY($3::varchar[])
-  AND ($4::bool OR author_id = $5::bigint)
-RETURNING id, author_id, title, cover_image, status, featured, view_count, update_at, publish_at
+  AND ($4::bool OR author_id = $5::bigint OR id = ANY(
+    SELECT post_id FROM post_collaborators
+    WHERE user_id = $5::bigint AND accepted = true
+  ))
+  AND (NOT $6::bool OR reviewer_id IS NULL
+    OR reviewer_id = $5::bigint)
+RETURNING id, author_id, title, cover_image, status, featured, view_count, update_at, publish_at, reviewer_id, version, visibility, password_hash, trash_status, delete_at
 `
 
 type UpdatePostStatusParams struct {
-	Status    string   `json:"status"`
-	Ids       []int64  `json:"ids"`
-	OldStatus []string `json:"old_status"`
-	IsAdmin   bool     `json:"is_admin"`
-	AuthorID  int64    `json:"author_id"`
+	Status     string   `json:"status"`
+	Ids        []int64  `json:"ids"`
+	OldStatus  []string `json:"old_status"`
+	IsAdmin    bool     `json:"is_admin"`
+	AuthorID   int64    `json:"author_id"`
+	CheckClaim bool     `json:"check_claim"`
 }
 
 func (q *Queries) UpdatePostStatus(ctx context.Context, arg UpdatePostStatusParams) ([]Post, error) {
@@ -723,6 +1090,7 @@ func (q *Queries) UpdatePostStatus(ctx context.Context, arg UpdatePostStatusPara
 		pq.Array(arg.OldStatus),
 		arg.IsAdmin,
 		arg.AuthorID,
+		arg.CheckClaim,
 	)
 	if err != nil {
 		return nil, err
@@ -741,6 +1109,12 @@ func (q *Queries) UpdatePostStatus(ctx context.Context, arg UpdatePostStatusPara
 			&i.ViewCount,
 			&i.UpdateAt,
 			&i.PublishAt,
+			&i.ReviewerID,
+			&i.Version,
+			&i.Visibility,
+			&i.PasswordHash,
+			&i.TrashStatus,
+			&i.DeleteAt,
 		); err != nil {
 			return nil, err
 		}
@@ -754,3 +1128,49 @@ func (q *Queries) UpdatePostStatus(ctx context.Context, arg UpdatePostStatusPara
 	}
 	return items, nil
 }
+
+const updatePostVisibility = `-- name: UpdatePostVisibility :one
+UPDATE posts
+SET visibility = $1::varchar,
+  password_hash = $2::varchar
+WHERE id = $3::bigint
+  AND ($4::bool OR author_id = $5::bigint)
+RETURNING id, author_id, title, cover_image, status, featured, view_count, update_at, publish_at, reviewer_id, version, visibility, password_hash, trash_status, delete_at
+`
+
+type UpdatePostVisibilityParams struct {
+	Visibility   string `json:"visibility"`
+	PasswordHash string `json:"password_hash"`
+	ID           int64  `json:"id"`
+	IsAdmin      bool   `json:"is_admin"`
+	AuthorID     int64  `json:"author_id"`
+}
+
+func (q *Queries) UpdatePostVisibility(ctx context.Context, arg UpdatePostVisibilityParams) (Post, error) {
+	row := q.db.QueryRowContext(ctx, updatePostVisibility,
+		arg.Visibility,
+		arg.PasswordHash,
+		arg.ID,
+		arg.IsAdmin,
+		arg.AuthorID,
+	)
+	var i Post
+	err := row.Scan(
+		&i.ID,
+		&i.AuthorID,
+		&i.Title,
+		&i.CoverImage,
+		&i.Status,
+		&i.Featured,
+		&i.ViewCount,
+		&i.UpdateAt,
+		&i.PublishAt,
+		&i.ReviewerID,
+		&i.Version,
+		&i.Visibility,
+		&i.PasswordHash,
+		&i.TrashStatus,
+		&i.DeleteAt,
+	)
+	return i, err
+}
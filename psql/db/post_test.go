@@ -4,21 +4,25 @@ import (
 	"context"
 	"database/sql"
 	"testing"
+	"time"
 
 	"github.com/bwen19/blog/util"
 	"github.com/stretchr/testify/require"
 )
 
-func TestDeletePosts(t *testing.T) {
+func TestTrashPost(t *testing.T) {
 	user := createRandomUser(t)
 	post1 := createRandomPost(t, user)
 
-	arg := DeletePostParams{
+	arg := TrashPostParams{
 		ID:       post1.ID,
 		AuthorID: post1.AuthorID,
 	}
-	err := testStore.DeletePost(context.Background(), arg)
+	trashed, err := testStore.TrashPost(context.Background(), arg)
 	require.NoError(t, err)
+	require.Equal(t, "trash", trashed.Status)
+	require.Equal(t, post1.Status, trashed.TrashStatus)
+	require.True(t, trashed.DeleteAt.Valid)
 
 	getArg := GetPostParams{
 		PostID:   post1.ID,
@@ -30,6 +34,60 @@ func TestDeletePosts(t *testing.T) {
 	require.Error(t, err)
 	require.EqualError(t, err, sql.ErrNoRows.Error())
 	require.Empty(t, post2)
+
+	restoreArg := RestorePostParams{
+		ID:       post1.ID,
+		AuthorID: createRandomUser(t).ID,
+	}
+	_, err = testStore.RestorePost(context.Background(), restoreArg)
+	require.EqualError(t, err, sql.ErrNoRows.Error())
+
+	restoreArg.AuthorID = post1.AuthorID
+	restored, err := testStore.RestorePost(context.Background(), restoreArg)
+	require.NoError(t, err)
+	require.Equal(t, post1.Status, restored.Status)
+	require.False(t, restored.DeleteAt.Valid)
+
+	post2, err = testStore.GetPost(context.Background(), getArg)
+	require.NoError(t, err)
+	require.Equal(t, post1.ID, post2.ID)
+}
+
+func TestTrashPublishedPost(t *testing.T) {
+	user := createRandomUser(t)
+	post := createRandomPost(t, user)
+
+	arg1 := UpdatePostStatusParams{
+		Ids:       []int64{post.ID},
+		OldStatus: []string{"draft"},
+		Status:    "publish",
+		IsAdmin:   true,
+	}
+	_, err := testStore.UpdatePostStatus(context.Background(), arg1)
+	require.NoError(t, err)
+
+	// Authors cannot trash published posts, admins can trash any post
+	arg2 := TrashPostParams{
+		ID:       post.ID,
+		AuthorID: user.ID,
+	}
+	_, err = testStore.TrashPost(context.Background(), arg2)
+	require.EqualError(t, err, sql.ErrNoRows.Error())
+
+	arg2.IsAdmin = true
+	trashed, err := testStore.TrashPost(context.Background(), arg2)
+	require.NoError(t, err)
+	require.Equal(t, "publish", trashed.TrashStatus)
+
+	_, err = testStore.ReadPost(context.Background(), ReadPostParams{PostID: post.ID})
+	require.EqualError(t, err, sql.ErrNoRows.Error())
+
+	nrows, err := testStore.PurgeTrashedPosts(context.Background(), trashed.DeleteAt.Time.Add(time.Second))
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, nrows, int64(1))
+
+	_, err = testStore.RestorePost(context.Background(), RestorePostParams{ID: post.ID, IsAdmin: true})
+	require.EqualError(t, err, sql.ErrNoRows.Error())
 }
 
 func TestUpdatePost(t *testing.T) {
@@ -40,6 +98,7 @@ func TestUpdatePost(t *testing.T) {
 		ID:       post1.ID,
 		Title:    sql.NullString{String: "test", Valid: true},
 		AuthorID: post1.AuthorID,
+		Version:  post1.Version,
 	}
 
 	post2, err := testStore.UpdatePost(context.Background(), arg)
@@ -67,6 +126,7 @@ func TestNotUpdatePost(t *testing.T) {
 		ID:       post1.ID,
 		Title:    sql.NullString{String: "test", Valid: false},
 		AuthorID: post1.AuthorID,
+		Version:  post1.Version,
 	}
 
 	post2, err := testStore.UpdatePost(context.Background(), arg)
@@ -77,6 +137,33 @@ func TestNotUpdatePost(t *testing.T) {
 	require.Equal(t, post1.Title, post2.Title)
 }
 
+func TestUpdatePostStaleVersion(t *testing.T) {
+	user := createRandomUser(t)
+	post1 := createRandomPost(t, user)
+	require.Equal(t, int64(1), post1.Version)
+
+	arg1 := UpdatePostParams{
+		ID:       post1.ID,
+		Title:    sql.NullString{String: "test", Valid: true},
+		AuthorID: post1.AuthorID,
+		Version:  post1.Version,
+	}
+	post2, err := testStore.UpdatePost(context.Background(), arg1)
+	require.NoError(t, err)
+	require.Equal(t, post1.Version+1, post2.Version)
+
+	_, err = testStore.UpdatePost(context.Background(), arg1)
+	require.EqualError(t, err, sql.ErrNoRows.Error())
+
+	arg2 := GetPostVersionParams{
+		ID:       post1.ID,
+		AuthorID: post1.AuthorID,
+	}
+	version, err := testStore.GetPostVersion(context.Background(), arg2)
+	require.NoError(t, err)
+	require.Equal(t, post2.Version, version)
+}
+
 func TestUpdatePostStatus(t *testing.T) {
 	user := createRandomUser(t)
 	post1 := createRandomPost(t, user)
@@ -241,7 +328,7 @@ func TestReadPost(t *testing.T) {
 	require.NoError(t, err)
 	require.NotEmpty(t, p1)
 	require.Equal(t, post.ID, p1.ID)
-	require.Equal(t, post.ViewCount+1, p1.ViewCount)
+	require.Equal(t, post.ViewCount, p1.ViewCount)
 	require.Equal(t, false, p1.Followed.Valid)
 
 	arg2 := CreatePostStarParams{
@@ -255,9 +342,16 @@ func TestReadPost(t *testing.T) {
 	require.NoError(t, err)
 	require.NotEmpty(t, p1)
 	require.Equal(t, post.ID, p1.ID)
-	require.Equal(t, post.ViewCount+2, p1.ViewCount)
+	require.Equal(t, post.ViewCount, p1.ViewCount)
 	require.Equal(t, false, p1.Followed.Valid)
 	require.Equal(t, int64(1), p1.StarCount)
+	require.Equal(t, false, p1.Starred.Valid)
+
+	arg.SelfID = post.AuthorID
+	p1, err = testStore.ReadPost(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, true, p1.Starred.Valid)
+	arg.SelfID = 0
 
 	arg3 := DeletePostStarParams{
 		PostID: post.ID,
@@ -270,7 +364,78 @@ func TestReadPost(t *testing.T) {
 	require.NoError(t, err)
 	require.NotEmpty(t, p1)
 	require.Equal(t, post.ID, p1.ID)
-	require.Equal(t, post.ViewCount+3, p1.ViewCount)
+	require.Equal(t, post.ViewCount, p1.ViewCount)
 	require.Equal(t, false, p1.Followed.Valid)
 	require.Equal(t, int64(0), p1.StarCount)
 }
+
+func TestPostVisibility(t *testing.T) {
+	author := createRandomUser(t)
+	follower := createRandomUser(t)
+	createRandomFollow(t, author.ID, follower.ID)
+
+	visibilities := []string{"public", "unlisted", "followers", "password"}
+	postIDs := []int64{}
+	for _, visibility := range visibilities {
+		post := createRandomPost(t, author)
+		postIDs = append(postIDs, post.ID)
+
+		arg := UpdatePostVisibilityParams{
+			ID:         post.ID,
+			Visibility: visibility,
+			AuthorID:   author.ID,
+		}
+		p, err := testStore.UpdatePostVisibility(context.Background(), arg)
+		require.NoError(t, err)
+		require.Equal(t, visibility, p.Visibility)
+	}
+
+	_, err := testStore.UpdatePostVisibility(context.Background(), UpdatePostVisibilityParams{
+		ID:         postIDs[0],
+		Visibility: "unlisted",
+		AuthorID:   follower.ID,
+	})
+	require.EqualError(t, err, sql.ErrNoRows.Error())
+
+	arg1 := UpdatePostStatusParams{
+		Ids:       postIDs,
+		OldStatus: []string{"draft"},
+		Status:    "publish",
+		IsAdmin:   true,
+	}
+	_, err = testStore.UpdatePostStatus(context.Background(), arg1)
+	require.NoError(t, err)
+
+	listed := func(selfID int64) []string {
+		arg := GetPostsParams{
+			Limit:         10,
+			AnyFeatured:   true,
+			AuthorID:      author.ID,
+			AnyCategory:   true,
+			AnyTag:        true,
+			AnyKeyword:    true,
+			PublishAtDesc: true,
+			SelfID:        selfID,
+		}
+		posts, err := testStore.GetPosts(context.Background(), arg)
+		require.NoError(t, err)
+
+		result := []string{}
+		for _, post := range posts {
+			result = append(result, post.Visibility)
+		}
+		return result
+	}
+	require.ElementsMatch(t, []string{"public", "password"}, listed(0))
+	require.ElementsMatch(t, []string{"public", "followers", "password"}, listed(follower.ID))
+	require.ElementsMatch(t, []string{"public", "followers", "password"}, listed(author.ID))
+
+	// Unlisted posts can still be read by link
+	post, err := testStore.ReadPost(context.Background(), ReadPostParams{PostID: postIDs[1]})
+	require.NoError(t, err)
+	require.Equal(t, "unlisted", post.Visibility)
+
+	post, err = testStore.ReadPost(context.Background(), ReadPostParams{PostID: postIDs[2], SelfID: follower.ID})
+	require.NoError(t, err)
+	require.True(t, post.Followed.Valid)
+}
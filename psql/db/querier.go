@@ -6,73 +6,173 @@ package db
 
 import (
 	"context"
+	"time"
 
 	"github.com/google/uuid"
 )
 
 type Querier interface {
+	AcceptPostCollaborator(ctx context.Context, arg AcceptPostCollaboratorParams) (PostCollaborator, error)
+	AddSeriesPost(ctx context.Context, arg AddSeriesPostParams) (SeriesPost, error)
+	ApplyPostRevision(ctx context.Context, postID int64) (Post, error)
+	ApplyPostRevisionContent(ctx context.Context, postID int64) error
+	AssignPostReviewer(ctx context.Context, arg AssignPostReviewerParams) (Post, error)
+	AssignRevisionReviewer(ctx context.Context, arg AssignRevisionReviewerParams) (PostRevision, error)
+	CanUsePostCover(ctx context.Context, arg CanUsePostCoverParams) (bool, error)
+	ClaimMediaVariants(ctx context.Context, id int64) (int64, error)
 	CreateCategory(ctx context.Context, name string) (Category, error)
 	CreateComment(ctx context.Context, arg CreateCommentParams) (CreateCommentRow, error)
 	CreateCommentStar(ctx context.Context, arg CreateCommentStarParams) error
 	CreateFollow(ctx context.Context, arg CreateFollowParams) error
+	CreateImportRecord(ctx context.Context, arg CreateImportRecordParams) error
+	CreateMedia(ctx context.Context, arg CreateMediaParams) (Media, error)
+	CreateMediaVariant(ctx context.Context, arg CreateMediaVariantParams) (MediaVariant, error)
 	CreateNotification(ctx context.Context, arg CreateNotificationParams) error
 	CreatePost(ctx context.Context, arg CreatePostParams) (Post, error)
 	CreatePostCategories(ctx context.Context, arg CreatePostCategoriesParams) ([]CreatePostCategoriesRow, error)
+	CreatePostCollaborator(ctx context.Context, arg CreatePostCollaboratorParams) (PostCollaborator, error)
 	CreatePostContent(ctx context.Context, arg CreatePostContentParams) (PostContent, error)
+	CreatePostRevision(ctx context.Context, arg CreatePostRevisionParams) error
 	CreatePostStar(ctx context.Context, arg CreatePostStarParams) error
 	CreatePostTags(ctx context.Context, arg CreatePostTagsParams) ([]CreatePostTagsRow, error)
+	CreateReadingList(ctx context.Context, arg CreateReadingListParams) (ReadingList, error)
+	CreateReviewNote(ctx context.Context, arg CreateReviewNoteParams) (ReviewNote, error)
+	CreateSeries(ctx context.Context, arg CreateSeriesParams) (Series, error)
 	CreateSession(ctx context.Context, arg CreateSessionParams) (Session, error)
 	CreateTag(ctx context.Context, name string) (Tag, error)
 	CreateUser(ctx context.Context, arg CreateUserParams) (User, error)
+	DeleteBookmark(ctx context.Context, arg DeleteBookmarkParams) (int64, error)
 	DeleteCategories(ctx context.Context, ids []int64) (int64, error)
 	DeleteComment(ctx context.Context, arg DeleteCommentParams) error
 	DeleteCommentStar(ctx context.Context, arg DeleteCommentStarParams) error
 	DeleteExpiredSessions(ctx context.Context) error
 	DeleteFollow(ctx context.Context, arg DeleteFollowParams) error
+	DeleteMedia(ctx context.Context, arg DeleteMediaParams) (Media, error)
+	DeleteMediaByPath(ctx context.Context, arg DeleteMediaByPathParams) error
 	DeleteMessages(ctx context.Context, ids []int64) (int64, error)
 	DeleteNotifications(ctx context.Context, arg DeleteNotificationsParams) (int64, error)
-	DeletePost(ctx context.Context, arg DeletePostParams) error
+	DeleteOrphanedMedia(ctx context.Context, arg DeleteOrphanedMediaParams) ([]Media, error)
 	DeletePostCategories(ctx context.Context, arg DeletePostCategoriesParams) error
+	DeletePostCollaborator(ctx context.Context, arg DeletePostCollaboratorParams) (int64, error)
+	DeletePostRevision(ctx context.Context, arg DeletePostRevisionParams) (int64, error)
 	DeletePostStar(ctx context.Context, arg DeletePostStarParams) error
 	DeletePostTags(ctx context.Context, arg DeletePostTagsParams) error
+	DeletePostViewers(ctx context.Context, before time.Time) (int64, error)
+	DeleteReadingList(ctx context.Context, arg DeleteReadingListParams) (int64, error)
+	DeleteSeries(ctx context.Context, arg DeleteSeriesParams) (int64, error)
+	DeleteSeriesPost(ctx context.Context, arg DeleteSeriesPostParams) (int64, error)
 	DeleteSession(ctx context.Context, arg DeleteSessionParams) error
 	DeleteSessions(ctx context.Context, ids []uuid.UUID) (int64, error)
+	DeleteStalePostRankings(ctx context.Context) (int64, error)
 	DeleteTags(ctx context.Context, ids []int64) (int64, error)
 	DeleteUsers(ctx context.Context, ids []int64) (int64, error)
+	FlushPostReads(ctx context.Context, arg FlushPostReadsParams) error
+	FlushPostViews(ctx context.Context, arg FlushPostViewsParams) (int64, error)
 	GetCategories(ctx context.Context) ([]Category, error)
+	GetCategoriesByIDs(ctx context.Context, ids []int64) ([]Category, error)
+	GetCategoryByName(ctx context.Context, name string) (Category, error)
+	GetComment(ctx context.Context, id int64) (Comment, error)
 	GetFeaturedPosts(ctx context.Context, limit int32) ([]GetFeaturedPostsRow, error)
+	GetFeed(ctx context.Context, arg GetFeedParams) ([]GetFeedRow, error)
+	GetFeedVisit(ctx context.Context, userID int64) (time.Time, error)
+	GetImportRecord(ctx context.Context, arg GetImportRecordParams) (int64, error)
+	GetMedia(ctx context.Context, arg GetMediaParams) (Media, error)
+	GetMediaByPath(ctx context.Context, arg GetMediaByPathParams) (Media, error)
+	GetMediaOwner(ctx context.Context, path string) (int64, error)
 	GetPost(ctx context.Context, arg GetPostParams) (GetPostRow, error)
+	GetPostAccess(ctx context.Context, arg GetPostAccessParams) (GetPostAccessRow, error)
+	GetPostByID(ctx context.Context, id int64) (Post, error)
 	GetPostCategories(ctx context.Context, postID int64) ([]Category, error)
+	GetPostCollaborator(ctx context.Context, arg GetPostCollaboratorParams) (PostCollaborator, error)
+	GetPostContent(ctx context.Context, id int64) (string, error)
+	GetPostRevision(ctx context.Context, arg GetPostRevisionParams) (PostRevision, error)
+	GetPostSeries(ctx context.Context, arg GetPostSeriesParams) (GetPostSeriesRow, error)
 	GetPostTags(ctx context.Context, postID int64) ([]Tag, error)
+	GetPostVersion(ctx context.Context, arg GetPostVersionParams) (int64, error)
 	GetPosts(ctx context.Context, arg GetPostsParams) ([]GetPostsRow, error)
+	GetRelatedPosts(ctx context.Context, arg GetRelatedPostsParams) ([]GetRelatedPostsRow, error)
+	GetSeries(ctx context.Context, id int64) (GetSeriesRow, error)
 	GetSession(ctx context.Context, id uuid.UUID) (Session, error)
+	GetTagsByIDs(ctx context.Context, ids []int64) ([]Tag, error)
 	GetTagsByName(ctx context.Context, name string) (Tag, error)
 	GetUnreadCount(ctx context.Context, userID int64) (int64, error)
 	GetUser(ctx context.Context, id int64) (User, error)
 	GetUserByEmail(ctx context.Context, email string) (User, error)
 	GetUserByUsername(ctx context.Context, username string) (User, error)
 	GetUserProfile(ctx context.Context, arg GetUserProfileParams) (GetUserProfileRow, error)
+	ImportComment(ctx context.Context, arg ImportCommentParams) (Comment, error)
+	ImportPost(ctx context.Context, arg ImportPostParams) (Post, error)
+	ListAdminIDs(ctx context.Context) ([]int64, error)
+	ListBookmarks(ctx context.Context, arg ListBookmarksParams) ([]ListBookmarksRow, error)
 	ListCategories(ctx context.Context, arg ListCategoriesParams) ([]ListCategoriesRow, error)
 	ListComments(ctx context.Context, arg ListCommentsParams) ([]ListCommentsRow, error)
+	ListDailyStats(ctx context.Context, arg ListDailyStatsParams) ([]ListDailyStatsRow, error)
 	ListFollowers(ctx context.Context, arg ListFollowersParams) ([]ListFollowersRow, error)
 	ListFollowings(ctx context.Context, arg ListFollowingsParams) ([]ListFollowingsRow, error)
+	ListMedia(ctx context.Context, arg ListMediaParams) ([]ListMediaRow, error)
+	ListMediaPaths(ctx context.Context) ([]string, error)
+	ListMediaVariants(ctx context.Context, mediaIds []int64) ([]MediaVariant, error)
 	ListMessages(ctx context.Context, arg ListMessagesParams) ([]ListMessagesRow, error)
 	ListNotifications(ctx context.Context, arg ListNotificationsParams) ([]ListNotificationsRow, error)
+	ListPostAccess(ctx context.Context, arg ListPostAccessParams) ([]ListPostAccessRow, error)
+	ListPostCollaborators(ctx context.Context, postID int64) ([]ListPostCollaboratorsRow, error)
+	ListPostDailyViews(ctx context.Context, arg ListPostDailyViewsParams) ([]ListPostDailyViewsRow, error)
 	ListPosts(ctx context.Context, arg ListPostsParams) ([]ListPostsRow, error)
+	ListReadingLists(ctx context.Context, userID int64) ([]ListReadingListsRow, error)
+	ListRelatedPostIDs(ctx context.Context, arg ListRelatedPostIDsParams) ([]int64, error)
 	ListReplies(ctx context.Context, arg ListRepliesParams) ([]ListRepliesRow, error)
+	ListReviewNotes(ctx context.Context, postID int64) ([]ListReviewNotesRow, error)
+	ListReviewQueue(ctx context.Context, arg ListReviewQueueParams) ([]ListReviewQueueRow, error)
+	ListSeries(ctx context.Context, arg ListSeriesParams) ([]ListSeriesRow, error)
+	ListSeriesPosts(ctx context.Context, arg ListSeriesPostsParams) ([]ListSeriesPostsRow, error)
 	ListSessions(ctx context.Context, arg ListSessionsParams) ([]ListSessionsRow, error)
+	ListSitemapAuthors(ctx context.Context) ([]ListSitemapAuthorsRow, error)
+	ListSitemapCategories(ctx context.Context) ([]ListSitemapCategoriesRow, error)
+	ListSitemapPosts(ctx context.Context) ([]ListSitemapPostsRow, error)
+	ListSitemapTags(ctx context.Context) ([]ListSitemapTagsRow, error)
+	ListStargazers(ctx context.Context, arg ListStargazersParams) ([]ListStargazersRow, error)
+	ListStarredPosts(ctx context.Context, arg ListStarredPostsParams) ([]ListStarredPostsRow, error)
+	ListStaticAuthors(ctx context.Context) ([]ListStaticAuthorsRow, error)
+	ListStaticPosts(ctx context.Context) ([]ListStaticPostsRow, error)
+	ListSyncPostIDs(ctx context.Context, arg ListSyncPostIDsParams) ([]int64, error)
 	ListTags(ctx context.Context, arg ListTagsParams) ([]ListTagsRow, error)
+	ListTopPosts(ctx context.Context, arg ListTopPostsParams) ([]ListTopPostsRow, error)
+	ListTrafficSources(ctx context.Context, arg ListTrafficSourcesParams) ([]ListTrafficSourcesRow, error)
+	ListTrendingTags(ctx context.Context, num int32) ([]ListTrendingTagsRow, error)
+	ListUnreferencedMedia(ctx context.Context, keepPaths []string) ([]int64, error)
 	ListUsers(ctx context.Context, arg ListUsersParams) ([]ListUsersRow, error)
+	ListVariantsByPaths(ctx context.Context, paths []string) ([]ListVariantsByPathsRow, error)
+	ListVariantsPendingMedia(ctx context.Context, limit int32) ([]Media, error)
 	MarkAllRead(ctx context.Context, userID int64) error
+	MarkMediaVariantsPending(ctx context.Context, id int64) error
 	MarkNotifications(ctx context.Context, arg MarkNotificationsParams) (int64, error)
+	MarkOrphanedMedia(ctx context.Context, ids []int64) ([]Media, error)
+	PurgeTrashedPosts(ctx context.Context, deleteBefore time.Time) (int64, error)
 	ReadPost(ctx context.Context, arg ReadPostParams) (ReadPostRow, error)
+	RefreshPostRankings(ctx context.Context, arg RefreshPostRankingsParams) (int64, error)
+	RestorePost(ctx context.Context, arg RestorePostParams) (Post, error)
+	SubmitPostRevisions(ctx context.Context, arg SubmitPostRevisionsParams) ([]PostRevision, error)
+	TrashPost(ctx context.Context, arg TrashPostParams) (Post, error)
+	TryRankingLock(ctx context.Context, lockKey int64) (bool, error)
+	UnmarkReferencedMedia(ctx context.Context, ids []int64) (int64, error)
 	UpdateCategory(ctx context.Context, arg UpdateCategoryParams) (Category, error)
 	UpdatePost(ctx context.Context, arg UpdatePostParams) (Post, error)
 	UpdatePostContent(ctx context.Context, arg UpdatePostContentParams) (PostContent, error)
 	UpdatePostFeature(ctx context.Context, arg UpdatePostFeatureParams) error
+	UpdatePostRevision(ctx context.Context, arg UpdatePostRevisionParams) (PostRevision, error)
 	UpdatePostStatus(ctx context.Context, arg UpdatePostStatusParams) ([]Post, error)
+	UpdatePostVisibility(ctx context.Context, arg UpdatePostVisibilityParams) (Post, error)
+	UpdateReadingList(ctx context.Context, arg UpdateReadingListParams) (ReadingList, error)
+	UpdateReadingListPositions(ctx context.Context, arg UpdateReadingListPositionsParams) (int64, error)
+	UpdateReviewedPost(ctx context.Context, arg UpdateReviewedPostParams) (Post, error)
+	UpdateReviewedRevision(ctx context.Context, arg UpdateReviewedRevisionParams) (PostRevision, error)
+	UpdateSeriesPositions(ctx context.Context, arg UpdateSeriesPositionsParams) (int64, error)
+	UpdateSyncedPost(ctx context.Context, arg UpdateSyncedPostParams) (Post, error)
+	UpdateSyncedPostContent(ctx context.Context, arg UpdateSyncedPostContentParams) error
 	UpdateTag(ctx context.Context, arg UpdateTagParams) (Tag, error)
 	UpdateUser(ctx context.Context, arg UpdateUserParams) (User, error)
+	UpsertBookmark(ctx context.Context, arg UpsertBookmarkParams) (Bookmark, error)
+	UpsertFeedVisit(ctx context.Context, userID int64) error
 }
 
 var _ Querier = (*Queries)(nil)
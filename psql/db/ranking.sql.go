@@ -0,0 +1,143 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.15.0
+// source: ranking.sql
+
+package db
+
+import (
+	"context"
+	"time"
+)
+
+const deleteStalePostRankings = `-- name: DeleteStalePostRankings :execrows
+DELETE FROM post_rankings
+WHERE post_id NOT IN (
+  SELECT id FROM posts WHERE status = 'publish'
+)
+`
+
+func (q *Queries) DeleteStalePostRankings(ctx context.Context) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteStalePostRankings)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const listTrendingTags = `-- name: ListTrendingTags :many
+SELECT t.id, t.name, sum(pr.score)::float8 score
+FROM post_rankings pr
+JOIN post_tags pt ON pt.post_id = pr.post_id
+JOIN tags t ON t.id = pt.tag_id
+GROUP BY t.id, t.name
+HAVING sum(pr.score) > 0
+ORDER BY score DESC, t.id ASC
+LIMIT $1::int
+`
+
+type ListTrendingTagsRow struct {
+	ID    int64   `json:"id"`
+	Name  string  `json:"name"`
+	Score float64 `json:"score"`
+}
+
+func (q *Queries) ListTrendingTags(ctx context.Context, num int32) ([]ListTrendingTagsRow, error) {
+	rows, err := q.db.QueryContext(ctx, listTrendingTags, num)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListTrendingTagsRow{}
+	for rows.Next() {
+		var i ListTrendingTagsRow
+		if err := rows.Scan(&i.ID, &i.Name, &i.Score); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const refreshPostRankings = `-- name: RefreshPostRankings :execrows
+WITH Param_CTE AS (
+  SELECT $1::float8 half_life,
+      $2::float8 view_weight,
+      $3::float8 star_weight,
+      $4::float8 comment_weight,
+      $5::timestamptz since
+),
+View_CTE AS (
+  SELECT pdv.post_id, sum(pdv.views * pm.view_weight * exp(-ln(2.0)
+      * extract(epoch FROM now() - (pdv.day + interval '12 hours')) / 3600 / pm.half_life)) score
+  FROM post_daily_views pdv
+  CROSS JOIN Param_CTE pm
+  WHERE pdv.day >= pm.since::date
+  GROUP BY pdv.post_id
+),
+Star_CTE AS (
+  SELECT ps.post_id, sum(pm.star_weight * exp(-ln(2.0)
+      * extract(epoch FROM now() - ps.create_at) / 3600 / pm.half_life)) score
+  FROM post_stars ps
+  CROSS JOIN Param_CTE pm
+  WHERE ps.create_at >= pm.since
+  GROUP BY ps.post_id
+),
+Comment_CTE AS (
+  SELECT cm.post_id, sum(pm.comment_weight * exp(-ln(2.0)
+      * extract(epoch FROM now() - cm.create_at) / 3600 / pm.half_life)) score
+  FROM comments cm
+  CROSS JOIN Param_CTE pm
+  WHERE cm.create_at >= pm.since
+  GROUP BY cm.post_id
+)
+INSERT INTO post_rankings (post_id, score, update_at)
+SELECT p.id, coalesce(vc.score, 0) + coalesce(sc.score, 0) + coalesce(cc.score, 0), now()
+FROM posts p
+LEFT JOIN View_CTE vc ON vc.post_id = p.id
+LEFT JOIN Star_CTE sc ON sc.post_id = p.id
+LEFT JOIN Comment_CTE cc ON cc.post_id = p.id
+WHERE p.status = 'publish'
+ORDER BY p.id
+ON CONFLICT (post_id) DO UPDATE
+SET score = excluded.score, update_at = excluded.update_at
+`
+
+type RefreshPostRankingsParams struct {
+	HalfLife      float64   `json:"half_life"`
+	ViewWeight    float64   `json:"view_weight"`
+	StarWeight    float64   `json:"star_weight"`
+	CommentWeight float64   `json:"comment_weight"`
+	Since         time.Time `json:"since"`
+}
+
+func (q *Queries) RefreshPostRankings(ctx context.Context, arg RefreshPostRankingsParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, refreshPostRankings,
+		arg.HalfLife,
+		arg.ViewWeight,
+		arg.StarWeight,
+		arg.CommentWeight,
+		arg.Since,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const tryRankingLock = `-- name: TryRankingLock :one
+SELECT pg_try_advisory_xact_lock($1::bigint)::bool
+`
+
+func (q *Queries) TryRankingLock(ctx context.Context, lockKey int64) (bool, error) {
+	row := q.db.QueryRowContext(ctx, tryRankingLock, lockKey)
+	var column_1 bool
+	err := row.Scan(&column_1)
+	return column_1, err
+}
@@ -0,0 +1,80 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpdateHotRankings(t *testing.T) {
+	author := createRandomUser(t)
+	reader := createRandomUser(t)
+	tag := createRandomTag(t)
+
+	postIDs := []int64{}
+	for i := 0; i < 2; i++ {
+		post := createRandomPost(t, author)
+		postIDs = append(postIDs, post.ID)
+	}
+
+	_, err := testStore.SetPostTags(context.Background(), SetPostTagsParams{
+		PostID: postIDs[1],
+		TagIDs: []int64{tag.ID},
+	})
+	require.NoError(t, err)
+
+	arg1 := UpdatePostStatusParams{
+		Ids:       postIDs,
+		Status:    "publish",
+		OldStatus: []string{"draft"},
+		IsAdmin:   true,
+	}
+	_, err = testStore.UpdatePostStatus(context.Background(), arg1)
+	require.NoError(t, err)
+
+	err = testStore.CreatePostStar(context.Background(), CreatePostStarParams{
+		PostID: postIDs[1],
+		UserID: reader.ID,
+	})
+	require.NoError(t, err)
+
+	arg2 := RefreshPostRankingsParams{
+		HalfLife:      24,
+		ViewWeight:    1,
+		StarWeight:    5,
+		CommentWeight: 3,
+		Since:         time.Now().Add(-time.Hour),
+	}
+	ok, err := testStore.UpdateHotRankings(context.Background(), arg2)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	arg3 := GetPostsParams{
+		Limit:        10,
+		AnyFeatured:  true,
+		AuthorID:     author.ID,
+		AnyCategory:  true,
+		AnyTag:       true,
+		AnyKeyword:   true,
+		HotScoreDesc: true,
+	}
+	posts, err := testStore.GetPosts(context.Background(), arg3)
+	require.NoError(t, err)
+	require.Len(t, posts, 2)
+	require.Equal(t, postIDs[1], posts[0].ID)
+	require.Greater(t, posts[0].HotScore, posts[1].HotScore)
+
+	tags, err := testStore.ListTrendingTags(context.Background(), 100)
+	require.NoError(t, err)
+
+	found := false
+	for _, trending := range tags {
+		if trending.ID == tag.ID {
+			found = true
+			require.Greater(t, trending.Score, float64(0))
+		}
+	}
+	require.True(t, found)
+}
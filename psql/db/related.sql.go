@@ -0,0 +1,181 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.15.0
+// source: related.sql
+
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const getRelatedPosts = `-- name: GetRelatedPosts :many
+SELECT p.id, p.title, p.cover_image, p.view_count, p.publish_at,
+    p.author_id, u.username, u.avatar
+FROM posts p
+JOIN users u ON u.id = p.author_id
+WHERE p.id = ANY($1::bigint[]) AND p.status = 'publish'
+  AND p.visibility = ANY('{public, password}'::varchar[])
+  AND NOT EXISTS (
+    SELECT 1 FROM post_stars ps
+    WHERE ps.post_id = p.id AND ps.user_id = $2::bigint
+  )
+  AND NOT EXISTS (
+    SELECT 1 FROM post_reads pr
+    WHERE pr.post_id = p.id AND pr.user_id = $2::bigint
+      AND pr.read_at > $3::timestamptz
+  )
+ORDER BY array_position($1::bigint[], p.id)
+LIMIT $4::int
+`
+
+type GetRelatedPostsParams struct {
+	Ids       []int64   `json:"ids"`
+	SelfID    int64     `json:"self_id"`
+	ReadSince time.Time `json:"read_since"`
+	Num       int32     `json:"num"`
+}
+
+type GetRelatedPostsRow struct {
+	ID         int64     `json:"id"`
+	Title      string    `json:"title"`
+	CoverImage string    `json:"cover_image"`
+	ViewCount  int64     `json:"view_count"`
+	PublishAt  time.Time `json:"publish_at"`
+	AuthorID   int64     `json:"author_id"`
+	Username   string    `json:"username"`
+	Avatar     string    `json:"avatar"`
+}
+
+func (q *Queries) GetRelatedPosts(ctx context.Context, arg GetRelatedPostsParams) ([]GetRelatedPostsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getRelatedPosts,
+		pq.Array(arg.Ids),
+		arg.SelfID,
+		arg.ReadSince,
+		arg.Num,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []GetRelatedPostsRow{}
+	for rows.Next() {
+		var i GetRelatedPostsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Title,
+			&i.CoverImage,
+			&i.ViewCount,
+			&i.PublishAt,
+			&i.AuthorID,
+			&i.Username,
+			&i.Avatar,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listRelatedPostIDs = `-- name: ListRelatedPostIDs :many
+WITH Target_CTE AS (
+  SELECT id, author_id,
+      to_tsquery('simple', coalesce(nullif(array_to_string(array(
+        SELECT quote_literal(lexeme)
+        FROM unnest(tsvector_to_array(to_tsvector('simple', title))) lexeme
+        WHERE strpos(lexeme, chr(92)) = 0
+      ), ' | '), ''), quote_literal(''))) title_query
+  FROM posts
+  WHERE id = $2::bigint
+),
+Tag_CTE AS (
+  SELECT post_id, count(*) AS shared
+  FROM post_tags
+  WHERE tag_id IN (
+    SELECT tag_id FROM post_tags WHERE post_id = $2::bigint
+  )
+  GROUP BY post_id
+),
+Category_CTE AS (
+  SELECT post_id, count(*) AS shared
+  FROM post_categories
+  WHERE category_id IN (
+    SELECT category_id FROM post_categories WHERE post_id = $2::bigint
+  )
+  GROUP BY post_id
+),
+Weight_CTE AS (
+  SELECT $3::float8 tag_weight,
+      $4::float8 category_weight,
+      $5::float8 author_weight,
+      $6::float8 text_weight
+),
+Score_CTE AS (
+  SELECT p.id, p.publish_at,
+      coalesce(tc.shared, 0) * w.tag_weight
+      + coalesce(cc.shared, 0) * w.category_weight
+      + CASE WHEN p.author_id = t.author_id THEN w.author_weight ELSE 0 END
+      + CASE WHEN w.text_weight > 0 THEN w.text_weight * ts_rank(
+          to_tsvector('simple', p.title), t.title_query) ELSE 0 END AS score
+  FROM posts p
+  CROSS JOIN Target_CTE t
+  CROSS JOIN Weight_CTE w
+  LEFT JOIN Tag_CTE tc ON tc.post_id = p.id
+  LEFT JOIN Category_CTE cc ON cc.post_id = p.id
+  WHERE p.status = 'publish' AND p.id <> t.id
+    AND p.visibility = ANY('{public, password}'::varchar[])
+)
+SELECT id FROM Score_CTE
+WHERE score > 0
+ORDER BY score DESC, publish_at DESC
+LIMIT $1::int
+`
+
+type ListRelatedPostIDsParams struct {
+	Num            int32   `json:"num"`
+	PostID         int64   `json:"post_id"`
+	TagWeight      float64 `json:"tag_weight"`
+	CategoryWeight float64 `json:"category_weight"`
+	AuthorWeight   float64 `json:"author_weight"`
+	TextWeight     float64 `json:"text_weight"`
+}
+
+func (q *Queries) ListRelatedPostIDs(ctx context.Context, arg ListRelatedPostIDsParams) ([]int64, error) {
+	rows, err := q.db.QueryContext(ctx, listRelatedPostIDs,
+		arg.Num,
+		arg.PostID,
+		arg.TagWeight,
+		arg.CategoryWeight,
+		arg.AuthorWeight,
+		arg.TextWeight,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []int64{}
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
@@ -0,0 +1,78 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRelatedPosts(t *testing.T) {
+	author := createRandomUser(t)
+	reader := createRandomUser(t)
+	tag := createRandomTag(t)
+
+	postIDs := []int64{}
+	for i := 0; i < 3; i++ {
+		post := createRandomPost(t, author)
+		postIDs = append(postIDs, post.ID)
+
+		_, err := testStore.SetPostTags(context.Background(), SetPostTagsParams{
+			PostID: post.ID,
+			TagIDs: []int64{tag.ID},
+		})
+		require.NoError(t, err)
+	}
+
+	arg1 := UpdatePostStatusParams{
+		Ids:       postIDs,
+		Status:    "publish",
+		OldStatus: []string{"draft"},
+		IsAdmin:   true,
+	}
+	_, err := testStore.UpdatePostStatus(context.Background(), arg1)
+	require.NoError(t, err)
+
+	arg2 := ListRelatedPostIDsParams{
+		Num:          10,
+		PostID:       postIDs[0],
+		TagWeight:    2,
+		AuthorWeight: 1,
+		TextWeight:   1,
+	}
+	ids, err := testStore.ListRelatedPostIDs(context.Background(), arg2)
+	require.NoError(t, err)
+	require.Contains(t, ids, postIDs[1])
+	require.Contains(t, ids, postIDs[2])
+	require.NotContains(t, ids, postIDs[0])
+
+	err = testStore.CreatePostStar(context.Background(), CreatePostStarParams{
+		PostID: postIDs[1],
+		UserID: reader.ID,
+	})
+	require.NoError(t, err)
+
+	arg3 := FlushPostReadsParams{
+		UserIds: []int64{reader.ID},
+		PostIds: []int64{postIDs[2]},
+	}
+	err = testStore.FlushPostReads(context.Background(), arg3)
+	require.NoError(t, err)
+
+	arg4 := GetRelatedPostsParams{
+		Ids:       []int64{postIDs[1], postIDs[2]},
+		SelfID:    reader.ID,
+		ReadSince: time.Now().Add(-time.Hour),
+		Num:       5,
+	}
+	posts, err := testStore.GetRelatedPosts(context.Background(), arg4)
+	require.NoError(t, err)
+	require.Empty(t, posts)
+
+	arg4.SelfID = 0
+	posts, err = testStore.GetRelatedPosts(context.Background(), arg4)
+	require.NoError(t, err)
+	require.Len(t, posts, 2)
+	require.Equal(t, postIDs[1], posts[0].ID)
+}
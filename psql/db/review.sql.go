@@ -0,0 +1,277 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.15.0
+// source: review.sql
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+const assignPostReviewer = `-- name: AssignPostReviewer :one
+UPDATE posts SET reviewer_id = $1::bigint
+WHERE id = $2::bigint AND status = 'review'
+  AND (reviewer_id IS NULL OR reviewer_id = $3::bigint
+    OR reviewer_id = $1::bigint)
+RETURNING id, author_id, title, cover_image, status, featured, view_count, update_at, publish_at, reviewer_id, version, visibility, password_hash, trash_status, delete_at
+`
+
+type AssignPostReviewerParams struct {
+	ReviewerID int64 `json:"reviewer_id"`
+	ID         int64 `json:"id"`
+	ClaimerID  int64 `json:"claimer_id"`
+}
+
+func (q *Queries) AssignPostReviewer(ctx context.Context, arg AssignPostReviewerParams) (Post, error) {
+	row := q.db.QueryRowContext(ctx, assignPostReviewer, arg.ReviewerID, arg.ID, arg.ClaimerID)
+	var i Post
+	err := row.Scan(
+		&i.ID,
+		&i.AuthorID,
+		&i.Title,
+		&i.CoverImage,
+		&i.Status,
+		&i.Featured,
+		&i.ViewCount,
+		&i.UpdateAt,
+		&i.PublishAt,
+		&i.ReviewerID,
+		&i.Version,
+		&i.Visibility,
+		&i.PasswordHash,
+		&i.TrashStatus,
+		&i.DeleteAt,
+	)
+	return i, err
+}
+
+const createReviewNote = `-- name: CreateReviewNote :one
+INSERT INTO review_notes (
+    post_id, user_id, kind, content, anchor_start, anchor_end, anchor_text
+)
+VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id, post_id, user_id, kind, content, anchor_start, anchor_end, anchor_text, create_at
+`
+
+type CreateReviewNoteParams struct {
+	PostID      int64         `json:"post_id"`
+	UserID      int64         `json:"user_id"`
+	Kind        string        `json:"kind"`
+	Content     string        `json:"content"`
+	AnchorStart sql.NullInt32 `json:"anchor_start"`
+	AnchorEnd   sql.NullInt32 `json:"anchor_end"`
+	AnchorText  string        `json:"anchor_text"`
+}
+
+func (q *Queries) CreateReviewNote(ctx context.Context, arg CreateReviewNoteParams) (ReviewNote, error) {
+	row := q.db.QueryRowContext(ctx, createReviewNote,
+		arg.PostID,
+		arg.UserID,
+		arg.Kind,
+		arg.Content,
+		arg.AnchorStart,
+		arg.AnchorEnd,
+		arg.AnchorText,
+	)
+	var i ReviewNote
+	err := row.Scan(
+		&i.ID,
+		&i.PostID,
+		&i.UserID,
+		&i.Kind,
+		&i.Content,
+		&i.AnchorStart,
+		&i.AnchorEnd,
+		&i.AnchorText,
+		&i.CreateAt,
+	)
+	return i, err
+}
+
+const listReviewNotes = `-- name: ListReviewNotes :many
+SELECT rn.id, rn.post_id, rn.user_id, rn.kind, rn.content, rn.anchor_start, rn.anchor_end, rn.anchor_text, rn.create_at, u.username, u.avatar
+FROM review_notes rn
+JOIN users u ON u.id = rn.user_id
+WHERE rn.post_id = $1::bigint
+ORDER BY rn.create_at ASC, rn.id ASC
+`
+
+type ListReviewNotesRow struct {
+	ID          int64         `json:"id"`
+	PostID      int64         `json:"post_id"`
+	UserID      int64         `json:"user_id"`
+	Kind        string        `json:"kind"`
+	Content     string        `json:"content"`
+	AnchorStart sql.NullInt32 `json:"anchor_start"`
+	AnchorEnd   sql.NullInt32 `json:"anchor_end"`
+	AnchorText  string        `json:"anchor_text"`
+	CreateAt    time.Time     `json:"create_at"`
+	Username    string        `json:"username"`
+	Avatar      string        `json:"avatar"`
+}
+
+func (q *Queries) ListReviewNotes(ctx context.Context, postID int64) ([]ListReviewNotesRow, error) {
+	rows, err := q.db.QueryContext(ctx, listReviewNotes, postID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListReviewNotesRow{}
+	for rows.Next() {
+		var i ListReviewNotesRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.PostID,
+			&i.UserID,
+			&i.Kind,
+			&i.Content,
+			&i.AnchorStart,
+			&i.AnchorEnd,
+			&i.AnchorText,
+			&i.CreateAt,
+			&i.Username,
+			&i.Avatar,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listReviewQueue = `-- name: ListReviewQueue :many
+WITH Data_CTE AS (
+  SELECT id, title, author_id, reviewer_id, update_at, false revision
+  FROM posts
+  WHERE status = 'review'
+    AND (NOT $3::bool OR reviewer_id IS NULL)
+    AND ($4::bool OR reviewer_id = $5::bigint)
+  UNION ALL
+  SELECT r.post_id, r.title, p.author_id, r.reviewer_id, r.update_at, true revision
+  FROM post_revisions r
+  JOIN posts p ON p.id = r.post_id
+  WHERE r.status = 'review' AND p.status = 'publish'
+    AND (NOT $3::bool OR r.reviewer_id IS NULL)
+    AND ($4::bool OR r.reviewer_id = $5::bigint)
+),
+Count_CTE AS (
+  SELECT count(*) total FROM Data_CTE
+)
+SELECT dc.id, dc.title, dc.author_id, dc.reviewer_id, dc.update_at, dc.revision, cnt.total, u.username, u.avatar,
+    ru.username r_username, ru.avatar r_avatar
+FROM Data_CTE dc
+CROSS JOIN Count_CTE cnt
+JOIN users u ON u.id = dc.author_id
+LEFT JOIN users ru ON ru.id = dc.reviewer_id
+ORDER BY dc.update_at ASC, dc.id ASC
+LIMIT $1
+OFFSET $2
+`
+
+type ListReviewQueueParams struct {
+	Limit       int32 `json:"limit"`
+	Offset      int32 `json:"offset"`
+	Unclaimed   bool  `json:"unclaimed"`
+	AnyReviewer bool  `json:"any_reviewer"`
+	ReviewerID  int64 `json:"reviewer_id"`
+}
+
+type ListReviewQueueRow struct {
+	ID         int64          `json:"id"`
+	Title      string         `json:"title"`
+	AuthorID   int64          `json:"author_id"`
+	ReviewerID sql.NullInt64  `json:"reviewer_id"`
+	UpdateAt   time.Time      `json:"update_at"`
+	Revision   bool           `json:"revision"`
+	Total      int64          `json:"total"`
+	Username   string         `json:"username"`
+	Avatar     string         `json:"avatar"`
+	RUsername  sql.NullString `json:"r_username"`
+	RAvatar    sql.NullString `json:"r_avatar"`
+}
+
+func (q *Queries) ListReviewQueue(ctx context.Context, arg ListReviewQueueParams) ([]ListReviewQueueRow, error) {
+	rows, err := q.db.QueryContext(ctx, listReviewQueue,
+		arg.Limit,
+		arg.Offset,
+		arg.Unclaimed,
+		arg.AnyReviewer,
+		arg.ReviewerID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListReviewQueueRow{}
+	for rows.Next() {
+		var i ListReviewQueueRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Title,
+			&i.AuthorID,
+			&i.ReviewerID,
+			&i.UpdateAt,
+			&i.Revision,
+			&i.Total,
+			&i.Username,
+			&i.Avatar,
+			&i.RUsername,
+			&i.RAvatar,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const updateReviewedPost = `-- name: UpdateReviewedPost :one
+UPDATE posts
+SET status = $1::varchar, reviewer_id = $2::bigint
+WHERE id = $3::bigint AND status = 'review'
+  AND (reviewer_id IS NULL OR reviewer_id = $2::bigint)
+RETURNING id, author_id, title, cover_image, status, featured, view_count, update_at, publish_at, reviewer_id, version, visibility, password_hash, trash_status, delete_at
+`
+
+type UpdateReviewedPostParams struct {
+	Status     string `json:"status"`
+	ReviewerID int64  `json:"reviewer_id"`
+	ID         int64  `json:"id"`
+}
+
+func (q *Queries) UpdateReviewedPost(ctx context.Context, arg UpdateReviewedPostParams) (Post, error) {
+	row := q.db.QueryRowContext(ctx, updateReviewedPost, arg.Status, arg.ReviewerID, arg.ID)
+	var i Post
+	err := row.Scan(
+		&i.ID,
+		&i.AuthorID,
+		&i.Title,
+		&i.CoverImage,
+		&i.Status,
+		&i.Featured,
+		&i.ViewCount,
+		&i.UpdateAt,
+		&i.PublishAt,
+		&i.ReviewerID,
+		&i.Version,
+		&i.Visibility,
+		&i.PasswordHash,
+		&i.TrashStatus,
+		&i.DeleteAt,
+	)
+	return i, err
+}
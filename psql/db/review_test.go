@@ -0,0 +1,121 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/bwen19/blog/util"
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomReviewPost(t *testing.T, user User) Post {
+	post := createRandomPost(t, user)
+
+	arg := UpdatePostStatusParams{
+		Ids:       []int64{post.ID},
+		Status:    "review",
+		OldStatus: []string{"draft"},
+		AuthorID:  user.ID,
+	}
+	posts, err := testStore.UpdatePostStatus(context.Background(), arg)
+	require.NoError(t, err)
+	require.Len(t, posts, 1)
+
+	return posts[0]
+}
+
+func TestClaimPost(t *testing.T) {
+	author := createRandomUser(t)
+	reviewer1 := createRandomUser(t)
+	reviewer2 := createRandomUser(t)
+	post := createRandomReviewPost(t, author)
+	require.False(t, post.ReviewerID.Valid)
+
+	arg1 := ListReviewQueueParams{
+		Limit:       5,
+		Unclaimed:   false,
+		AnyReviewer: false,
+		ReviewerID:  reviewer1.ID,
+	}
+	queue, err := testStore.ListReviewQueue(context.Background(), arg1)
+	require.NoError(t, err)
+	require.Empty(t, queue)
+
+	arg2 := AssignPostReviewerParams{
+		ID:         post.ID,
+		ReviewerID: reviewer1.ID,
+		ClaimerID:  reviewer1.ID,
+	}
+	post2, err := testStore.AssignPostReviewer(context.Background(), arg2)
+	require.NoError(t, err)
+	require.Equal(t, reviewer1.ID, post2.ReviewerID.Int64)
+
+	steal := AssignPostReviewerParams{
+		ID:         post.ID,
+		ReviewerID: reviewer2.ID,
+		ClaimerID:  reviewer2.ID,
+	}
+	_, err = testStore.AssignPostReviewer(context.Background(), steal)
+	require.EqualError(t, err, sql.ErrNoRows.Error())
+
+	queue, err = testStore.ListReviewQueue(context.Background(), arg1)
+	require.NoError(t, err)
+	require.Len(t, queue, 1)
+	require.Equal(t, post.ID, queue[0].ID)
+	require.Equal(t, author.Username, queue[0].Username)
+	require.Equal(t, reviewer1.Username, queue[0].RUsername.String)
+
+	arg3 := ReviewPostParams{
+		PostID:     post.ID,
+		ReviewerID: reviewer2.ID,
+		Status:     "publish",
+		Kind:       "approve",
+		Reason:     util.RandomText(5),
+	}
+	_, err = testStore.ReviewPost(context.Background(), arg3)
+	require.EqualError(t, err, sql.ErrNoRows.Error())
+}
+
+func TestReviewPost(t *testing.T) {
+	author := createRandomUser(t)
+	reviewer := createRandomUser(t)
+	post := createRandomReviewPost(t, author)
+
+	arg1 := CreateReviewNoteParams{
+		PostID:      post.ID,
+		UserID:      reviewer.ID,
+		Kind:        "note",
+		Content:     util.RandomText(10),
+		AnchorStart: sql.NullInt32{Int32: 0, Valid: true},
+		AnchorEnd:   sql.NullInt32{Int32: 4, Valid: true},
+		AnchorText:  "test",
+	}
+	note, err := testStore.CreateReviewNote(context.Background(), arg1)
+	require.NoError(t, err)
+	require.Equal(t, arg1.Content, note.Content)
+	require.Equal(t, arg1.AnchorText, note.AnchorText)
+
+	arg2 := ReviewPostParams{
+		PostID:     post.ID,
+		ReviewerID: reviewer.ID,
+		Status:     "revise",
+		Kind:       "changes",
+		Reason:     util.RandomText(5),
+	}
+	post2, err := testStore.ReviewPost(context.Background(), arg2)
+	require.NoError(t, err)
+	require.Equal(t, "revise", post2.Status)
+	require.Equal(t, reviewer.ID, post2.ReviewerID.Int64)
+
+	_, err = testStore.ReviewPost(context.Background(), arg2)
+	require.EqualError(t, err, sql.ErrNoRows.Error())
+
+	notes, err := testStore.ListReviewNotes(context.Background(), post.ID)
+	require.NoError(t, err)
+	require.Len(t, notes, 2)
+	require.Equal(t, "note", notes[0].Kind)
+	require.Equal(t, "changes", notes[1].Kind)
+	require.Equal(t, arg2.Reason, notes[1].Content)
+	require.Equal(t, reviewer.Username, notes[1].Username)
+}
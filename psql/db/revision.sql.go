@@ -0,0 +1,331 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.15.0
+// source: revision.sql
+
+package db
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/lib/pq"
+)
+
+const applyPostRevision = `-- name: ApplyPostRevision :one
+UPDATE posts p
+SET title = r.title,
+  cover_image = r.cover_image,
+  update_at = now(),
+  version = greatest(p.version, r.version)
+FROM post_revisions r
+WHERE r.post_id = p.id AND p.id = $1::bigint
+  AND p.status = 'publish'
+RETURNING p.id, p.author_id, p.title, p.cover_image, p.status, p.featured, p.view_count, p.update_at, p.publish_at, p.reviewer_id, p.version, p.visibility, p.password_hash, p.trash_status, p.delete_at
+`
+
+func (q *Queries) ApplyPostRevision(ctx context.Context, postID int64) (Post, error) {
+	row := q.db.QueryRowContext(ctx, applyPostRevision, postID)
+	var i Post
+	err := row.Scan(
+		&i.ID,
+		&i.AuthorID,
+		&i.Title,
+		&i.CoverImage,
+		&i.Status,
+		&i.Featured,
+		&i.ViewCount,
+		&i.UpdateAt,
+		&i.PublishAt,
+		&i.ReviewerID,
+		&i.Version,
+		&i.Visibility,
+		&i.PasswordHash,
+		&i.TrashStatus,
+		&i.DeleteAt,
+	)
+	return i, err
+}
+
+const applyPostRevisionContent = `-- name: ApplyPostRevisionContent :exec
+UPDATE post_contents pc
+SET content = r.content
+FROM post_revisions r
+WHERE r.post_id = pc.id AND pc.id = $1::bigint
+`
+
+func (q *Queries) ApplyPostRevisionContent(ctx context.Context, postID int64) error {
+	_, err := q.db.ExecContext(ctx, applyPostRevisionContent, postID)
+	return err
+}
+
+const assignRevisionReviewer = `-- name: AssignRevisionReviewer :one
+UPDATE post_revisions SET reviewer_id = $1::bigint
+WHERE post_id = $2::bigint AND status = 'review'
+  AND (reviewer_id IS NULL OR reviewer_id = $3::bigint
+    OR reviewer_id = $1::bigint)
+RETURNING post_id, title, cover_image, content, category_ids, tag_ids, status, reviewer_id, version, update_at
+`
+
+type AssignRevisionReviewerParams struct {
+	ReviewerID int64 `json:"reviewer_id"`
+	PostID     int64 `json:"post_id"`
+	ClaimerID  int64 `json:"claimer_id"`
+}
+
+func (q *Queries) AssignRevisionReviewer(ctx context.Context, arg AssignRevisionReviewerParams) (PostRevision, error) {
+	row := q.db.QueryRowContext(ctx, assignRevisionReviewer, arg.ReviewerID, arg.PostID, arg.ClaimerID)
+	var i PostRevision
+	err := row.Scan(
+		&i.PostID,
+		&i.Title,
+		&i.CoverImage,
+		&i.Content,
+		pq.Array(&i.CategoryIds),
+		pq.Array(&i.TagIds),
+		&i.Status,
+		&i.ReviewerID,
+		&i.Version,
+		&i.UpdateAt,
+	)
+	return i, err
+}
+
+const createPostRevision = `-- name: CreatePostRevision :exec
+INSERT INTO post_revisions (
+    post_id, title, cover_image, content, category_ids, tag_ids, version
+)
+SELECT p.id, p.title, p.cover_image, pc.content,
+    ARRAY(SELECT category_id FROM post_categories
+      WHERE post_id = p.id ORDER BY category_id)::bigint[],
+    ARRAY(SELECT tag_id FROM post_tags
+      WHERE post_id = p.id ORDER BY tag_id)::bigint[],
+    p.version
+FROM posts p
+JOIN post_contents pc ON pc.id = p.id
+WHERE p.id = $1::bigint AND p.status = 'publish'
+  AND (p.author_id = $2::bigint OR p.id = ANY(
+    SELECT post_id FROM post_collaborators
+    WHERE user_id = $2::bigint AND accepted = true
+  ))
+ON CONFLICT (post_id) DO NOTHING
+`
+
+type CreatePostRevisionParams struct {
+	PostID   int64 `json:"post_id"`
+	AuthorID int64 `json:"author_id"`
+}
+
+func (q *Queries) CreatePostRevision(ctx context.Context, arg CreatePostRevisionParams) error {
+	_, err := q.db.ExecContext(ctx, createPostRevision, arg.PostID, arg.AuthorID)
+	return err
+}
+
+const deletePostRevision = `-- name: DeletePostRevision :execrows
+DELETE FROM post_revisions
+WHERE post_id = $1::bigint
+  AND ($2::bool OR post_id = ANY(
+    SELECT id FROM posts
+    WHERE author_id = $3::bigint
+    UNION
+    SELECT post_id FROM post_collaborators
+    WHERE user_id = $3::bigint AND accepted = true
+  ))
+`
+
+type DeletePostRevisionParams struct {
+	PostID   int64 `json:"post_id"`
+	IsAdmin  bool  `json:"is_admin"`
+	AuthorID int64 `json:"author_id"`
+}
+
+func (q *Queries) DeletePostRevision(ctx context.Context, arg DeletePostRevisionParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deletePostRevision, arg.PostID, arg.IsAdmin, arg.AuthorID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const getPostRevision = `-- name: GetPostRevision :one
+SELECT r.post_id, r.title, r.cover_image, r.content, r.category_ids, r.tag_ids, r.status, r.reviewer_id, r.version, r.update_at FROM post_revisions r
+JOIN posts p ON p.id = r.post_id
+WHERE r.post_id = $1::bigint AND p.status = 'publish'
+  AND ($2::bool OR p.author_id = $3::bigint OR p.id = ANY(
+    SELECT post_id FROM post_collaborators
+    WHERE user_id = $3::bigint AND accepted = true
+  ))
+`
+
+type GetPostRevisionParams struct {
+	PostID   int64 `json:"post_id"`
+	IsAdmin  bool  `json:"is_admin"`
+	AuthorID int64 `json:"author_id"`
+}
+
+func (q *Queries) GetPostRevision(ctx context.Context, arg GetPostRevisionParams) (PostRevision, error) {
+	row := q.db.QueryRowContext(ctx, getPostRevision, arg.PostID, arg.IsAdmin, arg.AuthorID)
+	var i PostRevision
+	err := row.Scan(
+		&i.PostID,
+		&i.Title,
+		&i.CoverImage,
+		&i.Content,
+		pq.Array(&i.CategoryIds),
+		pq.Array(&i.TagIds),
+		&i.Status,
+		&i.ReviewerID,
+		&i.Version,
+		&i.UpdateAt,
+	)
+	return i, err
+}
+
+const submitPostRevisions = `-- name: SubmitPostRevisions :many
+UPDATE post_revisions
+SET status = 'review'
+WHERE post_id = ANY($1::bigint[])
+  AND status = ANY('{draft, revise}'::varchar[])
+  AND post_id = ANY(
+    SELECT p.id FROM posts p
+    WHERE p.status = 'publish'
+      AND ($2::bool OR p.author_id = $3::bigint OR p.id = ANY(
+        SELECT post_id FROM post_collaborators
+        WHERE user_id = $3::bigint AND accepted = true
+      ))
+  )
+RETURNING post_id, title, cover_image, content, category_ids, tag_ids, status, reviewer_id, version, update_at
+`
+
+type SubmitPostRevisionsParams struct {
+	Ids      []int64 `json:"ids"`
+	IsAdmin  bool    `json:"is_admin"`
+	AuthorID int64   `json:"author_id"`
+}
+
+func (q *Queries) SubmitPostRevisions(ctx context.Context, arg SubmitPostRevisionsParams) ([]PostRevision, error) {
+	rows, err := q.db.QueryContext(ctx, submitPostRevisions, pq.Array(arg.Ids), arg.IsAdmin, arg.AuthorID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []PostRevision{}
+	for rows.Next() {
+		var i PostRevision
+		if err := rows.Scan(
+			&i.PostID,
+			&i.Title,
+			&i.CoverImage,
+			&i.Content,
+			pq.Array(&i.CategoryIds),
+			pq.Array(&i.TagIds),
+			&i.Status,
+			&i.ReviewerID,
+			&i.Version,
+			&i.UpdateAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const updatePostRevision = `-- name: UpdatePostRevision :one
+UPDATE post_revisions
+SET
+  title = coalesce($1, title),
+  cover_image = coalesce($2, cover_image),
+  content = coalesce($3, content),
+  category_ids = coalesce($4::bigint[], category_ids),
+  tag_ids = coalesce($5::bigint[], tag_ids),
+  update_at = now(),
+  version = version + 1
+WHERE post_id = (
+    SELECT p.id FROM posts p
+    WHERE p.id = $6::bigint AND p.status = 'publish'
+      AND (p.author_id = $7::bigint OR p.id = ANY(
+        SELECT post_id FROM post_collaborators
+        WHERE user_id = $7::bigint AND accepted = true
+      ))
+  )
+  AND status = ANY('{draft, revise}'::varchar[])
+  AND version = $8::bigint
+RETURNING post_id, title, cover_image, content, category_ids, tag_ids, status, reviewer_id, version, update_at
+`
+
+type UpdatePostRevisionParams struct {
+	Title       sql.NullString `json:"title"`
+	CoverImage  sql.NullString `json:"cover_image"`
+	Content     sql.NullString `json:"content"`
+	CategoryIds []int64        `json:"category_ids"`
+	TagIds      []int64        `json:"tag_ids"`
+	PostID      int64          `json:"post_id"`
+	AuthorID    int64          `json:"author_id"`
+	Version     int64          `json:"version"`
+}
+
+func (q *Queries) UpdatePostRevision(ctx context.Context, arg UpdatePostRevisionParams) (PostRevision, error) {
+	row := q.db.QueryRowContext(ctx, updatePostRevision,
+		arg.Title,
+		arg.CoverImage,
+		arg.Content,
+		pq.Array(arg.CategoryIds),
+		pq.Array(arg.TagIds),
+		arg.PostID,
+		arg.AuthorID,
+		arg.Version,
+	)
+	var i PostRevision
+	err := row.Scan(
+		&i.PostID,
+		&i.Title,
+		&i.CoverImage,
+		&i.Content,
+		pq.Array(&i.CategoryIds),
+		pq.Array(&i.TagIds),
+		&i.Status,
+		&i.ReviewerID,
+		&i.Version,
+		&i.UpdateAt,
+	)
+	return i, err
+}
+
+const updateReviewedRevision = `-- name: UpdateReviewedRevision :one
+UPDATE post_revisions
+SET status = $1::varchar, reviewer_id = $2::bigint
+WHERE post_id = $3::bigint AND status = 'review'
+  AND (reviewer_id IS NULL OR reviewer_id = $2::bigint)
+RETURNING post_id, title, cover_image, content, category_ids, tag_ids, status, reviewer_id, version, update_at
+`
+
+type UpdateReviewedRevisionParams struct {
+	Status     string `json:"status"`
+	ReviewerID int64  `json:"reviewer_id"`
+	PostID     int64  `json:"post_id"`
+}
+
+func (q *Queries) UpdateReviewedRevision(ctx context.Context, arg UpdateReviewedRevisionParams) (PostRevision, error) {
+	row := q.db.QueryRowContext(ctx, updateReviewedRevision, arg.Status, arg.ReviewerID, arg.PostID)
+	var i PostRevision
+	err := row.Scan(
+		&i.PostID,
+		&i.Title,
+		&i.CoverImage,
+		&i.Content,
+		pq.Array(&i.CategoryIds),
+		pq.Array(&i.TagIds),
+		&i.Status,
+		&i.ReviewerID,
+		&i.Version,
+		&i.UpdateAt,
+	)
+	return i, err
+}
@@ -0,0 +1,164 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/bwen19/blog/util"
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomPublishedPost(t *testing.T, user User) Post {
+	post := createRandomReviewPost(t, user)
+
+	arg := UpdatePostStatusParams{
+		Ids:       []int64{post.ID},
+		Status:    "publish",
+		OldStatus: []string{"review"},
+		IsAdmin:   true,
+	}
+	posts, err := testStore.UpdatePostStatus(context.Background(), arg)
+	require.NoError(t, err)
+	require.Len(t, posts, 1)
+
+	return posts[0]
+}
+
+func TestUpdatePublishedPost(t *testing.T) {
+	author := createRandomUser(t)
+	post := createRandomPublishedPost(t, author)
+
+	arg1 := UpdatePostParams{
+		ID:       post.ID,
+		AuthorID: author.ID,
+		Title:    sql.NullString{String: util.RandomString(10), Valid: true},
+		Version:  post.Version,
+	}
+	_, err := testStore.UpdatePost(context.Background(), arg1)
+	require.EqualError(t, err, sql.ErrNoRows.Error())
+
+	arg2 := UpdatePostRevisionParams{
+		PostID:   post.ID,
+		AuthorID: author.ID,
+		Title:    arg1.Title,
+		Content:  sql.NullString{String: util.RandomText(20), Valid: true},
+		Version:  post.Version,
+	}
+	revision, err := testStore.UpdatePublishedPost(context.Background(), arg2)
+	require.NoError(t, err)
+	require.Equal(t, arg2.Title.String, revision.Title)
+	require.Equal(t, arg2.Content.String, revision.Content)
+	require.Equal(t, post.CoverImage, revision.CoverImage)
+	require.Equal(t, "draft", revision.Status)
+	require.Equal(t, post.Version+1, revision.Version)
+
+	// The live version is kept until the revision is approved
+	arg3 := GetPostParams{
+		PostID:   post.ID,
+		AuthorID: author.ID,
+	}
+	live, err := testStore.GetPost(context.Background(), arg3)
+	require.NoError(t, err)
+	require.Equal(t, post.Title, live.Title)
+	require.Equal(t, "publish", live.Status)
+	require.Equal(t, "draft", live.RevisionStatus)
+
+	_, err = testStore.UpdatePublishedPost(context.Background(), arg2)
+	require.EqualError(t, err, sql.ErrNoRows.Error())
+
+	arg4 := GetPostVersionParams{
+		ID:       post.ID,
+		AuthorID: author.ID,
+	}
+	version, err := testStore.GetPostVersion(context.Background(), arg4)
+	require.NoError(t, err)
+	require.Equal(t, revision.Version, version)
+
+	arg5 := DeletePostRevisionParams{
+		PostID:   post.ID,
+		AuthorID: author.ID,
+	}
+	n, err := testStore.DeletePostRevision(context.Background(), arg5)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), n)
+}
+
+func TestReviewPostRevision(t *testing.T) {
+	author := createRandomUser(t)
+	reviewer := createRandomUser(t)
+	post := createRandomPublishedPost(t, author)
+	category := createRandomCategory(t)
+
+	arg1 := UpdatePostRevisionParams{
+		PostID:      post.ID,
+		AuthorID:    author.ID,
+		Title:       sql.NullString{String: util.RandomString(10), Valid: true},
+		Content:     sql.NullString{String: util.RandomText(20), Valid: true},
+		CategoryIds: []int64{category.ID},
+		Version:     post.Version,
+	}
+	revision, err := testStore.UpdatePublishedPost(context.Background(), arg1)
+	require.NoError(t, err)
+
+	arg2 := SubmitPostRevisionsParams{
+		Ids:      []int64{post.ID},
+		AuthorID: author.ID,
+	}
+	revisions, err := testStore.SubmitPostRevisions(context.Background(), arg2)
+	require.NoError(t, err)
+	require.Len(t, revisions, 1)
+	require.Equal(t, "review", revisions[0].Status)
+
+	arg3 := ListReviewQueueParams{
+		Limit:       5,
+		Unclaimed:   true,
+		AnyReviewer: true,
+	}
+	queue, err := testStore.ListReviewQueue(context.Background(), arg3)
+	require.NoError(t, err)
+	var found bool
+	for _, item := range queue {
+		if item.ID == post.ID {
+			found = true
+			require.True(t, item.Revision)
+			require.Equal(t, revision.Title, item.Title)
+		}
+	}
+	require.True(t, found)
+
+	// Editing is locked while the revision is in review
+	arg1.Version = revision.Version
+	_, err = testStore.UpdatePublishedPost(context.Background(), arg1)
+	require.EqualError(t, err, sql.ErrNoRows.Error())
+
+	arg4 := ReviewPostParams{
+		PostID:     post.ID,
+		ReviewerID: reviewer.ID,
+		Status:     "publish",
+		Kind:       "approve",
+		Reason:     util.RandomText(5),
+	}
+	_, err = testStore.ReviewPost(context.Background(), arg4)
+	require.EqualError(t, err, sql.ErrNoRows.Error())
+
+	post2, err := testStore.ReviewPostRevision(context.Background(), arg4)
+	require.NoError(t, err)
+	require.Equal(t, "publish", post2.Status)
+	require.Equal(t, revision.Title, post2.Title)
+	require.Equal(t, revision.Version, post2.Version)
+	require.WithinDuration(t, post.PublishAt, post2.PublishAt, 0)
+
+	arg5 := GetPostParams{
+		PostID:   post.ID,
+		AuthorID: author.ID,
+	}
+	live, err := testStore.GetPost(context.Background(), arg5)
+	require.NoError(t, err)
+	require.Equal(t, revision.Content, live.Content)
+	require.Equal(t, []int64{category.ID}, live.CategoryIds)
+	require.Empty(t, live.RevisionStatus)
+
+	_, err = testStore.ReviewPostRevision(context.Background(), arg4)
+	require.EqualError(t, err, sql.ErrNoRows.Error())
+}
@@ -0,0 +1,377 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.15.0
+// source: series.sql
+
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const addSeriesPost = `-- name: AddSeriesPost :one
+INSERT INTO series_posts (series_id, post_id, position)
+SELECT s.id, p.id, coalesce((
+    SELECT max(position) FROM series_posts
+    WHERE series_id = s.id
+  ), 0) + 1
+FROM series s
+JOIN posts p ON p.id = $1::bigint AND p.author_id = s.author_id
+  AND p.status <> 'trash'
+WHERE s.id = $2::bigint AND s.author_id = $3::bigint
+RETURNING series_id, post_id, position
+`
+
+type AddSeriesPostParams struct {
+	PostID   int64 `json:"post_id"`
+	SeriesID int64 `json:"series_id"`
+	AuthorID int64 `json:"author_id"`
+}
+
+func (q *Queries) AddSeriesPost(ctx context.Context, arg AddSeriesPostParams) (SeriesPost, error) {
+	row := q.db.QueryRowContext(ctx, addSeriesPost, arg.PostID, arg.SeriesID, arg.AuthorID)
+	var i SeriesPost
+	err := row.Scan(&i.SeriesID, &i.PostID, &i.Position)
+	return i, err
+}
+
+const createSeries = `-- name: CreateSeries :one
+INSERT INTO series (author_id, title, description, cover_image)
+VALUES ($1, $2, $3, $4) RETURNING id, author_id, title, description, cover_image, create_at
+`
+
+type CreateSeriesParams struct {
+	AuthorID    int64  `json:"author_id"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	CoverImage  string `json:"cover_image"`
+}
+
+func (q *Queries) CreateSeries(ctx context.Context, arg CreateSeriesParams) (Series, error) {
+	row := q.db.QueryRowContext(ctx, createSeries,
+		arg.AuthorID,
+		arg.Title,
+		arg.Description,
+		arg.CoverImage,
+	)
+	var i Series
+	err := row.Scan(
+		&i.ID,
+		&i.AuthorID,
+		&i.Title,
+		&i.Description,
+		&i.CoverImage,
+		&i.CreateAt,
+	)
+	return i, err
+}
+
+const deleteSeries = `-- name: DeleteSeries :execrows
+DELETE FROM series
+WHERE id = $1::bigint
+  AND ($2::bool OR author_id = $3::bigint)
+`
+
+type DeleteSeriesParams struct {
+	ID       int64 `json:"id"`
+	IsAdmin  bool  `json:"is_admin"`
+	AuthorID int64 `json:"author_id"`
+}
+
+func (q *Queries) DeleteSeries(ctx context.Context, arg DeleteSeriesParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteSeries, arg.ID, arg.IsAdmin, arg.AuthorID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deleteSeriesPost = `-- name: DeleteSeriesPost :execrows
+DELETE FROM series_posts
+WHERE series_id = $1::bigint AND post_id = $2::bigint
+  AND series_id = ANY(
+    SELECT id FROM series WHERE author_id = $3::bigint
+  )
+`
+
+type DeleteSeriesPostParams struct {
+	SeriesID int64 `json:"series_id"`
+	PostID   int64 `json:"post_id"`
+	AuthorID int64 `json:"author_id"`
+}
+
+func (q *Queries) DeleteSeriesPost(ctx context.Context, arg DeleteSeriesPostParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteSeriesPost, arg.SeriesID, arg.PostID, arg.AuthorID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const getPostSeries = `-- name: GetPostSeries :one
+WITH Series_CTE AS (
+  SELECT series_id FROM series_posts
+  WHERE post_id = $1::bigint
+),
+Part_CTE AS (
+  SELECT sp.post_id,
+      row_number() OVER w part,
+      count(*) OVER () total,
+      coalesce(lag(p.id) OVER w, 0)::bigint prev_id,
+      coalesce(lag(p.title) OVER w, '')::varchar prev_title,
+      coalesce(lead(p.id) OVER w, 0)::bigint next_id,
+      coalesce(lead(p.title) OVER w, '')::varchar next_title
+  FROM series_posts sp
+  JOIN posts p ON p.id = sp.post_id AND p.status = 'publish'
+  WHERE sp.series_id = ANY(SELECT series_id FROM Series_CTE)
+    AND (p.id = $1::bigint OR $2::bool
+      OR p.visibility = ANY('{public, password}'::varchar[])
+      OR (p.visibility = 'followers' AND (p.author_id = $3::bigint OR p.author_id = ANY(
+        SELECT user_id FROM follows
+        WHERE follower_id = $3::bigint
+      ))))
+  WINDOW w AS (ORDER BY sp.position)
+)
+SELECT s.id, s.title, pc.part, pc.total, pc.prev_id,
+    pc.prev_title, pc.next_id, pc.next_title
+FROM Part_CTE pc
+JOIN series s ON s.id = ANY(SELECT series_id FROM Series_CTE)
+WHERE pc.post_id = $1::bigint
+`
+
+type GetPostSeriesParams struct {
+	PostID  int64 `json:"post_id"`
+	IsAdmin bool  `json:"is_admin"`
+	SelfID  int64 `json:"self_id"`
+}
+
+type GetPostSeriesRow struct {
+	ID        int64  `json:"id"`
+	Title     string `json:"title"`
+	Part      int64  `json:"part"`
+	Total     int64  `json:"total"`
+	PrevID    int64  `json:"prev_id"`
+	PrevTitle string `json:"prev_title"`
+	NextID    int64  `json:"next_id"`
+	NextTitle string `json:"next_title"`
+}
+
+func (q *Queries) GetPostSeries(ctx context.Context, arg GetPostSeriesParams) (GetPostSeriesRow, error) {
+	row := q.db.QueryRowContext(ctx, getPostSeries, arg.PostID, arg.IsAdmin, arg.SelfID)
+	var i GetPostSeriesRow
+	err := row.Scan(
+		&i.ID,
+		&i.Title,
+		&i.Part,
+		&i.Total,
+		&i.PrevID,
+		&i.PrevTitle,
+		&i.NextID,
+		&i.NextTitle,
+	)
+	return i, err
+}
+
+const getSeries = `-- name: GetSeries :one
+SELECT s.id, s.author_id, s.title, s.description, s.cover_image, s.create_at, u.username, u.avatar
+FROM series s
+JOIN users u ON u.id = s.author_id
+WHERE s.id = $1::bigint LIMIT 1
+`
+
+type GetSeriesRow struct {
+	ID          int64     `json:"id"`
+	AuthorID    int64     `json:"author_id"`
+	Title       string    `json:"title"`
+	Description string    `json:"description"`
+	CoverImage  string    `json:"cover_image"`
+	CreateAt    time.Time `json:"create_at"`
+	Username    string    `json:"username"`
+	Avatar      string    `json:"avatar"`
+}
+
+func (q *Queries) GetSeries(ctx context.Context, id int64) (GetSeriesRow, error) {
+	row := q.db.QueryRowContext(ctx, getSeries, id)
+	var i GetSeriesRow
+	err := row.Scan(
+		&i.ID,
+		&i.AuthorID,
+		&i.Title,
+		&i.Description,
+		&i.CoverImage,
+		&i.CreateAt,
+		&i.Username,
+		&i.Avatar,
+	)
+	return i, err
+}
+
+const listSeries = `-- name: ListSeries :many
+WITH Data_CTE AS (
+  SELECT s.id, s.author_id, s.title, s.description, s.cover_image, s.create_at,
+      (SELECT count(*) FROM series_posts sp
+        JOIN posts p ON p.id = sp.post_id
+        WHERE sp.series_id = s.id AND p.status = 'publish') post_count
+  FROM series s
+  WHERE s.author_id = $4::bigint
+),
+Count_CTE AS (
+  SELECT count(*) total FROM Data_CTE
+  WHERE post_count > 0 OR author_id = $3::bigint
+)
+SELECT dc.id, dc.author_id, dc.title, dc.description, dc.cover_image, dc.create_at, dc.post_count, cnt.total
+FROM Data_CTE dc
+CROSS JOIN Count_CTE cnt
+WHERE dc.post_count > 0 OR dc.author_id = $3::bigint
+ORDER BY dc.create_at DESC
+LIMIT $1
+OFFSET $2
+`
+
+type ListSeriesParams struct {
+	Limit    int32 `json:"limit"`
+	Offset   int32 `json:"offset"`
+	SelfID   int64 `json:"self_id"`
+	AuthorID int64 `json:"author_id"`
+}
+
+type ListSeriesRow struct {
+	ID          int64     `json:"id"`
+	AuthorID    int64     `json:"author_id"`
+	Title       string    `json:"title"`
+	Description string    `json:"description"`
+	CoverImage  string    `json:"cover_image"`
+	CreateAt    time.Time `json:"create_at"`
+	PostCount   int64     `json:"post_count"`
+	Total       int64     `json:"total"`
+}
+
+func (q *Queries) ListSeries(ctx context.Context, arg ListSeriesParams) ([]ListSeriesRow, error) {
+	rows, err := q.db.QueryContext(ctx, listSeries,
+		arg.Limit,
+		arg.Offset,
+		arg.SelfID,
+		arg.AuthorID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListSeriesRow{}
+	for rows.Next() {
+		var i ListSeriesRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.AuthorID,
+			&i.Title,
+			&i.Description,
+			&i.CoverImage,
+			&i.CreateAt,
+			&i.PostCount,
+			&i.Total,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listSeriesPosts = `-- name: ListSeriesPosts :many
+SELECT sp.position, p.id, p.title, p.status, p.publish_at
+FROM series_posts sp
+JOIN posts p ON p.id = sp.post_id
+WHERE sp.series_id = $1::bigint
+  AND ($2::bool AND p.status <> 'trash' OR p.status = 'publish')
+  AND ($2::bool OR $3::bool
+    OR p.visibility = ANY('{public, password}'::varchar[])
+    OR (p.visibility = 'followers' AND (p.author_id = $4::bigint OR p.author_id = ANY(
+      SELECT user_id FROM follows
+      WHERE follower_id = $4::bigint
+    ))))
+ORDER BY sp.position ASC
+`
+
+type ListSeriesPostsParams struct {
+	SeriesID  int64 `json:"series_id"`
+	AnyStatus bool  `json:"any_status"`
+	IsAdmin   bool  `json:"is_admin"`
+	SelfID    int64 `json:"self_id"`
+}
+
+type ListSeriesPostsRow struct {
+	Position  int32     `json:"position"`
+	ID        int64     `json:"id"`
+	Title     string    `json:"title"`
+	Status    string    `json:"status"`
+	PublishAt time.Time `json:"publish_at"`
+}
+
+func (q *Queries) ListSeriesPosts(ctx context.Context, arg ListSeriesPostsParams) ([]ListSeriesPostsRow, error) {
+	rows, err := q.db.QueryContext(ctx, listSeriesPosts,
+		arg.SeriesID,
+		arg.AnyStatus,
+		arg.IsAdmin,
+		arg.SelfID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListSeriesPostsRow{}
+	for rows.Next() {
+		var i ListSeriesPostsRow
+		if err := rows.Scan(
+			&i.Position,
+			&i.ID,
+			&i.Title,
+			&i.Status,
+			&i.PublishAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const updateSeriesPositions = `-- name: UpdateSeriesPositions :execrows
+UPDATE series_posts sp
+SET position = o.part
+FROM (
+  SELECT post_id, row_number() OVER (
+      ORDER BY array_position($2::bigint[], post_id) NULLS LAST, position
+    ) part
+  FROM series_posts
+  WHERE series_id = $1::bigint
+) o
+WHERE sp.series_id = $1::bigint AND sp.post_id = o.post_id
+`
+
+type UpdateSeriesPositionsParams struct {
+	SeriesID int64   `json:"series_id"`
+	PostIds  []int64 `json:"post_ids"`
+}
+
+func (q *Queries) UpdateSeriesPositions(ctx context.Context, arg UpdateSeriesPositionsParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, updateSeriesPositions, arg.SeriesID, pq.Array(arg.PostIds))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,229 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/bwen19/blog/util"
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomSeries(t *testing.T, user User) Series {
+	arg := CreateSeriesParams{
+		AuthorID:    user.ID,
+		Title:       util.RandomText(5),
+		Description: util.RandomText(20),
+		CoverImage:  "/image/post/default",
+	}
+
+	series, err := testStore.CreateSeries(context.Background(), arg)
+	require.NoError(t, err)
+	require.NotEmpty(t, series)
+
+	require.Equal(t, arg.AuthorID, series.AuthorID)
+	require.Equal(t, arg.Title, series.Title)
+	require.Equal(t, arg.Description, series.Description)
+	require.NotZero(t, series.ID)
+
+	return series
+}
+
+func TestAddSeriesPost(t *testing.T) {
+	user1 := createRandomUser(t)
+	user2 := createRandomUser(t)
+	series := createRandomSeries(t, user1)
+	post1 := createRandomPost(t, user1)
+	post2 := createRandomPost(t, user2)
+
+	arg := AddSeriesPostParams{
+		SeriesID: series.ID,
+		PostID:   post1.ID,
+		AuthorID: user1.ID,
+	}
+	seriesPost, err := testStore.AddSeriesPost(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, int32(1), seriesPost.Position)
+
+	_, err = testStore.AddSeriesPost(context.Background(), arg)
+	require.Error(t, err)
+
+	arg.PostID = post2.ID
+	_, err = testStore.AddSeriesPost(context.Background(), arg)
+	require.EqualError(t, err, sql.ErrNoRows.Error())
+}
+
+func TestSeriesNavigation(t *testing.T) {
+	user := createRandomUser(t)
+	series := createRandomSeries(t, user)
+
+	postIDs := []int64{}
+	for i := 0; i < 3; i++ {
+		post := createRandomPost(t, user)
+		postIDs = append(postIDs, post.ID)
+
+		arg := AddSeriesPostParams{
+			SeriesID: series.ID,
+			PostID:   post.ID,
+			AuthorID: user.ID,
+		}
+		seriesPost, err := testStore.AddSeriesPost(context.Background(), arg)
+		require.NoError(t, err)
+		require.Equal(t, int32(i+1), seriesPost.Position)
+	}
+
+	arg1 := UpdatePostStatusParams{
+		Ids:       []int64{postIDs[0], postIDs[2]},
+		Status:    "publish",
+		OldStatus: []string{"draft"},
+		IsAdmin:   true,
+	}
+	_, err := testStore.UpdatePostStatus(context.Background(), arg1)
+	require.NoError(t, err)
+
+	part, err := testStore.GetPostSeries(context.Background(), GetPostSeriesParams{PostID: postIDs[2]})
+	require.NoError(t, err)
+	require.Equal(t, series.ID, part.ID)
+	require.Equal(t, int64(2), part.Part)
+	require.Equal(t, int64(2), part.Total)
+	require.Equal(t, postIDs[0], part.PrevID)
+	require.Zero(t, part.NextID)
+
+	arg2 := UpdateSeriesPositionsParams{
+		SeriesID: series.ID,
+		PostIds:  []int64{postIDs[2], postIDs[1], postIDs[0]},
+	}
+	nrows, err := testStore.UpdateSeriesPositions(context.Background(), arg2)
+	require.NoError(t, err)
+	require.Equal(t, int64(3), nrows)
+
+	part, err = testStore.GetPostSeries(context.Background(), GetPostSeriesParams{PostID: postIDs[2]})
+	require.NoError(t, err)
+	require.Equal(t, int64(1), part.Part)
+	require.Zero(t, part.PrevID)
+	require.Equal(t, postIDs[0], part.NextID)
+
+	arg3 := ListSeriesPostsParams{
+		SeriesID:  series.ID,
+		AnyStatus: false,
+	}
+	posts, err := testStore.ListSeriesPosts(context.Background(), arg3)
+	require.NoError(t, err)
+	require.Len(t, posts, 2)
+	require.Equal(t, postIDs[2], posts[0].ID)
+
+	// Posts for followers are hidden from other readers
+	arg5 := UpdatePostVisibilityParams{
+		ID:         postIDs[0],
+		Visibility: "followers",
+		IsAdmin:    true,
+	}
+	_, err = testStore.UpdatePostVisibility(context.Background(), arg5)
+	require.NoError(t, err)
+
+	posts, err = testStore.ListSeriesPosts(context.Background(), arg3)
+	require.NoError(t, err)
+	require.Len(t, posts, 1)
+
+	part, err = testStore.GetPostSeries(context.Background(), GetPostSeriesParams{PostID: postIDs[2]})
+	require.NoError(t, err)
+	require.Equal(t, int64(1), part.Total)
+	require.Zero(t, part.NextID)
+
+	arg3.SelfID = user.ID
+	posts, err = testStore.ListSeriesPosts(context.Background(), arg3)
+	require.NoError(t, err)
+	require.Len(t, posts, 2)
+
+	arg4 := DeleteSeriesPostParams{
+		SeriesID: series.ID,
+		PostID:   postIDs[2],
+		AuthorID: user.ID,
+	}
+	nrows, err = testStore.DeleteSeriesPost(context.Background(), arg4)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), nrows)
+
+	_, err = testStore.GetPostSeries(context.Background(), GetPostSeriesParams{PostID: postIDs[2]})
+	require.EqualError(t, err, sql.ErrNoRows.Error())
+}
+
+func TestListSeries(t *testing.T) {
+	user := createRandomUser(t)
+	for i := 0; i < 3; i++ {
+		createRandomSeries(t, user)
+	}
+
+	arg := ListSeriesParams{
+		Limit:    5,
+		AuthorID: user.ID,
+		SelfID:   0,
+	}
+	series, err := testStore.ListSeries(context.Background(), arg)
+	require.NoError(t, err)
+	require.Empty(t, series)
+
+	arg.SelfID = user.ID
+	series, err = testStore.ListSeries(context.Background(), arg)
+	require.NoError(t, err)
+	require.Len(t, series, 3)
+	require.Equal(t, int64(3), series[0].Total)
+}
+
+func TestSeriesTrashedPost(t *testing.T) {
+	user := createRandomUser(t)
+	series := createRandomSeries(t, user)
+
+	postIDs := []int64{}
+	for i := 0; i < 3; i++ {
+		post := createRandomPost(t, user)
+		postIDs = append(postIDs, post.ID)
+
+		_, err := testStore.AddSeriesPost(context.Background(), AddSeriesPostParams{
+			SeriesID: series.ID,
+			PostID:   post.ID,
+			AuthorID: user.ID,
+		})
+		require.NoError(t, err)
+	}
+
+	arg1 := TrashPostParams{ID: postIDs[0], AuthorID: user.ID}
+	_, err := testStore.TrashPost(context.Background(), arg1)
+	require.NoError(t, err)
+
+	// Trashed posts are kept after the reordered ones
+	arg2 := UpdateSeriesPositionsParams{
+		SeriesID: series.ID,
+		PostIds:  []int64{postIDs[2], postIDs[1]},
+	}
+	nrows, err := testStore.UpdateSeriesPositions(context.Background(), arg2)
+	require.NoError(t, err)
+	require.Equal(t, int64(3), nrows)
+
+	_, err = testStore.RestorePost(context.Background(), RestorePostParams{ID: postIDs[0], AuthorID: user.ID})
+	require.NoError(t, err)
+
+	arg3 := ListSeriesPostsParams{
+		SeriesID:  series.ID,
+		AnyStatus: true,
+	}
+	posts, err := testStore.ListSeriesPosts(context.Background(), arg3)
+	require.NoError(t, err)
+	require.Len(t, posts, 3)
+	for i, postID := range []int64{postIDs[2], postIDs[1], postIDs[0]} {
+		require.Equal(t, postID, posts[i].ID)
+		require.Equal(t, int32(i+1), posts[i].Position)
+	}
+
+	// Trashed posts cannot be added to a series
+	post := createRandomPost(t, user)
+	_, err = testStore.TrashPost(context.Background(), TrashPostParams{ID: post.ID, AuthorID: user.ID})
+	require.NoError(t, err)
+
+	_, err = testStore.AddSeriesPost(context.Background(), AddSeriesPostParams{
+		SeriesID: series.ID,
+		PostID:   post.ID,
+		AuthorID: user.ID,
+	})
+	require.EqualError(t, err, sql.ErrNoRows.Error())
+}
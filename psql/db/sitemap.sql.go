@@ -0,0 +1,161 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.15.0
+// source: sitemap.sql
+
+package db
+
+import (
+	"context"
+	"time"
+)
+
+const listSitemapAuthors = `-- name: ListSitemapAuthors :many
+SELECT p.author_id id,
+    max(greatest(p.update_at, p.publish_at))::timestamptz lastmod
+FROM posts p
+JOIN users u ON u.id = p.author_id
+WHERE p.status = 'publish' AND p.visibility = 'public' AND u.deleted = false
+GROUP BY p.author_id
+ORDER BY p.author_id ASC
+`
+
+type ListSitemapAuthorsRow struct {
+	ID      int64     `json:"id"`
+	Lastmod time.Time `json:"lastmod"`
+}
+
+func (q *Queries) ListSitemapAuthors(ctx context.Context) ([]ListSitemapAuthorsRow, error) {
+	rows, err := q.db.QueryContext(ctx, listSitemapAuthors)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListSitemapAuthorsRow{}
+	for rows.Next() {
+		var i ListSitemapAuthorsRow
+		if err := rows.Scan(&i.ID, &i.Lastmod); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listSitemapCategories = `-- name: ListSitemapCategories :many
+SELECT pc.category_id id,
+    max(greatest(p.update_at, p.publish_at))::timestamptz lastmod
+FROM post_categories pc
+JOIN posts p ON p.id = pc.post_id
+WHERE p.status = 'publish' AND p.visibility = 'public'
+GROUP BY pc.category_id
+ORDER BY pc.category_id ASC
+`
+
+type ListSitemapCategoriesRow struct {
+	ID      int64     `json:"id"`
+	Lastmod time.Time `json:"lastmod"`
+}
+
+func (q *Queries) ListSitemapCategories(ctx context.Context) ([]ListSitemapCategoriesRow, error) {
+	rows, err := q.db.QueryContext(ctx, listSitemapCategories)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListSitemapCategoriesRow{}
+	for rows.Next() {
+		var i ListSitemapCategoriesRow
+		if err := rows.Scan(&i.ID, &i.Lastmod); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listSitemapPosts = `-- name: ListSitemapPosts :many
+SELECT id, greatest(update_at, publish_at)::timestamptz lastmod
+FROM posts
+WHERE status = 'publish' AND visibility = 'public'
+ORDER BY id ASC
+`
+
+type ListSitemapPostsRow struct {
+	ID      int64     `json:"id"`
+	Lastmod time.Time `json:"lastmod"`
+}
+
+func (q *Queries) ListSitemapPosts(ctx context.Context) ([]ListSitemapPostsRow, error) {
+	rows, err := q.db.QueryContext(ctx, listSitemapPosts)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListSitemapPostsRow{}
+	for rows.Next() {
+		var i ListSitemapPostsRow
+		if err := rows.Scan(&i.ID, &i.Lastmod); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listSitemapTags = `-- name: ListSitemapTags :many
+SELECT t.name,
+    max(greatest(p.update_at, p.publish_at))::timestamptz lastmod
+FROM post_tags pt
+JOIN tags t ON t.id = pt.tag_id
+JOIN posts p ON p.id = pt.post_id
+WHERE p.status = 'publish' AND p.visibility = 'public'
+GROUP BY t.id, t.name
+ORDER BY t.id ASC
+`
+
+type ListSitemapTagsRow struct {
+	Name    string    `json:"name"`
+	Lastmod time.Time `json:"lastmod"`
+}
+
+func (q *Queries) ListSitemapTags(ctx context.Context) ([]ListSitemapTagsRow, error) {
+	rows, err := q.db.QueryContext(ctx, listSitemapTags)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListSitemapTagsRow{}
+	for rows.Next() {
+		var i ListSitemapTagsRow
+		if err := rows.Scan(&i.Name, &i.Lastmod); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
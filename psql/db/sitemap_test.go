@@ -0,0 +1,58 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestListSitemap(t *testing.T) {
+	user := createRandomUser(t)
+	post := createRandomPost(t, user)
+	category := createRandomCategory(t)
+	tag := createRandomTag(t)
+
+	_, err := testStore.SetPostCategories(context.Background(), SetPostCategoriesParams{
+		PostID:      post.ID,
+		CategoryIDs: []int64{category.ID},
+	})
+	require.NoError(t, err)
+
+	_, err = testStore.SetPostTags(context.Background(), SetPostTagsParams{
+		PostID: post.ID,
+		TagIDs: []int64{tag.ID},
+	})
+	require.NoError(t, err)
+
+	posts, err := testStore.ListSitemapPosts(context.Background())
+	require.NoError(t, err)
+	for _, p := range posts {
+		require.NotEqual(t, post.ID, p.ID)
+	}
+
+	arg := UpdatePostStatusParams{
+		Ids:       []int64{post.ID},
+		Status:    "publish",
+		OldStatus: []string{"draft"},
+		IsAdmin:   true,
+	}
+	_, err = testStore.UpdatePostStatus(context.Background(), arg)
+	require.NoError(t, err)
+
+	posts, err = testStore.ListSitemapPosts(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, post.ID, posts[len(posts)-1].ID)
+
+	authors, err := testStore.ListSitemapAuthors(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, user.ID, authors[len(authors)-1].ID)
+
+	categories, err := testStore.ListSitemapCategories(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, category.ID, categories[len(categories)-1].ID)
+
+	tags, err := testStore.ListSitemapTags(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, tag.Name, tags[len(tags)-1].Name)
+}
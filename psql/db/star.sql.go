@@ -0,0 +1,172 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.15.0
+// source: star.sql
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+const listStargazers = `-- name: ListStargazers :many
+WITH Data_CTE AS (
+  SELECT ps.user_id, ps.create_at FROM post_stars ps
+  JOIN posts p ON p.id = ps.post_id AND p.status = 'publish'
+  JOIN users su ON su.id = ps.user_id
+  WHERE ps.post_id = $4::bigint
+    AND (su.public_stars OR su.id = $3::bigint OR $5::bool)
+),
+Count_CTE AS (
+  SELECT count(*) total FROM Data_CTE
+)
+SELECT dc.user_id, dc.create_at star_at, u.username,
+    u.avatar, u.intro, cnt.total, f.user_id followed
+FROM Data_CTE dc
+CROSS JOIN Count_CTE cnt
+JOIN users u ON u.id = dc.user_id
+LEFT JOIN follows f
+  ON f.user_id = dc.user_id AND f.follower_id = $3::bigint
+ORDER BY dc.create_at DESC, dc.user_id DESC
+LIMIT $1 OFFSET $2
+`
+
+type ListStargazersParams struct {
+	Limit   int32 `json:"limit"`
+	Offset  int32 `json:"offset"`
+	SelfID  int64 `json:"self_id"`
+	PostID  int64 `json:"post_id"`
+	IsAdmin bool  `json:"is_admin"`
+}
+
+type ListStargazersRow struct {
+	UserID   int64         `json:"user_id"`
+	StarAt   time.Time     `json:"star_at"`
+	Username string        `json:"username"`
+	Avatar   string        `json:"avatar"`
+	Intro    string        `json:"intro"`
+	Total    int64         `json:"total"`
+	Followed sql.NullInt64 `json:"followed"`
+}
+
+func (q *Queries) ListStargazers(ctx context.Context, arg ListStargazersParams) ([]ListStargazersRow, error) {
+	rows, err := q.db.QueryContext(ctx, listStargazers,
+		arg.Limit,
+		arg.Offset,
+		arg.SelfID,
+		arg.PostID,
+		arg.IsAdmin,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListStargazersRow{}
+	for rows.Next() {
+		var i ListStargazersRow
+		if err := rows.Scan(
+			&i.UserID,
+			&i.StarAt,
+			&i.Username,
+			&i.Avatar,
+			&i.Intro,
+			&i.Total,
+			&i.Followed,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listStarredPosts = `-- name: ListStarredPosts :many
+WITH Data_CTE AS (
+  SELECT ps.post_id, ps.create_at FROM post_stars ps
+  JOIN posts p ON p.id = ps.post_id AND p.status = 'publish'
+  WHERE ps.user_id = $3::bigint
+    AND (p.visibility = ANY('{public, password}'::varchar[])
+      OR (p.visibility = 'followers' AND (p.author_id = $4::bigint OR p.author_id = ANY(
+        SELECT user_id FROM follows
+        WHERE follower_id = $4::bigint
+      ))))
+),
+Count_CTE AS (
+  SELECT count(*) total FROM Data_CTE
+)
+SELECT p.id, p.title, p.cover_image, p.view_count, p.publish_at,
+    p.author_id, u.username, u.avatar, dc.create_at star_at, cnt.total
+FROM Data_CTE dc
+CROSS JOIN Count_CTE cnt
+JOIN posts p ON p.id = dc.post_id
+JOIN users u ON u.id = p.author_id
+ORDER BY dc.create_at DESC, dc.post_id DESC
+LIMIT $1 OFFSET $2
+`
+
+type ListStarredPostsParams struct {
+	Limit  int32 `json:"limit"`
+	Offset int32 `json:"offset"`
+	UserID int64 `json:"user_id"`
+	SelfID int64 `json:"self_id"`
+}
+
+type ListStarredPostsRow struct {
+	ID         int64     `json:"id"`
+	Title      string    `json:"title"`
+	CoverImage string    `json:"cover_image"`
+	ViewCount  int64     `json:"view_count"`
+	PublishAt  time.Time `json:"publish_at"`
+	AuthorID   int64     `json:"author_id"`
+	Username   string    `json:"username"`
+	Avatar     string    `json:"avatar"`
+	StarAt     time.Time `json:"star_at"`
+	Total      int64     `json:"total"`
+}
+
+func (q *Queries) ListStarredPosts(ctx context.Context, arg ListStarredPostsParams) ([]ListStarredPostsRow, error) {
+	rows, err := q.db.QueryContext(ctx, listStarredPosts,
+		arg.Limit,
+		arg.Offset,
+		arg.UserID,
+		arg.SelfID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListStarredPostsRow{}
+	for rows.Next() {
+		var i ListStarredPostsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Title,
+			&i.CoverImage,
+			&i.ViewCount,
+			&i.PublishAt,
+			&i.AuthorID,
+			&i.Username,
+			&i.Avatar,
+			&i.StarAt,
+			&i.Total,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
@@ -0,0 +1,76 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestListStars(t *testing.T) {
+	author := createRandomUser(t)
+	post := createRandomPost(t, author)
+
+	arg1 := UpdatePostStatusParams{
+		Ids:       []int64{post.ID},
+		Status:    "publish",
+		OldStatus: []string{"draft"},
+		IsAdmin:   true,
+	}
+	_, err := testStore.UpdatePostStatus(context.Background(), arg1)
+	require.NoError(t, err)
+
+	userIDs := []int64{}
+	for i := 0; i < 3; i++ {
+		user := createRandomUser(t)
+		userIDs = append(userIDs, user.ID)
+
+		arg := CreatePostStarParams{
+			PostID: post.ID,
+			UserID: user.ID,
+		}
+		err = testStore.CreatePostStar(context.Background(), arg)
+		require.NoError(t, err)
+	}
+	createRandomFollow(t, userIDs[2], author.ID)
+
+	arg2 := ListStargazersParams{
+		Limit:  2,
+		PostID: post.ID,
+		SelfID: author.ID,
+	}
+	users, err := testStore.ListStargazers(context.Background(), arg2)
+	require.NoError(t, err)
+	require.Len(t, users, 2)
+	require.Equal(t, int64(3), users[0].Total)
+	require.Equal(t, userIDs[2], users[0].UserID)
+	require.True(t, users[0].Followed.Valid)
+
+	arg4 := UpdateUserParams{
+		ID:          userIDs[2],
+		PublicStars: sql.NullBool{Bool: false, Valid: true},
+	}
+	_, err = testStore.UpdateUser(context.Background(), arg4)
+	require.NoError(t, err)
+
+	users, err = testStore.ListStargazers(context.Background(), arg2)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), users[0].Total)
+	require.Equal(t, userIDs[1], users[0].UserID)
+
+	arg2.IsAdmin = true
+	users, err = testStore.ListStargazers(context.Background(), arg2)
+	require.NoError(t, err)
+	require.Equal(t, int64(3), users[0].Total)
+
+	arg3 := ListStarredPostsParams{
+		Limit:  5,
+		UserID: userIDs[0],
+	}
+	posts, err := testStore.ListStarredPosts(context.Background(), arg3)
+	require.NoError(t, err)
+	require.Len(t, posts, 1)
+	require.Equal(t, post.ID, posts[0].ID)
+	require.Equal(t, author.ID, posts[0].AuthorID)
+}
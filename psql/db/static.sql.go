@@ -0,0 +1,140 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.15.0
+// source: static.sql
+
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const getPostContent = `-- name: GetPostContent :one
+SELECT content FROM post_contents
+WHERE id = $1
+`
+
+func (q *Queries) GetPostContent(ctx context.Context, id int64) (string, error) {
+	row := q.db.QueryRowContext(ctx, getPostContent, id)
+	var content string
+	err := row.Scan(&content)
+	return content, err
+}
+
+const listStaticAuthors = `-- name: ListStaticAuthors :many
+SELECT id, username, avatar, intro FROM users
+WHERE deleted = false AND id = ANY(
+  SELECT author_id FROM posts
+  WHERE status = 'publish' AND visibility = 'public'
+)
+ORDER BY id ASC
+`
+
+type ListStaticAuthorsRow struct {
+	ID       int64  `json:"id"`
+	Username string `json:"username"`
+	Avatar   string `json:"avatar"`
+	Intro    string `json:"intro"`
+}
+
+func (q *Queries) ListStaticAuthors(ctx context.Context) ([]ListStaticAuthorsRow, error) {
+	rows, err := q.db.QueryContext(ctx, listStaticAuthors)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListStaticAuthorsRow{}
+	for rows.Next() {
+		var i ListStaticAuthorsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Username,
+			&i.Avatar,
+			&i.Intro,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listStaticPosts = `-- name: ListStaticPosts :many
+WITH Category_CTE AS (
+  SELECT pc.post_id,
+      array_agg(c.id ORDER BY c.id)::bigint[] category_ids,
+      array_agg(c.name ORDER BY c.id)::varchar[] category_names
+  FROM post_categories pc
+  JOIN categories c ON c.id = pc.category_id
+  GROUP BY pc.post_id
+),
+Tag_CTE AS (
+  SELECT pt.post_id,
+      array_agg(t.name ORDER BY t.name)::varchar[] tag_names
+  FROM post_tags pt
+  JOIN tags t ON t.id = pt.tag_id
+  GROUP BY pt.post_id
+)
+SELECT p.id, p.title, p.cover_image, p.author_id,
+    p.publish_at, p.update_at, cc.category_ids,
+    cc.category_names, tc.tag_names
+FROM posts p
+LEFT JOIN Category_CTE cc ON cc.post_id = p.id
+LEFT JOIN Tag_CTE tc ON tc.post_id = p.id
+WHERE p.status = 'publish' AND p.visibility = 'public'
+ORDER BY p.publish_at DESC, p.id DESC
+`
+
+type ListStaticPostsRow struct {
+	ID            int64     `json:"id"`
+	Title         string    `json:"title"`
+	CoverImage    string    `json:"cover_image"`
+	AuthorID      int64     `json:"author_id"`
+	PublishAt     time.Time `json:"publish_at"`
+	UpdateAt      time.Time `json:"update_at"`
+	CategoryIds   []int64   `json:"category_ids"`
+	CategoryNames []string  `json:"category_names"`
+	TagNames      []string  `json:"tag_names"`
+}
+
+func (q *Queries) ListStaticPosts(ctx context.Context) ([]ListStaticPostsRow, error) {
+	rows, err := q.db.QueryContext(ctx, listStaticPosts)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListStaticPostsRow{}
+	for rows.Next() {
+		var i ListStaticPostsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Title,
+			&i.CoverImage,
+			&i.AuthorID,
+			&i.PublishAt,
+			&i.UpdateAt,
+			pq.Array(&i.CategoryIds),
+			pq.Array(&i.CategoryNames),
+			pq.Array(&i.TagNames),
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
@@ -0,0 +1,87 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func findStaticPost(posts []ListStaticPostsRow, id int64) (ListStaticPostsRow, bool) {
+	for _, p := range posts {
+		if p.ID == id {
+			return p, true
+		}
+	}
+	return ListStaticPostsRow{}, false
+}
+
+func TestListStatic(t *testing.T) {
+	user := createRandomUser(t)
+	post := createRandomPost(t, user)
+	category := createRandomCategory(t)
+	tag := createRandomTag(t)
+
+	_, err := testStore.SetPostCategories(context.Background(), SetPostCategoriesParams{
+		PostID:      post.ID,
+		CategoryIDs: []int64{category.ID},
+	})
+	require.NoError(t, err)
+
+	_, err = testStore.SetPostTags(context.Background(), SetPostTagsParams{
+		PostID: post.ID,
+		TagIDs: []int64{tag.ID},
+	})
+	require.NoError(t, err)
+
+	// Drafts are not part of the static site
+	posts, err := testStore.ListStaticPosts(context.Background())
+	require.NoError(t, err)
+	_, ok := findStaticPost(posts, post.ID)
+	require.False(t, ok)
+
+	arg := UpdatePostStatusParams{
+		Ids:       []int64{post.ID},
+		Status:    "publish",
+		OldStatus: []string{"draft"},
+		IsAdmin:   true,
+	}
+	_, err = testStore.UpdatePostStatus(context.Background(), arg)
+	require.NoError(t, err)
+
+	posts, err = testStore.ListStaticPosts(context.Background())
+	require.NoError(t, err)
+	row, ok := findStaticPost(posts, post.ID)
+	require.True(t, ok)
+	require.Equal(t, post.Title, row.Title)
+	require.Equal(t, user.ID, row.AuthorID)
+	require.Equal(t, []int64{category.ID}, row.CategoryIds)
+	require.Equal(t, []string{category.Name}, row.CategoryNames)
+	require.Equal(t, []string{tag.Name}, row.TagNames)
+
+	for i := 1; i < len(posts); i++ {
+		require.False(t, posts[i].PublishAt.After(posts[i-1].PublishAt))
+	}
+
+	authors, err := testStore.ListStaticAuthors(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, user.ID, authors[len(authors)-1].ID)
+	require.Equal(t, user.Username, authors[len(authors)-1].Username)
+
+	content, err := testStore.GetPostContent(context.Background(), post.ID)
+	require.NoError(t, err)
+	require.Equal(t, post.Content, content)
+
+	// Posts that are not public are left out as well
+	_, err = testStore.UpdatePostVisibility(context.Background(), UpdatePostVisibilityParams{
+		ID:         post.ID,
+		Visibility: "unlisted",
+		IsAdmin:    true,
+	})
+	require.NoError(t, err)
+
+	posts, err = testStore.ListStaticPosts(context.Background())
+	require.NoError(t, err)
+	_, ok = findStaticPost(posts, post.ID)
+	require.False(t, ok)
+}
@@ -12,6 +12,17 @@ type Store interface {
 	CreateNewPost(context.Context, CreateNewPostParams) (CreateNewPostRow, error)
 	SetPostCategories(context.Context, SetPostCategoriesParams) ([]Category, error)
 	SetPostTags(context.Context, SetPostTagsParams) ([]Tag, error)
+	EditPost(context.Context, EditPostParams) (EditPostResult, error)
+	BulkUpdatePostStatus(context.Context, BulkUpdatePostStatusParams) (BulkUpdatePostStatusResult, error)
+	SweepMedia(context.Context, SweepMediaParams) (SweepMediaResult, error)
+	AddMediaVariants(context.Context, AddMediaVariantsParams) ([]MediaVariant, error)
+	ReviewPost(context.Context, ReviewPostParams) (Post, error)
+	UpdatePublishedPost(context.Context, UpdatePostRevisionParams) (PostRevision, error)
+	ReviewPostRevision(context.Context, ReviewPostParams) (Post, error)
+	UpdateHotRankings(context.Context, RefreshPostRankingsParams) (bool, error)
+	ImportNewPost(context.Context, ImportNewPostParams) (Post, error)
+	ImportNewComment(context.Context, ImportNewCommentParams) (Comment, error)
+	SyncPost(context.Context, SyncPostParams) (Post, error)
 }
 
 // SqlStore provides all functions to execute db queries and transactions
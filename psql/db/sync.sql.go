@@ -0,0 +1,111 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.15.0
+// source: sync.sql
+
+package db
+
+import (
+	"context"
+	"time"
+)
+
+const listSyncPostIDs = `-- name: ListSyncPostIDs :many
+SELECT id FROM posts
+WHERE ($1::bool OR author_id = $2::bigint)
+  AND status <> 'trash'
+ORDER BY id ASC
+`
+
+type ListSyncPostIDsParams struct {
+	AnyAuthor bool  `json:"any_author"`
+	AuthorID  int64 `json:"author_id"`
+}
+
+func (q *Queries) ListSyncPostIDs(ctx context.Context, arg ListSyncPostIDsParams) ([]int64, error) {
+	rows, err := q.db.QueryContext(ctx, listSyncPostIDs, arg.AnyAuthor, arg.AuthorID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []int64{}
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const updateSyncedPost = `-- name: UpdateSyncedPost :one
+UPDATE posts
+SET
+  title = $2,
+  cover_image = $3,
+  publish_at = $4,
+  update_at = now(),
+  version = version + 1
+WHERE id = $1 AND version = $5::bigint
+  AND status = ANY('{draft, revise}'::varchar[])
+RETURNING id, author_id, title, cover_image, status, featured, view_count, update_at, publish_at, reviewer_id, version, visibility, password_hash, trash_status, delete_at
+`
+
+type UpdateSyncedPostParams struct {
+	ID         int64     `json:"id"`
+	Title      string    `json:"title"`
+	CoverImage string    `json:"cover_image"`
+	PublishAt  time.Time `json:"publish_at"`
+	Version    int64     `json:"version"`
+}
+
+func (q *Queries) UpdateSyncedPost(ctx context.Context, arg UpdateSyncedPostParams) (Post, error) {
+	row := q.db.QueryRowContext(ctx, updateSyncedPost,
+		arg.ID,
+		arg.Title,
+		arg.CoverImage,
+		arg.PublishAt,
+		arg.Version,
+	)
+	var i Post
+	err := row.Scan(
+		&i.ID,
+		&i.AuthorID,
+		&i.Title,
+		&i.CoverImage,
+		&i.Status,
+		&i.Featured,
+		&i.ViewCount,
+		&i.UpdateAt,
+		&i.PublishAt,
+		&i.ReviewerID,
+		&i.Version,
+		&i.Visibility,
+		&i.PasswordHash,
+		&i.TrashStatus,
+		&i.DeleteAt,
+	)
+	return i, err
+}
+
+const updateSyncedPostContent = `-- name: UpdateSyncedPostContent :exec
+UPDATE post_contents SET content = $2
+WHERE id = $1
+`
+
+type UpdateSyncedPostContentParams struct {
+	ID      int64  `json:"id"`
+	Content string `json:"content"`
+}
+
+func (q *Queries) UpdateSyncedPostContent(ctx context.Context, arg UpdateSyncedPostContentParams) error {
+	_, err := q.db.ExecContext(ctx, updateSyncedPostContent, arg.ID, arg.Content)
+	return err
+}
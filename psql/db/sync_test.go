@@ -0,0 +1,87 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/bwen19/blog/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSyncPost(t *testing.T) {
+	user := createRandomUser(t)
+	category := createRandomCategory(t)
+	tag1 := createRandomTag(t)
+	tag2 := createRandomTag(t)
+
+	arg1 := SyncPostParams{
+		AuthorID:    user.ID,
+		Title:       util.RandomString(10),
+		CoverImage:  util.RandomString(10),
+		Content:     util.RandomText(20),
+		Status:      "draft",
+		PublishAt:   time.Now().Add(-time.Hour),
+		CategoryIDs: []int64{category.ID},
+		TagIDs:      []int64{tag1.ID},
+	}
+	post1, err := testStore.SyncPost(context.Background(), arg1)
+	require.NoError(t, err)
+	require.NotZero(t, post1.ID)
+	require.Equal(t, arg1.Title, post1.Title)
+	require.Equal(t, arg1.Status, post1.Status)
+
+	arg2 := SyncPostParams{
+		ID:          post1.ID,
+		Version:     post1.Version,
+		Title:       util.RandomString(10),
+		CoverImage:  post1.CoverImage,
+		Content:     util.RandomText(20),
+		Status:      "publish",
+		PublishAt:   post1.PublishAt,
+		CategoryIDs: []int64{},
+		TagIDs:      []int64{tag2.ID},
+	}
+	post2, err := testStore.SyncPost(context.Background(), arg2)
+	require.NoError(t, err)
+	require.Equal(t, post1.ID, post2.ID)
+	require.Equal(t, arg2.Title, post2.Title)
+	require.Equal(t, post1.Version+1, post2.Version)
+	require.Equal(t, post1.Status, post2.Status)
+
+	post, err := testStore.GetPost(context.Background(), GetPostParams{PostID: post1.ID, IsAdmin: true})
+	require.NoError(t, err)
+	require.Equal(t, arg2.Content, post.Content)
+	require.Empty(t, post.CategoryIds)
+	require.Equal(t, []int64{tag2.ID}, post.TagIds)
+
+	// A post changed since the file was written is not overwritten
+	_, err = testStore.SyncPost(context.Background(), arg2)
+	require.EqualError(t, err, sql.ErrNoRows.Error())
+
+	// Posts in review or published are not changed by a sync
+	for _, post := range []Post{createRandomReviewPost(t, user), createRandomPublishedPost(t, user)} {
+		arg3 := SyncPostParams{
+			ID:          post.ID,
+			Version:     post.Version,
+			Title:       util.RandomString(10),
+			CoverImage:  post.CoverImage,
+			Content:     util.RandomText(20),
+			PublishAt:   post.PublishAt,
+			CategoryIDs: []int64{},
+			TagIDs:      []int64{},
+		}
+		_, err = testStore.SyncPost(context.Background(), arg3)
+		require.EqualError(t, err, sql.ErrNoRows.Error())
+
+		got, err := testStore.GetPost(context.Background(), GetPostParams{PostID: post.ID, IsAdmin: true})
+		require.NoError(t, err)
+		require.Equal(t, post.Title, got.Title)
+		require.Equal(t, post.Version, got.Version)
+	}
+
+	ids, err := testStore.ListSyncPostIDs(context.Background(), ListSyncPostIDsParams{AuthorID: user.ID})
+	require.NoError(t, err)
+	require.Equal(t, []int64{post1.ID}, ids)
+}
@@ -133,6 +133,35 @@ func (q *Queries) GetPostTags(ctx context.Context, postID int64) ([]Tag, error)
 	return items, nil
 }
 
+const getTagsByIDs = `-- name: GetTagsByIDs :many
+SELECT id, name FROM tags
+WHERE id = ANY($1::bigint[])
+ORDER BY id ASC
+`
+
+func (q *Queries) GetTagsByIDs(ctx context.Context, ids []int64) ([]Tag, error) {
+	rows, err := q.db.QueryContext(ctx, getTagsByIDs, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Tag{}
+	for rows.Next() {
+		var i Tag
+		if err := rows.Scan(&i.ID, &i.Name); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getTagsByName = `-- name: GetTagsByName :one
 SELECT id, name FROM tags
 WHERE name = $1::varchar LIMIT 1
@@ -2,6 +2,8 @@ package db
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"time"
 )
 
@@ -23,6 +25,8 @@ type CreateNewPostRow struct {
 	ViewCount  int64     `json:"view_count"`
 	UpdateAt   time.Time `json:"update_at"`
 	PublishAt  time.Time `json:"publish_at"`
+	Version    int64     `json:"version"`
+	Visibility string    `json:"visibility"`
 }
 
 func (store *SqlStore) CreateNewPost(ctx context.Context, arg CreateNewPostParams) (CreateNewPostRow, error) {
@@ -50,6 +54,16 @@ func (store *SqlStore) CreateNewPost(ctx context.Context, arg CreateNewPostParam
 			return err
 		}
 
+		arg3 := CreatePostCollaboratorParams{
+			PostID:   post.ID,
+			UserID:   post.AuthorID,
+			Role:     "owner",
+			Accepted: true,
+		}
+		if _, err = q.CreatePostCollaborator(ctx, arg3); err != nil {
+			return err
+		}
+
 		result.ID = post.ID
 		result.Title = post.Title
 		result.AuthorID = post.AuthorID
@@ -60,6 +74,8 @@ func (store *SqlStore) CreateNewPost(ctx context.Context, arg CreateNewPostParam
 		result.ViewCount = post.ViewCount
 		result.PublishAt = post.PublishAt
 		result.UpdateAt = post.UpdateAt
+		result.Version = post.Version
+		result.Visibility = post.Visibility
 		return err
 	})
 	return result, err
@@ -140,3 +156,693 @@ func (store *SqlStore) SetPostTags(ctx context.Context, arg SetPostTagsParams) (
 	})
 	return result, err
 }
+
+// -------------------------------------------------------------------
+
+// ErrBatchRolledBack is returned when an all-or-nothing batch is rolled back
+// because some of its items could not be updated
+var ErrBatchRolledBack = errors.New("batch rolled back")
+
+type BulkUpdatePostStatusParams struct {
+	UpdatePostStatusParams
+	// Also submit the pending revisions of published posts
+	SubmitRevisions bool `json:"submit_revisions"`
+	AllOrNothing    bool `json:"all_or_nothing"`
+}
+
+type BulkUpdatePostStatusResult struct {
+	Posts     []Post         `json:"posts"`
+	Revisions []PostRevision `json:"revisions"`
+}
+
+// Updated returns whether the post or its revision was updated
+func (r BulkUpdatePostStatusResult) Updated(postID int64) bool {
+	for _, post := range r.Posts {
+		if post.ID == postID {
+			return true
+		}
+	}
+	for _, revision := range r.Revisions {
+		if revision.PostID == postID {
+			return true
+		}
+	}
+	return false
+}
+
+// BulkUpdatePostStatus changes the status of many posts at once. With
+// AllOrNothing, nothing is changed unless every post could be updated, the
+// posts that would have been updated are returned with ErrBatchRolledBack.
+func (store *SqlStore) BulkUpdatePostStatus(ctx context.Context, arg BulkUpdatePostStatusParams) (BulkUpdatePostStatusResult, error) {
+	var result BulkUpdatePostStatusResult
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		var err error
+
+		result.Posts, err = q.UpdatePostStatus(ctx, arg.UpdatePostStatusParams)
+		if err != nil {
+			return err
+		}
+
+		if arg.SubmitRevisions {
+			arg1 := SubmitPostRevisionsParams{
+				Ids:      arg.Ids,
+				IsAdmin:  arg.IsAdmin,
+				AuthorID: arg.AuthorID,
+			}
+			if result.Revisions, err = q.SubmitPostRevisions(ctx, arg1); err != nil {
+				return err
+			}
+		}
+
+		if arg.AllOrNothing && len(result.Posts)+len(result.Revisions) < len(arg.Ids) {
+			return ErrBatchRolledBack
+		}
+		return nil
+	})
+	return result, err
+}
+
+// -------------------------------------------------------------------
+
+type EditPostParams struct {
+	ID          int64          `json:"id"`
+	AuthorID    int64          `json:"author_id"`
+	Title       sql.NullString `json:"title"`
+	CoverImage  sql.NullString `json:"cover_image"`
+	Content     sql.NullString `json:"content"`
+	CategoryIDs []int64        `json:"category_ids"`
+	TagIDs      []int64        `json:"tag_ids"`
+	Version     int64          `json:"version"`
+}
+
+type EditPostResult struct {
+	Post       Post       `json:"post"`
+	Content    string     `json:"content"`
+	Categories []Category `json:"categories"`
+	Tags       []Tag      `json:"tags"`
+}
+
+// EditPost updates a draft with its content, categories and tags in one
+// transaction. Nil categories or tags are left unchanged.
+func (store *SqlStore) EditPost(ctx context.Context, arg EditPostParams) (EditPostResult, error) {
+	var result EditPostResult
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		var err error
+
+		arg1 := UpdatePostParams{
+			ID:         arg.ID,
+			AuthorID:   arg.AuthorID,
+			Title:      arg.Title,
+			CoverImage: arg.CoverImage,
+			Version:    arg.Version,
+		}
+		if result.Post, err = q.UpdatePost(ctx, arg1); err != nil {
+			return err
+		}
+
+		if arg.Content.Valid {
+			arg2 := UpdatePostContentParams{
+				ID:       arg.ID,
+				Content:  arg.Content.String,
+				AuthorID: arg.AuthorID,
+			}
+			content, err := q.UpdatePostContent(ctx, arg2)
+			if err != nil {
+				return err
+			}
+			result.Content = content.Content
+		} else {
+			if result.Content, err = q.GetPostContent(ctx, arg.ID); err != nil {
+				return err
+			}
+		}
+
+		if arg.CategoryIDs != nil {
+			arg3 := DeletePostCategoriesParams{
+				PostID:      arg.ID,
+				CategoryIds: arg.CategoryIDs,
+			}
+			if err = q.DeletePostCategories(ctx, arg3); err != nil {
+				return err
+			}
+
+			arg4 := CreatePostCategoriesParams{
+				PostID:      arg.ID,
+				CategoryIds: arg.CategoryIDs,
+			}
+			categories, err := q.CreatePostCategories(ctx, arg4)
+			if err != nil {
+				return err
+			}
+			for _, category := range categories {
+				result.Categories = append(result.Categories, Category(category))
+			}
+		} else {
+			if result.Categories, err = q.GetPostCategories(ctx, arg.ID); err != nil {
+				return err
+			}
+		}
+
+		if arg.TagIDs != nil {
+			arg5 := DeletePostTagsParams{
+				PostID: arg.ID,
+				TagIds: arg.TagIDs,
+			}
+			if err = q.DeletePostTags(ctx, arg5); err != nil {
+				return err
+			}
+
+			arg6 := CreatePostTagsParams{
+				PostID: arg.ID,
+				TagIds: arg.TagIDs,
+			}
+			tags, err := q.CreatePostTags(ctx, arg6)
+			if err != nil {
+				return err
+			}
+			for _, tag := range tags {
+				result.Tags = append(result.Tags, Tag(tag))
+			}
+		} else {
+			if result.Tags, err = q.GetPostTags(ctx, arg.ID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return result, err
+}
+
+// -------------------------------------------------------------------
+
+type ReviewPostParams struct {
+	PostID     int64  `json:"post_id"`
+	ReviewerID int64  `json:"reviewer_id"`
+	Status     string `json:"status"`
+	Kind       string `json:"kind"`
+	Reason     string `json:"reason"`
+}
+
+func (store *SqlStore) ReviewPost(ctx context.Context, arg ReviewPostParams) (Post, error) {
+	var result Post
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		var err error
+
+		arg1 := UpdateReviewedPostParams{
+			ID:         arg.PostID,
+			Status:     arg.Status,
+			ReviewerID: arg.ReviewerID,
+		}
+		result, err = q.UpdateReviewedPost(ctx, arg1)
+		if err != nil {
+			return err
+		}
+
+		arg2 := CreateReviewNoteParams{
+			PostID:  arg.PostID,
+			UserID:  arg.ReviewerID,
+			Kind:    arg.Kind,
+			Content: arg.Reason,
+		}
+		_, err = q.CreateReviewNote(ctx, arg2)
+		return err
+	})
+	return result, err
+}
+
+// -------------------------------------------------------------------
+
+// UpdatePublishedPost edits the pending revision of a published post, the
+// revision starts as a copy of the live post on the first edit
+func (store *SqlStore) UpdatePublishedPost(ctx context.Context, arg UpdatePostRevisionParams) (PostRevision, error) {
+	var result PostRevision
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		var err error
+
+		arg1 := CreatePostRevisionParams{
+			PostID:   arg.PostID,
+			AuthorID: arg.AuthorID,
+		}
+		if err = q.CreatePostRevision(ctx, arg1); err != nil {
+			return err
+		}
+
+		result, err = q.UpdatePostRevision(ctx, arg)
+		return err
+	})
+	return result, err
+}
+
+// -------------------------------------------------------------------
+
+// ReviewPostRevision approves or rejects the pending revision of a published
+// post. An approved revision replaces the live content and is removed, the
+// publish date of the post is kept.
+func (store *SqlStore) ReviewPostRevision(ctx context.Context, arg ReviewPostParams) (Post, error) {
+	var result Post
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		var err error
+
+		// Approved revisions stay in review until they are applied and removed
+		arg1 := UpdateReviewedRevisionParams{
+			PostID:     arg.PostID,
+			Status:     "review",
+			ReviewerID: arg.ReviewerID,
+		}
+		if arg.Status != "publish" {
+			arg1.Status = "revise"
+		}
+		revision, err := q.UpdateReviewedRevision(ctx, arg1)
+		if err != nil {
+			return err
+		}
+
+		arg2 := CreateReviewNoteParams{
+			PostID:  arg.PostID,
+			UserID:  arg.ReviewerID,
+			Kind:    arg.Kind,
+			Content: arg.Reason,
+		}
+		if _, err = q.CreateReviewNote(ctx, arg2); err != nil {
+			return err
+		}
+
+		if arg.Status != "publish" {
+			result, err = q.GetPostByID(ctx, arg.PostID)
+			return err
+		}
+
+		if result, err = q.ApplyPostRevision(ctx, arg.PostID); err != nil {
+			return err
+		}
+		if err = q.ApplyPostRevisionContent(ctx, arg.PostID); err != nil {
+			return err
+		}
+
+		arg3 := DeletePostCategoriesParams{
+			PostID:      arg.PostID,
+			CategoryIds: revision.CategoryIds,
+		}
+		if err = q.DeletePostCategories(ctx, arg3); err != nil {
+			return err
+		}
+		arg4 := CreatePostCategoriesParams{
+			PostID:      arg.PostID,
+			CategoryIds: revision.CategoryIds,
+		}
+		if _, err = q.CreatePostCategories(ctx, arg4); err != nil {
+			return err
+		}
+
+		arg5 := DeletePostTagsParams{
+			PostID: arg.PostID,
+			TagIds: revision.TagIds,
+		}
+		if err = q.DeletePostTags(ctx, arg5); err != nil {
+			return err
+		}
+		arg6 := CreatePostTagsParams{
+			PostID: arg.PostID,
+			TagIds: revision.TagIds,
+		}
+		if _, err = q.CreatePostTags(ctx, arg6); err != nil {
+			return err
+		}
+
+		arg7 := DeletePostRevisionParams{
+			PostID:  arg.PostID,
+			IsAdmin: true,
+		}
+		_, err = q.DeletePostRevision(ctx, arg7)
+		return err
+	})
+	return result, err
+}
+
+// -------------------------------------------------------------------
+
+// Advisory lock that keeps replicas from refreshing rankings at the same time
+const rankingLockKey = 20221101
+
+// UpdateHotRankings recomputes the hot scores of published posts. It returns
+// false without doing anything when another replica is already refreshing.
+func (store *SqlStore) UpdateHotRankings(ctx context.Context, arg RefreshPostRankingsParams) (bool, error) {
+	var result bool
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		var err error
+
+		result, err = q.TryRankingLock(ctx, rankingLockKey)
+		if err != nil || !result {
+			return err
+		}
+
+		if _, err = q.RefreshPostRankings(ctx, arg); err != nil {
+			return err
+		}
+
+		_, err = q.DeleteStalePostRankings(ctx)
+		return err
+	})
+	return result, err
+}
+
+// -------------------------------------------------------------------
+
+type ImportNewPostParams struct {
+	Source      string    `json:"source"`
+	ExternalID  string    `json:"external_id"`
+	AuthorID    int64     `json:"author_id"`
+	Title       string    `json:"title"`
+	CoverImage  string    `json:"cover_image"`
+	Content     string    `json:"content"`
+	Status      string    `json:"status"`
+	UpdateAt    time.Time `json:"update_at"`
+	PublishAt   time.Time `json:"publish_at"`
+	CategoryIDs []int64   `json:"category_ids"`
+	TagIDs      []int64   `json:"tag_ids"`
+}
+
+// ImportNewPost creates a post from another blog together with its record,
+// so that importing the same post again can be detected
+func (store *SqlStore) ImportNewPost(ctx context.Context, arg ImportNewPostParams) (Post, error) {
+	var result Post
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		var err error
+
+		arg1 := ImportPostParams{
+			AuthorID:   arg.AuthorID,
+			Title:      arg.Title,
+			CoverImage: arg.CoverImage,
+			Status:     arg.Status,
+			UpdateAt:   arg.UpdateAt,
+			PublishAt:  arg.PublishAt,
+		}
+		result, err = q.ImportPost(ctx, arg1)
+		if err != nil {
+			return err
+		}
+
+		arg2 := CreatePostContentParams{
+			ID:      result.ID,
+			Content: arg.Content,
+		}
+		if _, err = q.CreatePostContent(ctx, arg2); err != nil {
+			return err
+		}
+
+		arg3 := CreatePostCollaboratorParams{
+			PostID:   result.ID,
+			UserID:   result.AuthorID,
+			Role:     "owner",
+			Accepted: true,
+		}
+		if _, err = q.CreatePostCollaborator(ctx, arg3); err != nil {
+			return err
+		}
+
+		if len(arg.CategoryIDs) > 0 {
+			arg4 := CreatePostCategoriesParams{
+				PostID:      result.ID,
+				CategoryIds: arg.CategoryIDs,
+			}
+			if _, err = q.CreatePostCategories(ctx, arg4); err != nil {
+				return err
+			}
+		}
+
+		if len(arg.TagIDs) > 0 {
+			arg5 := CreatePostTagsParams{
+				PostID: result.ID,
+				TagIds: arg.TagIDs,
+			}
+			if _, err = q.CreatePostTags(ctx, arg5); err != nil {
+				return err
+			}
+		}
+
+		arg6 := CreateImportRecordParams{
+			Source:     arg.Source,
+			Kind:       "post",
+			ExternalID: arg.ExternalID,
+			LocalID:    result.ID,
+		}
+		return q.CreateImportRecord(ctx, arg6)
+	})
+	return result, err
+}
+
+// -------------------------------------------------------------------
+
+type ImportNewCommentParams struct {
+	Source     string `json:"source"`
+	ExternalID string `json:"external_id"`
+	ImportCommentParams
+}
+
+// ImportNewComment creates a comment from another blog together with its record
+func (store *SqlStore) ImportNewComment(ctx context.Context, arg ImportNewCommentParams) (Comment, error) {
+	var result Comment
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		var err error
+
+		result, err = q.ImportComment(ctx, arg.ImportCommentParams)
+		if err != nil {
+			return err
+		}
+
+		arg1 := CreateImportRecordParams{
+			Source:     arg.Source,
+			Kind:       "comment",
+			ExternalID: arg.ExternalID,
+			LocalID:    result.ID,
+		}
+		return q.CreateImportRecord(ctx, arg1)
+	})
+	return result, err
+}
+
+// -------------------------------------------------------------------
+
+type SyncPostParams struct {
+	ID          int64     `json:"id"`
+	Version     int64     `json:"version"`
+	AuthorID    int64     `json:"author_id"`
+	Title       string    `json:"title"`
+	CoverImage  string    `json:"cover_image"`
+	Content     string    `json:"content"`
+	Status      string    `json:"status"`
+	PublishAt   time.Time `json:"publish_at"`
+	CategoryIDs []int64   `json:"category_ids"`
+	TagIDs      []int64   `json:"tag_ids"`
+}
+
+// SyncPost saves a post edited as a Markdown file. A post without ID is
+// created for the author with the given status, otherwise the post keeps
+// its status and is only updated if it is a draft or being revised and its
+// version is unchanged since the file was written, or sql.ErrNoRows is
+// returned. Posts in review or published are only changed on the blog.
+func (store *SqlStore) SyncPost(ctx context.Context, arg SyncPostParams) (Post, error) {
+	var result Post
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		var err error
+
+		if arg.ID == 0 {
+			arg1 := ImportPostParams{
+				AuthorID:   arg.AuthorID,
+				Title:      arg.Title,
+				CoverImage: arg.CoverImage,
+				Status:     arg.Status,
+				UpdateAt:   time.Now(),
+				PublishAt:  arg.PublishAt,
+			}
+			result, err = q.ImportPost(ctx, arg1)
+			if err != nil {
+				return err
+			}
+
+			arg2 := CreatePostContentParams{
+				ID:      result.ID,
+				Content: arg.Content,
+			}
+			if _, err = q.CreatePostContent(ctx, arg2); err != nil {
+				return err
+			}
+
+			arg3 := CreatePostCollaboratorParams{
+				PostID:   result.ID,
+				UserID:   result.AuthorID,
+				Role:     "owner",
+				Accepted: true,
+			}
+			if _, err = q.CreatePostCollaborator(ctx, arg3); err != nil {
+				return err
+			}
+		} else {
+			arg1 := UpdateSyncedPostParams{
+				ID:         arg.ID,
+				Title:      arg.Title,
+				CoverImage: arg.CoverImage,
+				PublishAt:  arg.PublishAt,
+				Version:    arg.Version,
+			}
+			result, err = q.UpdateSyncedPost(ctx, arg1)
+			if err != nil {
+				return err
+			}
+
+			arg2 := UpdateSyncedPostContentParams{
+				ID:      arg.ID,
+				Content: arg.Content,
+			}
+			if err = q.UpdateSyncedPostContent(ctx, arg2); err != nil {
+				return err
+			}
+		}
+
+		arg4 := DeletePostCategoriesParams{
+			PostID:      result.ID,
+			CategoryIds: arg.CategoryIDs,
+		}
+		if err = q.DeletePostCategories(ctx, arg4); err != nil {
+			return err
+		}
+
+		arg5 := CreatePostCategoriesParams{
+			PostID:      result.ID,
+			CategoryIds: arg.CategoryIDs,
+		}
+		if _, err = q.CreatePostCategories(ctx, arg5); err != nil {
+			return err
+		}
+
+		arg6 := DeletePostTagsParams{
+			PostID: result.ID,
+			TagIds: arg.TagIDs,
+		}
+		if err = q.DeletePostTags(ctx, arg6); err != nil {
+			return err
+		}
+
+		arg7 := CreatePostTagsParams{
+			PostID: result.ID,
+			TagIds: arg.TagIDs,
+		}
+		_, err = q.CreatePostTags(ctx, arg7)
+		return err
+	})
+	return result, err
+}
+
+// -------------------------------------------------------------------
+
+var errDryRun = errors.New("dry run")
+
+type SweepMediaParams struct {
+	// Paths that are never collected, such as the default avatar and cover
+	KeepPaths []string `json:"keep_paths"`
+	// Orphans marked before this time are deleted
+	OrphanBefore time.Time `json:"orphan_before"`
+	DryRun       bool      `json:"dry_run"`
+}
+
+type SweepMediaResult struct {
+	Marked   []Media        `json:"marked"`
+	Restored int64          `json:"restored"`
+	Deleted  []Media        `json:"deleted"`
+	Variants []MediaVariant `json:"variants"`
+}
+
+// SweepMedia marks the media that is no longer referenced by any post, series
+// or user, restores the marked media that is referenced again and deletes the
+// media marked before OrphanBefore. The files of deleted media and their
+// variants have to be removed by the caller. A dry run reports the same
+// result without changes.
+func (store *SqlStore) SweepMedia(ctx context.Context, arg SweepMediaParams) (SweepMediaResult, error) {
+	var result SweepMediaResult
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		var err error
+
+		ids, err := q.ListUnreferencedMedia(ctx, arg.KeepPaths)
+		if err != nil {
+			return err
+		}
+
+		if result.Restored, err = q.UnmarkReferencedMedia(ctx, ids); err != nil {
+			return err
+		}
+		if result.Marked, err = q.MarkOrphanedMedia(ctx, ids); err != nil {
+			return err
+		}
+
+		// Variants are deleted along with their media, so they are listed first
+		variants, err := q.ListMediaVariants(ctx, ids)
+		if err != nil {
+			return err
+		}
+
+		arg1 := DeleteOrphanedMediaParams{
+			Ids:          ids,
+			OrphanBefore: arg.OrphanBefore,
+		}
+		if result.Deleted, err = q.DeleteOrphanedMedia(ctx, arg1); err != nil {
+			return err
+		}
+
+		deleted := make(map[int64]bool, len(result.Deleted))
+		for _, media := range result.Deleted {
+			deleted[media.ID] = true
+		}
+		result.Variants = []MediaVariant{}
+		for _, variant := range variants {
+			if deleted[variant.MediaID] {
+				result.Variants = append(result.Variants, variant)
+			}
+		}
+
+		if arg.DryRun {
+			return errDryRun
+		}
+		return nil
+	})
+	if err == errDryRun {
+		err = nil
+	}
+	return result, err
+}
+
+// -------------------------------------------------------------------
+
+type AddMediaVariantsParams struct {
+	MediaID int64 `json:"media_id"`
+	// MediaID of the variants is set to the id of the media
+	Variants []CreateMediaVariantParams `json:"variants"`
+}
+
+// AddMediaVariants records the resized variants of a media all at once. It
+// fails if the media has been deleted since its variants were written.
+func (store *SqlStore) AddMediaVariants(ctx context.Context, arg AddMediaVariantsParams) ([]MediaVariant, error) {
+	result := make([]MediaVariant, 0, len(arg.Variants))
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		for _, arg1 := range arg.Variants {
+			arg1.MediaID = arg.MediaID
+			variant, err := q.CreateMediaVariant(ctx, arg1)
+			if err != nil {
+				return err
+			}
+			result = append(result, variant)
+		}
+		return nil
+	})
+	return result, err
+}
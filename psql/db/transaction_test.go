@@ -2,6 +2,7 @@ package db
 
 import (
 	"context"
+	"database/sql"
 	"testing"
 
 	"github.com/bwen19/blog/util"
@@ -121,3 +122,160 @@ func TestAll(t *testing.T) {
 	require.NoError(t, err)
 	require.NotEmpty(t, posts)
 }
+
+func TestEditPost(t *testing.T) {
+	user := createRandomUser(t)
+	post := createRandomPost(t, user)
+	category := createRandomCategory(t)
+	tag := createRandomTag(t)
+
+	arg := EditPostParams{
+		ID:          post.ID,
+		AuthorID:    user.ID,
+		Title:       sql.NullString{String: util.RandomText(10), Valid: true},
+		Content:     sql.NullString{String: util.RandomText(50), Valid: true},
+		CategoryIDs: []int64{category.ID},
+		TagIDs:      []int64{tag.ID},
+		Version:     post.Version,
+	}
+	result, err := testStore.EditPost(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, arg.Title.String, result.Post.Title)
+	require.Equal(t, post.CoverImage, result.Post.CoverImage)
+	require.Equal(t, post.Version+1, result.Post.Version)
+	require.Equal(t, arg.Content.String, result.Content)
+	require.Len(t, result.Categories, 1)
+	require.Equal(t, category.ID, result.Categories[0].ID)
+	require.Len(t, result.Tags, 1)
+	require.Equal(t, tag.ID, result.Tags[0].ID)
+
+	// Fields left out of the edit are returned unchanged
+	arg2 := EditPostParams{
+		ID:       post.ID,
+		AuthorID: user.ID,
+		Version:  result.Post.Version,
+	}
+	result2, err := testStore.EditPost(context.Background(), arg2)
+	require.NoError(t, err)
+	require.Equal(t, result.Post.Title, result2.Post.Title)
+	require.Equal(t, result.Content, result2.Content)
+	require.Equal(t, result.Categories, result2.Categories)
+	require.Equal(t, result.Tags, result2.Tags)
+
+	_, err = testStore.EditPost(context.Background(), arg2)
+	require.EqualError(t, err, sql.ErrNoRows.Error())
+}
+
+func TestEditPostRollback(t *testing.T) {
+	user := createRandomUser(t)
+	oldCategory := createRandomCategory(t)
+	oldTag := createRandomTag(t)
+	newCategory := createRandomCategory(t)
+	newTag := createRandomTag(t)
+
+	// Each step fails at the first statement after it, the last one at commit
+	steps := []struct {
+		name  string
+		query string
+	}{
+		{"post", "UpdatePostContent"},
+		{"content", "DeletePostCategories"},
+		{"categories", "DeletePostTags"},
+		{"tags", "COMMIT"},
+	}
+
+	for _, step := range steps {
+		t.Run(step.name, func(t *testing.T) {
+			post := createRandomPost(t, user)
+
+			_, err := testStore.SetPostCategories(context.Background(), SetPostCategoriesParams{
+				PostID:      post.ID,
+				CategoryIDs: []int64{oldCategory.ID},
+			})
+			require.NoError(t, err)
+			_, err = testStore.SetPostTags(context.Background(), SetPostTagsParams{
+				PostID: post.ID,
+				TagIDs: []int64{oldTag.ID},
+			})
+			require.NoError(t, err)
+
+			faultStore := newFaultStore(t, step.query)
+			arg := EditPostParams{
+				ID:          post.ID,
+				AuthorID:    user.ID,
+				Title:       sql.NullString{String: util.RandomText(10), Valid: true},
+				CoverImage:  sql.NullString{String: "/image/post/new", Valid: true},
+				Content:     sql.NullString{String: util.RandomText(50), Valid: true},
+				CategoryIDs: []int64{newCategory.ID},
+				TagIDs:      []int64{newTag.ID},
+				Version:     post.Version,
+			}
+			_, err = faultStore.EditPost(context.Background(), arg)
+			require.ErrorIs(t, err, errFault)
+
+			arg2 := GetPostParams{
+				PostID:   post.ID,
+				AuthorID: user.ID,
+			}
+			post2, err := testStore.GetPost(context.Background(), arg2)
+			require.NoError(t, err)
+			require.Equal(t, post.Title, post2.Title)
+			require.Equal(t, post.CoverImage, post2.CoverImage)
+			require.Equal(t, post.Content, post2.Content)
+			require.Equal(t, post.Version, post2.Version)
+			require.Equal(t, []int64{oldCategory.ID}, post2.CategoryIds)
+			require.Equal(t, []int64{oldTag.ID}, post2.TagIds)
+		})
+	}
+}
+
+func TestBulkUpdatePostStatus(t *testing.T) {
+	author := createRandomUser(t)
+	other := createRandomUser(t)
+	draft := createRandomPost(t, author)
+	review := createRandomReviewPost(t, author)
+	foreign := createRandomPost(t, other)
+
+	ids := []int64{draft.ID, review.ID, foreign.ID}
+	arg := BulkUpdatePostStatusParams{
+		UpdatePostStatusParams: UpdatePostStatusParams{
+			Ids:       ids,
+			Status:    "review",
+			OldStatus: []string{"draft", "revise"},
+			AuthorID:  author.ID,
+		},
+		AllOrNothing: true,
+	}
+
+	// Nothing is changed when a single post fails
+	result, err := testStore.BulkUpdatePostStatus(context.Background(), arg)
+	require.ErrorIs(t, err, ErrBatchRolledBack)
+	require.True(t, result.Updated(draft.ID))
+	require.False(t, result.Updated(review.ID))
+
+	post, err := testStore.GetPost(context.Background(), GetPostParams{PostID: draft.ID, AuthorID: author.ID})
+	require.NoError(t, err)
+	require.Equal(t, "draft", post.Status)
+
+	arg.AllOrNothing = false
+	result, err = testStore.BulkUpdatePostStatus(context.Background(), arg)
+	require.NoError(t, err)
+	require.Len(t, result.Posts, 1)
+	require.Equal(t, draft.ID, result.Posts[0].ID)
+	require.Equal(t, "review", result.Posts[0].Status)
+
+	access, err := testStore.ListPostAccess(context.Background(), ListPostAccessParams{
+		Ids:      []int64{review.ID, foreign.ID, util.RandomInt(1e9, 2e9)},
+		AuthorID: author.ID,
+	})
+	require.NoError(t, err)
+	require.Len(t, access, 2)
+	for _, row := range access {
+		if row.ID == review.ID {
+			require.True(t, row.IsAuthor)
+			require.Equal(t, "review", row.Status)
+		} else {
+			require.False(t, row.IsAuthor)
+		}
+	}
+}
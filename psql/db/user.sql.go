@@ -15,7 +15,7 @@ import (
 
 const createUser = `-- name: CreateUser :one
 INSERT INTO users (username, email, hashed_password, avatar, role)
-VALUES ($1, $2, $3, $4, $5) RETURNING id, username, email, hashed_password, avatar, intro, role, deleted, create_at
+VALUES ($1, $2, $3, $4, $5) RETURNING id, username, email, hashed_password, avatar, intro, role, deleted, create_at, public_stars
 `
 
 type CreateUserParams struct {
@@ -45,6 +45,7 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		&i.Role,
 		&i.Deleted,
 		&i.CreateAt,
+		&i.PublicStars,
 	)
 	return i, err
 }
@@ -62,7 +63,7 @@ func (q *Queries) DeleteUsers(ctx context.Context, ids []int64) (int64, error) {
 }
 
 const getUser = `-- name: GetUser :one
-SELECT id, username, email, hashed_password, avatar, intro, role, deleted, create_at FROM users
+SELECT id, username, email, hashed_password, avatar, intro, role, deleted, create_at, public_stars FROM users
 WHERE id = $1 LIMIT 1
 `
 
@@ -79,12 +80,13 @@ func (q *Queries) GetUser(ctx context.Context, id int64) (User, error) {
 		&i.Role,
 		&i.Deleted,
 		&i.CreateAt,
+		&i.PublicStars,
 	)
 	return i, err
 }
 
 const getUserByEmail = `-- name: GetUserByEmail :one
-SELECT id, username, email, hashed_password, avatar, intro, role, deleted, create_at FROM users
+SELECT id, username, email, hashed_password, avatar, intro, role, deleted, create_at, public_stars FROM users
 WHERE email = $1 LIMIT 1
 `
 
@@ -101,12 +103,13 @@ func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error
 		&i.Role,
 		&i.Deleted,
 		&i.CreateAt,
+		&i.PublicStars,
 	)
 	return i, err
 }
 
 const getUserByUsername = `-- name: GetUserByUsername :one
-SELECT id, username, email, hashed_password, avatar, intro, role, deleted, create_at FROM users
+SELECT id, username, email, hashed_password, avatar, intro, role, deleted, create_at, public_stars FROM users
 WHERE username = $1 LIMIT 1
 `
 
@@ -123,6 +126,7 @@ func (q *Queries) GetUserByUsername(ctx context.Context, username string) (User,
 		&i.Role,
 		&i.Deleted,
 		&i.CreateAt,
+		&i.PublicStars,
 	)
 	return i, err
 }
@@ -135,7 +139,7 @@ WITH SUM_CTE AS (
     SELECT p.id, p.view_count, count(ps.user_id) star_count
     FROM posts p
     LEFT JOIN post_stars ps ON ps.post_id = p.id
-    WHERE p.author_id = $1::bigint
+    WHERE p.author_id = $1::bigint AND p.status <> 'trash'
     GROUP BY p.id, p.view_count
   ) pc
 )
@@ -186,6 +190,34 @@ func (q *Queries) GetUserProfile(ctx context.Context, arg GetUserProfileParams)
 	return i, err
 }
 
+const listAdminIDs = `-- name: ListAdminIDs :many
+SELECT id FROM users
+WHERE role = 'admin' AND deleted = false
+`
+
+func (q *Queries) ListAdminIDs(ctx context.Context) ([]int64, error) {
+	rows, err := q.db.QueryContext(ctx, listAdminIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []int64{}
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listUsers = `-- name: ListUsers :many
 WITH Data_CTE AS (
   SELECT id, username, email, avatar, role, deleted, create_at
@@ -296,9 +328,10 @@ SET
   avatar = coalesce($5, avatar),
   intro = coalesce($6, intro),
   role = coalesce($7, role),
-  deleted = coalesce($8, deleted)
+  deleted = coalesce($8, deleted),
+  public_stars = coalesce($9, public_stars)
 WHERE id = $1
-RETURNING id, username, email, hashed_password, avatar, intro, role, deleted, create_at
+RETURNING id, username, email, hashed_password, avatar, intro, role, deleted, create_at, public_stars
 `
 
 type UpdateUserParams struct {
@@ -310,6 +343,7 @@ type UpdateUserParams struct {
 	Intro          sql.NullString `json:"intro"`
 	Role           sql.NullString `json:"role"`
 	Deleted        sql.NullBool   `json:"deleted"`
+	PublicStars    sql.NullBool   `json:"public_stars"`
 }
 
 func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) (User, error) {
@@ -322,6 +356,7 @@ func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) (User, e
 		arg.Intro,
 		arg.Role,
 		arg.Deleted,
+		arg.PublicStars,
 	)
 	var i User
 	err := row.Scan(
@@ -334,6 +369,7 @@ func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) (User, e
 		&i.Role,
 		&i.Deleted,
 		&i.CreateAt,
+		&i.PublicStars,
 	)
 	return i, err
 }
@@ -0,0 +1,160 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.15.0
+// source: view.sql
+
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const deletePostViewers = `-- name: DeletePostViewers :execrows
+DELETE FROM post_viewers
+WHERE view_at < $1::timestamptz
+`
+
+func (q *Queries) DeletePostViewers(ctx context.Context, before time.Time) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deletePostViewers, before)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const flushPostReads = `-- name: FlushPostReads :exec
+INSERT INTO post_reads (user_id, post_id, read_at)
+SELECT r.user_id, r.post_id, now()
+FROM (
+  SELECT unnest($1::bigint[]) user_id,
+      unnest($2::bigint[]) post_id
+) r
+JOIN posts p ON p.id = r.post_id
+JOIN users u ON u.id = r.user_id
+ORDER BY r.user_id, r.post_id
+ON CONFLICT (user_id, post_id) DO UPDATE SET read_at = now()
+`
+
+type FlushPostReadsParams struct {
+	UserIds []int64 `json:"user_ids"`
+	PostIds []int64 `json:"post_ids"`
+}
+
+func (q *Queries) FlushPostReads(ctx context.Context, arg FlushPostReadsParams) error {
+	_, err := q.db.ExecContext(ctx, flushPostReads, pq.Array(arg.UserIds), pq.Array(arg.PostIds))
+	return err
+}
+
+const flushPostViews = `-- name: FlushPostViews :execrows
+WITH Viewer_CTE AS (
+  INSERT INTO post_viewers (post_id, visitor, view_at, source)
+  SELECT v.post_id, v.visitor, now(), v.source
+  FROM (
+    SELECT unnest($1::bigint[]) post_id,
+        unnest($2::varchar[]) visitor,
+        unnest($3::varchar[]) source
+  ) v
+  JOIN posts p ON p.id = v.post_id
+  ORDER BY v.post_id, v.visitor
+  ON CONFLICT (post_id, visitor) DO UPDATE
+  SET view_at = now(), source = excluded.source
+  WHERE post_viewers.view_at < $4::timestamptz
+  RETURNING post_id, source
+),
+Count_CTE AS (
+  SELECT post_id, count(*) delta
+  FROM Viewer_CTE
+  GROUP BY post_id
+),
+Daily_CTE AS (
+  INSERT INTO post_daily_views (post_id, day, views)
+  SELECT post_id, current_date, delta FROM Count_CTE
+  ON CONFLICT (post_id, day) DO UPDATE
+  SET views = post_daily_views.views + excluded.views
+  RETURNING post_id
+),
+Source_CTE AS (
+  INSERT INTO post_daily_sources (post_id, day, source, views)
+  SELECT post_id, current_date, source, count(*)
+  FROM Viewer_CTE
+  GROUP BY post_id, source
+  ORDER BY post_id, source
+  ON CONFLICT (post_id, day, source) DO UPDATE
+  SET views = post_daily_sources.views + excluded.views
+),
+Lock_CTE AS (
+  SELECT id FROM posts
+  WHERE id IN (SELECT post_id FROM Daily_CTE)
+  ORDER BY id
+  FOR UPDATE
+)
+UPDATE posts
+SET view_count = view_count + cc.delta
+FROM Count_CTE cc, Lock_CTE lc
+WHERE posts.id = cc.post_id AND lc.id = cc.post_id
+`
+
+type FlushPostViewsParams struct {
+	PostIds  []int64   `json:"post_ids"`
+	Visitors []string  `json:"visitors"`
+	Sources  []string  `json:"sources"`
+	Since    time.Time `json:"since"`
+}
+
+func (q *Queries) FlushPostViews(ctx context.Context, arg FlushPostViewsParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, flushPostViews,
+		pq.Array(arg.PostIds),
+		pq.Array(arg.Visitors),
+		pq.Array(arg.Sources),
+		arg.Since,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const listPostDailyViews = `-- name: ListPostDailyViews :many
+SELECT day, views FROM post_daily_views
+WHERE post_id = $1::bigint
+  AND day >= $2::date
+  AND day <= $3::date
+ORDER BY day ASC
+`
+
+type ListPostDailyViewsParams struct {
+	PostID   int64     `json:"post_id"`
+	StartDay time.Time `json:"start_day"`
+	EndDay   time.Time `json:"end_day"`
+}
+
+type ListPostDailyViewsRow struct {
+	Day   time.Time `json:"day"`
+	Views int64     `json:"views"`
+}
+
+func (q *Queries) ListPostDailyViews(ctx context.Context, arg ListPostDailyViewsParams) ([]ListPostDailyViewsRow, error) {
+	rows, err := q.db.QueryContext(ctx, listPostDailyViews, arg.PostID, arg.StartDay, arg.EndDay)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListPostDailyViewsRow{}
+	for rows.Next() {
+		var i ListPostDailyViewsRow
+		if err := rows.Scan(&i.Day, &i.Views); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
@@ -0,0 +1,62 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/bwen19/blog/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFlushPostViews(t *testing.T) {
+	user := createRandomUser(t)
+	post1 := createRandomPost(t, user)
+	post2 := createRandomPost(t, user)
+	visitor1 := util.RandomString(10)
+	visitor2 := util.RandomString(10)
+
+	arg1 := FlushPostViewsParams{
+		PostIds:  []int64{post1.ID, post1.ID, post2.ID},
+		Visitors: []string{visitor1, visitor2, visitor1},
+		Sources:  []string{"direct", "google.com", "direct"},
+		Since:    time.Now().Add(-time.Hour),
+	}
+	nrows, err := testStore.FlushPostViews(context.Background(), arg1)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), nrows)
+
+	// Visitors seen within the window are not counted again
+	nrows, err = testStore.FlushPostViews(context.Background(), arg1)
+	require.NoError(t, err)
+	require.Zero(t, nrows)
+
+	arg1.Since = time.Now().Add(time.Second)
+	arg1.PostIds = []int64{post1.ID}
+	arg1.Visitors = []string{visitor1}
+	arg1.Sources = []string{"direct"}
+	nrows, err = testStore.FlushPostViews(context.Background(), arg1)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), nrows)
+
+	arg2 := ListPostDailyViewsParams{
+		PostID:   post1.ID,
+		StartDay: time.Now().AddDate(0, 0, -1),
+		EndDay:   time.Now().AddDate(0, 0, 1),
+	}
+	views, err := testStore.ListPostDailyViews(context.Background(), arg2)
+	require.NoError(t, err)
+	require.Len(t, views, 1)
+	require.Equal(t, int64(3), views[0].Views)
+
+	arg3 := GetPostParams{
+		PostID:   post1.ID,
+		AuthorID: user.ID,
+	}
+	post, err := testStore.GetPost(context.Background(), arg3)
+	require.NoError(t, err)
+	require.Equal(t, int64(3), post.ViewCount)
+
+	_, err = testStore.DeletePostViewers(context.Background(), time.Now().Add(time.Second))
+	require.NoError(t, err)
+}
@@ -0,0 +1,15 @@
+CREATE TABLE "post_collaborators" (
+  "post_id" bigint,
+  "user_id" bigint,
+  "role" varchar NOT NULL DEFAULT 'editor',
+  "accepted" boolean NOT NULL DEFAULT false,
+  "create_at" timestamptz NOT NULL DEFAULT (now()),
+  PRIMARY KEY ("post_id", "user_id")
+);
+
+ALTER TABLE "post_collaborators" ADD FOREIGN KEY ("post_id") REFERENCES "posts" ("id") ON DELETE CASCADE ON UPDATE NO ACTION;
+
+ALTER TABLE "post_collaborators" ADD FOREIGN KEY ("user_id") REFERENCES "users" ("id") ON DELETE CASCADE ON UPDATE NO ACTION;
+
+INSERT INTO post_collaborators (post_id, user_id, role, accepted, create_at)
+SELECT id, author_id, 'owner', true, update_at FROM posts;
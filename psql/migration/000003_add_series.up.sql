@@ -0,0 +1,21 @@
+CREATE TABLE "series" (
+  "id" bigserial PRIMARY KEY,
+  "author_id" bigint NOT NULL,
+  "title" varchar NOT NULL,
+  "description" varchar NOT NULL DEFAULT '',
+  "cover_image" varchar NOT NULL,
+  "create_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE TABLE "series_posts" (
+  "series_id" bigint,
+  "post_id" bigint UNIQUE NOT NULL,
+  "position" int NOT NULL,
+  PRIMARY KEY ("series_id", "post_id")
+);
+
+ALTER TABLE "series" ADD FOREIGN KEY ("author_id") REFERENCES "users" ("id") ON DELETE CASCADE ON UPDATE NO ACTION;
+
+ALTER TABLE "series_posts" ADD FOREIGN KEY ("series_id") REFERENCES "series" ("id") ON DELETE CASCADE ON UPDATE NO ACTION;
+
+ALTER TABLE "series_posts" ADD FOREIGN KEY ("post_id") REFERENCES "posts" ("id") ON DELETE CASCADE ON UPDATE NO ACTION;
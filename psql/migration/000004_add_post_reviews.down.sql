@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS review_notes;
+ALTER TABLE posts DROP COLUMN IF EXISTS reviewer_id;
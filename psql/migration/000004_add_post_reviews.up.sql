@@ -0,0 +1,19 @@
+ALTER TABLE "posts" ADD COLUMN "reviewer_id" bigint;
+
+CREATE TABLE "review_notes" (
+  "id" bigserial PRIMARY KEY,
+  "post_id" bigint NOT NULL,
+  "user_id" bigint NOT NULL,
+  "kind" varchar NOT NULL DEFAULT 'note',
+  "content" text NOT NULL,
+  "anchor_start" int,
+  "anchor_end" int,
+  "anchor_text" text NOT NULL DEFAULT '',
+  "create_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+ALTER TABLE "posts" ADD FOREIGN KEY ("reviewer_id") REFERENCES "users" ("id") ON DELETE SET NULL ON UPDATE NO ACTION;
+
+ALTER TABLE "review_notes" ADD FOREIGN KEY ("post_id") REFERENCES "posts" ("id") ON DELETE CASCADE ON UPDATE NO ACTION;
+
+ALTER TABLE "review_notes" ADD FOREIGN KEY ("user_id") REFERENCES "users" ("id") ON DELETE CASCADE ON UPDATE NO ACTION;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS post_daily_views;
+DROP TABLE IF EXISTS post_viewers;
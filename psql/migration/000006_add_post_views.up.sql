@@ -0,0 +1,19 @@
+CREATE TABLE "post_viewers" (
+  "post_id" bigint,
+  "visitor" varchar,
+  "view_at" timestamptz NOT NULL DEFAULT (now()),
+  PRIMARY KEY ("post_id", "visitor")
+);
+
+CREATE TABLE "post_daily_views" (
+  "post_id" bigint,
+  "day" date,
+  "views" bigint NOT NULL DEFAULT 0,
+  PRIMARY KEY ("post_id", "day")
+);
+
+CREATE INDEX ON "post_viewers" ("view_at");
+
+ALTER TABLE "post_viewers" ADD FOREIGN KEY ("post_id") REFERENCES "posts" ("id") ON DELETE CASCADE ON UPDATE NO ACTION;
+
+ALTER TABLE "post_daily_views" ADD FOREIGN KEY ("post_id") REFERENCES "posts" ("id") ON DELETE CASCADE ON UPDATE NO ACTION;
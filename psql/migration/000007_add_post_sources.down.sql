@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS post_daily_sources;
+ALTER TABLE post_viewers DROP COLUMN IF EXISTS source;
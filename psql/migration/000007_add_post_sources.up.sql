@@ -0,0 +1,11 @@
+ALTER TABLE "post_viewers" ADD COLUMN "source" varchar NOT NULL DEFAULT '';
+
+CREATE TABLE "post_daily_sources" (
+  "post_id" bigint,
+  "day" date,
+  "source" varchar,
+  "views" bigint NOT NULL DEFAULT 0,
+  PRIMARY KEY ("post_id", "day", "source")
+);
+
+ALTER TABLE "post_daily_sources" ADD FOREIGN KEY ("post_id") REFERENCES "posts" ("id") ON DELETE CASCADE ON UPDATE NO ACTION;
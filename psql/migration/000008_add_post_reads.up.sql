@@ -0,0 +1,10 @@
+CREATE TABLE "post_reads" (
+  "user_id" bigint,
+  "post_id" bigint,
+  "read_at" timestamptz NOT NULL DEFAULT (now()),
+  PRIMARY KEY ("user_id", "post_id")
+);
+
+ALTER TABLE "post_reads" ADD FOREIGN KEY ("user_id") REFERENCES "users" ("id") ON DELETE CASCADE ON UPDATE NO ACTION;
+
+ALTER TABLE "post_reads" ADD FOREIGN KEY ("post_id") REFERENCES "posts" ("id") ON DELETE CASCADE ON UPDATE NO ACTION;
@@ -0,0 +1,9 @@
+CREATE TABLE "post_rankings" (
+  "post_id" bigint PRIMARY KEY,
+  "score" float8 NOT NULL DEFAULT 0,
+  "update_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON "post_rankings" ("score");
+
+ALTER TABLE "post_rankings" ADD FOREIGN KEY ("post_id") REFERENCES "posts" ("id") ON DELETE CASCADE ON UPDATE NO ACTION;
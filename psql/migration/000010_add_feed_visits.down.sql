@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS feed_visits;
+
+DROP INDEX IF EXISTS follows_follower_id_idx;
+
+DROP INDEX IF EXISTS posts_author_id_publish_at_idx;
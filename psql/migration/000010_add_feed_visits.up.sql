@@ -0,0 +1,10 @@
+CREATE TABLE "feed_visits" (
+  "user_id" bigint PRIMARY KEY,
+  "visit_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON "follows" ("follower_id");
+
+CREATE INDEX ON "posts" ("author_id", "publish_at");
+
+ALTER TABLE "feed_visits" ADD FOREIGN KEY ("user_id") REFERENCES "users" ("id") ON DELETE CASCADE ON UPDATE NO ACTION;
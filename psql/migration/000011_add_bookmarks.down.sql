@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS bookmarks;
+
+DROP TABLE IF EXISTS reading_lists;
@@ -0,0 +1,28 @@
+CREATE TABLE "reading_lists" (
+  "id" bigserial PRIMARY KEY,
+  "user_id" bigint NOT NULL,
+  "name" varchar NOT NULL,
+  "position" int NOT NULL,
+  "create_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE TABLE "bookmarks" (
+  "user_id" bigint,
+  "post_id" bigint,
+  "list_id" bigint NOT NULL,
+  "note" varchar NOT NULL DEFAULT '',
+  "create_at" timestamptz NOT NULL DEFAULT (now()),
+  PRIMARY KEY ("user_id", "post_id")
+);
+
+CREATE UNIQUE INDEX ON "reading_lists" ("user_id", "name");
+
+CREATE INDEX ON "bookmarks" ("list_id", "create_at");
+
+ALTER TABLE "reading_lists" ADD FOREIGN KEY ("user_id") REFERENCES "users" ("id") ON DELETE CASCADE ON UPDATE NO ACTION;
+
+ALTER TABLE "bookmarks" ADD FOREIGN KEY ("user_id") REFERENCES "users" ("id") ON DELETE CASCADE ON UPDATE NO ACTION;
+
+ALTER TABLE "bookmarks" ADD FOREIGN KEY ("post_id") REFERENCES "posts" ("id") ON DELETE CASCADE ON UPDATE NO ACTION;
+
+ALTER TABLE "bookmarks" ADD FOREIGN KEY ("list_id") REFERENCES "reading_lists" ("id") ON DELETE CASCADE ON UPDATE NO ACTION;
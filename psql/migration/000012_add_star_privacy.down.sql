@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS post_stars_user_id_create_at_idx;
+
+ALTER TABLE users DROP COLUMN IF EXISTS public_stars;
@@ -0,0 +1,3 @@
+ALTER TABLE "users" ADD COLUMN "public_stars" boolean NOT NULL DEFAULT true;
+
+CREATE INDEX ON "post_stars" ("user_id", "create_at");
@@ -0,0 +1,8 @@
+CREATE TABLE "import_records" (
+  "source" varchar,
+  "kind" varchar,
+  "external_id" varchar,
+  "local_id" bigint NOT NULL,
+  "create_at" timestamptz NOT NULL DEFAULT (now()),
+  PRIMARY KEY ("source", "kind", "external_id")
+);
@@ -0,0 +1,2 @@
+ALTER TABLE "posts" ADD COLUMN "visibility" varchar NOT NULL DEFAULT 'public';
+ALTER TABLE "posts" ADD COLUMN "password_hash" varchar NOT NULL DEFAULT '';
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS posts_status_delete_at_idx;
+
+UPDATE posts SET status = coalesce(nullif(trash_status, ''), 'draft')
+WHERE status = 'trash';
+
+ALTER TABLE posts DROP COLUMN IF EXISTS delete_at;
+ALTER TABLE posts DROP COLUMN IF EXISTS trash_status;
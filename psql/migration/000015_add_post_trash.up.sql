@@ -0,0 +1,4 @@
+ALTER TABLE "posts" ADD COLUMN "trash_status" varchar NOT NULL DEFAULT '';
+ALTER TABLE "posts" ADD COLUMN "delete_at" timestamptz;
+
+CREATE INDEX ON "posts" ("status", "delete_at");
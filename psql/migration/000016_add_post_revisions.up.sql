@@ -0,0 +1,18 @@
+CREATE TABLE "post_revisions" (
+  "post_id" bigint PRIMARY KEY,
+  "title" varchar NOT NULL,
+  "cover_image" varchar NOT NULL,
+  "content" text NOT NULL,
+  "category_ids" bigint[] NOT NULL DEFAULT '{}',
+  "tag_ids" bigint[] NOT NULL DEFAULT '{}',
+  "status" varchar NOT NULL DEFAULT 'draft',
+  "reviewer_id" bigint,
+  "version" bigint NOT NULL,
+  "update_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON "post_revisions" ("status");
+
+ALTER TABLE "post_revisions" ADD FOREIGN KEY ("post_id") REFERENCES "posts" ("id") ON DELETE CASCADE ON UPDATE NO ACTION;
+
+ALTER TABLE "post_revisions" ADD FOREIGN KEY ("reviewer_id") REFERENCES "users" ("id") ON DELETE SET NULL ON UPDATE NO ACTION;
@@ -0,0 +1,16 @@
+CREATE TABLE "media" (
+  "id" bigserial PRIMARY KEY,
+  "owner_id" bigint NOT NULL,
+  "path" varchar UNIQUE NOT NULL,
+  "size" bigint NOT NULL,
+  "mime_type" varchar NOT NULL,
+  "width" int NOT NULL DEFAULT 0,
+  "height" int NOT NULL DEFAULT 0,
+  "sha256" varchar NOT NULL,
+  "alt_text" varchar NOT NULL DEFAULT '',
+  "create_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON "media" ("owner_id", "create_at");
+
+ALTER TABLE "media" ADD FOREIGN KEY ("owner_id") REFERENCES "users" ("id") ON DELETE CASCADE ON UPDATE NO ACTION;
@@ -0,0 +1 @@
+ALTER TABLE "media" ADD COLUMN "orphan_at" timestamptz;
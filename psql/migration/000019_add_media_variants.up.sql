@@ -0,0 +1,13 @@
+CREATE TABLE "media_variants" (
+  "id" bigserial PRIMARY KEY,
+  "media_id" bigint NOT NULL,
+  "path" varchar UNIQUE NOT NULL,
+  "size" bigint NOT NULL,
+  "mime_type" varchar NOT NULL,
+  "width" int NOT NULL,
+  "height" int NOT NULL
+);
+
+CREATE INDEX ON "media_variants" ("media_id");
+
+ALTER TABLE "media_variants" ADD FOREIGN KEY ("media_id") REFERENCES "media" ("id") ON DELETE CASCADE ON UPDATE NO ACTION;
@@ -0,0 +1 @@
+ALTER TABLE "media" ADD COLUMN "variants_pending" boolean NOT NULL DEFAULT false;
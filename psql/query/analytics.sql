@@ -0,0 +1,103 @@
+-- name: ListDailyStats :many
+WITH Post_CTE AS (
+  SELECT id FROM posts
+  WHERE (@site_wide::bool OR author_id = @author_id::bigint OR id = ANY(
+      SELECT post_id FROM post_collaborators
+      WHERE user_id = @author_id::bigint AND accepted = true
+    ))
+    AND status <> 'trash'
+),
+Day_CTE AS (
+  SELECT generate_series(@start_day::date, @end_day::date, interval '1 day')::date AS day
+),
+View_CTE AS (
+  SELECT day, sum(views) AS views
+  FROM post_daily_views
+  WHERE post_id IN (SELECT id FROM Post_CTE)
+    AND day BETWEEN @start_day::date AND @end_day::date
+  GROUP BY day
+),
+Star_CTE AS (
+  SELECT create_at::date AS day, count(*) AS stars
+  FROM post_stars
+  WHERE post_id IN (SELECT id FROM Post_CTE)
+    AND create_at::date BETWEEN @start_day::date AND @end_day::date
+  GROUP BY create_at::date
+),
+Comment_CTE AS (
+  SELECT create_at::date AS day, count(*) AS comments
+  FROM comments
+  WHERE post_id IN (SELECT id FROM Post_CTE)
+    AND create_at::date BETWEEN @start_day::date AND @end_day::date
+  GROUP BY create_at::date
+),
+Follower_CTE AS (
+  SELECT create_at::date AS day, count(*) AS followers
+  FROM follows
+  WHERE (@site_wide::bool OR user_id = @author_id::bigint)
+    AND create_at::date BETWEEN @start_day::date AND @end_day::date
+  GROUP BY create_at::date
+)
+SELECT dc.day,
+    coalesce(vc.views, 0)::bigint AS views,
+    coalesce(sc.stars, 0)::bigint AS stars,
+    coalesce(cc.comments, 0)::bigint AS comments,
+    coalesce(fc.followers, 0)::bigint AS followers
+FROM Day_CTE dc
+LEFT JOIN View_CTE vc ON vc.day = dc.day
+LEFT JOIN Star_CTE sc ON sc.day = dc.day
+LEFT JOIN Comment_CTE cc ON cc.day = dc.day
+LEFT JOIN Follower_CTE fc ON fc.day = dc.day
+ORDER BY dc.day ASC;
+
+-- name: ListTopPosts :many
+WITH View_CTE AS (
+  SELECT post_id, sum(views) AS views
+  FROM post_daily_views
+  WHERE day BETWEEN @start_day::date AND @end_day::date
+  GROUP BY post_id
+),
+Star_CTE AS (
+  SELECT post_id, count(*) AS stars
+  FROM post_stars
+  WHERE create_at::date BETWEEN @start_day::date AND @end_day::date
+  GROUP BY post_id
+),
+Comment_CTE AS (
+  SELECT post_id, count(*) AS comments
+  FROM comments
+  WHERE create_at::date BETWEEN @start_day::date AND @end_day::date
+  GROUP BY post_id
+)
+SELECT p.id, p.title, p.author_id, u.username, u.avatar,
+    coalesce(vc.views, 0)::bigint AS views,
+    coalesce(sc.stars, 0)::bigint AS stars,
+    coalesce(cc.comments, 0)::bigint AS comments
+FROM posts p
+JOIN users u ON u.id = p.author_id
+LEFT JOIN View_CTE vc ON vc.post_id = p.id
+LEFT JOIN Star_CTE sc ON sc.post_id = p.id
+LEFT JOIN Comment_CTE cc ON cc.post_id = p.id
+WHERE p.status = 'publish'
+  AND (@site_wide::bool OR p.author_id = @author_id::bigint OR p.id = ANY(
+    SELECT post_id FROM post_collaborators
+    WHERE user_id = @author_id::bigint AND accepted = true
+  ))
+ORDER BY views DESC, stars DESC, comments DESC, p.id DESC
+LIMIT @num::int;
+
+-- name: ListTrafficSources :many
+SELECT source, sum(views)::bigint AS views
+FROM post_daily_sources
+WHERE day BETWEEN @start_day::date AND @end_day::date
+  AND post_id IN (
+    SELECT id FROM posts
+    WHERE (@site_wide::bool OR author_id = @author_id::bigint OR id = ANY(
+        SELECT post_id FROM post_collaborators
+        WHERE user_id = @author_id::bigint AND accepted = true
+      ))
+      AND status <> 'trash'
+  )
+GROUP BY source
+ORDER BY views DESC, source ASC
+LIMIT @num::int;
\ No newline at end of file
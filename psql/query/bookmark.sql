@@ -0,0 +1,75 @@
+-- name: CreateReadingList :one
+INSERT INTO reading_lists (user_id, name, position)
+SELECT @user_id::bigint, @name::varchar, coalesce((
+    SELECT max(position) FROM reading_lists
+    WHERE user_id = @user_id::bigint
+  ), 0) + 1
+RETURNING *;
+
+-- name: UpdateReadingList :one
+UPDATE reading_lists SET name = @name::varchar
+WHERE id = @id::bigint AND user_id = @user_id::bigint
+RETURNING *;
+
+-- name: DeleteReadingList :execrows
+DELETE FROM reading_lists
+WHERE id = @id::bigint AND user_id = @user_id::bigint;
+
+-- name: ListReadingLists :many
+SELECT rl.*,
+    (SELECT count(*) FROM bookmarks bk
+      WHERE bk.list_id = rl.id) bookmark_count
+FROM reading_lists rl
+WHERE rl.user_id = @user_id::bigint
+ORDER BY rl.position ASC, rl.id ASC;
+
+-- name: UpdateReadingListPositions :execrows
+UPDATE reading_lists
+SET position = array_position(@list_ids::bigint[], id)
+WHERE user_id = @user_id::bigint
+  AND id = ANY(@list_ids::bigint[]);
+
+-- name: UpsertBookmark :one
+INSERT INTO bookmarks (user_id, post_id, list_id, note)
+SELECT rl.user_id, p.id, rl.id, @note::varchar
+FROM reading_lists rl
+JOIN posts p ON p.id = @post_id::bigint AND p.status = 'publish'
+WHERE rl.id = @list_id::bigint AND rl.user_id = @user_id::bigint
+  AND (p.visibility = ANY('{public, password}'::varchar[])
+    OR (p.visibility = 'followers' AND (p.author_id = @user_id::bigint OR p.author_id = ANY(
+      SELECT user_id FROM follows
+      WHERE follower_id = @user_id::bigint
+    ))))
+ON CONFLICT (user_id, post_id) DO UPDATE
+SET list_id = excluded.list_id,
+  note = CASE WHEN @has_note::bool THEN excluded.note ELSE bookmarks.note END
+RETURNING *;
+
+-- name: DeleteBookmark :execrows
+DELETE FROM bookmarks
+WHERE user_id = @user_id::bigint AND post_id = @post_id::bigint;
+
+-- name: ListBookmarks :many
+WITH Data_CTE AS (
+  SELECT b.post_id, b.note, b.create_at FROM bookmarks b
+  JOIN posts p ON p.id = b.post_id AND p.status <> 'trash'
+  WHERE b.list_id = @list_id::bigint AND b.user_id = @user_id::bigint
+    AND (p.visibility = ANY('{public, password}'::varchar[])
+      OR (p.visibility = 'followers' AND (p.author_id = @user_id::bigint OR p.author_id = ANY(
+        SELECT user_id FROM follows
+        WHERE follower_id = @user_id::bigint
+      ))))
+),
+Count_CTE AS (
+  SELECT count(*) total FROM Data_CTE
+)
+SELECT dc.post_id, dc.note, dc.create_at, cnt.total,
+    p.title, p.cover_image, p.status, p.publish_at,
+    p.author_id, u.username, u.avatar
+FROM Data_CTE dc
+CROSS JOIN Count_CTE cnt
+JOIN posts p ON p.id = dc.post_id
+JOIN users u ON u.id = p.author_id
+ORDER BY dc.create_at DESC, dc.post_id DESC
+LIMIT $1
+OFFSET $2;
\ No newline at end of file
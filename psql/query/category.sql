@@ -51,3 +51,8 @@ WHERE id = ANY(
   SELECT category_id FROM post_categories
   WHERE post_id = $1
 );
+
+-- name: GetCategoriesByIDs :many
+SELECT * FROM categories
+WHERE id = ANY(@ids::bigint[])
+ORDER BY id ASC;
@@ -0,0 +1,28 @@
+-- name: CreatePostCollaborator :one
+INSERT INTO post_collaborators (post_id, user_id, role, accepted)
+VALUES ($1, $2, $3, $4) RETURNING *;
+
+-- name: AcceptPostCollaborator :one
+UPDATE post_collaborators SET accepted = true
+WHERE post_id = @post_id::bigint AND user_id = @user_id::bigint
+  AND accepted = false
+RETURNING *;
+
+-- name: DeletePostCollaborator :execrows
+DELETE FROM post_collaborators
+WHERE post_id = @post_id::bigint AND user_id = @user_id::bigint
+  AND role <> 'owner';
+
+-- name: GetPostCollaborator :one
+SELECT * FROM post_collaborators
+WHERE post_id = @post_id::bigint AND user_id = @user_id::bigint
+LIMIT 1;
+
+-- name: ListPostCollaborators :many
+SELECT pcb.*, u.username, u.avatar
+FROM post_collaborators pcb
+JOIN users u ON u.id = pcb.user_id
+WHERE pcb.post_id = @post_id::bigint
+ORDER BY
+  CASE WHEN pcb.role = 'owner' THEN 0 ELSE 1 END ASC,
+  pcb.create_at ASC;
\ No newline at end of file
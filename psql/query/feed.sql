@@ -0,0 +1,63 @@
+-- name: GetFeedVisit :one
+SELECT visit_at FROM feed_visits
+WHERE user_id = $1;
+
+-- name: UpsertFeedVisit :exec
+INSERT INTO feed_visits (user_id, visit_at)
+VALUES ($1, now())
+ON CONFLICT (user_id) DO UPDATE
+SET visit_at = excluded.visit_at;
+
+-- name: GetFeed :many
+WITH Following_CTE AS (
+  SELECT user_id FROM follows
+  WHERE follower_id = @self_id::bigint
+),
+Post_CTE AS (
+  SELECT id, title, author_id, cover_image, view_count, publish_at, visibility
+  FROM posts
+  WHERE status = 'publish'
+    AND (visibility = ANY('{public, password}'::varchar[])
+      OR (visibility = 'followers' AND author_id = ANY(SELECT user_id FROM Following_CTE)))
+    AND (author_id = ANY(SELECT user_id FROM Following_CTE) OR id = ANY(
+      SELECT post_id FROM post_collaborators
+      WHERE user_id = ANY(SELECT user_id FROM Following_CTE) AND accepted = true
+    ))
+    AND (NOT @has_cursor::bool OR (publish_at, id) < (@cursor_time::timestamptz, @cursor_id::bigint))
+  ORDER BY publish_at DESC, id DESC
+  LIMIT @num::int
+),
+Tag_CTE AS (
+  SELECT pt.post_id,
+      array_agg(t.id)::bigint[] tag_ids,
+      array_agg(t.name)::varchar[] tag_names
+  FROM post_tags pt
+  JOIN tags t
+    ON pt.tag_id = t.id
+    AND pt.post_id = ANY(SELECT id FROM Post_CTE)
+  GROUP BY pt.post_id
+),
+Author_CTE AS (
+  SELECT pcb.post_id,
+      array_agg(u.id ORDER BY pcb.role = 'owner' DESC, pcb.create_at)::bigint[] author_ids,
+      array_agg(u.username ORDER BY pcb.role = 'owner' DESC, pcb.create_at)::varchar[] author_names,
+      array_agg(u.avatar ORDER BY pcb.role = 'owner' DESC, pcb.create_at)::varchar[] author_avatars
+  FROM post_collaborators pcb
+  JOIN users u
+    ON pcb.user_id = u.id
+    AND pcb.accepted = true
+    AND pcb.post_id = ANY(SELECT id FROM Post_CTE)
+  GROUP BY pcb.post_id
+)
+SELECT p.*, u.username, u.avatar,
+    tc.tag_ids, tc.tag_names, ac.author_ids,
+    ac.author_names, ac.author_avatars,
+    (SELECT count(*) FROM comments cm
+      WHERE cm.post_id = p.id) comment_count,
+    (SELECT count(*) FROM post_stars ps
+      WHERE ps.post_id = p.id) star_count
+FROM Post_CTE p
+JOIN users u ON u.id = p.author_id
+LEFT JOIN Tag_CTE tc ON tc.post_id = p.id
+LEFT JOIN Author_CTE ac ON ac.post_id = p.id
+ORDER BY p.publish_at DESC, p.id DESC;
\ No newline at end of file
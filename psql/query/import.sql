@@ -0,0 +1,27 @@
+-- name: GetImportRecord :one
+SELECT local_id FROM import_records
+WHERE source = $1 AND kind = $2 AND external_id = $3;
+
+-- name: CreateImportRecord :exec
+INSERT INTO import_records (source, kind, external_id, local_id)
+VALUES ($1, $2, $3, $4);
+
+-- name: ImportPost :one
+INSERT INTO posts (
+    author_id, title, cover_image, status, update_at, publish_at
+)
+VALUES ($1, $2, $3, $4, $5, $6) RETURNING *;
+
+-- name: ImportComment :one
+INSERT INTO comments (
+    post_id, user_id, parent_id, reply_user_id, content, create_at
+)
+VALUES ($1, $2, $3, $4, $5, $6) RETURNING *;
+
+-- name: GetComment :one
+SELECT * FROM comments
+WHERE id = $1 LIMIT 1;
+
+-- name: GetCategoryByName :one
+SELECT * FROM categories
+WHERE name = @name::varchar LIMIT 1;
\ No newline at end of file
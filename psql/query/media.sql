@@ -0,0 +1,148 @@
+-- name: CreateMedia :one
+INSERT INTO media (
+    owner_id, path, size, mime_type, width, height, sha256, alt_text
+)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING *;
+
+-- name: GetMedia :one
+SELECT * FROM media
+WHERE id = @id::bigint AND owner_id = @owner_id::bigint;
+
+-- name: GetMediaByPath :one
+SELECT * FROM media
+WHERE path = @path::varchar AND owner_id = @owner_id::bigint;
+
+-- name: CanUsePostCover :one
+SELECT EXISTS (
+  SELECT 1 FROM posts
+  WHERE id = @post_id::bigint AND cover_image = @path::varchar
+  UNION ALL
+  SELECT 1 FROM post_revisions
+  WHERE post_id = @post_id::bigint AND cover_image = @path::varchar
+  UNION ALL
+  SELECT 1 FROM media m
+  WHERE m.path = @path::varchar AND (m.owner_id = @user_id::bigint OR m.owner_id = ANY(
+    SELECT user_id FROM post_collaborators
+    WHERE post_id = @post_id::bigint AND accepted = true
+  ))
+)::bool;
+
+-- name: ListMedia :many
+WITH Data_CTE AS (
+  SELECT * FROM media
+  WHERE owner_id = @owner_id::bigint
+    AND (@any_keyword::bool OR alt_text ILIKE @keyword::varchar OR path ILIKE @keyword::varchar)
+    AND (@any_type::bool OR mime_type LIKE @mime_type::varchar)
+),
+Count_CTE AS (
+  SELECT count(*) total FROM Data_CTE
+)
+SELECT dc.*, cnt.total
+FROM Data_CTE dc
+CROSS JOIN Count_CTE cnt
+ORDER BY dc.create_at DESC, dc.id DESC
+LIMIT $1
+OFFSET $2;
+
+-- name: DeleteMedia :one
+DELETE FROM media m
+WHERE m.id = @id::bigint AND m.owner_id = @owner_id::bigint
+  AND NOT EXISTS (SELECT 1 FROM posts WHERE cover_image = m.path)
+  AND NOT EXISTS (SELECT 1 FROM series WHERE cover_image = m.path)
+  AND NOT EXISTS (SELECT 1 FROM users WHERE avatar = m.path)
+  AND NOT EXISTS (SELECT 1 FROM post_contents WHERE strpos(content, m.path) > 0)
+  AND NOT EXISTS (
+    SELECT 1 FROM post_revisions
+    WHERE cover_image = m.path OR strpos(content, m.path) > 0
+  )
+RETURNING *;
+
+-- name: DeleteMediaByPath :exec
+DELETE FROM media
+WHERE path = @path::varchar AND owner_id = @owner_id::bigint;
+
+-- name: ListUnreferencedMedia :many
+SELECT m.id FROM media m
+WHERE m.path <> ALL(@keep_paths::varchar[])
+  AND NOT EXISTS (SELECT 1 FROM posts WHERE cover_image = m.path)
+  AND NOT EXISTS (SELECT 1 FROM series WHERE cover_image = m.path)
+  AND NOT EXISTS (SELECT 1 FROM users WHERE avatar = m.path)
+  AND NOT EXISTS (SELECT 1 FROM post_contents WHERE strpos(content, m.path) > 0)
+  AND NOT EXISTS (
+    SELECT 1 FROM post_revisions
+    WHERE cover_image = m.path OR strpos(content, m.path) > 0
+  );
+
+-- name: MarkOrphanedMedia :many
+UPDATE media SET orphan_at = now()
+WHERE id = ANY(@ids::bigint[]) AND orphan_at IS NULL
+RETURNING *;
+
+-- name: UnmarkReferencedMedia :execrows
+UPDATE media SET orphan_at = NULL
+WHERE orphan_at IS NOT NULL AND id <> ALL(@ids::bigint[]);
+
+-- name: DeleteOrphanedMedia :many
+DELETE FROM media
+WHERE id = ANY(@ids::bigint[])
+  AND orphan_at < @orphan_before::timestamptz
+RETURNING *;
+
+-- name: CreateMediaVariant :one
+INSERT INTO media_variants (
+    media_id, path, size, mime_type, width, height
+)
+VALUES ($1, $2, $3, $4, $5, $6) RETURNING *;
+
+-- name: MarkMediaVariantsPending :exec
+UPDATE media SET variants_pending = true
+WHERE id = @id::bigint;
+
+-- name: ListVariantsPendingMedia :many
+SELECT * FROM media
+WHERE variants_pending
+ORDER BY id
+LIMIT $1;
+
+-- name: ClaimMediaVariants :execrows
+UPDATE media SET variants_pending = false
+WHERE id = @id::bigint AND variants_pending;
+
+-- name: ListMediaVariants :many
+SELECT * FROM media_variants
+WHERE media_id = ANY(@media_ids::bigint[])
+ORDER BY media_id, mime_type, width;
+
+-- name: ListVariantsByPaths :many
+SELECT m.path AS media_path, v.*
+FROM media_variants v
+JOIN media m ON m.id = v.media_id
+WHERE m.path = ANY(@paths::varchar[])
+ORDER BY m.path, v.mime_type, v.width;
+
+-- name: ListMediaPaths :many
+SELECT path FROM media
+UNION ALL
+SELECT path FROM media_variants;
+
+-- name: GetMediaOwner :one
+SELECT owner_id FROM (
+  SELECT id owner_id, 1 rank FROM users
+  WHERE avatar = @path::varchar
+  UNION ALL
+  SELECT author_id, 2 FROM posts
+  WHERE cover_image = @path::varchar
+  UNION ALL
+  SELECT author_id, 3 FROM series
+  WHERE cover_image = @path::varchar
+  UNION ALL
+  SELECT p.author_id, 4 FROM post_contents pc
+  JOIN posts p ON p.id = pc.id
+  WHERE strpos(pc.content, @path::varchar) > 0
+  UNION ALL
+  SELECT p.author_id, 5 FROM post_revisions r
+  JOIN posts p ON p.id = r.post_id
+  WHERE r.cover_image = @path::varchar OR strpos(r.content, @path::varchar) > 0
+) o
+ORDER BY rank
+LIMIT 1;
\ No newline at end of file
@@ -6,46 +6,124 @@ VALUES ($1, $2, $3) RETURNING *;
 INSERT INTO post_contents (id, content)
 VALUES ($1, $2) RETURNING *;
 
--- name: DeletePost :exec
+-- name: TrashPost :one
+UPDATE posts
+SET trash_status = status, status = 'trash', delete_at = now()
+WHERE id = @id::bigint AND status <> 'trash'
+  AND (@is_admin::bool OR (author_id = @author_id::bigint
+    AND status = ANY('{draft, revise}'::varchar[])))
+RETURNING *;
+
+-- name: RestorePost :one
+UPDATE posts
+SET status = trash_status, trash_status = '', delete_at = NULL
+WHERE id = @id::bigint AND status = 'trash'
+  AND (@is_admin::bool OR author_id = @author_id::bigint)
+RETURNING *;
+
+-- name: PurgeTrashedPosts :execrows
 DELETE FROM posts
-WHERE id = @id::bigint AND author_id = @author_id::bigint
-  AND status = ANY('{draft, revise}'::varchar[]);
+WHERE status = 'trash' AND delete_at < @delete_before::timestamptz;
 
 -- name: UpdatePost :one
 UPDATE posts
 SET
   title = coalesce(sqlc.narg('title'), title),
   cover_image = coalesce(sqlc.narg('cover_image'), cover_image),
-  update_at = now()
-WHERE id = $1 AND author_id = @author_id::bigint
+  update_at = now(),
+  version = version + 1
+WHERE id = $1
+  AND (author_id = @author_id::bigint OR id = ANY(
+    SELECT post_id FROM post_collaborators
+    WHERE user_id = @author_id::bigint AND accepted = true
+  ))
   AND status = ANY('{draft, revise}'::varchar[])
+  AND version = @version::bigint
 RETURNING *;
 
+-- name: GetPostByID :one
+SELECT * FROM posts
+WHERE id = $1;
+
+-- name: GetPostVersion :one
+SELECT coalesce(r.version, p.version)::bigint AS version
+FROM posts p
+LEFT JOIN post_revisions r ON r.post_id = p.id
+WHERE p.id = $1
+  AND (p.author_id = @author_id::bigint OR p.id = ANY(
+    SELECT post_id FROM post_collaborators
+    WHERE user_id = @author_id::bigint AND accepted = true
+  ))
+  AND (p.status = ANY('{draft, revise}'::varchar[])
+    OR p.status = 'publish' AND (r.status IS NULL OR r.status = ANY('{draft, revise}'::varchar[])));
+
 -- name: UpdatePostContent :one
 UPDATE post_contents
 SET content = $2
 WHERE id = (
   SELECT p.id FROM posts p
   WHERE p.id = $1
-    AND author_id = @author_id::bigint
+    AND (author_id = @author_id::bigint OR p.id = ANY(
+      SELECT post_id FROM post_collaborators
+      WHERE user_id = @author_id::bigint AND accepted = true
+    ))
     AND status = ANY('{draft, revise}'::varchar[])
 ) RETURNING *;
 
 -- name: UpdatePostStatus :many
 UPDATE posts SET status = @status::varchar
 WHERE id = ANY(@ids::bigint[]) AND status = ANY(@old_status::varchar[])
-  AND (@is_admin::bool OR author_id = @author_id::bigint)
+  AND (@is_admin::bool OR author_id = @author_id::bigint OR id = ANY(
+    SELECT post_id FROM post_collaborators
+    WHERE user_id = @author_id::bigint AND accepted = true
+  ))
+  AND (NOT @check_claim::bool OR reviewer_id IS NULL
+    OR reviewer_id = @author_id::bigint)
 RETURNING *;
 
+-- name: ListPostAccess :many
+SELECT p.id, p.status,
+  (p.author_id = @author_id::bigint OR p.id = ANY(
+    SELECT post_id FROM post_collaborators
+    WHERE user_id = @author_id::bigint AND accepted = true
+  ))::bool is_author,
+  (p.reviewer_id IS NOT NULL AND p.reviewer_id <> @author_id::bigint)::bool claimed
+FROM posts p
+WHERE p.id = ANY(@ids::bigint[]) AND p.status <> 'trash';
+
+-- name: GetPostAccess :one
+SELECT p.id, p.status, p.visibility, p.password_hash,
+  coalesce((
+    SELECT array_agg(user_id) FROM post_collaborators
+    WHERE post_id = p.id AND accepted = true
+  ), '{}')::bigint[] author_ids,
+  EXISTS (
+    SELECT 1 FROM follows
+    WHERE user_id = p.author_id AND follower_id = @self_id::bigint
+  )::bool followed
+FROM posts p
+WHERE p.id = @post_id::bigint AND p.status <> 'trash';
+
 -- name: UpdatePostFeature :exec
 UPDATE posts SET featured = @featured::bool
 WHERE id = @id::bigint;
 
+-- name: UpdatePostVisibility :one
+UPDATE posts
+SET visibility = @visibility::varchar,
+  password_hash = @password_hash::varchar
+WHERE id = @id::bigint
+  AND (@is_admin::bool OR author_id = @author_id::bigint)
+RETURNING *;
+
 -- name: ListPosts :many
 WITH Data_CTE AS (
   SELECT * FROM posts
-  WHERE (@is_admin::bool OR author_id = @author_id::bigint)
-    AND (@any_status::bool OR status = @status::varchar)
+  WHERE (@is_admin::bool OR author_id = @author_id::bigint OR id = ANY(
+      SELECT post_id FROM post_collaborators
+      WHERE user_id = @author_id::bigint AND accepted = true
+    ))
+    AND (@any_status::bool AND status <> 'trash' OR status = @status::varchar)
     AND (@any_keyword::bool OR title LIKE @keyword::varchar)
 ),
 Count_CTE AS (
@@ -113,13 +191,17 @@ Tag_CTE AS (
   GROUP BY pt.post_id
 )
 SELECT p.*, pc.content, cc.category_ids, cc.category_names,
-    tc.tag_ids, tc.tag_names
+    tc.tag_ids, tc.tag_names, coalesce(pr.status, '')::varchar revision_status
 FROM posts p
 JOIN post_contents pc ON pc.id = p.id
 LEFT JOIN Category_CTE cc ON cc.post_id = p.id
 LEFT JOIN Tag_CTE tc ON tc.post_id = p.id
-WHERE p.id = @post_id::bigint
-  AND (@is_admin::bool OR author_id = @author_id::bigint)
+LEFT JOIN post_revisions pr ON pr.post_id = p.id
+WHERE p.id = @post_id::bigint AND p.status <> 'trash'
+  AND (@is_admin::bool OR author_id = @author_id::bigint OR p.id = ANY(
+    SELECT post_id FROM post_collaborators
+    WHERE user_id = @author_id::bigint AND accepted = true
+  ))
 LIMIT 1;
 
 -- name: GetFeaturedPosts :many
@@ -132,17 +214,30 @@ SELECT p.id, p.title, p.cover_image, p.view_count,
 FROM posts p
 JOIN users u ON u.id = p.author_id
 WHERE featured = true AND status = 'publish'
+  AND visibility = ANY('{public, password}'::varchar[])
 ORDER BY random()
 LIMIT $1;
 
 -- name: GetPosts :many
 WITH Data_CTE AS (
   SELECT id, title, author_id, cover_image,
-      featured, view_count, publish_at
+      featured, view_count, publish_at, visibility,
+      coalesce((
+        SELECT score FROM post_rankings pr
+        WHERE pr.post_id = posts.id
+      ), 0)::float8 hot_score
   FROM posts
   WHERE status = 'publish'
+    AND (visibility = ANY('{public, password}'::varchar[])
+      OR (visibility = 'followers' AND (author_id = @self_id::bigint OR author_id = ANY(
+        SELECT user_id FROM follows
+        WHERE follower_id = @self_id::bigint
+      ))))
     AND (@any_featured::bool OR featured = @featured::bool)
-    AND (@any_author::bool OR author_id = @author_id::bigint)
+    AND (@any_author::bool OR author_id = @author_id::bigint OR id = ANY(
+      SELECT post_id FROM post_collaborators
+      WHERE user_id = @author_id::bigint AND accepted = true
+    ))
     AND (@any_category::bool OR id = ANY(
       SELECT post_id FROM post_categories
       WHERE category_id = @category_id::bigint
@@ -163,6 +258,8 @@ Post_CTE AS (
     CASE WHEN @publish_at_desc::bool THEN publish_at END DESC,
     CASE WHEN @view_count_asc::bool THEN view_count END ASC,
     CASE WHEN @view_count_desc::bool THEN view_count END DESC,
+    CASE WHEN @hot_score_asc::bool THEN hot_score END ASC,
+    CASE WHEN @hot_score_desc::bool THEN hot_score END DESC,
     id ASC
   LIMIT $1
   OFFSET $2
@@ -176,9 +273,23 @@ Tag_CTE AS (
     ON pt.tag_id = t.id
     AND pt.post_id = ANY(SELECT id FROM Post_CTE)
   GROUP BY pt.post_id
+),
+Author_CTE AS (
+  SELECT pcb.post_id,
+      array_agg(u.id ORDER BY pcb.role = 'owner' DESC, pcb.create_at)::bigint[] author_ids,
+      array_agg(u.username ORDER BY pcb.role = 'owner' DESC, pcb.create_at)::varchar[] author_names,
+      array_agg(u.avatar ORDER BY pcb.role = 'owner' DESC, pcb.create_at)::varchar[] author_avatars
+  FROM post_collaborators pcb
+  JOIN users u
+    ON pcb.user_id = u.id
+    AND pcb.accepted = true
+    AND pcb.post_id = ANY(SELECT id FROM Post_CTE)
+  GROUP BY pcb.post_id
 )
 SELECT p.*, cnt.total, u.username, u.avatar,
-    tc.tag_ids, tc.tag_names,
+    tc.tag_ids, tc.tag_names, ac.author_ids,
+    ac.author_names, ac.author_avatars,
+    bk.list_id bookmark_list_id, st.user_id starred,
     (SELECT count(*) FROM comments cm
       WHERE cm.post_id = p.id) comment_count,
     (SELECT count(*) FROM post_stars ps
@@ -186,13 +297,17 @@ SELECT p.*, cnt.total, u.username, u.avatar,
 FROM Post_CTE p
 CROSS JOIN Count_CTE cnt
 JOIN users u ON u.id = p.author_id
-LEFT JOIN Tag_CTE tc ON tc.post_id = p.id;
+LEFT JOIN Tag_CTE tc ON tc.post_id = p.id
+LEFT JOIN Author_CTE ac ON ac.post_id = p.id
+LEFT JOIN bookmarks bk
+  ON bk.post_id = p.id AND bk.user_id = @self_id::bigint
+LEFT JOIN post_stars st
+  ON st.post_id = p.id AND st.user_id = @self_id::bigint;
 
 -- name: ReadPost :one
 WITH Post_CTE AS (
-  UPDATE posts SET view_count = view_count + 1
+  SELECT * FROM posts
   WHERE id = @post_id::bigint AND status = 'publish'
-  RETURNING *
 ),
 Category_CTE AS (
   SELECT pc.post_id,
@@ -211,11 +326,26 @@ Tag_CTE AS (
   JOIN tags t
     ON pt.tag_id = t.id AND pt.post_id = @post_id::bigint
   GROUP BY pt.post_id
+),
+Author_CTE AS (
+  SELECT pcb.post_id,
+      array_agg(u.id ORDER BY pcb.role = 'owner' DESC, pcb.create_at)::bigint[] author_ids,
+      array_agg(u.username ORDER BY pcb.role = 'owner' DESC, pcb.create_at)::varchar[] author_names,
+      array_agg(u.avatar ORDER BY pcb.role = 'owner' DESC, pcb.create_at)::varchar[] author_avatars
+  FROM post_collaborators pcb
+  JOIN users u
+    ON pcb.user_id = u.id
+    AND pcb.accepted = true
+    AND pcb.post_id = @post_id::bigint
+  GROUP BY pcb.post_id
 )
 SELECT p.id, p.title, pc.content, p.view_count, p.publish_at,
-    p.author_id, u.username, u.avatar, u.intro,
+    p.visibility, p.password_hash, p.author_id, u.username,
+    u.avatar, u.intro,
     cc.category_ids, cc.category_names, tc.tag_ids,
-    tc.tag_names, fu.follower_id followed,
+    tc.tag_names, ac.author_ids, ac.author_names,
+    ac.author_avatars, fu.follower_id followed,
+    bk.list_id bookmark_list_id, st.user_id starred,
     (SELECT count(*) FROM follows f
       WHERE f.user_id = p.author_id) follower_count,
     (SELECT count(*) FROM follows f
@@ -227,8 +357,13 @@ JOIN post_contents pc ON pc.id = p.id
 JOIN users u ON u.id = p.author_id
 LEFT JOIN Category_CTE cc ON cc.post_id = p.id
 LEFT JOIN Tag_CTE tc ON tc.post_id = p.id
+LEFT JOIN Author_CTE ac ON ac.post_id = p.id
 LEFT JOIN follows fu
-  ON fu.user_id = p.author_id AND fu.follower_id = @self_id::bigint;
+  ON fu.user_id = p.author_id AND fu.follower_id = @self_id::bigint
+LEFT JOIN bookmarks bk
+  ON bk.post_id = p.id AND bk.user_id = @self_id::bigint
+LEFT JOIN post_stars st
+  ON st.post_id = p.id AND st.user_id = @self_id::bigint;
 
 -- name: CreatePostStar :exec
 INSERT INTO post_stars (post_id, user_id)
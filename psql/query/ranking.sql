@@ -0,0 +1,61 @@
+-- name: TryRankingLock :one
+SELECT pg_try_advisory_xact_lock(@lock_key::bigint)::bool;
+
+-- name: RefreshPostRankings :execrows
+WITH Param_CTE AS (
+  SELECT @half_life::float8 half_life,
+      @view_weight::float8 view_weight,
+      @star_weight::float8 star_weight,
+      @comment_weight::float8 comment_weight,
+      @since::timestamptz since
+),
+View_CTE AS (
+  SELECT pdv.post_id, sum(pdv.views * pm.view_weight * exp(-ln(2.0)
+      * extract(epoch FROM now() - (pdv.day + interval '12 hours')) / 3600 / pm.half_life)) score
+  FROM post_daily_views pdv
+  CROSS JOIN Param_CTE pm
+  WHERE pdv.day >= pm.since::date
+  GROUP BY pdv.post_id
+),
+Star_CTE AS (
+  SELECT ps.post_id, sum(pm.star_weight * exp(-ln(2.0)
+      * extract(epoch FROM now() - ps.create_at) / 3600 / pm.half_life)) score
+  FROM post_stars ps
+  CROSS JOIN Param_CTE pm
+  WHERE ps.create_at >= pm.since
+  GROUP BY ps.post_id
+),
+Comment_CTE AS (
+  SELECT cm.post_id, sum(pm.comment_weight * exp(-ln(2.0)
+      * extract(epoch FROM now() - cm.create_at) / 3600 / pm.half_life)) score
+  FROM comments cm
+  CROSS JOIN Param_CTE pm
+  WHERE cm.create_at >= pm.since
+  GROUP BY cm.post_id
+)
+INSERT INTO post_rankings (post_id, score, update_at)
+SELECT p.id, coalesce(vc.score, 0) + coalesce(sc.score, 0) + coalesce(cc.score, 0), now()
+FROM posts p
+LEFT JOIN View_CTE vc ON vc.post_id = p.id
+LEFT JOIN Star_CTE sc ON sc.post_id = p.id
+LEFT JOIN Comment_CTE cc ON cc.post_id = p.id
+WHERE p.status = 'publish'
+ORDER BY p.id
+ON CONFLICT (post_id) DO UPDATE
+SET score = excluded.score, update_at = excluded.update_at;
+
+-- name: DeleteStalePostRankings :execrows
+DELETE FROM post_rankings
+WHERE post_id NOT IN (
+  SELECT id FROM posts WHERE status = 'publish'
+);
+
+-- name: ListTrendingTags :many
+SELECT t.id, t.name, sum(pr.score)::float8 score
+FROM post_rankings pr
+JOIN post_tags pt ON pt.post_id = pr.post_id
+JOIN tags t ON t.id = pt.tag_id
+GROUP BY t.id, t.name
+HAVING sum(pr.score) > 0
+ORDER BY score DESC, t.id ASC
+LIMIT @num::int;
\ No newline at end of file
@@ -0,0 +1,71 @@
+-- name: ListRelatedPostIDs :many
+WITH Target_CTE AS (
+  SELECT id, author_id,
+      to_tsquery('simple', coalesce(nullif(array_to_string(array(
+        SELECT quote_literal(lexeme)
+        FROM unnest(tsvector_to_array(to_tsvector('simple', title))) lexeme
+        WHERE strpos(lexeme, chr(92)) = 0
+      ), ' | '), ''), quote_literal(''))) title_query
+  FROM posts
+  WHERE id = @post_id::bigint
+),
+Tag_CTE AS (
+  SELECT post_id, count(*) AS shared
+  FROM post_tags
+  WHERE tag_id IN (
+    SELECT tag_id FROM post_tags WHERE post_id = @post_id::bigint
+  )
+  GROUP BY post_id
+),
+Category_CTE AS (
+  SELECT post_id, count(*) AS shared
+  FROM post_categories
+  WHERE category_id IN (
+    SELECT category_id FROM post_categories WHERE post_id = @post_id::bigint
+  )
+  GROUP BY post_id
+),
+Weight_CTE AS (
+  SELECT @tag_weight::float8 tag_weight,
+      @category_weight::float8 category_weight,
+      @author_weight::float8 author_weight,
+      @text_weight::float8 text_weight
+),
+Score_CTE AS (
+  SELECT p.id, p.publish_at,
+      coalesce(tc.shared, 0) * w.tag_weight
+      + coalesce(cc.shared, 0) * w.category_weight
+      + CASE WHEN p.author_id = t.author_id THEN w.author_weight ELSE 0 END
+      + CASE WHEN w.text_weight > 0 THEN w.text_weight * ts_rank(
+          to_tsvector('simple', p.title), t.title_query) ELSE 0 END AS score
+  FROM posts p
+  CROSS JOIN Target_CTE t
+  CROSS JOIN Weight_CTE w
+  LEFT JOIN Tag_CTE tc ON tc.post_id = p.id
+  LEFT JOIN Category_CTE cc ON cc.post_id = p.id
+  WHERE p.status = 'publish' AND p.id <> t.id
+    AND p.visibility = ANY('{public, password}'::varchar[])
+)
+SELECT id FROM Score_CTE
+WHERE score > 0
+ORDER BY score DESC, publish_at DESC
+LIMIT @num::int;
+
+-- name: GetRelatedPosts :many
+SELECT p.id, p.title, p.cover_image, p.view_count, p.publish_at,
+    p.author_id, u.username, u.avatar
+FROM posts p
+JOIN users u ON u.id = p.author_id
+WHERE p.id = ANY(@ids::bigint[]) AND p.status = 'publish'
+  AND p.visibility = ANY('{public, password}'::varchar[])
+  AND NOT EXISTS (
+    SELECT 1 FROM post_stars ps
+    WHERE ps.post_id = p.id AND ps.user_id = @self_id::bigint
+  )
+  AND NOT EXISTS (
+    SELECT 1 FROM post_reads pr
+    WHERE pr.post_id = p.id AND pr.user_id = @self_id::bigint
+      AND pr.read_at > @read_since::timestamptz
+  )
+ORDER BY array_position(@ids::bigint[], p.id)
+LIMIT @num::int;
\ No newline at end of file
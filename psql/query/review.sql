@@ -0,0 +1,54 @@
+-- name: AssignPostReviewer :one
+UPDATE posts SET reviewer_id = @reviewer_id::bigint
+WHERE id = @id::bigint AND status = 'review'
+  AND (reviewer_id IS NULL OR reviewer_id = @claimer_id::bigint
+    OR reviewer_id = @reviewer_id::bigint)
+RETURNING *;
+
+-- name: UpdateReviewedPost :one
+UPDATE posts
+SET status = @status::varchar, reviewer_id = @reviewer_id::bigint
+WHERE id = @id::bigint AND status = 'review'
+  AND (reviewer_id IS NULL OR reviewer_id = @reviewer_id::bigint)
+RETURNING *;
+
+-- name: CreateReviewNote :one
+INSERT INTO review_notes (
+    post_id, user_id, kind, content, anchor_start, anchor_end, anchor_text
+)
+VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING *;
+
+-- name: ListReviewNotes :many
+SELECT rn.*, u.username, u.avatar
+FROM review_notes rn
+JOIN users u ON u.id = rn.user_id
+WHERE rn.post_id = @post_id::bigint
+ORDER BY rn.create_at ASC, rn.id ASC;
+
+-- name: ListReviewQueue :many
+WITH Data_CTE AS (
+  SELECT id, title, author_id, reviewer_id, update_at, false revision
+  FROM posts
+  WHERE status = 'review'
+    AND (NOT @unclaimed::bool OR reviewer_id IS NULL)
+    AND (@any_reviewer::bool OR reviewer_id = @reviewer_id::bigint)
+  UNION ALL
+  SELECT r.post_id, r.title, p.author_id, r.reviewer_id, r.update_at, true revision
+  FROM post_revisions r
+  JOIN posts p ON p.id = r.post_id
+  WHERE r.status = 'review' AND p.status = 'publish'
+    AND (NOT @unclaimed::bool OR r.reviewer_id IS NULL)
+    AND (@any_reviewer::bool OR r.reviewer_id = @reviewer_id::bigint)
+),
+Count_CTE AS (
+  SELECT count(*) total FROM Data_CTE
+)
+SELECT dc.*, cnt.total, u.username, u.avatar,
+    ru.username r_username, ru.avatar r_avatar
+FROM Data_CTE dc
+CROSS JOIN Count_CTE cnt
+JOIN users u ON u.id = dc.author_id
+LEFT JOIN users ru ON ru.id = dc.reviewer_id
+ORDER BY dc.update_at ASC, dc.id ASC
+LIMIT $1
+OFFSET $2;
\ No newline at end of file
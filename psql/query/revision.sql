@@ -0,0 +1,106 @@
+-- name: CreatePostRevision :exec
+INSERT INTO post_revisions (
+    post_id, title, cover_image, content, category_ids, tag_ids, version
+)
+SELECT p.id, p.title, p.cover_image, pc.content,
+    ARRAY(SELECT category_id FROM post_categories
+      WHERE post_id = p.id ORDER BY category_id)::bigint[],
+    ARRAY(SELECT tag_id FROM post_tags
+      WHERE post_id = p.id ORDER BY tag_id)::bigint[],
+    p.version
+FROM posts p
+JOIN post_contents pc ON pc.id = p.id
+WHERE p.id = @post_id::bigint AND p.status = 'publish'
+  AND (p.author_id = @author_id::bigint OR p.id = ANY(
+    SELECT post_id FROM post_collaborators
+    WHERE user_id = @author_id::bigint AND accepted = true
+  ))
+ON CONFLICT (post_id) DO NOTHING;
+
+-- name: UpdatePostRevision :one
+UPDATE post_revisions
+SET
+  title = coalesce(sqlc.narg('title'), title),
+  cover_image = coalesce(sqlc.narg('cover_image'), cover_image),
+  content = coalesce(sqlc.narg('content'), content),
+  category_ids = coalesce(sqlc.narg('category_ids')::bigint[], category_ids),
+  tag_ids = coalesce(sqlc.narg('tag_ids')::bigint[], tag_ids),
+  update_at = now(),
+  version = version + 1
+WHERE post_id = (
+    SELECT p.id FROM posts p
+    WHERE p.id = @post_id::bigint AND p.status = 'publish'
+      AND (p.author_id = @author_id::bigint OR p.id = ANY(
+        SELECT post_id FROM post_collaborators
+        WHERE user_id = @author_id::bigint AND accepted = true
+      ))
+  )
+  AND status = ANY('{draft, revise}'::varchar[])
+  AND version = @version::bigint
+RETURNING *;
+
+-- name: GetPostRevision :one
+SELECT r.* FROM post_revisions r
+JOIN posts p ON p.id = r.post_id
+WHERE r.post_id = @post_id::bigint AND p.status = 'publish'
+  AND (@is_admin::bool OR p.author_id = @author_id::bigint OR p.id = ANY(
+    SELECT post_id FROM post_collaborators
+    WHERE user_id = @author_id::bigint AND accepted = true
+  ));
+
+-- name: DeletePostRevision :execrows
+DELETE FROM post_revisions
+WHERE post_id = @post_id::bigint
+  AND (@is_admin::bool OR post_id = ANY(
+    SELECT id FROM posts
+    WHERE author_id = @author_id::bigint
+    UNION
+    SELECT post_id FROM post_collaborators
+    WHERE user_id = @author_id::bigint AND accepted = true
+  ));
+
+-- name: SubmitPostRevisions :many
+UPDATE post_revisions
+SET status = 'review'
+WHERE post_id = ANY(@ids::bigint[])
+  AND status = ANY('{draft, revise}'::varchar[])
+  AND post_id = ANY(
+    SELECT p.id FROM posts p
+    WHERE p.status = 'publish'
+      AND (@is_admin::bool OR p.author_id = @author_id::bigint OR p.id = ANY(
+        SELECT post_id FROM post_collaborators
+        WHERE user_id = @author_id::bigint AND accepted = true
+      ))
+  )
+RETURNING *;
+
+-- name: AssignRevisionReviewer :one
+UPDATE post_revisions SET reviewer_id = @reviewer_id::bigint
+WHERE post_id = @post_id::bigint AND status = 'review'
+  AND (reviewer_id IS NULL OR reviewer_id = @claimer_id::bigint
+    OR reviewer_id = @reviewer_id::bigint)
+RETURNING *;
+
+-- name: UpdateReviewedRevision :one
+UPDATE post_revisions
+SET status = @status::varchar, reviewer_id = @reviewer_id::bigint
+WHERE post_id = @post_id::bigint AND status = 'review'
+  AND (reviewer_id IS NULL OR reviewer_id = @reviewer_id::bigint)
+RETURNING *;
+
+-- name: ApplyPostRevision :one
+UPDATE posts p
+SET title = r.title,
+  cover_image = r.cover_image,
+  update_at = now(),
+  version = greatest(p.version, r.version)
+FROM post_revisions r
+WHERE r.post_id = p.id AND p.id = @post_id::bigint
+  AND p.status = 'publish'
+RETURNING p.*;
+
+-- name: ApplyPostRevisionContent :exec
+UPDATE post_contents pc
+SET content = r.content
+FROM post_revisions r
+WHERE r.post_id = pc.id AND pc.id = @post_id::bigint;
\ No newline at end of file
@@ -0,0 +1,110 @@
+-- name: CreateSeries :one
+INSERT INTO series (author_id, title, description, cover_image)
+VALUES ($1, $2, $3, $4) RETURNING *;
+
+-- name: DeleteSeries :execrows
+DELETE FROM series
+WHERE id = @id::bigint
+  AND (@is_admin::bool OR author_id = @author_id::bigint);
+
+-- name: GetSeries :one
+SELECT s.*, u.username, u.avatar
+FROM series s
+JOIN users u ON u.id = s.author_id
+WHERE s.id = @id::bigint LIMIT 1;
+
+-- name: ListSeries :many
+WITH Data_CTE AS (
+  SELECT s.*,
+      (SELECT count(*) FROM series_posts sp
+        JOIN posts p ON p.id = sp.post_id
+        WHERE sp.series_id = s.id AND p.status = 'publish') post_count
+  FROM series s
+  WHERE s.author_id = @author_id::bigint
+),
+Count_CTE AS (
+  SELECT count(*) total FROM Data_CTE
+  WHERE post_count > 0 OR author_id = @self_id::bigint
+)
+SELECT dc.*, cnt.total
+FROM Data_CTE dc
+CROSS JOIN Count_CTE cnt
+WHERE dc.post_count > 0 OR dc.author_id = @self_id::bigint
+ORDER BY dc.create_at DESC
+LIMIT $1
+OFFSET $2;
+
+-- name: AddSeriesPost :one
+INSERT INTO series_posts (series_id, post_id, position)
+SELECT s.id, p.id, coalesce((
+    SELECT max(position) FROM series_posts
+    WHERE series_id = s.id
+  ), 0) + 1
+FROM series s
+JOIN posts p ON p.id = @post_id::bigint AND p.author_id = s.author_id
+  AND p.status <> 'trash'
+WHERE s.id = @series_id::bigint AND s.author_id = @author_id::bigint
+RETURNING *;
+
+-- name: DeleteSeriesPost :execrows
+DELETE FROM series_posts
+WHERE series_id = @series_id::bigint AND post_id = @post_id::bigint
+  AND series_id = ANY(
+    SELECT id FROM series WHERE author_id = @author_id::bigint
+  );
+
+-- name: ListSeriesPosts :many
+SELECT sp.position, p.id, p.title, p.status, p.publish_at
+FROM series_posts sp
+JOIN posts p ON p.id = sp.post_id
+WHERE sp.series_id = @series_id::bigint
+  AND (@any_status::bool AND p.status <> 'trash' OR p.status = 'publish')
+  AND (@any_status::bool OR @is_admin::bool
+    OR p.visibility = ANY('{public, password}'::varchar[])
+    OR (p.visibility = 'followers' AND (p.author_id = @self_id::bigint OR p.author_id = ANY(
+      SELECT user_id FROM follows
+      WHERE follower_id = @self_id::bigint
+    ))))
+ORDER BY sp.position ASC;
+
+-- name: UpdateSeriesPositions :execrows
+UPDATE series_posts sp
+SET position = o.part
+FROM (
+  SELECT post_id, row_number() OVER (
+      ORDER BY array_position(@post_ids::bigint[], post_id) NULLS LAST, position
+    ) part
+  FROM series_posts
+  WHERE series_id = @series_id::bigint
+) o
+WHERE sp.series_id = @series_id::bigint AND sp.post_id = o.post_id;
+
+-- name: GetPostSeries :one
+WITH Series_CTE AS (
+  SELECT series_id FROM series_posts
+  WHERE post_id = @post_id::bigint
+),
+Part_CTE AS (
+  SELECT sp.post_id,
+      row_number() OVER w part,
+      count(*) OVER () total,
+      coalesce(lag(p.id) OVER w, 0)::bigint prev_id,
+      coalesce(lag(p.title) OVER w, '')::varchar prev_title,
+      coalesce(lead(p.id) OVER w, 0)::bigint next_id,
+      coalesce(lead(p.title) OVER w, '')::varchar next_title
+  FROM series_posts sp
+  JOIN posts p ON p.id = sp.post_id AND p.status = 'publish'
+  WHERE sp.series_id = ANY(SELECT series_id FROM Series_CTE)
+    AND (p.id = @post_id::bigint OR @is_admin::bool
+      OR p.visibility = ANY('{public, password}'::varchar[])
+      OR (p.visibility = 'followers' AND (p.author_id = @self_id::bigint OR p.author_id = ANY(
+        SELECT user_id FROM follows
+        WHERE follower_id = @self_id::bigint
+      ))))
+  WINDOW w AS (ORDER BY sp.position)
+)
+SELECT s.id, s.title, pc.part, pc.total, pc.prev_id,
+    pc.prev_title, pc.next_id, pc.next_title
+FROM Part_CTE pc
+JOIN series s ON s.id = ANY(SELECT series_id FROM Series_CTE)
+WHERE pc.post_id = @post_id::bigint;
\ No newline at end of file
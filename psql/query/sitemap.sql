@@ -0,0 +1,33 @@
+-- name: ListSitemapPosts :many
+SELECT id, greatest(update_at, publish_at)::timestamptz lastmod
+FROM posts
+WHERE status = 'publish' AND visibility = 'public'
+ORDER BY id ASC;
+
+-- name: ListSitemapAuthors :many
+SELECT p.author_id id,
+    max(greatest(p.update_at, p.publish_at))::timestamptz lastmod
+FROM posts p
+JOIN users u ON u.id = p.author_id
+WHERE p.status = 'publish' AND p.visibility = 'public' AND u.deleted = false
+GROUP BY p.author_id
+ORDER BY p.author_id ASC;
+
+-- name: ListSitemapCategories :many
+SELECT pc.category_id id,
+    max(greatest(p.update_at, p.publish_at))::timestamptz lastmod
+FROM post_categories pc
+JOIN posts p ON p.id = pc.post_id
+WHERE p.status = 'publish' AND p.visibility = 'public'
+GROUP BY pc.category_id
+ORDER BY pc.category_id ASC;
+
+-- name: ListSitemapTags :many
+SELECT t.name,
+    max(greatest(p.update_at, p.publish_at))::timestamptz lastmod
+FROM post_tags pt
+JOIN tags t ON t.id = pt.tag_id
+JOIN posts p ON p.id = pt.post_id
+WHERE p.status = 'publish' AND p.visibility = 'public'
+GROUP BY t.id, t.name
+ORDER BY t.id ASC;
\ No newline at end of file
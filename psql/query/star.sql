@@ -0,0 +1,43 @@
+-- name: ListStargazers :many
+WITH Data_CTE AS (
+  SELECT ps.user_id, ps.create_at FROM post_stars ps
+  JOIN posts p ON p.id = ps.post_id AND p.status = 'publish'
+  JOIN users su ON su.id = ps.user_id
+  WHERE ps.post_id = @post_id::bigint
+    AND (su.public_stars OR su.id = @self_id::bigint OR @is_admin::bool)
+),
+Count_CTE AS (
+  SELECT count(*) total FROM Data_CTE
+)
+SELECT dc.user_id, dc.create_at star_at, u.username,
+    u.avatar, u.intro, cnt.total, f.user_id followed
+FROM Data_CTE dc
+CROSS JOIN Count_CTE cnt
+JOIN users u ON u.id = dc.user_id
+LEFT JOIN follows f
+  ON f.user_id = dc.user_id AND f.follower_id = @self_id::bigint
+ORDER BY dc.create_at DESC, dc.user_id DESC
+LIMIT $1 OFFSET $2;
+
+-- name: ListStarredPosts :many
+WITH Data_CTE AS (
+  SELECT ps.post_id, ps.create_at FROM post_stars ps
+  JOIN posts p ON p.id = ps.post_id AND p.status = 'publish'
+  WHERE ps.user_id = @user_id::bigint
+    AND (p.visibility = ANY('{public, password}'::varchar[])
+      OR (p.visibility = 'followers' AND (p.author_id = @self_id::bigint OR p.author_id = ANY(
+        SELECT user_id FROM follows
+        WHERE follower_id = @self_id::bigint
+      ))))
+),
+Count_CTE AS (
+  SELECT count(*) total FROM Data_CTE
+)
+SELECT p.id, p.title, p.cover_image, p.view_count, p.publish_at,
+    p.author_id, u.username, u.avatar, dc.create_at star_at, cnt.total
+FROM Data_CTE dc
+CROSS JOIN Count_CTE cnt
+JOIN posts p ON p.id = dc.post_id
+JOIN users u ON u.id = p.author_id
+ORDER BY dc.create_at DESC, dc.post_id DESC
+LIMIT $1 OFFSET $2;
\ No newline at end of file
@@ -0,0 +1,36 @@
+-- name: ListStaticPosts :many
+WITH Category_CTE AS (
+  SELECT pc.post_id,
+      array_agg(c.id ORDER BY c.id)::bigint[] category_ids,
+      array_agg(c.name ORDER BY c.id)::varchar[] category_names
+  FROM post_categories pc
+  JOIN categories c ON c.id = pc.category_id
+  GROUP BY pc.post_id
+),
+Tag_CTE AS (
+  SELECT pt.post_id,
+      array_agg(t.name ORDER BY t.name)::varchar[] tag_names
+  FROM post_tags pt
+  JOIN tags t ON t.id = pt.tag_id
+  GROUP BY pt.post_id
+)
+SELECT p.id, p.title, p.cover_image, p.author_id,
+    p.publish_at, p.update_at, cc.category_ids,
+    cc.category_names, tc.tag_names
+FROM posts p
+LEFT JOIN Category_CTE cc ON cc.post_id = p.id
+LEFT JOIN Tag_CTE tc ON tc.post_id = p.id
+WHERE p.status = 'publish' AND p.visibility = 'public'
+ORDER BY p.publish_at DESC, p.id DESC;
+
+-- name: ListStaticAuthors :many
+SELECT id, username, avatar, intro FROM users
+WHERE deleted = false AND id = ANY(
+  SELECT author_id FROM posts
+  WHERE status = 'publish' AND visibility = 'public'
+)
+ORDER BY id ASC;
+
+-- name: GetPostContent :one
+SELECT content FROM post_contents
+WHERE id = $1;
\ No newline at end of file
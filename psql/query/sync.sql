@@ -0,0 +1,21 @@
+-- name: ListSyncPostIDs :many
+SELECT id FROM posts
+WHERE (@any_author::bool OR author_id = @author_id::bigint)
+  AND status <> 'trash'
+ORDER BY id ASC;
+
+-- name: UpdateSyncedPost :one
+UPDATE posts
+SET
+  title = $2,
+  cover_image = $3,
+  publish_at = $4,
+  update_at = now(),
+  version = version + 1
+WHERE id = $1 AND version = @version::bigint
+  AND status = ANY('{draft, revise}'::varchar[])
+RETURNING *;
+
+-- name: UpdateSyncedPostContent :exec
+UPDATE post_contents SET content = $2
+WHERE id = $1;
\ No newline at end of file
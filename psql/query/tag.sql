@@ -63,3 +63,8 @@ WHERE id = ANY(
   SELECT tag_id FROM post_tags
   WHERE post_id = $1
 );
+
+-- name: GetTagsByIDs :many
+SELECT * FROM tags
+WHERE id = ANY(@ids::bigint[])
+ORDER BY id ASC;
@@ -14,7 +14,8 @@ SET
   avatar = coalesce(sqlc.narg('avatar'), avatar),
   intro = coalesce(sqlc.narg('intro'), intro),
   role = coalesce(sqlc.narg('role'), role),
-  deleted = coalesce(sqlc.narg('deleted'), deleted)
+  deleted = coalesce(sqlc.narg('deleted'), deleted),
+  public_stars = coalesce(sqlc.narg('public_stars'), public_stars)
 WHERE id = $1
 RETURNING *;
 
@@ -53,7 +54,7 @@ WITH SUM_CTE AS (
     SELECT p.id, p.view_count, count(ps.user_id) star_count
     FROM posts p
     LEFT JOIN post_stars ps ON ps.post_id = p.id
-    WHERE p.author_id = @user_id::bigint
+    WHERE p.author_id = @user_id::bigint AND p.status <> 'trash'
     GROUP BY p.id, p.view_count
   ) pc
 )
@@ -80,3 +81,8 @@ WHERE username = $1 LIMIT 1;
 -- name: GetUserByEmail :one
 SELECT * FROM users
 WHERE email = $1 LIMIT 1;
+
+
+-- name: ListAdminIDs :many
+SELECT id FROM users
+WHERE role = 'admin' AND deleted = false;
\ No newline at end of file
@@ -0,0 +1,70 @@
+-- name: FlushPostViews :execrows
+WITH Viewer_CTE AS (
+  INSERT INTO post_viewers (post_id, visitor, view_at, source)
+  SELECT v.post_id, v.visitor, now(), v.source
+  FROM (
+    SELECT unnest(@post_ids::bigint[]) post_id,
+        unnest(@visitors::varchar[]) visitor,
+        unnest(@sources::varchar[]) source
+  ) v
+  JOIN posts p ON p.id = v.post_id
+  ORDER BY v.post_id, v.visitor
+  ON CONFLICT (post_id, visitor) DO UPDATE
+  SET view_at = now(), source = excluded.source
+  WHERE post_viewers.view_at < @since::timestamptz
+  RETURNING post_id, source
+),
+Count_CTE AS (
+  SELECT post_id, count(*) delta
+  FROM Viewer_CTE
+  GROUP BY post_id
+),
+Daily_CTE AS (
+  INSERT INTO post_daily_views (post_id, day, views)
+  SELECT post_id, current_date, delta FROM Count_CTE
+  ON CONFLICT (post_id, day) DO UPDATE
+  SET views = post_daily_views.views + excluded.views
+  RETURNING post_id
+),
+Source_CTE AS (
+  INSERT INTO post_daily_sources (post_id, day, source, views)
+  SELECT post_id, current_date, source, count(*)
+  FROM Viewer_CTE
+  GROUP BY post_id, source
+  ORDER BY post_id, source
+  ON CONFLICT (post_id, day, source) DO UPDATE
+  SET views = post_daily_sources.views + excluded.views
+),
+Lock_CTE AS (
+  SELECT id FROM posts
+  WHERE id IN (SELECT post_id FROM Daily_CTE)
+  ORDER BY id
+  FOR UPDATE
+)
+UPDATE posts
+SET view_count = view_count + cc.delta
+FROM Count_CTE cc, Lock_CTE lc
+WHERE posts.id = cc.post_id AND lc.id = cc.post_id;
+
+-- name: DeletePostViewers :execrows
+DELETE FROM post_viewers
+WHERE view_at < @before::timestamptz;
+
+-- name: ListPostDailyViews :many
+SELECT day, views FROM post_daily_views
+WHERE post_id = @post_id::bigint
+  AND day >= @start_day::date
+  AND day <= @end_day::date
+ORDER BY day ASC;
+
+-- name: FlushPostReads :exec
+INSERT INTO post_reads (user_id, post_id, read_at)
+SELECT r.user_id, r.post_id, now()
+FROM (
+  SELECT unnest(@user_ids::bigint[]) user_id,
+      unnest(@post_ids::bigint[]) post_id
+) r
+JOIN posts p ON p.id = r.post_id
+JOIN users u ON u.id = r.user_id
+ORDER BY r.user_id, r.post_id
+ON CONFLICT (user_id, post_id) DO UPDATE SET read_at = now();
\ No newline at end of file
@@ -11,7 +11,8 @@ CREATE TABLE "users" (
   "intro" varchar NOT NULL DEFAULT '',
   "role" varchar NOT NULL DEFAULT 'user',
   "deleted" boolean NOT NULL DEFAULT false,
-  "create_at" timestamptz NOT NULL DEFAULT (now())
+  "create_at" timestamptz NOT NULL DEFAULT (now()),
+  "public_stars" boolean NOT NULL DEFAULT true
 );
 
 CREATE TABLE "sessions" (
@@ -50,7 +51,13 @@ CREATE TABLE "posts" (
   "featured" boolean NOT NULL DEFAULT false,
   "view_count" bigint NOT NULL DEFAULT 0,
   "update_at" timestamptz NOT NULL DEFAULT (now()),
-  "publish_at" timestamptz NOT NULL DEFAULT (now())
+  "publish_at" timestamptz NOT NULL DEFAULT (now()),
+  "reviewer_id" bigint,
+  "version" bigint NOT NULL DEFAULT 1,
+  "visibility" varchar NOT NULL DEFAULT 'public',
+  "password_hash" varchar NOT NULL DEFAULT '',
+  "trash_status" varchar NOT NULL DEFAULT '',
+  "delete_at" timestamptz
 );
 
 CREATE TABLE "post_contents" (
@@ -65,6 +72,148 @@ CREATE TABLE "post_stars" (
   PRIMARY KEY ("post_id", "user_id")
 );
 
+CREATE TABLE "post_collaborators" (
+  "post_id" bigint,
+  "user_id" bigint,
+  "role" varchar NOT NULL DEFAULT 'editor',
+  "accepted" boolean NOT NULL DEFAULT false,
+  "create_at" timestamptz NOT NULL DEFAULT (now()),
+  PRIMARY KEY ("post_id", "user_id")
+);
+
+CREATE TABLE "series" (
+  "id" bigserial PRIMARY KEY,
+  "author_id" bigint NOT NULL,
+  "title" varchar NOT NULL,
+  "description" varchar NOT NULL DEFAULT '',
+  "cover_image" varchar NOT NULL,
+  "create_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE TABLE "series_posts" (
+  "series_id" bigint,
+  "post_id" bigint UNIQUE NOT NULL,
+  "position" int NOT NULL,
+  PRIMARY KEY ("series_id", "post_id")
+);
+
+CREATE TABLE "review_notes" (
+  "id" bigserial PRIMARY KEY,
+  "post_id" bigint NOT NULL,
+  "user_id" bigint NOT NULL,
+  "kind" varchar NOT NULL DEFAULT 'note',
+  "content" text NOT NULL,
+  "anchor_start" int,
+  "anchor_end" int,
+  "anchor_text" text NOT NULL DEFAULT '',
+  "create_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE TABLE "post_revisions" (
+  "post_id" bigint PRIMARY KEY,
+  "title" varchar NOT NULL,
+  "cover_image" varchar NOT NULL,
+  "content" text NOT NULL,
+  "category_ids" bigint[] NOT NULL DEFAULT '{}',
+  "tag_ids" bigint[] NOT NULL DEFAULT '{}',
+  "status" varchar NOT NULL DEFAULT 'draft',
+  "reviewer_id" bigint,
+  "version" bigint NOT NULL,
+  "update_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE TABLE "post_viewers" (
+  "post_id" bigint,
+  "visitor" varchar,
+  "view_at" timestamptz NOT NULL DEFAULT (now()),
+  "source" varchar NOT NULL DEFAULT '',
+  PRIMARY KEY ("post_id", "visitor")
+);
+
+CREATE TABLE "post_daily_views" (
+  "post_id" bigint,
+  "day" date,
+  "views" bigint NOT NULL DEFAULT 0,
+  PRIMARY KEY ("post_id", "day")
+);
+
+CREATE TABLE "post_daily_sources" (
+  "post_id" bigint,
+  "day" date,
+  "source" varchar,
+  "views" bigint NOT NULL DEFAULT 0,
+  PRIMARY KEY ("post_id", "day", "source")
+);
+
+CREATE TABLE "post_reads" (
+  "user_id" bigint,
+  "post_id" bigint,
+  "read_at" timestamptz NOT NULL DEFAULT (now()),
+  PRIMARY KEY ("user_id", "post_id")
+);
+
+CREATE TABLE "post_rankings" (
+  "post_id" bigint PRIMARY KEY,
+  "score" float8 NOT NULL DEFAULT 0,
+  "update_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE TABLE "feed_visits" (
+  "user_id" bigint PRIMARY KEY,
+  "visit_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE TABLE "reading_lists" (
+  "id" bigserial PRIMARY KEY,
+  "user_id" bigint NOT NULL,
+  "name" varchar NOT NULL,
+  "position" int NOT NULL,
+  "create_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE TABLE "bookmarks" (
+  "user_id" bigint,
+  "post_id" bigint,
+  "list_id" bigint NOT NULL,
+  "note" varchar NOT NULL DEFAULT '',
+  "create_at" timestamptz NOT NULL DEFAULT (now()),
+  PRIMARY KEY ("user_id", "post_id")
+);
+
+CREATE TABLE "import_records" (
+  "source" varchar,
+  "kind" varchar,
+  "external_id" varchar,
+  "local_id" bigint NOT NULL,
+  "create_at" timestamptz NOT NULL DEFAULT (now()),
+  PRIMARY KEY ("source", "kind", "external_id")
+);
+
+CREATE TABLE "media" (
+  "id" bigserial PRIMARY KEY,
+  "owner_id" bigint NOT NULL,
+  "path" varchar UNIQUE NOT NULL,
+  "size" bigint NOT NULL,
+  "mime_type" varchar NOT NULL,
+  "width" int NOT NULL DEFAULT 0,
+  "height" int NOT NULL DEFAULT 0,
+  "sha256" varchar NOT NULL,
+  "alt_text" varchar NOT NULL DEFAULT '',
+  "create_at" timestamptz NOT NULL DEFAULT (now()),
+  "orphan_at" timestamptz,
+  "variants_pending" boolean NOT NULL DEFAULT false
+);
+
+CREATE TABLE "media_variants" (
+  "id" bigserial PRIMARY KEY,
+  "media_id" bigint NOT NULL,
+  "path" varchar UNIQUE NOT NULL,
+  "size" bigint NOT NULL,
+  "mime_type" varchar NOT NULL,
+  "width" int NOT NULL,
+  "height" int NOT NULL
+);
+
 CREATE TABLE "categories" (
   "id" bigserial PRIMARY KEY,
   "name" varchar UNIQUE NOT NULL
@@ -103,6 +252,27 @@ CREATE TABLE "comment_stars" (
   PRIMARY KEY ("comment_id", "user_id")
 );
 
+CREATE INDEX ON "follows" ("follower_id");
+
+CREATE INDEX ON "posts" ("author_id", "publish_at");
+CREATE INDEX ON "posts" ("status", "delete_at");
+
+CREATE INDEX ON "post_revisions" ("status");
+
+CREATE INDEX ON "post_stars" ("user_id", "create_at");
+
+CREATE INDEX ON "post_viewers" ("view_at");
+
+CREATE INDEX ON "post_rankings" ("score");
+
+CREATE UNIQUE INDEX ON "reading_lists" ("user_id", "name");
+
+CREATE INDEX ON "bookmarks" ("list_id", "create_at");
+
+CREATE INDEX ON "media" ("owner_id", "create_at");
+
+CREATE INDEX ON "media_variants" ("media_id");
+
 ALTER TABLE "sessions" ADD FOREIGN KEY ("user_id") REFERENCES "users" ("id") ON DELETE CASCADE ON UPDATE NO ACTION;
 
 ALTER TABLE "notifications" ADD FOREIGN KEY ("user_id") REFERENCES "users" ("id") ON DELETE CASCADE ON UPDATE NO ACTION;
@@ -113,12 +283,58 @@ ALTER TABLE "follows" ADD FOREIGN KEY ("follower_id") REFERENCES "users" ("id")
 
 ALTER TABLE "posts" ADD FOREIGN KEY ("author_id") REFERENCES "users" ("id") ON DELETE NO ACTION ON UPDATE NO ACTION;
 
+ALTER TABLE "posts" ADD FOREIGN KEY ("reviewer_id") REFERENCES "users" ("id") ON DELETE SET NULL ON UPDATE NO ACTION;
+
+ALTER TABLE "review_notes" ADD FOREIGN KEY ("post_id") REFERENCES "posts" ("id") ON DELETE CASCADE ON UPDATE NO ACTION;
+
+ALTER TABLE "review_notes" ADD FOREIGN KEY ("user_id") REFERENCES "users" ("id") ON DELETE CASCADE ON UPDATE NO ACTION;
+
+ALTER TABLE "post_revisions" ADD FOREIGN KEY ("post_id") REFERENCES "posts" ("id") ON DELETE CASCADE ON UPDATE NO ACTION;
+
+ALTER TABLE "post_revisions" ADD FOREIGN KEY ("reviewer_id") REFERENCES "users" ("id") ON DELETE SET NULL ON UPDATE NO ACTION;
+
+ALTER TABLE "post_viewers" ADD FOREIGN KEY ("post_id") REFERENCES "posts" ("id") ON DELETE CASCADE ON UPDATE NO ACTION;
+
+ALTER TABLE "post_daily_views" ADD FOREIGN KEY ("post_id") REFERENCES "posts" ("id") ON DELETE CASCADE ON UPDATE NO ACTION;
+
+ALTER TABLE "post_daily_sources" ADD FOREIGN KEY ("post_id") REFERENCES "posts" ("id") ON DELETE CASCADE ON UPDATE NO ACTION;
+
+ALTER TABLE "post_reads" ADD FOREIGN KEY ("user_id") REFERENCES "users" ("id") ON DELETE CASCADE ON UPDATE NO ACTION;
+
+ALTER TABLE "post_reads" ADD FOREIGN KEY ("post_id") REFERENCES "posts" ("id") ON DELETE CASCADE ON UPDATE NO ACTION;
+
+ALTER TABLE "post_rankings" ADD FOREIGN KEY ("post_id") REFERENCES "posts" ("id") ON DELETE CASCADE ON UPDATE NO ACTION;
+
+ALTER TABLE "feed_visits" ADD FOREIGN KEY ("user_id") REFERENCES "users" ("id") ON DELETE CASCADE ON UPDATE NO ACTION;
+
+ALTER TABLE "reading_lists" ADD FOREIGN KEY ("user_id") REFERENCES "users" ("id") ON DELETE CASCADE ON UPDATE NO ACTION;
+
+ALTER TABLE "bookmarks" ADD FOREIGN KEY ("user_id") REFERENCES "users" ("id") ON DELETE CASCADE ON UPDATE NO ACTION;
+
+ALTER TABLE "bookmarks" ADD FOREIGN KEY ("post_id") REFERENCES "posts" ("id") ON DELETE CASCADE ON UPDATE NO ACTION;
+
+ALTER TABLE "bookmarks" ADD FOREIGN KEY ("list_id") REFERENCES "reading_lists" ("id") ON DELETE CASCADE ON UPDATE NO ACTION;
+
+ALTER TABLE "media" ADD FOREIGN KEY ("owner_id") REFERENCES "users" ("id") ON DELETE CASCADE ON UPDATE NO ACTION;
+
+ALTER TABLE "media_variants" ADD FOREIGN KEY ("media_id") REFERENCES "media" ("id") ON DELETE CASCADE ON UPDATE NO ACTION;
+
 ALTER TABLE "post_contents" ADD FOREIGN KEY ("id") REFERENCES "posts" ("id") ON DELETE CASCADE ON UPDATE NO ACTION;
 
 ALTER TABLE "post_stars" ADD FOREIGN KEY ("post_id") REFERENCES "posts" ("id") ON DELETE CASCADE ON UPDATE NO ACTION;
 
 ALTER TABLE "post_stars" ADD FOREIGN KEY ("user_id") REFERENCES "users" ("id") ON DELETE CASCADE ON UPDATE NO ACTION;
 
+ALTER TABLE "post_collaborators" ADD FOREIGN KEY ("post_id") REFERENCES "posts" ("id") ON DELETE CASCADE ON UPDATE NO ACTION;
+
+ALTER TABLE "post_collaborators" ADD FOREIGN KEY ("user_id") REFERENCES "users" ("id") ON DELETE CASCADE ON UPDATE NO ACTION;
+
+ALTER TABLE "series" ADD FOREIGN KEY ("author_id") REFERENCES "users" ("id") ON DELETE CASCADE ON UPDATE NO ACTION;
+
+ALTER TABLE "series_posts" ADD FOREIGN KEY ("series_id") REFERENCES "series" ("id") ON DELETE CASCADE ON UPDATE NO ACTION;
+
+ALTER TABLE "series_posts" ADD FOREIGN KEY ("post_id") REFERENCES "posts" ("id") ON DELETE CASCADE ON UPDATE NO ACTION;
+
 ALTER TABLE "post_categories" ADD FOREIGN KEY ("post_id") REFERENCES "posts" ("id") ON DELETE CASCADE ON UPDATE NO ACTION;
 
 ALTER TABLE "post_categories" ADD FOREIGN KEY ("category_id") REFERENCES "categories" ("id") ON DELETE CASCADE ON UPDATE NO ACTION;
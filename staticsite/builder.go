@@ -0,0 +1,546 @@
+package staticsite
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bwen19/blog/psql/db"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+)
+
+const (
+	// File in the output directory that remembers what was built
+	stateFile = ".build.json"
+	// Number of posts on the home page and in feeds
+	latestPosts = 20
+)
+
+type Options struct {
+	// OutDir is the directory the site is written to
+	OutDir string
+	// TemplateDir holds templates that replace the built-in ones
+	TemplateDir string
+	// PublicPath is where uploaded images are read from
+	PublicPath string
+	SiteURL    string
+	SiteTitle  string
+	// Full rebuilds all posts instead of the changed ones
+	Full bool
+}
+
+// Report summarizes a build
+type Report struct {
+	Posts     int `json:"posts"`
+	Rendered  int `json:"rendered"`
+	Written   int `json:"written"`
+	Unchanged int `json:"unchanged"`
+	Removed   int `json:"removed"`
+	Assets    int `json:"assets"`
+}
+
+// buildState is saved after each build, so that the next build only
+// renders posts whose update_at changed and removes pages that are gone
+type buildState struct {
+	Posts map[int64]time.Time `json:"posts"`
+	Pages []string            `json:"pages"`
+}
+
+type siteInfo struct {
+	Title string
+	URL   string
+}
+
+type link struct {
+	Name string
+	Path string
+}
+
+type author struct {
+	ID       int64
+	Username string
+	Avatar   string
+	Intro    string
+	Path     string
+}
+
+type post struct {
+	ID         int64
+	Title      string
+	CoverImage string
+	Path       string
+	Author     *author
+	PublishAt  time.Time
+	UpdateAt   time.Time
+	Categories []link
+	Tags       []link
+	Content    template.HTML
+}
+
+type archiveGroup struct {
+	Label string
+	Posts []*post
+}
+
+// page is the data passed to the templates
+type page struct {
+	Site    siteInfo
+	Title   string
+	Path    string
+	Feed    string
+	Post    *post
+	Posts   []*post
+	Author  *author
+	Archive []archiveGroup
+}
+
+// Builder renders the published posts of the blog to a static site
+type Builder struct {
+	store     db.Store
+	opts      Options
+	site      siteInfo
+	templates map[string]*template.Template
+	markdown  goldmark.Markdown
+
+	state    buildState
+	pages    map[string]bool
+	assets   map[string]bool
+	contents map[int64]template.HTML
+	report   *Report
+}
+
+func NewBuilder(store db.Store, opts Options) (*Builder, error) {
+	templates, err := loadTemplates(opts.TemplateDir)
+	if err != nil {
+		return nil, fmt.Errorf("cannot load templates: %w", err)
+	}
+
+	return &Builder{
+		store: store,
+		opts:  opts,
+		site: siteInfo{
+			Title: opts.SiteTitle,
+			URL:   strings.TrimRight(opts.SiteURL, "/"),
+		},
+		templates: templates,
+		markdown:  goldmark.New(goldmark.WithExtensions(extension.GFM)),
+	}, nil
+}
+
+// Build writes the site to the output directory
+func (b *Builder) Build(ctx context.Context) (*Report, error) {
+	b.pages = map[string]bool{}
+	b.assets = map[string]bool{}
+	b.contents = map[int64]template.HTML{}
+	b.report = &Report{}
+
+	if err := os.MkdirAll(b.opts.OutDir, 0755); err != nil {
+		return nil, err
+	}
+	if err := b.loadState(); err != nil {
+		return nil, err
+	}
+
+	posts, authors, err := b.loadPosts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	b.report.Posts = len(posts)
+
+	next := buildState{Posts: map[int64]time.Time{}}
+	for _, p := range posts {
+		next.Posts[p.ID] = p.UpdateAt
+		if err = b.buildPost(ctx, p); err != nil {
+			return nil, err
+		}
+	}
+
+	if err = b.buildLists(ctx, posts, authors); err != nil {
+		return nil, err
+	}
+	if err = b.buildSitemap(); err != nil {
+		return nil, err
+	}
+	if err = b.copyAssets(); err != nil {
+		return nil, err
+	}
+	if err = b.removeStalePages(); err != nil {
+		return nil, err
+	}
+
+	for name := range b.pages {
+		next.Pages = append(next.Pages, name)
+	}
+	sort.Strings(next.Pages)
+	b.state = next
+	if err = b.saveState(); err != nil {
+		return nil, err
+	}
+	return b.report, nil
+}
+
+func (b *Builder) loadPosts(ctx context.Context) ([]*post, []*author, error) {
+	users, err := b.store.ListStaticAuthors(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	authors := make([]*author, 0, len(users))
+	authorMap := map[int64]*author{}
+	for _, user := range users {
+		a := &author{
+			ID:       user.ID,
+			Username: user.Username,
+			Avatar:   b.asset(user.Avatar),
+			Intro:    user.Intro,
+			Path:     fmt.Sprintf("/user/%d/", user.ID),
+		}
+		authors = append(authors, a)
+		authorMap[a.ID] = a
+	}
+
+	rows, err := b.store.ListStaticPosts(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	posts := make([]*post, 0, len(rows))
+	for _, row := range rows {
+		p := &post{
+			ID:         row.ID,
+			Title:      row.Title,
+			CoverImage: b.asset(row.CoverImage),
+			Path:       fmt.Sprintf("/post/%d/", row.ID),
+			Author:     authorMap[row.AuthorID],
+			PublishAt:  row.PublishAt,
+			UpdateAt:   row.UpdateAt,
+		}
+		for i, id := range row.CategoryIds {
+			p.Categories = append(p.Categories, link{
+				Name: row.CategoryNames[i],
+				Path: fmt.Sprintf("/category/%d/", id),
+			})
+		}
+		for _, name := range row.TagNames {
+			// Tags that cannot be a directory name get no page and no link
+			if !validTagName(name) {
+				continue
+			}
+			p.Tags = append(p.Tags, link{
+				Name: name,
+				Path: "/tag/" + url.PathEscape(name) + "/",
+			})
+		}
+		posts = append(posts, p)
+	}
+	return posts, authors, nil
+}
+
+// buildPost renders the page of a post unless it is unchanged since the
+// last build
+func (b *Builder) buildPost(ctx context.Context, p *post) error {
+	name := pageName(p.Path)
+	if !b.opts.Full {
+		if updateAt, ok := b.state.Posts[p.ID]; ok && updateAt.Equal(p.UpdateAt) {
+			if _, err := os.Stat(filepath.Join(b.opts.OutDir, name)); err == nil {
+				b.pages[name] = true
+				b.report.Unchanged++
+				return nil
+			}
+		}
+	}
+
+	content, err := b.content(ctx, p.ID)
+	if err != nil {
+		return err
+	}
+	p.Content = content
+	b.report.Rendered++
+
+	data := page{
+		Title: p.Title,
+		Path:  p.Path,
+		Post:  p,
+	}
+	return b.render("post.html", data)
+}
+
+// buildLists renders the home, archive, author, category and tag pages
+// together with their feeds
+func (b *Builder) buildLists(ctx context.Context, posts []*post, authors []*author) error {
+	latest := posts
+	if len(latest) > latestPosts {
+		latest = latest[:latestPosts]
+	}
+	if err := b.render("index.html", page{Path: "/", Posts: latest}); err != nil {
+		return err
+	}
+	if err := b.buildFeed(ctx, "/feed.xml", b.site.Title, "/", posts); err != nil {
+		return err
+	}
+
+	archive := []archiveGroup{}
+	for _, p := range posts {
+		label := p.PublishAt.Format("January 2006")
+		if n := len(archive); n == 0 || archive[n-1].Label != label {
+			archive = append(archive, archiveGroup{Label: label})
+		}
+		archive[len(archive)-1].Posts = append(archive[len(archive)-1].Posts, p)
+	}
+	if err := b.render("archive.html", page{Title: "Archive", Path: "/archive/", Archive: archive}); err != nil {
+		return err
+	}
+
+	for _, a := range authors {
+		list := filterPosts(posts, func(p *post) bool { return p.Author == a })
+		if err := b.buildList(ctx, a.Username, a.Path, list, a); err != nil {
+			return err
+		}
+	}
+
+	categories := map[string]link{}
+	tags := map[string]link{}
+	for _, p := range posts {
+		for _, c := range p.Categories {
+			categories[c.Path] = c
+		}
+		for _, t := range p.Tags {
+			tags[t.Path] = t
+		}
+	}
+
+	for _, c := range categories {
+		list := filterPosts(posts, func(p *post) bool { return hasLink(p.Categories, c.Path) })
+		if err := b.buildList(ctx, c.Name, c.Path, list, nil); err != nil {
+			return err
+		}
+	}
+	for _, t := range tags {
+		list := filterPosts(posts, func(p *post) bool { return hasLink(p.Tags, t.Path) })
+		if err := b.buildList(ctx, "#"+t.Name, t.Path, list, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *Builder) buildList(ctx context.Context, title string, pagePath string, posts []*post, a *author) error {
+	data := page{
+		Title:  title,
+		Path:   pagePath,
+		Feed:   pagePath + "feed.xml",
+		Posts:  posts,
+		Author: a,
+	}
+	if err := b.render("list.html", data); err != nil {
+		return err
+	}
+	return b.buildFeed(ctx, data.Feed, title+" - "+b.site.Title, pagePath, posts)
+}
+
+// content returns the HTML of a post, images of the post are collected to
+// be copied to the output directory
+func (b *Builder) content(ctx context.Context, postID int64) (template.HTML, error) {
+	if content, ok := b.contents[postID]; ok {
+		return content, nil
+	}
+
+	source, err := b.store.GetPostContent(ctx, postID)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err = b.markdown.Convert([]byte(source), &buf); err != nil {
+		return "", err
+	}
+	for _, match := range localSrc.FindAllStringSubmatch(buf.String(), -1) {
+		b.asset(match[1])
+	}
+
+	content := template.HTML(buf.String())
+	b.contents[postID] = content
+	return content, nil
+}
+
+func (b *Builder) render(name string, data page) error {
+	data.Site = b.site
+
+	var buf bytes.Buffer
+	if err := b.templates[name].ExecuteTemplate(&buf, "layout", data); err != nil {
+		return fmt.Errorf("cannot render %s: %w", data.Path, err)
+	}
+	return b.writePage(pageName(data.Path), buf.Bytes())
+}
+
+// writePage writes a file of the site, files that are already up to date
+// are not touched
+func (b *Builder) writePage(name string, data []byte) error {
+	b.pages[name] = true
+	fileName := filepath.Join(b.opts.OutDir, filepath.FromSlash(name))
+
+	if old, err := os.ReadFile(fileName); err == nil && bytes.Equal(old, data) {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(fileName), 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(fileName, data, 0644); err != nil {
+		return err
+	}
+	b.report.Written++
+	return nil
+}
+
+// removeStalePages deletes the pages of the last build that were not built
+// this time, like those of unpublished posts
+func (b *Builder) removeStalePages() error {
+	for _, name := range b.state.Pages {
+		if b.pages[name] {
+			continue
+		}
+
+		fileName := filepath.Join(b.opts.OutDir, filepath.FromSlash(name))
+		if err := os.Remove(fileName); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		b.report.Removed++
+
+		// Remove directories left empty, up to the output directory
+		for dir := filepath.Dir(fileName); dir != filepath.Clean(b.opts.OutDir); dir = filepath.Dir(dir) {
+			if os.Remove(dir) != nil {
+				break
+			}
+		}
+	}
+	return nil
+}
+
+var localSrc = regexp.MustCompile(`src="(/[^/"][^"]*)"`)
+
+// asset registers a file of PUBLIC_PATH to be copied and returns its path
+func (b *Builder) asset(src string) string {
+	if strings.HasPrefix(src, "/") && !strings.HasPrefix(src, "//") {
+		b.assets[path.Clean(src)] = true
+	}
+	return src
+}
+
+// copyAssets copies the referenced files that are new or changed
+func (b *Builder) copyAssets() error {
+	for src := range b.assets {
+		from := filepath.Join(b.opts.PublicPath, filepath.FromSlash(src))
+		info, err := os.Stat(from)
+		if err != nil || info.IsDir() {
+			continue
+		}
+
+		to := filepath.Join(b.opts.OutDir, filepath.FromSlash(src))
+		if old, err := os.Stat(to); err == nil && old.Size() == info.Size() && old.ModTime().Equal(info.ModTime()) {
+			continue
+		}
+
+		if err = copyFile(from, to); err != nil {
+			return err
+		}
+		if err = os.Chtimes(to, info.ModTime(), info.ModTime()); err != nil {
+			return err
+		}
+		b.report.Assets++
+	}
+	return nil
+}
+
+func copyFile(from string, to string) error {
+	src, err := os.Open(from)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	if err = os.MkdirAll(filepath.Dir(to), 0755); err != nil {
+		return err
+	}
+	dst, err := os.Create(to)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(dst, src); err != nil {
+		dst.Close()
+		return err
+	}
+	return dst.Close()
+}
+
+func (b *Builder) loadState() error {
+	b.state = buildState{Posts: map[int64]time.Time{}}
+
+	data, err := os.ReadFile(filepath.Join(b.opts.OutDir, stateFile))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if err = json.Unmarshal(data, &b.state); err != nil {
+		return fmt.Errorf("invalid %s: %w", stateFile, err)
+	}
+	return nil
+}
+
+func (b *Builder) saveState() error {
+	data, err := json.MarshalIndent(b.state, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(b.opts.OutDir, stateFile), data, 0644)
+}
+
+// pageName maps the path of a page to its file in the output directory
+func pageName(pagePath string) string {
+	name := strings.TrimPrefix(pagePath, "/")
+	if name == "" || strings.HasSuffix(name, "/") {
+		name += "index.html"
+	}
+	if unescaped, err := url.PathUnescape(name); err == nil {
+		name = unescaped
+	}
+	return name
+}
+
+// validTagName reports whether a tag name can be the directory of its page
+func validTagName(name string) bool {
+	return name != "" && name != "." && name != ".." && !strings.Contains(name, "/")
+}
+
+func filterPosts(posts []*post, keep func(*post) bool) []*post {
+	result := []*post{}
+	for _, p := range posts {
+		if keep(p) {
+			result = append(result, p)
+		}
+	}
+	return result
+}
+
+func hasLink(links []link, linkPath string) bool {
+	for _, l := range links {
+		if l.Path == linkPath {
+			return true
+		}
+	}
+	return false
+}
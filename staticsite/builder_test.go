@@ -0,0 +1,160 @@
+package staticsite
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bwen19/blog/psql/db"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeStore serves the queries of a build from memory
+type fakeStore struct {
+	db.Store
+	authors  []db.ListStaticAuthorsRow
+	posts    []db.ListStaticPostsRow
+	contents map[int64]string
+}
+
+func (s *fakeStore) ListStaticAuthors(ctx context.Context) ([]db.ListStaticAuthorsRow, error) {
+	return s.authors, nil
+}
+
+func (s *fakeStore) ListStaticPosts(ctx context.Context) ([]db.ListStaticPostsRow, error) {
+	return s.posts, nil
+}
+
+func (s *fakeStore) GetPostContent(ctx context.Context, id int64) (string, error) {
+	return s.contents[id], nil
+}
+
+func newTestBuilder(t *testing.T, store *fakeStore) (*Builder, string) {
+	publicPath := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(publicPath, "image", "post"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(publicPath, "image", "post", "cover.jpg"), []byte("jpg"), 0644))
+
+	outDir := t.TempDir()
+	builder, err := NewBuilder(store, Options{
+		OutDir:     outDir,
+		PublicPath: publicPath,
+		SiteURL:    "https://example.com/",
+		SiteTitle:  "Test Blog",
+	})
+	require.NoError(t, err)
+	return builder, outDir
+}
+
+func newTestStore() *fakeStore {
+	publishAt := time.Date(2022, 5, 1, 8, 0, 0, 0, time.UTC)
+	return &fakeStore{
+		authors: []db.ListStaticAuthorsRow{
+			{ID: 1, Username: "alice", Avatar: "/image/avatar/default", Intro: "Hi"},
+		},
+		posts: []db.ListStaticPostsRow{
+			{
+				ID:            2,
+				Title:         "Second",
+				CoverImage:    "/image/post/cover.jpg",
+				AuthorID:      1,
+				PublishAt:     publishAt.Add(time.Hour),
+				UpdateAt:      publishAt.Add(time.Hour),
+				CategoryIds:   []int64{3},
+				CategoryNames: []string{"Go"},
+				TagNames:      []string{"a/b", "..", "grpc"},
+			},
+			{
+				ID:        1,
+				Title:     "First",
+				AuthorID:  1,
+				PublishAt: publishAt,
+				UpdateAt:  publishAt,
+				TagNames:  []string{"grpc"},
+			},
+		},
+		contents: map[int64]string{
+			1: "# First\n\nHello",
+			2: "# Second\n\n![cover](/image/post/cover.jpg)",
+		},
+	}
+}
+
+func TestBuild(t *testing.T) {
+	store := newTestStore()
+	builder, outDir := newTestBuilder(t, store)
+
+	report, err := builder.Build(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 2, report.Posts)
+	require.Equal(t, 2, report.Rendered)
+	require.Equal(t, 1, report.Assets)
+
+	for _, name := range []string{
+		"index.html",
+		"feed.xml",
+		"sitemap.xml",
+		"archive/index.html",
+		"post/1/index.html",
+		"post/2/index.html",
+		"user/1/index.html",
+		"category/3/index.html",
+		"tag/grpc/index.html",
+		"tag/grpc/feed.xml",
+		"image/post/cover.jpg",
+	} {
+		require.FileExists(t, filepath.Join(outDir, filepath.FromSlash(name)))
+	}
+
+	// Tags that cannot be a directory get neither a page nor a link
+	require.NoDirExists(t, filepath.Join(outDir, "tag", "a"))
+	page, err := os.ReadFile(filepath.Join(outDir, "post", "2", "index.html"))
+	require.NoError(t, err)
+	require.Contains(t, string(page), `href="/tag/grpc/"`)
+	require.NotContains(t, string(page), "/tag/a%2Fb/")
+	require.NotContains(t, string(page), "/tag/../")
+
+	// Unchanged posts are not rendered again
+	report, err = builder.Build(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 0, report.Rendered)
+	require.Equal(t, 2, report.Unchanged)
+	require.Equal(t, 0, report.Written)
+
+	// Pages of unpublished posts and their tags are removed
+	store.posts = store.posts[:1]
+	store.posts[0].TagNames = nil
+	report, err = builder.Build(context.Background())
+	require.NoError(t, err)
+	require.Positive(t, report.Removed)
+	require.NoFileExists(t, filepath.Join(outDir, "post", "1", "index.html"))
+	require.NoDirExists(t, filepath.Join(outDir, "tag"))
+}
+
+func TestValidTagName(t *testing.T) {
+	testCases := map[string]bool{
+		"grpc":   true,
+		"c++":    true,
+		"a b":    true,
+		"":       false,
+		".":      false,
+		"..":     false,
+		"a/b":    false,
+		"/":      false,
+		"...":    true,
+		"中文":     true,
+		"v1.2.3": true,
+	}
+	for name, valid := range testCases {
+		require.Equal(t, valid, validTagName(name), name)
+	}
+}
+
+func TestPageName(t *testing.T) {
+	require.Equal(t, "index.html", pageName("/"))
+	require.Equal(t, "post/1/index.html", pageName("/post/1/"))
+	require.Equal(t, "feed.xml", pageName("/feed.xml"))
+	require.Equal(t, "tag/c++/index.html", pageName("/tag/c++/"))
+	require.Equal(t, "tag/a b/index.html", pageName("/tag/a%20b/"))
+}
@@ -0,0 +1,171 @@
+package staticsite
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+type rssItem struct {
+	Title       string   `xml:"title"`
+	Link        string   `xml:"link"`
+	GUID        string   `xml:"guid"`
+	PubDate     string   `xml:"pubDate"`
+	Categories  []string `xml:"category"`
+	Description string   `xml:"description"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	LastBuildDate string    `xml:"lastBuildDate,omitempty"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+// buildFeed writes an RSS feed of the latest posts of a list
+func (b *Builder) buildFeed(ctx context.Context, feedPath string, title string, pagePath string, posts []*post) error {
+	if len(posts) > latestPosts {
+		posts = posts[:latestPosts]
+	}
+
+	channel := rssChannel{
+		Title:       title,
+		Link:        b.site.URL + pagePath,
+		Description: title,
+	}
+	var lastmod time.Time
+	for _, p := range posts {
+		content, err := b.content(ctx, p.ID)
+		if err != nil {
+			return err
+		}
+
+		item := rssItem{
+			Title:       p.Title,
+			Link:        b.site.URL + p.Path,
+			GUID:        b.site.URL + p.Path,
+			PubDate:     p.PublishAt.UTC().Format(time.RFC1123Z),
+			Description: string(content),
+		}
+		for _, c := range p.Categories {
+			item.Categories = append(item.Categories, c.Name)
+		}
+		for _, t := range p.Tags {
+			item.Categories = append(item.Categories, t.Name)
+		}
+		channel.Items = append(channel.Items, item)
+
+		if p.UpdateAt.After(lastmod) {
+			lastmod = p.UpdateAt
+		}
+	}
+
+	// The build date follows the posts, so that the feed is only rewritten
+	// when one of its posts changed
+	if !lastmod.IsZero() {
+		channel.LastBuildDate = lastmod.UTC().Format(time.RFC1123Z)
+	}
+
+	data, err := marshalXML(&rssFeed{Version: "2.0", Channel: channel})
+	if err != nil {
+		return err
+	}
+	return b.writePage(pageName(feedPath), data)
+}
+
+// Maximum number of urls allowed in a single sitemap file
+const sitemapMaxURLs = 50000
+
+const sitemapXMLNS = "http://www.sitemaps.org/schemas/sitemap/0.9"
+
+type sitemapURL struct {
+	Loc string `xml:"loc"`
+}
+
+type sitemapURLSet struct {
+	XMLName xml.Name     `xml:"urlset"`
+	XMLNS   string       `xml:"xmlns,attr"`
+	URLs    []sitemapURL `xml:"url"`
+}
+
+type sitemapIndex struct {
+	XMLName  xml.Name     `xml:"sitemapindex"`
+	XMLNS    string       `xml:"xmlns,attr"`
+	Sitemaps []sitemapURL `xml:"sitemap"`
+}
+
+// buildSitemap lists the HTML pages of the site in sitemap.xml, large sites
+// get a sitemap index and sitemaps/{n}.xml files
+func (b *Builder) buildSitemap() error {
+	urls := []sitemapURL{}
+	for name := range b.pages {
+		if pagePath, ok := htmlPagePath(name); ok {
+			urls = append(urls, sitemapURL{Loc: b.site.URL + pagePath})
+		}
+	}
+	sort.Slice(urls, func(i, j int) bool { return urls[i].Loc < urls[j].Loc })
+
+	if len(urls) <= sitemapMaxURLs {
+		data, err := marshalXML(&sitemapURLSet{XMLNS: sitemapXMLNS, URLs: urls})
+		if err != nil {
+			return err
+		}
+		return b.writePage("sitemap.xml", data)
+	}
+
+	index := &sitemapIndex{XMLNS: sitemapXMLNS}
+	for start, n := 0, 1; start < len(urls); start, n = start+sitemapMaxURLs, n+1 {
+		end := start + sitemapMaxURLs
+		if end > len(urls) {
+			end = len(urls)
+		}
+
+		data, err := marshalXML(&sitemapURLSet{XMLNS: sitemapXMLNS, URLs: urls[start:end]})
+		if err != nil {
+			return err
+		}
+		name := fmt.Sprintf("sitemaps/%d.xml", n)
+		if err = b.writePage(name, data); err != nil {
+			return err
+		}
+		index.Sitemaps = append(index.Sitemaps, sitemapURL{Loc: b.site.URL + "/" + name})
+	}
+
+	data, err := marshalXML(index)
+	if err != nil {
+		return err
+	}
+	return b.writePage("sitemap.xml", data)
+}
+
+// htmlPagePath maps an index.html file of the site back to its page path
+func htmlPagePath(name string) (string, bool) {
+	if name != "index.html" && !strings.HasSuffix(name, "/index.html") {
+		return "", false
+	}
+
+	segments := strings.Split(strings.TrimSuffix(name, "index.html"), "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return "/" + strings.Join(segments, "/"), true
+}
+
+func marshalXML(v interface{}) ([]byte, error) {
+	data, err := xml.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), data...), nil
+}
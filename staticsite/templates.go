@@ -0,0 +1,67 @@
+package staticsite
+
+import (
+	"embed"
+	"errors"
+	"html/template"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+//go:embed templates/*.html
+var defaultTemplates embed.FS
+
+// Page templates, each one is rendered within layout.html
+var pageTemplates = []string{"index.html", "post.html", "list.html", "archive.html"}
+
+var templateFuncs = template.FuncMap{
+	"date": func(t time.Time) string { return t.Format("Jan 2, 2006") },
+	"iso":  func(t time.Time) string { return t.UTC().Format(time.RFC3339) },
+}
+
+// loadTemplates parses the built-in templates. A template of the same name
+// in dir replaces the built-in one.
+func loadTemplates(dir string) (map[string]*template.Template, error) {
+	layout, err := readTemplate(dir, "layout.html")
+	if err != nil {
+		return nil, err
+	}
+	base, err := template.New("layout.html").Funcs(templateFuncs).Parse(layout)
+	if err != nil {
+		return nil, err
+	}
+
+	templates := map[string]*template.Template{}
+	for _, name := range pageTemplates {
+		text, err := readTemplate(dir, name)
+		if err != nil {
+			return nil, err
+		}
+
+		tmpl, err := base.Clone()
+		if err != nil {
+			return nil, err
+		}
+		if _, err = tmpl.New(name).Parse(text); err != nil {
+			return nil, err
+		}
+		templates[name] = tmpl
+	}
+	return templates, nil
+}
+
+func readTemplate(dir string, name string) (string, error) {
+	if dir != "" {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err == nil {
+			return string(data), nil
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			return "", err
+		}
+	}
+
+	data, err := defaultTemplates.ReadFile("templates/" + name)
+	return string(data), err
+}
@@ -1,6 +1,7 @@
 package util
 
 import (
+	"fmt"
 	"time"
 
 	"github.com/spf13/viper"
@@ -20,6 +21,26 @@ type Config struct {
 	TokenSymmetricKey    string        `mapstructure:"TOKEN_SYMMETRIC_KEY"`
 	AccessTokenDuration  time.Duration `mapstructure:"ACCESS_TOKEN_DURATION"`
 	RefreshTokenDuration time.Duration `mapstructure:"REFRESH_TOKEN_DURATION"`
+	SiteURL              string        `mapstructure:"SITE_URL"`
+	SiteTitle            string        `mapstructure:"SITE_TITLE"`
+	RobotsPath           string        `mapstructure:"ROBOTS_PATH"`
+	SitemapCacheDuration time.Duration `mapstructure:"SITEMAP_CACHE_DURATION"`
+	ViewDedupWindow      time.Duration `mapstructure:"VIEW_DEDUP_WINDOW"`
+	ViewFlushInterval    time.Duration `mapstructure:"VIEW_FLUSH_INTERVAL"`
+	ViewBufferSize       int           `mapstructure:"VIEW_BUFFER_SIZE"`
+	RelatedCacheDuration time.Duration `mapstructure:"RELATED_CACHE_DURATION"`
+	RelatedReadWindow    time.Duration `mapstructure:"RELATED_READ_WINDOW"`
+	RelatedTextMatch     bool          `mapstructure:"RELATED_TEXT_MATCH"`
+	HotRefreshInterval   time.Duration `mapstructure:"HOT_REFRESH_INTERVAL"`
+	HotHalfLife          time.Duration `mapstructure:"HOT_HALF_LIFE"`
+	HotWindow            time.Duration `mapstructure:"HOT_WINDOW"`
+	HotViewWeight        float64       `mapstructure:"HOT_VIEW_WEIGHT"`
+	HotStarWeight        float64       `mapstructure:"HOT_STAR_WEIGHT"`
+	HotCommentWeight     float64       `mapstructure:"HOT_COMMENT_WEIGHT"`
+	TrashRetention       time.Duration `mapstructure:"TRASH_RETENTION"`
+	TrashPurgeInterval   time.Duration `mapstructure:"TRASH_PURGE_INTERVAL"`
+	MediaSweepInterval   time.Duration `mapstructure:"MEDIA_SWEEP_INTERVAL"`
+	MediaOrphanGrace     time.Duration `mapstructure:"MEDIA_ORPHAN_GRACE"`
 }
 
 func LoadConfig(path string) (config Config, err error) {
@@ -35,5 +56,42 @@ func LoadConfig(path string) (config Config, err error) {
 	}
 
 	err = viper.Unmarshal(&config)
+	if err != nil {
+		return
+	}
+
+	err = config.validate()
 	return
 }
+
+// validate rejects settings that would break the background jobs at runtime,
+// such as tickers with a zero interval
+func (config Config) validate() error {
+	durations := []struct {
+		key   string
+		value time.Duration
+	}{
+		{"VIEW_DEDUP_WINDOW", config.ViewDedupWindow},
+		{"VIEW_FLUSH_INTERVAL", config.ViewFlushInterval},
+		{"HOT_REFRESH_INTERVAL", config.HotRefreshInterval},
+		{"HOT_HALF_LIFE", config.HotHalfLife},
+		{"HOT_WINDOW", config.HotWindow},
+		{"RELATED_CACHE_DURATION", config.RelatedCacheDuration},
+		{"RELATED_READ_WINDOW", config.RelatedReadWindow},
+		{"SITEMAP_CACHE_DURATION", config.SitemapCacheDuration},
+		{"TRASH_RETENTION", config.TrashRetention},
+		{"TRASH_PURGE_INTERVAL", config.TrashPurgeInterval},
+		{"MEDIA_SWEEP_INTERVAL", config.MediaSweepInterval},
+		{"MEDIA_ORPHAN_GRACE", config.MediaOrphanGrace},
+	}
+	for _, d := range durations {
+		if d.value <= 0 {
+			return fmt.Errorf("%s must be positive, got %s", d.key, d.value)
+		}
+	}
+
+	if config.ViewBufferSize <= 0 {
+		return fmt.Errorf("VIEW_BUFFER_SIZE must be positive, got %d", config.ViewBufferSize)
+	}
+	return nil
+}
@@ -0,0 +1,31 @@
+package util
+
+import "strings"
+
+// Substrings found in the user agents of well-known crawlers and bots
+var crawlerSignatures = []string{
+	"bot",
+	"crawl",
+	"spider",
+	"slurp",
+	"facebookexternalhit",
+	"embedly",
+	"quora link preview",
+	"whatsapp",
+	"headlesschrome",
+	"lighthouse",
+	"python-requests",
+	"curl/",
+	"wget/",
+}
+
+// IsCrawler reports whether the user agent belongs to a known crawler
+func IsCrawler(userAgent string) bool {
+	ua := strings.ToLower(userAgent)
+	for _, signature := range crawlerSignatures {
+		if strings.Contains(ua, signature) {
+			return true
+		}
+	}
+	return false
+}